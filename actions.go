@@ -18,9 +18,20 @@ package apcore
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/go-fed/activity/pub"
 	"github.com/go-fed/apcore/app"
 	"github.com/go-fed/apcore/framework"
+	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/paths"
 	"github.com/go-fed/apcore/services"
 	"github.com/go-fed/apcore/util"
 )
@@ -41,6 +52,9 @@ func doCreateTables(configFilePath string, a app.Application, debug bool, scheme
 			return err
 		}
 	}
+	if err := models.MigrateTables(tx, d, ms); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
@@ -119,3 +133,686 @@ func doInitServerProfile(configFilePath string, a app.Application, debug bool, s
 	}
 	return tx.Commit()
 }
+
+func doListPeers(configFilePath string, a app.Application, debug bool, scheme string) error {
+	db, peers, err := newPeerService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ps, err := peers.AllPeers(util.Context{context.Background()})
+	if err != nil {
+		return err
+	}
+	fmtTime := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tSOFTWARE\tVERSION\tFIRST SEEN\tLAST SEEN\tDELIVERED\tFAILED\tLAST FAILURE")
+	for _, p := range ps {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			p.Host,
+			p.SoftwareName,
+			p.SoftwareVersion,
+			fmtTime(p.FirstSeen),
+			fmtTime(p.LastSeen),
+			p.DeliverySuccesses,
+			p.DeliveryFailures,
+			fmtTime(p.LastDeliveryFailure))
+	}
+	return w.Flush()
+}
+
+func doSuspendUser(configFilePath string, a app.Application, debug bool, scheme string, username string) error {
+	db, users, _, err := newUserService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	u, err := userByUsername(c, users, username)
+	if err != nil {
+		return err
+	}
+	if err := users.SuspendUser(c, paths.UUID(u.ID)); err != nil {
+		return err
+	}
+	util.InfoLogger.Infof("Suspended user %s", username)
+	return nil
+}
+
+func doUnsuspendUser(configFilePath string, a app.Application, debug bool, scheme string, username string) error {
+	db, users, _, err := newUserService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	u, err := userByUsername(c, users, username)
+	if err != nil {
+		return err
+	}
+	if err := users.UnsuspendUser(c, paths.UUID(u.ID)); err != nil {
+		return err
+	}
+	util.InfoLogger.Infof("Unsuspended user %s", username)
+	return nil
+}
+
+func doDeleteUser(configFilePath string, a app.Application, debug bool, scheme string, username string) error {
+	c := util.Context{context.Background()}
+	u, err := lookupUser(c, configFilePath, a, debug, scheme, username)
+	if err != nil {
+		return err
+	}
+	// The full server is built, but not started, so that the deletion is
+	// federated.
+	_, fw, closeFn, err := newServerAndFramework(configFilePath, a, debug)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+	if err := fw.DeleteUser(c, paths.UUID(u.ID)); err != nil {
+		return err
+	}
+	util.InfoLogger.Infof("Deleted user %s", username)
+	return nil
+}
+
+// lookupUser finds a user with a short-lived user service, so that its
+// database connections are released before the full server is built.
+func lookupUser(c util.Context, configFilePath string, a app.Application, debug bool, scheme string, username string) (*services.User, error) {
+	db, users, _, err := newUserService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return userByUsername(c, users, username)
+}
+
+func userByUsername(c util.Context, users *services.Users, username string) (*services.User, error) {
+	if len(username) == 0 {
+		return nil, fmt.Errorf("the username flag is required")
+	}
+	u, err := users.UserByUsername(c, username)
+	if err != nil {
+		return nil, err
+	} else if u == nil {
+		return nil, fmt.Errorf("no user with username %q", username)
+	} else if !u.Deleted.IsZero() {
+		return nil, fmt.Errorf("user %q is deleted", username)
+	}
+	return u, nil
+}
+
+// listResolutionsCount is the number of most recent resolutions listed by the
+// list-resolutions action.
+const listResolutionsCount = 50
+
+func doListPolicies(configFilePath string, a app.Application, debug bool, scheme string, instance bool, username string) error {
+	db, users, policies, err := newPolicyService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	actor, err := policyOwnerIRI(c, users, policies, instance, username)
+	if err != nil {
+		return err
+	}
+	ps, err := policies.ForActor(c, actor)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPURPOSE\tENABLED\tMONITOR\tNAME\tDESCRIPTION")
+	for _, p := range ps {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%t\t%s\t%s\n",
+			p.ID,
+			p.Purpose,
+			p.Enabled,
+			p.Monitor,
+			p.Policy.Name,
+			p.Policy.Description)
+	}
+	return w.Flush()
+}
+
+func doCreatePolicy(configFilePath string, a app.Application, debug bool, scheme string, instance bool, username, purpose, policyFile string) error {
+	po, err := readPolicyFile(policyFile)
+	if err != nil {
+		return err
+	}
+	db, users, policies, err := newPolicyService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	actor, err := policyOwnerIRI(c, users, policies, instance, username)
+	if err != nil {
+		return err
+	}
+	id, err := policies.Create(c, actor, models.Purpose(purpose), po, true, false)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, id)
+	return nil
+}
+
+func doUpdatePolicy(configFilePath string, a app.Application, debug bool, scheme string, instance bool, username, policyID, policyFile string) error {
+	po, err := readPolicyFile(policyFile)
+	if err != nil {
+		return err
+	}
+	db, users, policies, err := newPolicyService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	actor, err := policyOwnerIRI(c, users, policies, instance, username)
+	if err != nil {
+		return err
+	} else if err := requirePolicyID(policyID); err != nil {
+		return err
+	}
+	if err := policies.Update(c, actor, policyID, po); err != nil {
+		return err
+	}
+	util.InfoLogger.Infof("Updated policy %s", policyID)
+	return nil
+}
+
+func doDeletePolicy(configFilePath string, a app.Application, debug bool, scheme string, instance bool, username, policyID string) error {
+	db, users, policies, err := newPolicyService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	actor, err := policyOwnerIRI(c, users, policies, instance, username)
+	if err != nil {
+		return err
+	} else if err := requirePolicyID(policyID); err != nil {
+		return err
+	}
+	if err := policies.Delete(c, actor, policyID); err != nil {
+		return err
+	}
+	util.InfoLogger.Infof("Deleted policy %s", policyID)
+	return nil
+}
+
+func doSetPolicyEnabled(configFilePath string, a app.Application, debug bool, scheme string, instance bool, username, policyID string, enabled bool) error {
+	db, users, policies, err := newPolicyService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	actor, err := policyOwnerIRI(c, users, policies, instance, username)
+	if err != nil {
+		return err
+	} else if err := requirePolicyID(policyID); err != nil {
+		return err
+	}
+	if err := policies.SetEnabled(c, actor, policyID, enabled); err != nil {
+		return err
+	}
+	if enabled {
+		util.InfoLogger.Infof("Enabled policy %s", policyID)
+	} else {
+		util.InfoLogger.Infof("Disabled policy %s", policyID)
+	}
+	return nil
+}
+
+func doImportPolicies(configFilePath string, a app.Application, debug bool, scheme string, instance bool, username, policyFile string) error {
+	if len(policyFile) == 0 {
+		return fmt.Errorf("the policy_file flag is required")
+	}
+	b, err := ioutil.ReadFile(policyFile)
+	if err != nil {
+		return err
+	}
+	pd, err := models.ParsePolicies(string(b))
+	if err != nil {
+		return err
+	}
+	db, users, policies, err := newPolicyService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	actor, err := policyOwnerIRI(c, users, policies, instance, username)
+	if err != nil {
+		return err
+	}
+	created, updated, err := policies.Import(c, actor, pd)
+	if err != nil {
+		return err
+	}
+	util.InfoLogger.Infof("Created %d and updated %d policies", created, updated)
+	return nil
+}
+
+func doExportPolicies(configFilePath string, a app.Application, debug bool, scheme string, instance bool, username string) error {
+	db, users, policies, err := newPolicyService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	actor, err := policyOwnerIRI(c, users, policies, instance, username)
+	if err != nil {
+		return err
+	}
+	ps, err := policies.ForActor(c, actor)
+	if err != nil {
+		return err
+	}
+	pd := make([]models.PolicyDefinition, len(ps))
+	for i, p := range ps {
+		pd[i] = models.PolicyDefinition{
+			Purpose: p.Purpose,
+			Enabled: p.Enabled,
+			Monitor: p.Monitor,
+			Policy:  p.Policy,
+		}
+	}
+	_, err = fmt.Fprint(os.Stdout, models.FormatPolicies(pd))
+	return err
+}
+
+func doSetPolicyMonitor(configFilePath string, a app.Application, debug bool, scheme string, instance bool, username, policyID string, monitor bool) error {
+	db, users, policies, err := newPolicyService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	actor, err := policyOwnerIRI(c, users, policies, instance, username)
+	if err != nil {
+		return err
+	} else if err := requirePolicyID(policyID); err != nil {
+		return err
+	}
+	if err := policies.SetMonitor(c, actor, policyID, monitor); err != nil {
+		return err
+	}
+	if monitor {
+		util.InfoLogger.Infof("Monitoring policy %s", policyID)
+	} else {
+		util.InfoLogger.Infof("Enforcing policy %s", policyID)
+	}
+	return nil
+}
+
+func doSimulatePolicy(configFilePath string, a app.Application, debug bool, scheme string, purpose, policyFile string, sample int) error {
+	po, err := readPolicyFile(policyFile)
+	if err != nil {
+		return err
+	} else if sample <= 0 {
+		return fmt.Errorf("the sample flag must be positive")
+	}
+	db, _, policies, err := newPolicyService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rs, err := policies.Simulate(util.Context{context.Background()}, models.Purpose(purpose), po, sample)
+	if err != nil {
+		return err
+	}
+	matched := 0
+	for _, r := range rs {
+		if !r.Matched {
+			continue
+		}
+		matched++
+		fmt.Fprintf(os.Stdout, "%s\n", r.IRI)
+		for _, l := range r.MatchLog {
+			fmt.Fprintf(os.Stdout, "  %s\n", l)
+		}
+	}
+	fmt.Fprintf(os.Stdout, "%d of %d matched\n", matched, len(rs))
+	return nil
+}
+
+func doListResolutions(configFilePath string, a app.Application, debug bool, scheme string, instance bool, username, policyID string) error {
+	db, users, policies, err := newPolicyService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	actor, err := policyOwnerIRI(c, users, policies, instance, username)
+	if err != nil {
+		return err
+	} else if err := requirePolicyID(policyID); err != nil {
+		return err
+	}
+	rs, _, err := policies.ResolutionsFor(c, actor, policyID, 0, listResolutionsCount)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tSCOPE\tMATCHED\tIRI")
+	for _, r := range rs {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\n",
+			r.Time.Format(time.RFC3339),
+			r.Scope,
+			r.Matched,
+			r.IRI)
+	}
+	return w.Flush()
+}
+
+func actorIRIByUsername(c util.Context, users *services.Users, username string) (*url.URL, error) {
+	u, err := userByUsername(c, users, username)
+	if err != nil {
+		return nil, err
+	}
+	return pub.GetId(u.Actor)
+}
+
+// policyOwnerIRI returns the IRI of the instance actor when managing
+// instance-wide policies, or of the user with the username otherwise.
+func policyOwnerIRI(c util.Context, users *services.Users, policies *services.Policies, instance bool, username string) (*url.URL, error) {
+	if !instance {
+		return actorIRIByUsername(c, users, username)
+	} else if len(username) > 0 {
+		return nil, fmt.Errorf("the instance and username flags cannot both be set")
+	}
+	return policies.InstanceActorIRI(), nil
+}
+
+func requirePolicyID(policyID string) error {
+	if len(policyID) == 0 {
+		return fmt.Errorf("the policy flag is required")
+	}
+	return nil
+}
+
+func readPolicyFile(policyFile string) (po models.Policy, err error) {
+	if len(policyFile) == 0 {
+		err = fmt.Errorf("the policy_file flag is required")
+		return
+	}
+	var b []byte
+	b, err = ioutil.ReadFile(policyFile)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(b, &po)
+	return
+}
+
+// listReportsCount is the number of oldest reports listed by the list-reports
+// action.
+const listReportsCount = 100
+
+func doListReports(configFilePath string, a app.Application, debug bool, scheme string, resolved bool) error {
+	db, _, reports, err := newReportService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	rs, err := reports.List(c, resolved, 0, listReportsCount)
+	if err != nil {
+		return err
+	}
+	optIRI := func(u *url.URL) string {
+		if u == nil {
+			return "-"
+		}
+		return u.String()
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tREPORTER\tTARGET\tOBJECTS\tFORWARDED\tASSIGNEE\tCONTENT\tRESOLUTION")
+	for _, r := range rs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%t\t%s\t%q\t%q\n",
+			r.ID,
+			r.Created.Format(time.RFC3339),
+			r.Reporter,
+			r.Target,
+			len(r.Objects),
+			r.Forwarded,
+			optIRI(r.Assignee),
+			r.Content,
+			r.Resolution)
+	}
+	return w.Flush()
+}
+
+func doAssignReport(configFilePath string, a app.Application, debug bool, scheme string, reportID, username string) error {
+	db, users, reports, err := newReportService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	if err := requireReportID(reportID); err != nil {
+		return err
+	}
+	u, err := userByUsername(c, users, username)
+	if err != nil {
+		return err
+	}
+	p, err := users.Privileges(c, u.ID, nil)
+	if err != nil {
+		return err
+	} else if !p.Admin {
+		return fmt.Errorf("user %q is not an admin", username)
+	}
+	actor, err := pub.GetId(u.Actor)
+	if err != nil {
+		return err
+	}
+	if err := reports.Assign(c, reportID, actor); err != nil {
+		return err
+	}
+	util.InfoLogger.Infof("Assigned report %s to %s", reportID, username)
+	return nil
+}
+
+func doResolveReport(configFilePath string, a app.Application, debug bool, scheme string, reportID, resolution string) error {
+	db, _, reports, err := newReportService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	if err := requireReportID(reportID); err != nil {
+		return err
+	}
+	if err := reports.Resolve(c, reportID, resolution); err != nil {
+		return err
+	}
+	util.InfoLogger.Infof("Resolved report %s", reportID)
+	return nil
+}
+
+func requireReportID(reportID string) error {
+	if len(reportID) == 0 {
+		return fmt.Errorf("the report flag is required")
+	}
+	return nil
+}
+
+// listQuarantinedCount is the number of oldest pending quarantined activities
+// listed by the list-quarantined action.
+const listQuarantinedCount = 100
+
+func doListQuarantined(configFilePath string, a app.Application, debug bool, scheme string) error {
+	db, quarantine, err := newQuarantineService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	qs, err := quarantine.List(c, models.QuarantinePending, 0, listQuarantinedCount)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tRECIPIENT\tTYPE\tACTIVITY\tPOLICY")
+	for _, q := range qs {
+		id, err := pub.GetId(q.Activity)
+		if err != nil {
+			return err
+		}
+		policyID := q.PolicyID
+		if len(policyID) == 0 {
+			policyID = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			q.ID,
+			q.Created.Format(time.RFC3339),
+			q.Actor,
+			q.Activity.GetTypeName(),
+			id,
+			policyID)
+	}
+	return w.Flush()
+}
+
+func doApproveQuarantined(configFilePath string, a app.Application, debug bool, scheme string, id string) error {
+	if err := requireQuarantinedID(id); err != nil {
+		return err
+	}
+	// The full server is built, but not started, so that the activity is
+	// delivered to the inbox with all of its side effects.
+	_, fw, closeFn, err := newServerAndFramework(configFilePath, a, debug)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+	c := util.Context{context.Background()}
+	if err := fw.ApproveQuarantined(c, id); err != nil {
+		return err
+	}
+	util.InfoLogger.Infof("Approved quarantined activity %s", id)
+	return nil
+}
+
+func doRejectQuarantined(configFilePath string, a app.Application, debug bool, scheme string, id string) error {
+	if err := requireQuarantinedID(id); err != nil {
+		return err
+	}
+	db, quarantine, err := newQuarantineService(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	if err := quarantine.Review(c, id, models.QuarantineRejected); err != nil {
+		return err
+	}
+	util.InfoLogger.Infof("Rejected quarantined activity %s", id)
+	return nil
+}
+
+func requireQuarantinedID(id string) error {
+	if len(id) == 0 {
+		return fmt.Errorf("the quarantined flag is required")
+	}
+	return nil
+}
+
+func doListClients(configFilePath string, a app.Application, debug bool, scheme string) error {
+	db, oauth, err := newOAuth2Service(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	cs, err := oauth.RegisteredClients(c)
+	if err != nil {
+		return err
+	}
+	optStr := func(s string) string {
+		if len(s) == 0 {
+			return "-"
+		}
+		return s
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tNAME\tWEBSITE\tRESOURCE SERVER\tREDIRECT URIS")
+	for _, ci := range cs {
+		fmt.Fprintf(w, "%s\t%s\t%q\t%s\t%t\t%s\n",
+			ci.ID,
+			ci.Created.Format(time.RFC3339),
+			ci.Name,
+			optStr(ci.Website),
+			ci.ResourceServer,
+			strings.Join(ci.RedirectURIs, " "))
+	}
+	return w.Flush()
+}
+
+func doRemoveClient(configFilePath string, a app.Application, debug bool, scheme string, id string) error {
+	if len(id) == 0 {
+		return fmt.Errorf("the client flag is required")
+	}
+	db, oauth, err := newOAuth2Service(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	if err := oauth.RemoveRegisteredClient(c, id); err != nil {
+		return err
+	}
+	util.InfoLogger.Infof("Removed OAuth2 client %s", id)
+	return nil
+}
+
+func doSetResourceServer(configFilePath string, a app.Application, debug bool, scheme string, id string, b bool) error {
+	if len(id) == 0 {
+		return fmt.Errorf("the client flag is required")
+	}
+	db, oauth, err := newOAuth2Service(configFilePath, a, debug, scheme)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	c := util.Context{context.Background()}
+	if err := oauth.SetResourceServer(c, id, b); err != nil {
+		return err
+	}
+	if b {
+		util.InfoLogger.Infof("OAuth2 client %s may now introspect the tokens of other clients", id)
+	} else {
+		util.InfoLogger.Infof("OAuth2 client %s may no longer introspect the tokens of other clients", id)
+	}
+	return nil
+}
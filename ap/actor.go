@@ -37,6 +37,11 @@ func NewActor(c *config.Config,
 	po *services.Policies,
 	f *services.Followers,
 	u *services.Users,
+	fe *services.Featured,
+	bl *services.Blocks,
+	fr *services.FollowRequests,
+	rp *services.Reports,
+	q *services.Quarantine,
 	tc *conn.Controller) (actor pub.Actor, err error) {
 
 	common := NewCommonBehavior(a, db, tc, o, pk)
@@ -45,8 +50,8 @@ func NewActor(c *config.Config,
 	if !isC2S && !isS2S {
 		err = fmt.Errorf("the Application is neither a C2SApplication nor a S2SApplication")
 	} else if isC2S && isS2S {
-		c2s := NewSocialBehavior(ca, o)
-		s2s := NewFederatingBehavior(c, sa, db, po, pk, f, u, tc)
+		c2s := NewSocialBehavior(ca, o, bl, fr)
+		s2s := NewFederatingBehavior(c, sa, db, po, pk, f, u, fe, bl, fr, rp, q, tc)
 		actor = pub.NewActor(
 			common,
 			c2s,
@@ -54,14 +59,14 @@ func NewActor(c *config.Config,
 			apdb,
 			clock)
 	} else if isC2S {
-		c2s := NewSocialBehavior(ca, o)
+		c2s := NewSocialBehavior(ca, o, bl, fr)
 		actor = pub.NewSocialActor(
 			common,
 			c2s,
 			apdb,
 			clock)
 	} else {
-		s2s := NewFederatingBehavior(c, sa, db, po, pk, f, u, tc)
+		s2s := NewFederatingBehavior(c, sa, db, po, pk, f, u, fe, bl, fr, rp, q, tc)
 		actor = pub.NewFederatingActor(
 			common,
 			s2s,
@@ -23,8 +23,10 @@ import (
 	"sync"
 
 	"github.com/go-fed/activity/pub"
+	"github.com/go-fed/activity/streams"
 	"github.com/go-fed/activity/streams/vocab"
 	"github.com/go-fed/apcore/app"
+	"github.com/go-fed/apcore/paths"
 )
 
 var _ pub.Database = &APDB{}
@@ -94,5 +96,32 @@ func (a *APDB) NewID(c context.Context, t vocab.Type) (id *url.URL, err error) {
 		Host:   a.host,
 		Path:   path,
 	}
+	// New objects get their own replies, likes, and shares collections,
+	// which are stored along with the object.
+	if oc, ok := t.(objectCollectioner); ok && !streams.IsOrExtendsActivityStreamsActivity(t) {
+		setObjectCollections(oc, id)
+	}
 	return
 }
+
+type objectCollectioner interface {
+	SetActivityStreamsReplies(vocab.ActivityStreamsRepliesProperty)
+	SetActivityStreamsLikes(vocab.ActivityStreamsLikesProperty)
+	SetActivityStreamsShares(vocab.ActivityStreamsSharesProperty)
+}
+
+// setObjectCollections points the object's replies, likes, and shares
+// properties at the collections maintained for it.
+func setObjectCollections(oc objectCollectioner, id *url.URL) {
+	replies := streams.NewActivityStreamsRepliesProperty()
+	replies.SetIRI(paths.ObjectCollectionIRIFor(id, paths.RepliesCollection))
+	oc.SetActivityStreamsReplies(replies)
+
+	likes := streams.NewActivityStreamsLikesProperty()
+	likes.SetIRI(paths.ObjectCollectionIRIFor(id, paths.LikesCollection))
+	oc.SetActivityStreamsLikes(likes)
+
+	shares := streams.NewActivityStreamsSharesProperty()
+	shares.SetIRI(paths.ObjectCollectionIRIFor(id, paths.SharesCollection))
+	oc.SetActivityStreamsShares(shares)
+}
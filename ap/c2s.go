@@ -20,11 +20,13 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 
 	"github.com/go-fed/activity/pub"
 	"github.com/go-fed/activity/streams/vocab"
 	"github.com/go-fed/apcore/app"
 	"github.com/go-fed/apcore/framework/oauth2"
+	"github.com/go-fed/apcore/services"
 	"github.com/go-fed/apcore/util"
 	oa2 "github.com/go-fed/oauth2"
 )
@@ -34,12 +36,16 @@ var _ pub.SocialProtocol = &SocialBehavior{}
 type SocialBehavior struct {
 	app app.C2SApplication
 	o   *oauth2.Server
+	bl  *services.Blocks
+	fr  *services.FollowRequests
 }
 
-func NewSocialBehavior(app app.C2SApplication, o *oauth2.Server) *SocialBehavior {
+func NewSocialBehavior(app app.C2SApplication, o *oauth2.Server, bl *services.Blocks, fr *services.FollowRequests) *SocialBehavior {
 	return &SocialBehavior{
 		app: app,
 		o:   o,
+		bl:  bl,
+		fr:  fr,
 	}
 }
 
@@ -47,6 +53,16 @@ func (s *SocialBehavior) PostOutboxRequestBodyHook(c context.Context, r *http.Re
 	ctx := util.Context{c}
 	ctx.WithActivityStream(data)
 	out = ctx.Context
+	// Do not deliver to actors the user has blocked, except for the Block
+	// itself.
+	if _, isBlock := data.(vocab.ActivityStreamsBlock); isBlock {
+		return
+	}
+	var actorIRI *url.URL
+	if actorIRI, err = ctx.ActorIRI(); err != nil {
+		return
+	}
+	err = s.bl.RemoveBlockedAddressees(ctx, actorIRI, data)
 	return
 }
 
@@ -65,9 +81,174 @@ func (s *SocialBehavior) AuthenticatePostOutbox(c context.Context, w http.Respon
 func (s *SocialBehavior) SocialCallbacks(c context.Context) (wrapped pub.SocialWrappedCallbacks, other []interface{}, err error) {
 	wrapped = pub.SocialWrappedCallbacks{}
 	other = s.app.ApplySocialCallbacks(&wrapped)
+	other = s.wrapBlockCallbacks(&wrapped, other)
+	other = s.wrapFollowRequestCallbacks(other)
 	return
 }
 
+// wrapFollowRequestCallbacks resolves the user's pending Follows when the user
+// Accepts or Rejects them, before handing off to any application-provided
+// behavior.
+func (s *SocialBehavior) wrapFollowRequestCallbacks(other []interface{}) []interface{} {
+	var appAccept func(context.Context, vocab.ActivityStreamsAccept) error
+	var appReject func(context.Context, vocab.ActivityStreamsReject) error
+	var rest []interface{}
+	for _, o := range other {
+		switch fn := o.(type) {
+		case func(context.Context, vocab.ActivityStreamsAccept) error:
+			appAccept = fn
+		case func(context.Context, vocab.ActivityStreamsReject) error:
+			appReject = fn
+		default:
+			rest = append(rest, o)
+		}
+	}
+	return append(rest,
+		func(c context.Context, a vocab.ActivityStreamsAccept) error {
+			if err := s.resolveFollowRequests(util.Context{c}, a.GetActivityStreamsObject(), true); err != nil {
+				return err
+			}
+			if appAccept != nil {
+				return appAccept(c, a)
+			}
+			return nil
+		},
+		func(c context.Context, a vocab.ActivityStreamsReject) error {
+			if err := s.resolveFollowRequests(util.Context{c}, a.GetActivityStreamsObject(), false); err != nil {
+				return err
+			}
+			if appReject != nil {
+				return appReject(c, a)
+			}
+			return nil
+		})
+}
+
+// resolveFollowRequests accepts or rejects the user's pending Follows that are
+// objects of an Accept or Reject.
+func (s *SocialBehavior) resolveFollowRequests(c util.Context, op vocab.ActivityStreamsObjectProperty, accept bool) error {
+	if op == nil {
+		return nil
+	}
+	actorIRI, err := c.ActorIRI()
+	if err != nil {
+		return err
+	}
+	byActivity, byFollower := s.fr.RejectActivity, s.fr.Reject
+	if accept {
+		byActivity, byFollower = s.fr.AcceptActivity, s.fr.Accept
+	}
+	for iter := op.Begin(); iter != op.End(); iter = iter.Next() {
+		if iter.IsIRI() {
+			if _, err := byActivity(c, actorIRI, iter.GetIRI()); err != nil {
+				return err
+			}
+		} else if iter.IsActivityStreamsFollow() {
+			if err := resolveFollowRequest(c, byActivity, byFollower, actorIRI, iter.GetActivityStreamsFollow()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// wrapBlockCallbacks records the user's blocks, and their undoing, before
+// handing off to any application-provided behavior.
+//
+// The default Block behavior prevents the Block from being delivered, but
+// federated peers rely on it to hide the user from the blocked actor. So
+// unless the application has overridden it, it is replaced here.
+func (s *SocialBehavior) wrapBlockCallbacks(wrapped *pub.SocialWrappedCallbacks, other []interface{}) []interface{} {
+	appUndo := wrapped.Undo
+	wrapped.Undo = func(c context.Context, a vocab.ActivityStreamsUndo) error {
+		if err := s.undoBlock(util.Context{c}, a); err != nil {
+			return err
+		}
+		if appUndo != nil {
+			return appUndo(c, a)
+		}
+		return nil
+	}
+	for _, o := range other {
+		if _, ok := o.(func(context.Context, vocab.ActivityStreamsBlock) error); ok {
+			return other
+		}
+	}
+	appBlock := wrapped.Block
+	return append(other, func(c context.Context, a vocab.ActivityStreamsBlock) error {
+		if err := s.recordBlock(util.Context{c}, a); err != nil {
+			return err
+		}
+		if appBlock != nil {
+			return appBlock(c, a)
+		}
+		return nil
+	})
+}
+
+// recordBlock records the user blocking each object of the Block.
+func (s *SocialBehavior) recordBlock(c util.Context, a vocab.ActivityStreamsBlock) error {
+	op := a.GetActivityStreamsObject()
+	if op == nil || op.Len() == 0 {
+		return pub.ErrObjectRequired
+	}
+	actorIRI, err := c.ActorIRI()
+	if err != nil {
+		return err
+	}
+	activity, err := pub.GetId(a)
+	if err != nil {
+		return err
+	}
+	for iter := op.Begin(); iter != op.End(); iter = iter.Next() {
+		blocked, err := pub.ToId(iter)
+		if err != nil {
+			return err
+		}
+		if _, err := s.bl.Block(c, actorIRI, blocked, activity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// undoBlock removes the user's blocks undone by the Undo. An embedded Block
+// is matched by its objects, otherwise the Block is matched by its IRI.
+func (s *SocialBehavior) undoBlock(c util.Context, a vocab.ActivityStreamsUndo) error {
+	op := a.GetActivityStreamsObject()
+	if op == nil {
+		return nil
+	}
+	actorIRI, err := c.ActorIRI()
+	if err != nil {
+		return err
+	}
+	for iter := op.Begin(); iter != op.End(); iter = iter.Next() {
+		if iter.IsIRI() {
+			if _, err := s.bl.UnblockActivity(c, actorIRI, iter.GetIRI()); err != nil {
+				return err
+			}
+			continue
+		} else if !iter.IsActivityStreamsBlock() {
+			continue
+		}
+		bop := iter.GetActivityStreamsBlock().GetActivityStreamsObject()
+		if bop == nil {
+			continue
+		}
+		for bIter := bop.Begin(); bIter != bop.End(); bIter = bIter.Next() {
+			blocked, err := pub.ToId(bIter)
+			if err != nil {
+				return err
+			}
+			if _, _, err := s.bl.Unblock(c, actorIRI, blocked); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (s *SocialBehavior) DefaultCallback(c context.Context, activity pub.Activity) error {
 	return fmt.Errorf("Unhandled client Activity of type: %s", activity.GetTypeName())
 }
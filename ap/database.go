@@ -41,6 +41,7 @@ type Database struct {
 	followers             *services.Followers
 	following             *services.Following
 	liked                 *services.Liked
+	objects               *services.ObjectCollections
 	any                   *services.Any
 	defaultCollectionSize int
 	maxCollectionPageSize int
@@ -55,6 +56,7 @@ func NewDatabase(scheme string,
 	followers *services.Followers,
 	following *services.Following,
 	liked *services.Liked,
+	objects *services.ObjectCollections,
 	any *services.Any) *Database {
 	return &Database{
 		scheme:                scheme,
@@ -66,6 +68,7 @@ func NewDatabase(scheme string,
 		followers:             followers,
 		following:             following,
 		liked:                 liked,
+		objects:               objects,
 		any:                   any,
 		defaultCollectionSize: c.DatabaseConfig.DefaultCollectionPageSize,
 		maxCollectionPageSize: c.DatabaseConfig.MaxCollectionPageSize,
@@ -63,8 +63,19 @@ func (f *instanceActorFederatingBehavior) PostInboxRequestBodyHook(c context.Con
 }
 
 func (f *instanceActorFederatingBehavior) AuthenticatePostInbox(c context.Context, w http.ResponseWriter, r *http.Request) (out context.Context, authenticated bool, err error) {
-	authenticated, err = verifyHttpSignatures(c, r, f.db, f.pk, f.tc)
 	out = c
+	// Approved quarantined activities were authenticated when they were
+	// first received.
+	if (util.Context{c}).IsQuarantineApproved() {
+		authenticated = true
+		return
+	}
+	var keyID *url.URL
+	authenticated, keyID, err = verifyHttpSignatures(c, r, f.db, f.pk, f.tc)
+	if err == nil && authenticated {
+		// Hosts that only ever deliver to us are peers too.
+		f.tc.PeerSeen(util.Context{c}, keyID.Host)
+	}
 	return
 }
 
@@ -42,6 +42,11 @@ type FederatingBehavior struct {
 	pk                      *services.PrivateKeys
 	f                       *services.Followers
 	u                       *services.Users
+	fe                      *services.Featured
+	bl                      *services.Blocks
+	fr                      *services.FollowRequests
+	rp                      *services.Reports
+	q                       *services.Quarantine
 	tc                      *conn.Controller
 }
 
@@ -52,6 +57,11 @@ func NewFederatingBehavior(c *config.Config,
 	pk *services.PrivateKeys,
 	f *services.Followers,
 	u *services.Users,
+	fe *services.Featured,
+	bl *services.Blocks,
+	fr *services.FollowRequests,
+	rp *services.Reports,
+	q *services.Quarantine,
 	tc *conn.Controller) *FederatingBehavior {
 	return &FederatingBehavior{
 		maxInboxForwardingDepth: c.ActivityPubConfig.MaxInboxForwardingRecursionDepth,
@@ -62,6 +72,11 @@ func NewFederatingBehavior(c *config.Config,
 		pk:                      pk,
 		f:                       f,
 		u:                       u,
+		fe:                      fe,
+		bl:                      bl,
+		fr:                      fr,
+		rp:                      rp,
+		q:                       q,
 		tc:                      tc,
 	}
 }
@@ -74,8 +89,34 @@ func (f *FederatingBehavior) PostInboxRequestBodyHook(c context.Context, r *http
 }
 
 func (f *FederatingBehavior) AuthenticatePostInbox(c context.Context, w http.ResponseWriter, r *http.Request) (out context.Context, authenticated bool, err error) {
-	authenticated, err = verifyHttpSignatures(c, r, f.db, f.pk, f.tc)
 	out = c
+	// Suspended and deleted users no longer federate.
+	ctx := util.Context{c}
+	var uuid paths.UUID
+	uuid, err = ctx.UserPathUUID()
+	if err != nil {
+		return
+	}
+	var u *services.User
+	u, err = f.u.UserByID(ctx, uuid)
+	if err != nil {
+		return
+	} else if u != nil && (!u.Suspended.IsZero() || !u.Deleted.IsZero()) {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+	// Approved quarantined activities were authenticated when they were
+	// first received.
+	if ctx.IsQuarantineApproved() {
+		authenticated = true
+		return
+	}
+	var keyID *url.URL
+	authenticated, keyID, err = verifyHttpSignatures(c, r, f.db, f.pk, f.tc)
+	if err == nil && authenticated {
+		// Hosts that only ever deliver to us are peers too.
+		f.tc.PeerSeen(util.Context{c}, keyID.Host)
+	}
 	return
 }
 
@@ -89,7 +130,30 @@ func (f *FederatingBehavior) Blocked(c context.Context, actorIRIs []*url.URL) (b
 	if actorID, err = ctx.ActorIRI(); err != nil {
 		return
 	}
+	blocked, err = f.bl.IsBlocking(ctx, actorID, actorIRIs)
+	if err != nil || blocked {
+		return
+	}
 	blocked, err = f.po.IsBlocked(ctx, actorID, activity)
+	if err != nil || blocked {
+		return
+	}
+	if !ctx.IsQuarantineApproved() {
+		var quarantined bool
+		var resolutionID string
+		quarantined, resolutionID, err = f.po.Quarantine(ctx, actorID, activity)
+		if err != nil {
+			return
+		} else if quarantined {
+			if _, err = f.q.Hold(ctx, actorID, activity, resolutionID); err == nil {
+				err = services.ErrQuarantined
+			}
+			return
+		}
+	}
+	// Rewrite this recipient's copy before the side effects, callbacks,
+	// and storage see it.
+	err = f.po.ApplyRewrites(ctx, actorID, activity)
 	return
 }
 
@@ -109,9 +173,325 @@ func (f *FederatingBehavior) FederatingCallbacks(c context.Context) (wrapped pub
 		OnFollow: prefs.OnFollow,
 	}
 	other = f.app.ApplyFederatingCallbacks(&wrapped)
+	f.wrapFeaturedCallbacks(&wrapped)
+	f.wrapBlockCallbacks(&wrapped)
+	f.wrapFollowRequestCallbacks(&wrapped, prefs.OnFollow)
+	f.wrapDeleteCallbacks(&wrapped)
+	other = f.wrapObjectCollectionCallbacks(&wrapped, other)
+	other = f.wrapReportCallbacks(other)
 	return
 }
 
+// wrapFeaturedCallbacks keeps federated featured collections up to date before
+// handing off to any application-provided behavior.
+func (f *FederatingBehavior) wrapFeaturedCallbacks(wrapped *pub.FederatingWrappedCallbacks) {
+	appAdd := wrapped.Add
+	wrapped.Add = func(c context.Context, a vocab.ActivityStreamsAdd) error {
+		if err := f.featuredAdd(util.Context{c}, a); err != nil {
+			return err
+		}
+		if appAdd != nil {
+			return appAdd(c, a)
+		}
+		return nil
+	}
+	appRemove := wrapped.Remove
+	wrapped.Remove = func(c context.Context, a vocab.ActivityStreamsRemove) error {
+		if err := f.featuredRemove(util.Context{c}, a); err != nil {
+			return err
+		}
+		if appRemove != nil {
+			return appRemove(c, a)
+		}
+		return nil
+	}
+}
+
+// featuredAdd records items added to a federated actor's featured collection.
+func (f *FederatingBehavior) featuredAdd(c util.Context, a vocab.ActivityStreamsAdd) error {
+	return f.forFeaturedTargets(c,
+		a.GetActivityStreamsActor(),
+		a.GetActivityStreamsObject(),
+		a.GetActivityStreamsTarget(),
+		func(actor, featured, item *url.URL) error {
+			return f.fe.AddFederated(c, actor, featured, item)
+		})
+}
+
+// featuredRemove records items removed from a federated actor's featured
+// collection.
+func (f *FederatingBehavior) featuredRemove(c util.Context, a vocab.ActivityStreamsRemove) error {
+	return f.forFeaturedTargets(c,
+		a.GetActivityStreamsActor(),
+		a.GetActivityStreamsObject(),
+		a.GetActivityStreamsTarget(),
+		func(actor, featured, item *url.URL) error {
+			return f.fe.RemoveFederated(c, featured, item)
+		})
+}
+
+// forFeaturedTargets calls fn for every object and every target that is the
+// featured collection of one of the activity's actors. Targets owned by this
+// server are left to the default ActivityPub behavior.
+func (f *FederatingBehavior) forFeaturedTargets(c util.Context,
+	actors vocab.ActivityStreamsActorProperty,
+	objects vocab.ActivityStreamsObjectProperty,
+	targets vocab.ActivityStreamsTargetProperty,
+	fn func(actor, featured, item *url.URL) error) error {
+	if actors == nil || objects == nil || targets == nil {
+		return nil
+	}
+	var items []*url.URL
+	for iter := objects.Begin(); iter != objects.End(); iter = iter.Next() {
+		id, err := pub.ToId(iter)
+		if err != nil {
+			return err
+		}
+		items = append(items, id)
+	}
+	for iter := targets.Begin(); iter != targets.End(); iter = iter.Next() {
+		target, err := pub.ToId(iter)
+		if err != nil {
+			return err
+		}
+		if owns, err := f.db.Owns(c.Context, target); err != nil {
+			return err
+		} else if owns {
+			continue
+		}
+		for aIter := actors.Begin(); aIter != actors.End(); aIter = aIter.Next() {
+			actor, err := pub.ToId(aIter)
+			if err != nil {
+				return err
+			} else if actor.Host != target.Host {
+				continue
+			}
+			// An actor that cannot be fetched only means its featured
+			// collection is not tracked, so the activity is still
+			// accepted.
+			b, err := dereferenceAsUser(c, f.pk, f.tc, actor)
+			if err != nil {
+				util.ErrorLogger.Errorf("skipping featured collection of %s: %s", actor, err)
+				continue
+			}
+			featured, err := getFeaturedFromResponse(c.Context, b)
+			if err != nil {
+				util.ErrorLogger.Errorf("skipping featured collection of %s: %s", actor, err)
+				continue
+			} else if featured == nil || featured.String() != target.String() {
+				continue
+			}
+			for _, item := range items {
+				if err := fn(actor, featured, item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// wrapBlockCallbacks records federated actors blocking the local user, and
+// their undoing of those blocks, before handing off to any
+// application-provided behavior.
+func (f *FederatingBehavior) wrapBlockCallbacks(wrapped *pub.FederatingWrappedCallbacks) {
+	appBlock := wrapped.Block
+	wrapped.Block = func(c context.Context, a vocab.ActivityStreamsBlock) error {
+		if err := f.recordBlock(util.Context{c}, a); err != nil {
+			return err
+		}
+		if appBlock != nil {
+			return appBlock(c, a)
+		}
+		return nil
+	}
+	appUndo := wrapped.Undo
+	wrapped.Undo = func(c context.Context, a vocab.ActivityStreamsUndo) error {
+		if err := f.undoBlock(util.Context{c}, a); err != nil {
+			return err
+		}
+		if appUndo != nil {
+			return appUndo(c, a)
+		}
+		return nil
+	}
+}
+
+// recordBlock records each actor of the Block as blocking the local user, if
+// the local user is one of its objects. This removes any follow relationship
+// between them.
+func (f *FederatingBehavior) recordBlock(c util.Context, a vocab.ActivityStreamsBlock) error {
+	localActor, err := c.ActorIRI()
+	if err != nil {
+		return err
+	}
+	if !hasIRI(a.GetActivityStreamsObject(), localActor) {
+		return nil
+	}
+	activity, err := pub.GetId(a)
+	if err != nil {
+		return err
+	}
+	actors := a.GetActivityStreamsActor()
+	if actors == nil {
+		return nil
+	}
+	for iter := actors.Begin(); iter != actors.End(); iter = iter.Next() {
+		actor, err := pub.ToId(iter)
+		if err != nil {
+			return err
+		}
+		if _, err := f.bl.Block(c, actor, localActor, activity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// undoBlock removes the blocks of the local user undone by the Undo. An
+// embedded Block is matched by its actor and object, otherwise the Block is
+// matched by its IRI.
+func (f *FederatingBehavior) undoBlock(c util.Context, a vocab.ActivityStreamsUndo) error {
+	localActor, err := c.ActorIRI()
+	if err != nil {
+		return err
+	}
+	actors := a.GetActivityStreamsActor()
+	op := a.GetActivityStreamsObject()
+	if actors == nil || op == nil {
+		return nil
+	}
+	for iter := op.Begin(); iter != op.End(); iter = iter.Next() {
+		var embedded vocab.ActivityStreamsBlock
+		if iter.IsActivityStreamsBlock() {
+			embedded = iter.GetActivityStreamsBlock()
+			if !hasIRI(embedded.GetActivityStreamsObject(), localActor) {
+				continue
+			}
+		} else if !iter.IsIRI() {
+			continue
+		}
+		for aIter := actors.Begin(); aIter != actors.End(); aIter = aIter.Next() {
+			actor, err := pub.ToId(aIter)
+			if err != nil {
+				return err
+			}
+			if embedded != nil {
+				_, _, err = f.bl.Unblock(c, actor, localActor)
+			} else {
+				_, err = f.bl.UnblockActivity(c, actor, iter.GetIRI())
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// wrapFollowRequestCallbacks keeps Follows awaiting the user's approval before
+// handing off to any application-provided behavior. Such Follows are removed
+// if they are undone before being approved.
+func (f *FederatingBehavior) wrapFollowRequestCallbacks(wrapped *pub.FederatingWrappedCallbacks, onFollow pub.OnFollowBehavior) {
+	if onFollow == pub.OnFollowDoNothing {
+		appFollow := wrapped.Follow
+		wrapped.Follow = func(c context.Context, a vocab.ActivityStreamsFollow) error {
+			if err := f.recordFollowRequest(util.Context{c}, a); err != nil {
+				return err
+			}
+			if appFollow != nil {
+				return appFollow(c, a)
+			}
+			return nil
+		}
+	}
+	appUndo := wrapped.Undo
+	wrapped.Undo = func(c context.Context, a vocab.ActivityStreamsUndo) error {
+		if err := f.undoFollowRequest(util.Context{c}, a); err != nil {
+			return err
+		}
+		if appUndo != nil {
+			return appUndo(c, a)
+		}
+		return nil
+	}
+}
+
+// recordFollowRequest keeps the Follow for the user's approval, if the user is
+// one of its objects.
+func (f *FederatingBehavior) recordFollowRequest(c util.Context, a vocab.ActivityStreamsFollow) error {
+	localActor, err := c.ActorIRI()
+	if err != nil {
+		return err
+	}
+	if !hasIRI(a.GetActivityStreamsObject(), localActor) {
+		return nil
+	}
+	return f.fr.Create(c, localActor, a)
+}
+
+// undoFollowRequest removes the user's pending Follows that are undone.
+func (f *FederatingBehavior) undoFollowRequest(c util.Context, a vocab.ActivityStreamsUndo) error {
+	localActor, err := c.ActorIRI()
+	if err != nil {
+		return err
+	}
+	op := a.GetActivityStreamsObject()
+	if op == nil {
+		return nil
+	}
+	for iter := op.Begin(); iter != op.End(); iter = iter.Next() {
+		if iter.IsIRI() {
+			if _, err := f.fr.RejectActivity(c, localActor, iter.GetIRI()); err != nil {
+				return err
+			}
+		} else if iter.IsActivityStreamsFollow() {
+			if err := resolveFollowRequest(c, f.fr.RejectActivity, f.fr.Reject, localActor, iter.GetActivityStreamsFollow()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// wrapDeleteCallbacks purges the federated data of actors deleting
+// themselves before handing off to any application-provided behavior.
+func (f *FederatingBehavior) wrapDeleteCallbacks(wrapped *pub.FederatingWrappedCallbacks) {
+	appDelete := wrapped.Delete
+	wrapped.Delete = func(c context.Context, a vocab.ActivityStreamsDelete) error {
+		if err := f.purgeDeletedActors(util.Context{c}, a); err != nil {
+			return err
+		}
+		if appDelete != nil {
+			return appDelete(c, a)
+		}
+		return nil
+	}
+}
+
+// purgeDeletedActors removes all federated data of each actor of the Delete
+// that is also one of its objects.
+func (f *FederatingBehavior) purgeDeletedActors(c util.Context, a vocab.ActivityStreamsDelete) error {
+	op := a.GetActivityStreamsObject()
+	actors := a.GetActivityStreamsActor()
+	if op == nil || actors == nil {
+		return nil
+	}
+	for iter := actors.Begin(); iter != actors.End(); iter = iter.Next() {
+		actor, err := pub.ToId(iter)
+		if err != nil {
+			return err
+		}
+		if !hasIRI(op, actor) {
+			continue
+		}
+		if err := f.db.data.DeleteFederatedActor(c, actor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (f *FederatingBehavior) DefaultCallback(c context.Context, activity pub.Activity) error {
 	activityIRI, err := pub.GetId(activity)
 	if err != nil {
@@ -178,3 +558,185 @@ func (f *FederatingBehavior) GetInbox(c context.Context, r *http.Request) (ocp v
 	// ThenChange(router.go)
 	return
 }
+
+// wrapObjectCollectionCallbacks maintains the replies, likes, and shares
+// collections of local objects before handing off to any application-provided
+// behavior.
+//
+// The default Like and Announce behaviors embed the likes and shares directly
+// into the stored object, which would replace the IRIs of the maintained
+// collections. So unless the application has overridden them, they are
+// replaced here.
+func (f *FederatingBehavior) wrapObjectCollectionCallbacks(wrapped *pub.FederatingWrappedCallbacks, other []interface{}) []interface{} {
+	appCreate := wrapped.Create
+	wrapped.Create = func(c context.Context, a vocab.ActivityStreamsCreate) error {
+		if err := f.recordReplies(util.Context{c}, a); err != nil {
+			return err
+		}
+		if appCreate != nil {
+			return appCreate(c, a)
+		}
+		return nil
+	}
+	appUndo := wrapped.Undo
+	wrapped.Undo = func(c context.Context, a vocab.ActivityStreamsUndo) error {
+		if err := f.undoLikesAndShares(util.Context{c}, a); err != nil {
+			return err
+		}
+		if appUndo != nil {
+			return appUndo(c, a)
+		}
+		return nil
+	}
+	var likeOverridden, announceOverridden bool
+	for _, o := range other {
+		switch o.(type) {
+		case func(context.Context, vocab.ActivityStreamsLike) error:
+			likeOverridden = true
+		case func(context.Context, vocab.ActivityStreamsAnnounce) error:
+			announceOverridden = true
+		}
+	}
+	if !likeOverridden {
+		appLike := wrapped.Like
+		other = append(other, func(c context.Context, a vocab.ActivityStreamsLike) error {
+			if err := f.recordObjectCollectionItem(util.Context{c}, a.GetActivityStreamsObject(), paths.LikesCollection, a, true); err != nil {
+				return err
+			}
+			if appLike != nil {
+				return appLike(c, a)
+			}
+			return nil
+		})
+	}
+	if !announceOverridden {
+		appAnnounce := wrapped.Announce
+		other = append(other, func(c context.Context, a vocab.ActivityStreamsAnnounce) error {
+			if err := f.recordObjectCollectionItem(util.Context{c}, a.GetActivityStreamsObject(), paths.SharesCollection, a, true); err != nil {
+				return err
+			}
+			if appAnnounce != nil {
+				return appAnnounce(c, a)
+			}
+			return nil
+		})
+	}
+	return other
+}
+
+// wrapReportCallbacks stores incoming Flag activities as reports for the
+// moderators, before handing off to any application-provided behavior.
+func (f *FederatingBehavior) wrapReportCallbacks(other []interface{}) []interface{} {
+	var appFlag func(context.Context, vocab.ActivityStreamsFlag) error
+	for i, o := range other {
+		if fn, ok := o.(func(context.Context, vocab.ActivityStreamsFlag) error); ok {
+			appFlag = fn
+			other = append(other[:i:i], other[i+1:]...)
+			break
+		}
+	}
+	return append(other, func(c context.Context, a vocab.ActivityStreamsFlag) error {
+		if _, err := f.rp.Receive(util.Context{c}, a); err != nil {
+			return err
+		}
+		if appFlag != nil {
+			return appFlag(c, a)
+		}
+		return nil
+	})
+}
+
+type inReplyToer interface {
+	GetActivityStreamsInReplyTo() vocab.ActivityStreamsInReplyToProperty
+}
+
+// recordReplies adds the created objects to the replies collections of the
+// local objects they are in reply to. The replies collection is served to
+// anyone who may view the parent, so only public replies are recorded.
+func (f *FederatingBehavior) recordReplies(c util.Context, a vocab.ActivityStreamsCreate) error {
+	op := a.GetActivityStreamsObject()
+	if op == nil {
+		return nil
+	}
+	for iter := op.Begin(); iter != op.End(); iter = iter.Next() {
+		t := iter.GetType()
+		if t == nil {
+			continue
+		}
+		irt, ok := t.(inReplyToer)
+		if !ok || irt.GetActivityStreamsInReplyTo() == nil || !isPublic(addressedTo(t)) {
+			continue
+		}
+		id, err := pub.GetId(t)
+		if err != nil {
+			return err
+		}
+		for rIter := irt.GetActivityStreamsInReplyTo().Begin(); rIter != irt.GetActivityStreamsInReplyTo().End(); rIter = rIter.Next() {
+			parent, err := pub.ToId(rIter)
+			if err != nil {
+				return err
+			}
+			if err := f.updateObjectCollection(c, parent, paths.RepliesCollection, id, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// undoLikesAndShares removes undone Like and Announce activities from the
+// likes and shares collections of local objects.
+func (f *FederatingBehavior) undoLikesAndShares(c util.Context, a vocab.ActivityStreamsUndo) error {
+	op := a.GetActivityStreamsObject()
+	if op == nil {
+		return nil
+	}
+	for iter := op.Begin(); iter != op.End(); iter = iter.Next() {
+		var err error
+		if like := iter.GetActivityStreamsLike(); like != nil {
+			err = f.recordObjectCollectionItem(c, like.GetActivityStreamsObject(), paths.LikesCollection, like, false)
+		} else if announce := iter.GetActivityStreamsAnnounce(); announce != nil {
+			err = f.recordObjectCollectionItem(c, announce.GetActivityStreamsObject(), paths.SharesCollection, announce, false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordObjectCollectionItem adds or removes the activity from the collection
+// of every local object that is the activity's object.
+func (f *FederatingBehavior) recordObjectCollectionItem(c util.Context, op vocab.ActivityStreamsObjectProperty, oc paths.ObjectCollection, activity vocab.Type, add bool) error {
+	if op == nil {
+		return nil
+	}
+	id, err := pub.GetId(activity)
+	if err != nil {
+		return err
+	}
+	for iter := op.Begin(); iter != op.End(); iter = iter.Next() {
+		objID, err := pub.ToId(iter)
+		if err != nil {
+			return err
+		}
+		if err := f.updateObjectCollection(c, objID, oc, id, add); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateObjectCollection adds or removes the item from the collection of the
+// object, if the object is local and not an actor.
+func (f *FederatingBehavior) updateObjectCollection(c util.Context, objID *url.URL, oc paths.ObjectCollection, item *url.URL, add bool) error {
+	if owns, err := f.db.Owns(c.Context, objID); err != nil {
+		return err
+	} else if !owns || paths.IsUserPath(objID) || paths.IsInstanceActorPath(objID) {
+		return nil
+	}
+	if add {
+		return f.db.objects.Add(c, objID, oc, item)
+	}
+	return f.db.objects.Remove(c, objID, oc, item)
+}
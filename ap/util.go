@@ -41,7 +41,7 @@ type publicKeyer interface {
 	GetW3IDSecurityV1PublicKey() vocab.W3IDSecurityV1PublicKeyProperty
 }
 
-func getPublicKeyFromResponse(c context.Context, b []byte, keyId *url.URL) (p crypto.PublicKey, err error) {
+func getPublicKeyFromResponse(c context.Context, b []byte, keyId *url.URL) (p crypto.PublicKey, owner *url.URL, err error) {
 	m := make(map[string]interface{}, 0)
 	err = json.Unmarshal(b, &m)
 	if err != nil {
@@ -88,6 +88,9 @@ func getPublicKeyFromResponse(c context.Context, b []byte, keyId *url.URL) (p cr
 		err = fmt.Errorf("publicKeyPem property is not provided or it is not embedded as a value")
 		return
 	}
+	if op := pkpFound.GetW3IDSecurityV1Owner(); op != nil && op.IsIRI() {
+		owner = op.GetIRI()
+	}
 	pubKeyPem := pkPemProp.Get()
 	var block *pem.Block
 	block, _ = pem.Decode([]byte(pubKeyPem))
@@ -103,7 +106,7 @@ func verifyHttpSignatures(c context.Context,
 	r *http.Request,
 	db *Database,
 	pk *services.PrivateKeys,
-	tc *conn.Controller) (authenticated bool, err error) {
+	tc *conn.Controller) (authenticated bool, keyID *url.URL, err error) {
 	// 1. Figure out what key we need to verify
 	ctx := util.Context{c}
 	var v httpsig.Verifier
@@ -117,35 +120,161 @@ func verifyHttpSignatures(c context.Context,
 	if err != nil {
 		return
 	}
-	// 2. Get our user's credentials
+	keyID = kIdIRI
+	// 2. Fetch the public key of the other actor using our user's
+	// credentials
+	var b []byte
+	b, err = dereferenceAsUser(ctx, pk, tc, kIdIRI)
+	if err != nil {
+		return
+	}
+	pKey, _, err := getPublicKeyFromResponse(c, b, kIdIRI)
+	if err != nil {
+		return
+	}
+	// 3. Verify the other actor's key
+	algo := tc.GetFirstAlgorithm()
+	authenticated = nil == v.Verify(pKey, algo)
+	return
+}
+
+// verifyHttpSignaturesOwner verifies the HTTP Signatures of a request that is
+// not handled on behalf of any particular user, fetching the public key with
+// the instance actor's credentials. It returns the actor owning the signing
+// key, or nil if the signature does not verify. The owner must be on the same
+// host as the key, so a key cannot claim to belong to another server's actor.
+func verifyHttpSignaturesOwner(c context.Context,
+	r *http.Request,
+	pk *services.PrivateKeys,
+	tc *conn.Controller) (owner *url.URL, err error) {
+	var v httpsig.Verifier
+	v, err = httpsig.NewVerifier(r)
+	if err != nil {
+		return
+	}
+	var kIdIRI *url.URL
+	kIdIRI, err = url.Parse(v.KeyId())
+	if err != nil {
+		return
+	}
+	privKey, pubKeyURL, err := pk.GetUserHTTPSignatureKeyForInstanceActor(util.Context{c})
+	if err != nil {
+		return
+	}
+	tp, err := tc.Get(privKey, pubKeyURL.String())
+	if err != nil {
+		return
+	}
+	b, err := tp.Dereference(c, kIdIRI)
+	if err != nil {
+		return
+	}
+	pKey, keyOwner, err := getPublicKeyFromResponse(c, b, kIdIRI)
+	if err != nil {
+		return
+	} else if keyOwner == nil {
+		err = fmt.Errorf("publicKey has no owner: %s", kIdIRI)
+		return
+	} else if keyOwner.Host != kIdIRI.Host {
+		err = fmt.Errorf("publicKey %s has an owner on another host: %s", kIdIRI, keyOwner)
+		return
+	}
+	if v.Verify(pKey, tc.GetFirstAlgorithm()) == nil {
+		owner = keyOwner
+	}
+	return
+}
+
+// dereferenceAsUser fetches the IRI, signing the request with the credentials
+// of the user whose inbox or outbox is handling the current request.
+func dereferenceAsUser(c util.Context,
+	pk *services.PrivateKeys,
+	tc *conn.Controller,
+	iri *url.URL) (b []byte, err error) {
 	var userUUID paths.UUID
-	userUUID, err = ctx.UserPathUUID()
+	userUUID, err = c.UserPathUUID()
 	if err != nil {
 		return
 	}
 	var privKey *rsa.PrivateKey
 	var pubKeyURL *url.URL
-	privKey, pubKeyURL, err = pk.GetUserHTTPSignatureKey(ctx, userUUID)
+	privKey, pubKeyURL, err = pk.GetUserHTTPSignatureKey(c, userUUID)
 	if err != nil {
 		return
 	}
-	pubKeyId := pubKeyURL.String()
-	// 3. Fetch the public key of the other actor using our credentials
-	tp, err := tc.Get(privKey, pubKeyId)
+	var tp pub.Transport
+	tp, err = tc.Get(privKey, pubKeyURL.String())
 	if err != nil {
 		return
 	}
-	var b []byte
-	b, err = tp.Dereference(c, kIdIRI)
+	return tp.Dereference(c.Context, iri)
+}
+
+type featureder interface {
+	GetTootFeatured() vocab.TootFeaturedProperty
+}
+
+// getFeaturedFromResponse obtains the IRI of the featured collection of the
+// actor in the response, or nil if the actor has none.
+func getFeaturedFromResponse(c context.Context, b []byte) (featured *url.URL, err error) {
+	m := make(map[string]interface{}, 0)
+	err = json.Unmarshal(b, &m)
 	if err != nil {
 		return
 	}
-	pKey, err := getPublicKeyFromResponse(c, b, kIdIRI)
+	var t vocab.Type
+	t, err = streams.ToType(c, m)
 	if err != nil {
 		return
 	}
-	// 4. Verify the other actor's key
-	algo := tc.GetFirstAlgorithm()
-	authenticated = nil == v.Verify(pKey, algo)
-	return
+	fr, ok := t.(featureder)
+	if !ok {
+		return
+	}
+	fp := fr.GetTootFeatured()
+	if fp == nil || !fp.HasAny() {
+		return
+	}
+	return pub.ToId(fp)
+}
+
+// hasIRI determines whether the object property contains the IRI, whether as
+// an IRI or as an embedded value with that id.
+func hasIRI(op vocab.ActivityStreamsObjectProperty, iri *url.URL) bool {
+	if op == nil {
+		return false
+	}
+	for iter := op.Begin(); iter != op.End(); iter = iter.Next() {
+		if id, err := pub.ToId(iter); err == nil && id.String() == iri.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFollowRequest accepts or rejects the pending Follow of the local
+// actor. The Follow is matched by its id, or by its actors if it has none.
+func resolveFollowRequest(c util.Context,
+	byActivity func(c util.Context, actor, followID *url.URL) (vocab.ActivityStreamsFollow, error),
+	byFollower func(c util.Context, actor, follower *url.URL) (vocab.ActivityStreamsFollow, error),
+	localActor *url.URL,
+	follow vocab.ActivityStreamsFollow) error {
+	if id, err := pub.GetId(follow); err == nil {
+		_, err = byActivity(c, localActor, id)
+		return err
+	}
+	actors := follow.GetActivityStreamsActor()
+	if actors == nil {
+		return nil
+	}
+	for iter := actors.Begin(); iter != actors.End(); iter = iter.Next() {
+		follower, err := pub.ToId(iter)
+		if err != nil {
+			return err
+		}
+		if _, err := byFollower(c, localActor, follower); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,229 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ap
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-fed/activity/pub"
+	"github.com/go-fed/activity/streams/vocab"
+	"github.com/go-fed/apcore/app"
+	"github.com/go-fed/apcore/framework/conn"
+	"github.com/go-fed/apcore/framework/oauth2"
+	"github.com/go-fed/apcore/paths"
+	"github.com/go-fed/apcore/services"
+	"github.com/go-fed/apcore/util"
+)
+
+// Visibility enforces the addressing of local objects when they are fetched:
+//
+//   - Objects addressed to the Public collection are visible to all.
+//   - Objects addressed to the followers of their owner are visible to requests
+//     signed by one of those followers.
+//   - Otherwise, objects are visible only to requests signed by an addressed
+//     actor, or to the owning or an addressed local user authenticated with
+//     OAuth2. The token must be granted the AllScope, or a scope the
+//     application permits to view private inbox items (for an addressed user)
+//     or outbox items (for the owner).
+//   - Deleted objects, replaced by a Tombstone, are visible to all.
+//
+// The replies, likes, and shares collections of an object are visible to the
+// same requesters as the object itself.
+type Visibility struct {
+	scheme    string
+	host      string
+	a         app.Application
+	o         *oauth2.Server
+	data      *services.Data
+	followers *services.Followers
+	pk        *services.PrivateKeys
+	tc        *conn.Controller
+}
+
+func NewVisibility(scheme, host string,
+	a app.Application,
+	o *oauth2.Server,
+	data *services.Data,
+	followers *services.Followers,
+	pk *services.PrivateKeys,
+	tc *conn.Controller) *Visibility {
+	return &Visibility{
+		scheme:    scheme,
+		host:      host,
+		a:         a,
+		o:         o,
+		data:      data,
+		followers: followers,
+		pk:        pk,
+		tc:        tc,
+	}
+}
+
+var _ app.AuthorizeFunc = (&Visibility{}).Authorize
+
+// Authorize permits the request if it is not for a local object, or if the
+// requester may view the local object.
+func (v *Visibility) Authorize(c util.Context, w http.ResponseWriter, r *http.Request, db app.Database) (permit bool, err error) {
+	var id *url.URL
+	if id, err = c.CompleteRequestURL(); err != nil {
+		return
+	}
+	if _, objectID, ok := paths.ObjectCollectionFor(id); ok {
+		id = objectID
+	}
+	var t vocab.Type
+	if t, err = v.data.GetLocalObject(c, id); err != nil {
+		return
+	} else if t == nil {
+		permit = true
+		return
+	} else if _, ok := t.(vocab.ActivityStreamsTombstone); ok {
+		// Deleted objects are always answered with 410 Gone.
+		permit = true
+		return
+	}
+	addressed := addressedTo(t)
+	if isPublic(addressed) {
+		permit = true
+		return
+	}
+	owners := ownersOf(t)
+	// Local users authenticated with OAuth2
+	if userID, scopes, auth, vErr := v.o.Validate(w, r); vErr == nil && auth {
+		user := paths.UUIDIRIFor(v.scheme, v.host, paths.UserPathKey, paths.UUID(userID))
+		scope := strings.Join(scopes, " ")
+		if addressed[user.String()] {
+			if permit, err = v.scopePermits(scopes, scope, v.a.ScopePermitsPrivateGetInbox); err != nil || permit {
+				return
+			}
+		}
+		for _, owner := range owners {
+			if owner.String() == user.String() {
+				if permit, err = v.scopePermits(scopes, scope, v.a.ScopePermitsPrivateGetOutbox); err != nil || permit {
+					return
+				}
+			}
+		}
+	}
+	// Actors signing the request with HTTP Signatures
+	signer, sErr := verifyHttpSignaturesOwner(c, r, v.pk, v.tc)
+	if sErr != nil || signer == nil {
+		return
+	}
+	if addressed[signer.String()] {
+		permit = true
+		return
+	}
+	for _, owner := range owners {
+		if !v.data.Owns(owner) {
+			continue
+		}
+		var followers *url.URL
+		if followers, err = paths.IRIForActorID(paths.FollowersPathKey, owner); err != nil {
+			return
+		} else if !addressed[followers.String()] {
+			continue
+		}
+		if permit, err = v.followers.ContainsForActor(c, owner, signer); err != nil || permit {
+			return
+		}
+	}
+	return
+}
+
+// isPublic determines whether the addressing includes the public collection.
+func isPublic(addressed map[string]bool) bool {
+	return addressed[pub.PublicActivityPubIRI] || addressed["as:Public"] || addressed["Public"]
+}
+
+// addressedTo returns the set of IRIs in the to, bto, cc, bcc, and audience
+// properties of the object.
+func addressedTo(t vocab.Type) map[string]bool {
+	addressed := make(map[string]bool)
+	add := func(n int, at func(int) pub.IdProperty) {
+		for i := 0; i < n; i++ {
+			if id, err := pub.ToId(at(i)); err == nil {
+				addressed[id.String()] = true
+			}
+		}
+	}
+	if v, ok := t.(interface {
+		GetActivityStreamsTo() vocab.ActivityStreamsToProperty
+	}); ok && v.GetActivityStreamsTo() != nil {
+		p := v.GetActivityStreamsTo()
+		add(p.Len(), func(i int) pub.IdProperty { return p.At(i) })
+	}
+	if v, ok := t.(interface {
+		GetActivityStreamsBto() vocab.ActivityStreamsBtoProperty
+	}); ok && v.GetActivityStreamsBto() != nil {
+		p := v.GetActivityStreamsBto()
+		add(p.Len(), func(i int) pub.IdProperty { return p.At(i) })
+	}
+	if v, ok := t.(interface {
+		GetActivityStreamsCc() vocab.ActivityStreamsCcProperty
+	}); ok && v.GetActivityStreamsCc() != nil {
+		p := v.GetActivityStreamsCc()
+		add(p.Len(), func(i int) pub.IdProperty { return p.At(i) })
+	}
+	if v, ok := t.(interface {
+		GetActivityStreamsBcc() vocab.ActivityStreamsBccProperty
+	}); ok && v.GetActivityStreamsBcc() != nil {
+		p := v.GetActivityStreamsBcc()
+		add(p.Len(), func(i int) pub.IdProperty { return p.At(i) })
+	}
+	if v, ok := t.(interface {
+		GetActivityStreamsAudience() vocab.ActivityStreamsAudienceProperty
+	}); ok && v.GetActivityStreamsAudience() != nil {
+		p := v.GetActivityStreamsAudience()
+		add(p.Len(), func(i int) pub.IdProperty { return p.At(i) })
+	}
+	return addressed
+}
+
+// ownersOf returns the actors and attributedTo of the object.
+func ownersOf(t vocab.Type) (owners []*url.URL) {
+	if v, ok := t.(interface {
+		GetActivityStreamsActor() vocab.ActivityStreamsActorProperty
+	}); ok && v.GetActivityStreamsActor() != nil {
+		for iter := v.GetActivityStreamsActor().Begin(); iter != v.GetActivityStreamsActor().End(); iter = iter.Next() {
+			if id, err := pub.ToId(iter); err == nil {
+				owners = append(owners, id)
+			}
+		}
+	}
+	if v, ok := t.(interface {
+		GetActivityStreamsAttributedTo() vocab.ActivityStreamsAttributedToProperty
+	}); ok && v.GetActivityStreamsAttributedTo() != nil {
+		for iter := v.GetActivityStreamsAttributedTo().Begin(); iter != v.GetActivityStreamsAttributedTo().End(); iter = iter.Next() {
+			if id, err := pub.ToId(iter); err == nil {
+				owners = append(owners, id)
+			}
+		}
+	}
+	return
+}
+
+// scopePermits determines whether a token granted the scopes may view private
+// objects: either it has the AllScope, or the application's check permits it.
+func (v *Visibility) scopePermits(scopes []string, scope string, check func(string) (bool, error)) (bool, error) {
+	if app.ScopesPermit(scopes, app.AllScope) {
+		return true, nil
+	}
+	return check(scope)
+}
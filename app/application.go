@@ -202,6 +202,9 @@ type Application interface {
 	// ScopePermitsPrivateGetInbox determines if an OAuth token scope
 	// permits the bearer to view private (non-Public) messages in an
 	// actor's inbox.
+	//
+	// The scope is the space-delimited value granted to the token, which
+	// ParseScopes splits into scope names.
 	ScopePermitsPrivateGetInbox(scope string) (permitted bool, err error)
 	// ScopePermitsPrivateGetOutbox determines if an OAuth token scope
 	// permits the bearer to view private (non-Public) messages in an
@@ -232,6 +235,29 @@ type Application interface {
 	Software() Software
 }
 
+// NodeInfoApplication is an Application that adds its own entries to the
+// metadata served in NodeInfo. Implementing this interface is optional.
+type NodeInfoApplication interface {
+	// NodeInfoMetadata returns application-specific entries to add to the
+	// NodeInfo "metadata" object. Entries returned here replace any entries
+	// with the same key that are populated by apcore from the server
+	// preferences.
+	NodeInfoMetadata(c context.Context) (map[string]interface{}, error)
+}
+
+// EndpointsApplication is an Application that serves additional endpoints for
+// ActivityPub clients, which apcore advertises in the "endpoints" of local
+// user actors alongside the OAuth2 endpoints. Implementing this interface is
+// optional.
+type EndpointsApplication interface {
+	// ProxyURLPath is the path of the endpoint fetching remote objects on
+	// behalf of the authenticated user, or empty if it is not served.
+	ProxyURLPath() string
+	// UploadMediaPath is the path of the endpoint accepting media uploads
+	// from the authenticated user, or empty if it is not served.
+	UploadMediaPath() string
+}
+
 // C2SApplication is an Application with additional methods required to support
 // the C2S, or Social, ActivityPub protocol.
 type C2SApplication interface {
@@ -329,6 +355,8 @@ type S2SApplication interface {
 	//     }
 	//
 	// Note: The `OnFollow` value will already be populated by the user's
-	// preferred behavior upon receiving a Follow request.
+	// preferred behavior upon receiving a Follow request. When it is
+	// pub.OnFollowDoNothing, the Follow is kept for the user to approve
+	// later with Framework's AcceptFollowRequest or RejectFollowRequest.
 	ApplyFederatingCallbacks(fwc *pub.FederatingWrappedCallbacks) (others []interface{})
 }
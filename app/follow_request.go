@@ -0,0 +1,31 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/go-fed/activity/streams/vocab"
+)
+
+// FollowRequest is a Follow of a local user that awaits the user's approval.
+type FollowRequest struct {
+	Follower *url.URL
+	Follow   vocab.ActivityStreamsFollow
+	Created  time.Time
+}
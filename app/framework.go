@@ -19,6 +19,7 @@ package app
 import (
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/go-fed/activity/streams/vocab"
 	"github.com/go-fed/apcore/paths"
@@ -31,15 +32,54 @@ type Framework interface {
 
 	UserIRI(userUUID paths.UUID) *url.URL
 
+	// AccountDomain is the domain used in the handles of local users, such
+	// as "user@example.com". It is the same as the configured host unless
+	// the administrator has delegated a different account domain to this
+	// server.
+	AccountDomain() string
+
 	// Validate attempts to obtain and validate the OAuth token or first
 	// party credential in the request. This can be called in your handlers
 	// at request-handing time.
 	//
-	// If an error is returned, both the token and authentication values
+	// The returned scopes are the ones granted to the token. Use
+	// ScopesPermit to check them.
+	//
+	// If an error is returned, the user, scopes, and authentication values
 	// should be ignored.
+	Validate(w http.ResponseWriter, r *http.Request) (userID paths.UUID, scopes []string, authenticated bool, err error)
+
+	// RequestedScopes returns the registered scopes requested by the
+	// client in an OAuth2 authorization request, so the authorization page
+	// can ask the user for consent. An error is returned if an unknown
+	// scope is requested.
 	//
-	// TODO: Scopes
-	Validate(w http.ResponseWriter, r *http.Request) (userID paths.UUID, authenticated bool, err error)
+	// The authorization page must submit the scopes the user consents to
+	// under the GrantedScopesFormKey; scopes that are not submitted are
+	// not granted, so a page without that key denies every request.
+	RequestedScopes(r *http.Request) ([]Scope, error)
+
+	// RequestingClient returns the registered OAuth2 client making the
+	// authorization request, so the authorization page can show its name,
+	// website, and logo. An error is returned if the client is not
+	// registered or is using a redirect URI it did not register.
+	RequestingClient(r *http.Request) (*OAuth2Client, error)
+
+	// OAuth2Clients lists the OAuth2 clients that registered themselves
+	// with this server.
+	OAuth2Clients(c util.Context) ([]OAuth2Client, error)
+
+	// RemoveOAuth2Client deletes a registered OAuth2 client, revoking all
+	// of the tokens granted to it.
+	RemoveOAuth2Client(c util.Context, id string) error
+
+	// OAuth2Grants lists the clients holding active tokens granted by the
+	// user, including the user's own browser sessions.
+	OAuth2Grants(c util.Context, userID paths.UUID) ([]OAuth2Grant, error)
+
+	// RevokeOAuth2Grant revokes every token the user granted to the
+	// client, such as to sign out a lost device.
+	RevokeOAuth2Grant(c util.Context, userID paths.UUID, clientID string) error
 
 	// Send will send an Activity or Object on behalf of the user.
 	//
@@ -51,6 +91,119 @@ type Framework interface {
 
 	// TODO: Determine if we need this.
 	GetByIRI(c util.Context, id *url.URL) (vocab.Type, error)
+	// GetByIRIForUser is GetByIRI for data read on behalf of the user.
+	// Rewrite policies are applied to activities as they are received,
+	// but federated data is stored once for all of its local recipients,
+	// so it is returned with the instance's and the user's rewrite
+	// policies applied again.
+	GetByIRIForUser(c util.Context, userID paths.UUID, id *url.URL) (vocab.Type, error)
+
+	// Peers lists the remote hosts this server has exchanged federated
+	// traffic with, ordered by host.
+	Peers(c util.Context) ([]Peer, error)
+
+	// Pin adds the object to the user's featured collection and sends an
+	// Add activity to the user's followers. Only local objects whose actor
+	// or attributedTo is the user may be pinned. Pinning an object that is
+	// already featured does nothing.
+	Pin(c util.Context, userID paths.UUID, object *url.URL) error
+	// Unpin removes the object from the user's featured collection and
+	// sends a Remove activity to the user's followers. Unpinning an
+	// object that is not featured does nothing.
+	Unpin(c util.Context, userID paths.UUID, object *url.URL) error
+	// Featured returns the featured collection of a local user, or the
+	// last known featured collection of a federated actor as maintained
+	// by the Add and Remove activities it has sent to this server.
+	Featured(c util.Context, actorIRI *url.URL) (vocab.ActivityStreamsOrderedCollection, error)
+
+	// Block prevents the user and the actor from interacting: the follow
+	// relationships between them are removed, Activities from the actor
+	// are no longer accepted into the user's inbox, and the actor is
+	// removed from the addressing of Activities the user sends. A Block
+	// activity is sent to the actor. Blocking an actor that is already
+	// blocked does nothing.
+	Block(c util.Context, userID paths.UUID, actor *url.URL) error
+	// Unblock removes the user's block of the actor and sends an
+	// Undo{Block} to the actor. Unblocking an actor that is not blocked
+	// does nothing. Follow relationships removed by the block are not
+	// restored.
+	Unblock(c util.Context, userID paths.UUID, actor *url.URL) error
+	// Blocks lists the actors blocked by the user, most recent first.
+	Blocks(c util.Context, userID paths.UUID) ([]Block, error)
+
+	// MuteActor hides the actor from the user until the expiry, or forever
+	// if the expiry is the zero time. Unlike blocking, nobody is notified
+	// and nothing else changes. Muting an actor already muted replaces its
+	// expiry.
+	MuteActor(c util.Context, userID paths.UUID, actor *url.URL, expires time.Time) error
+	// MuteDomain hides all actors on the domain from the user until the
+	// expiry, or forever if the expiry is the zero time. Muting a domain
+	// already muted replaces its expiry.
+	MuteDomain(c util.Context, userID paths.UUID, domain string, expires time.Time) error
+	// UnmuteActor removes the user's mute of the actor, if any.
+	UnmuteActor(c util.Context, userID paths.UUID, actor *url.URL) error
+	// UnmuteDomain removes the user's mute of the domain, if any.
+	UnmuteDomain(c util.Context, userID paths.UUID, domain string) error
+	// Mutes lists the user's mutes that have not expired, most recent
+	// first.
+	Mutes(c util.Context, userID paths.UUID) ([]Mute, error)
+	// FilterMuted removes the items from a page of the user's inbox, such
+	// as the one passed to the GetInboxWebHandlerFunc, whose actor or
+	// attributedTo is muted by the user or whose id is on a muted domain.
+	// The page's totalItems is left unchanged.
+	FilterMuted(c util.Context, userID paths.UUID, page vocab.ActivityStreamsOrderedCollectionPage) error
+
+	// FollowRequests lists the Follows of the user awaiting approval,
+	// oldest first. Follows await approval when the user's OnFollow
+	// preference is pub.OnFollowDoNothing.
+	FollowRequests(c util.Context, userID paths.UUID) ([]FollowRequest, error)
+	// AcceptFollowRequest adds the follower to the user's followers and
+	// sends an Accept of the pending Follow. It does nothing if there is
+	// no pending Follow by the follower.
+	AcceptFollowRequest(c util.Context, userID paths.UUID, follower *url.URL) error
+	// RejectFollowRequest sends a Reject of the pending Follow. It does
+	// nothing if there is no pending Follow by the follower.
+	RejectFollowRequest(c util.Context, userID paths.UUID, follower *url.URL) error
+
+	// SuspendUser prevents the user from logging in and federating, and
+	// revokes its OAuth2 tokens. Its actor is served as a Tombstone while
+	// suspended. The instance actor cannot be suspended.
+	SuspendUser(c util.Context, userID paths.UUID) error
+	// UnsuspendUser lifts the user's suspension.
+	UnsuspendUser(c util.Context, userID paths.UUID) error
+	// DeleteUser sends a Delete of the user's actor to its followers and
+	// all known peers, then purges the user's local data and OAuth2
+	// tokens. A Tombstone is kept in its place so that its username cannot
+	// be reused. The user's keys are kept until its failed deliveries,
+	// including the Delete, are no longer retried. The instance actor
+	// cannot be deleted.
+	DeleteUser(c util.Context, userID paths.UUID) error
+
+	// Report files the user's report about the actor and, optionally, some
+	// of its objects, returning the report's id. If forward is true and
+	// the actor is on another server, the report is also sent to that
+	// server as a Flag from the instance actor, so that the reporter is not
+	// revealed. A failure to forward the report is logged rather than
+	// returned, and failed deliveries of the Flag are retried.
+	Report(c util.Context, userID paths.UUID, actor *url.URL, objects []*url.URL, content string, forward bool) (string, error)
+	// Reports lists a page of either the open or the resolved reports,
+	// including those received from other servers, oldest first.
+	Reports(c util.Context, resolved bool, offset, n int) ([]Report, error)
+	// AssignReport sets the admin handling the report.
+	AssignReport(c util.Context, reportID string, adminID paths.UUID) error
+	// ResolveReport closes an open report with the moderator's resolution.
+	ResolveReport(c util.Context, reportID, resolution string) error
+
+	// QuarantinedActivities lists a page of the incoming activities held by
+	// quarantine policies that await review, oldest first.
+	QuarantinedActivities(c util.Context, offset, n int) ([]QuarantinedActivity, error)
+	// ApproveQuarantined delivers the held activity to its recipient's
+	// inbox, running the same side effects as if it had never been
+	// quarantined. An activity is only delivered once, even if it is
+	// approved concurrently; if delivery fails, it awaits review again.
+	ApproveQuarantined(c util.Context, id string) error
+	// RejectQuarantined discards the held activity without delivering it.
+	RejectQuarantined(c util.Context, id string) error
 }
 
 type Session interface {
@@ -0,0 +1,32 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"net/url"
+	"time"
+)
+
+// Mute is a local user's mute of an actor or of a domain. Exactly one of
+// Actor and Domain is set.
+type Mute struct {
+	Actor   *url.URL
+	Domain  string
+	Created time.Time
+	// Expires is the zero time if the mute does not expire.
+	Expires time.Time
+}
@@ -0,0 +1,49 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"net/url"
+	"time"
+)
+
+// OAuth2Client is a third party OAuth2 client that registered itself with this
+// server using dynamic client registration.
+type OAuth2Client struct {
+	ID   string
+	Name string
+	// Website and Logo are nil if the client did not provide them.
+	Website      *url.URL
+	Logo         *url.URL
+	RedirectURIs []*url.URL
+	Created      time.Time
+}
+
+// OAuth2Grant describes the active tokens a user granted to one OAuth2 client.
+type OAuth2Grant struct {
+	ClientID string
+	// ClientName is empty for first party clients and for registered
+	// clients that did not provide a name.
+	ClientName string
+	// FirstParty is true for the credentials created when the user logs
+	// into this server's web pages, one per browser session.
+	FirstParty bool
+	// Scopes are all of the scopes granted among the tokens.
+	Scopes     []string
+	Tokens     int
+	LastIssued time.Time
+}
@@ -0,0 +1,39 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"time"
+)
+
+// Peer is a remote host this server has exchanged federated traffic with.
+type Peer struct {
+	Host      string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// SoftwareName and SoftwareVersion are reported by the peer's NodeInfo.
+	// They are empty if the NodeInfo has never been successfully fetched.
+	SoftwareName    string
+	SoftwareVersion string
+	// NodeInfoFetched is the zero time if NodeInfo has never been fetched.
+	NodeInfoFetched time.Time
+	// Delivery health for Activities sent to this peer.
+	DeliverySuccesses   int
+	DeliveryFailures    int
+	LastDeliverySuccess time.Time
+	LastDeliveryFailure time.Time
+}
@@ -0,0 +1,43 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"net/url"
+	"time"
+)
+
+// Report is a report about an actor for moderators to review, either received
+// in a Flag from another server or filed by a local user.
+type Report struct {
+	ID       string
+	Created  time.Time
+	Reporter *url.URL
+	Target   *url.URL
+	// Objects are the reported objects, such as offending notes.
+	Objects []*url.URL
+	Content string
+	// Forwarded is true if the report was sent as a Flag to the server of
+	// the reported actor.
+	Forwarded bool
+	// Assignee is the actor of the moderator handling the report, or nil
+	// if it is unassigned.
+	Assignee *url.URL
+	// Resolved is the zero time if the report is still open.
+	Resolved   time.Time
+	Resolution string
+}
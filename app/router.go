@@ -30,6 +30,10 @@ type Router interface {
 	Get(name string) Route
 	WebOnlyHandle(path string, handler http.Handler) Route
 	WebOnlyHandleFunc(path string, f func(http.ResponseWriter, *http.Request)) Route
+	// ScopedHandle serves the handler only to requests authenticated with
+	// all of the given OAuth2 scopes. Others receive a 401 or 403 status.
+	ScopedHandle(path string, handler http.Handler, scopes ...string) Route
+	ScopedHandleFunc(path string, f func(http.ResponseWriter, *http.Request), scopes ...string) Route
 	Handle(path string, handler http.Handler) Route
 	HandleFunc(path string, f func(http.ResponseWriter, *http.Request)) Route
 	Headers(pairs ...string) Route
@@ -52,6 +56,8 @@ type Route interface {
 	HandleAccessTokenRequest(path string) Route
 	WebOnlyHandler(path string, handler http.Handler) Route
 	WebOnlyHandlerFunc(path string, f func(http.ResponseWriter, *http.Request)) Route
+	ScopedHandler(path string, handler http.Handler, scopes ...string) Route
+	ScopedHandlerFunc(path string, f func(http.ResponseWriter, *http.Request), scopes ...string) Route
 	Handler(handler http.Handler) Route
 	HandlerFunc(f func(http.ResponseWriter, *http.Request)) Route
 	Headers(pairs ...string) Route
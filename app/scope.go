@@ -0,0 +1,77 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"strings"
+)
+
+const (
+	// AllScope is the OAuth2 scope granting every other scope. It is the
+	// scope given to first party credentials.
+	AllScope = "all"
+	// PoliciesScope is the OAuth2 scope permitting management of a user's
+	// federation and moderation policies.
+	PoliciesScope = "policies"
+	// OpenIDScope is the OpenID Connect scope requesting an ID token and
+	// access to the userinfo endpoint, to sign in with a user's account.
+	OpenIDScope = "openid"
+	// GrantedScopesFormKey is the form key used on the authorization page
+	// to submit each scope the user consents to grant. If absent, none of
+	// the requested scopes are granted and the request is denied.
+	GrantedScopesFormKey = "granted_scope"
+)
+
+// Scope is an OAuth2 scope that a client may request and a user may grant.
+type Scope struct {
+	// Name is the value used in the OAuth2 "scope" parameter. It must not
+	// contain spaces.
+	Name string
+	// Description is a human readable explanation of what the scope
+	// permits, shown to users when they are asked for consent.
+	Description string
+}
+
+// ScopeApplication is an Application that declares its own OAuth2 scopes in
+// addition to the ones built into apcore. Implementing this interface is
+// optional. Clients may only request scopes that are declared.
+type ScopeApplication interface {
+	Scopes() []Scope
+}
+
+// ParseScopes splits a space-delimited OAuth2 scope value into scope names.
+func ParseScopes(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// ScopesPermit determines whether the granted scopes include all of the
+// required ones. The AllScope permits everything.
+func ScopesPermit(granted []string, required ...string) bool {
+	has := make(map[string]bool, len(granted))
+	for _, g := range granted {
+		if g == AllScope {
+			return true
+		}
+		has[g] = true
+	}
+	for _, r := range required {
+		if !has[r] {
+			return false
+		}
+	}
+	return true
+}
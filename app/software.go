@@ -27,6 +27,7 @@ type Software struct {
 	MinorVersion int
 	PatchVersion int
 	Repository   string
+	Homepage     string
 }
 
 func (s Software) String() string {
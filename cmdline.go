@@ -37,6 +37,17 @@ var (
 	infoLogFileFlag  = flag.String("info_log_file", "", "Log file for info, defaults to stdout")
 	errorLogFileFlag = flag.String("error_log_file", "", "Log file for errors, defaults to stderr")
 	configFlag       = flag.String("config", "config.ini", "Path to the configuration file")
+	usernameFlag     = flag.String("username", "", "Username of the account for the user and policy actions")
+	instanceFlag     = flag.Bool("instance", false, "Apply the policy actions to the instance-wide policies owned by the instance actor instead of the account given by the username flag")
+	policyFlag       = flag.String("policy", "", "ID of the policy for the policy actions")
+	purposeFlag      = flag.String("purpose", "federated_block", "Purpose of the policy for the create-policy action: federated_block, force_sensitive, strip_media, drop_public, delist, or quarantine")
+	policyFileFlag   = flag.String("policy_file", "", "Path to the JSON policy file for the create-policy, update-policy, and simulate-policy actions, or to the policy language file for the import-policies action")
+	sampleFlag       = flag.Int("sample", 100, "Number of the most recently received federated data the simulate-policy action applies the policy to")
+	reportFlag       = flag.String("report", "", "ID of the report for the assign-report and resolve-report actions")
+	resolvedFlag     = flag.Bool("resolved", false, "List resolved instead of open reports for the list-reports action")
+	resolutionFlag   = flag.String("resolution", "", "Description of how the report was handled for the resolve-report action")
+	quarantinedFlag  = flag.String("quarantined", "", "ID of the quarantined activity for the approve-quarantined and reject-quarantined actions")
+	clientFlag       = flag.String("client", "", "ID of the registered OAuth2 client for the remove-client, flag-resource-server, and unflag-resource-server actions")
 )
 
 // Usage is overridable so client applications can add custom additional
@@ -127,6 +138,136 @@ var (
 		Description: "Create or overwrite the server configuration in a guided flow.",
 		Action:      configureFn,
 	}
+	listPeers cmdAction = cmdAction{
+		Name:        "list-peers",
+		Description: "Lists the federated peers this server has exchanged traffic with, their software, and delivery health. Requires a database.",
+		Action:      listPeersFn,
+	}
+	suspendUser cmdAction = cmdAction{
+		Name:        "suspend-user",
+		Description: "Suspends the account given by the username flag, preventing it from logging in and federating. Requires a database.",
+		Action:      suspendUserFn,
+	}
+	unsuspendUser cmdAction = cmdAction{
+		Name:        "unsuspend-user",
+		Description: "Lifts the suspension of the account given by the username flag. Requires a database.",
+		Action:      unsuspendUserFn,
+	}
+	deleteUser cmdAction = cmdAction{
+		Name:        "delete-user",
+		Description: "Deletes the account given by the username flag, federating the deletion to its followers and known peers. Requires a database.",
+		Action:      deleteUserFn,
+	}
+	listPolicies cmdAction = cmdAction{
+		Name:        "list-policies",
+		Description: "Lists the policies of the account given by the username flag, or the instance-wide policies with the instance flag. Requires a database.",
+		Action:      listPoliciesFn,
+	}
+	createPolicy cmdAction = cmdAction{
+		Name:        "create-policy",
+		Description: "Creates a policy with the purpose flag from the policy_file flag for the account given by the username flag, or for the whole instance with the instance flag. Requires a database.",
+		Action:      createPolicyFn,
+	}
+	updatePolicy cmdAction = cmdAction{
+		Name:        "update-policy",
+		Description: "Replaces the policy given by the policy flag with the policy_file flag. Requires a database.",
+		Action:      updatePolicyFn,
+	}
+	deletePolicy cmdAction = cmdAction{
+		Name:        "delete-policy",
+		Description: "Deletes the policy given by the policy flag, along with its resolutions. Requires a database.",
+		Action:      deletePolicyFn,
+	}
+	enablePolicy cmdAction = cmdAction{
+		Name:        "enable-policy",
+		Description: "Enables the policy given by the policy flag. Requires a database.",
+		Action:      enablePolicyFn,
+	}
+	disablePolicy cmdAction = cmdAction{
+		Name:        "disable-policy",
+		Description: "Disables the policy given by the policy flag, so it is no longer applied. Requires a database.",
+		Action:      disablePolicyFn,
+	}
+	importPolicies cmdAction = cmdAction{
+		Name:        "import-policies",
+		Description: "Creates the policies written in the policy language in the policy_file flag for the account given by the username flag, or for the whole instance with the instance flag, replacing existing policies with the same name and purpose. Requires a database.",
+		Action:      importPoliciesFn,
+	}
+	exportPolicies cmdAction = cmdAction{
+		Name:        "export-policies",
+		Description: "Prints the policies of the account given by the username flag, or the instance-wide policies with the instance flag, in the policy language. Requires a database.",
+		Action:      exportPoliciesFn,
+	}
+	monitorPolicy cmdAction = cmdAction{
+		Name:        "monitor-policy",
+		Description: "Puts the policy given by the policy flag in monitor-only mode, recording its resolutions without blocking or rewriting anything. Requires a database.",
+		Action:      monitorPolicyFn,
+	}
+	enforcePolicy cmdAction = cmdAction{
+		Name:        "enforce-policy",
+		Description: "Takes the policy given by the policy flag out of monitor-only mode, so matches take effect again. Requires a database.",
+		Action:      enforcePolicyFn,
+	}
+	simulatePolicy cmdAction = cmdAction{
+		Name:        "simulate-policy",
+		Description: "Applies the policy_file flag with the purpose flag to a sample of recently received federated data, printing what would match without recording or enforcing anything. Requires a database.",
+		Action:      simulatePolicyFn,
+	}
+	listResolutions cmdAction = cmdAction{
+		Name:        "list-resolutions",
+		Description: "Lists the most recent resolutions of the policy given by the policy flag. Requires a database.",
+		Action:      listResolutionsFn,
+	}
+	listReports cmdAction = cmdAction{
+		Name:        "list-reports",
+		Description: "Lists the open moderation reports, or the resolved ones with the resolved flag. Requires a database.",
+		Action:      listReportsFn,
+	}
+	assignReport cmdAction = cmdAction{
+		Name:        "assign-report",
+		Description: "Assigns the report given by the report flag to the admin account given by the username flag. Requires a database.",
+		Action:      assignReportFn,
+	}
+	resolveReport cmdAction = cmdAction{
+		Name:        "resolve-report",
+		Description: "Resolves the report given by the report flag with the resolution flag. Requires a database.",
+		Action:      resolveReportFn,
+	}
+	listQuarantined cmdAction = cmdAction{
+		Name:        "list-quarantined",
+		Description: "Lists the incoming activities held by quarantine policies that await review. Requires a database.",
+		Action:      listQuarantinedFn,
+	}
+	approveQuarantined cmdAction = cmdAction{
+		Name:        "approve-quarantined",
+		Description: "Delivers the quarantined activity given by the quarantined flag to its recipient's inbox. Requires a database.",
+		Action:      approveQuarantinedFn,
+	}
+	rejectQuarantined cmdAction = cmdAction{
+		Name:        "reject-quarantined",
+		Description: "Discards the quarantined activity given by the quarantined flag. Requires a database.",
+		Action:      rejectQuarantinedFn,
+	}
+	listClients cmdAction = cmdAction{
+		Name:        "list-clients",
+		Description: "Lists the OAuth2 clients that registered themselves with this server. Requires a database.",
+		Action:      listClientsFn,
+	}
+	removeClient cmdAction = cmdAction{
+		Name:        "remove-client",
+		Description: "Removes the registered OAuth2 client given by the client flag and revokes its tokens. Requires a database.",
+		Action:      removeClientFn,
+	}
+	flagResourceServer cmdAction = cmdAction{
+		Name:        "flag-resource-server",
+		Description: "Flags the registered OAuth2 client given by the client flag as a resource server, which may introspect the tokens issued to other clients. Requires a database.",
+		Action:      flagResourceServerFn,
+	}
+	unflagResourceServer cmdAction = cmdAction{
+		Name:        "unflag-resource-server",
+		Description: "Stops the registered OAuth2 client given by the client flag from introspecting the tokens issued to other clients. Requires a database.",
+		Action:      unflagResourceServerFn,
+	}
 	version cmdAction = cmdAction{
 		Name:        "version",
 		Description: "List the current software and version.",
@@ -147,6 +288,32 @@ func init() {
 		initDb,
 		initAdmin,
 		configure,
+		listPeers,
+		suspendUser,
+		unsuspendUser,
+		deleteUser,
+		listPolicies,
+		createPolicy,
+		updatePolicy,
+		deletePolicy,
+		enablePolicy,
+		disablePolicy,
+		importPolicies,
+		exportPolicies,
+		monitorPolicy,
+		enforcePolicy,
+		simulatePolicy,
+		listResolutions,
+		listReports,
+		assignReport,
+		resolveReport,
+		listQuarantined,
+		approveQuarantined,
+		rejectQuarantined,
+		listClients,
+		removeClient,
+		flagResourceServer,
+		unflagResourceServer,
 		version,
 		help,
 	}
@@ -302,6 +469,142 @@ func configureFn(a app.Application) error {
 	return nil
 }
 
+// The 'list-peers' command line action.
+func listPeersFn(a app.Application) error {
+	return doListPeers(*configFlag, a, *devFlag, schemeFromFlags())
+}
+
+// The 'suspend-user' command line action.
+func suspendUserFn(a app.Application) error {
+	return doSuspendUser(*configFlag, a, *devFlag, schemeFromFlags(), *usernameFlag)
+}
+
+// The 'unsuspend-user' command line action.
+func unsuspendUserFn(a app.Application) error {
+	return doUnsuspendUser(*configFlag, a, *devFlag, schemeFromFlags(), *usernameFlag)
+}
+
+// The 'delete-user' command line action.
+func deleteUserFn(a app.Application) error {
+	del, err := framework.PromptDeleteUser(*usernameFlag)
+	if err != nil {
+		return err
+	} else if !del {
+		return nil
+	}
+	return doDeleteUser(*configFlag, a, *devFlag, schemeFromFlags(), *usernameFlag)
+}
+
+// The 'list-policies' command line action.
+func listPoliciesFn(a app.Application) error {
+	return doListPolicies(*configFlag, a, *devFlag, schemeFromFlags(), *instanceFlag, *usernameFlag)
+}
+
+// The 'create-policy' command line action.
+func createPolicyFn(a app.Application) error {
+	return doCreatePolicy(*configFlag, a, *devFlag, schemeFromFlags(), *instanceFlag, *usernameFlag, *purposeFlag, *policyFileFlag)
+}
+
+// The 'update-policy' command line action.
+func updatePolicyFn(a app.Application) error {
+	return doUpdatePolicy(*configFlag, a, *devFlag, schemeFromFlags(), *instanceFlag, *usernameFlag, *policyFlag, *policyFileFlag)
+}
+
+// The 'delete-policy' command line action.
+func deletePolicyFn(a app.Application) error {
+	return doDeletePolicy(*configFlag, a, *devFlag, schemeFromFlags(), *instanceFlag, *usernameFlag, *policyFlag)
+}
+
+// The 'enable-policy' command line action.
+func enablePolicyFn(a app.Application) error {
+	return doSetPolicyEnabled(*configFlag, a, *devFlag, schemeFromFlags(), *instanceFlag, *usernameFlag, *policyFlag, true)
+}
+
+// The 'disable-policy' command line action.
+func disablePolicyFn(a app.Application) error {
+	return doSetPolicyEnabled(*configFlag, a, *devFlag, schemeFromFlags(), *instanceFlag, *usernameFlag, *policyFlag, false)
+}
+
+// The 'import-policies' command line action.
+func importPoliciesFn(a app.Application) error {
+	return doImportPolicies(*configFlag, a, *devFlag, schemeFromFlags(), *instanceFlag, *usernameFlag, *policyFileFlag)
+}
+
+// The 'export-policies' command line action.
+func exportPoliciesFn(a app.Application) error {
+	return doExportPolicies(*configFlag, a, *devFlag, schemeFromFlags(), *instanceFlag, *usernameFlag)
+}
+
+// The 'monitor-policy' command line action.
+func monitorPolicyFn(a app.Application) error {
+	return doSetPolicyMonitor(*configFlag, a, *devFlag, schemeFromFlags(), *instanceFlag, *usernameFlag, *policyFlag, true)
+}
+
+// The 'enforce-policy' command line action.
+func enforcePolicyFn(a app.Application) error {
+	return doSetPolicyMonitor(*configFlag, a, *devFlag, schemeFromFlags(), *instanceFlag, *usernameFlag, *policyFlag, false)
+}
+
+// The 'simulate-policy' command line action.
+func simulatePolicyFn(a app.Application) error {
+	return doSimulatePolicy(*configFlag, a, *devFlag, schemeFromFlags(), *purposeFlag, *policyFileFlag, *sampleFlag)
+}
+
+// The 'list-resolutions' command line action.
+func listResolutionsFn(a app.Application) error {
+	return doListResolutions(*configFlag, a, *devFlag, schemeFromFlags(), *instanceFlag, *usernameFlag, *policyFlag)
+}
+
+// The 'list-reports' command line action.
+func listReportsFn(a app.Application) error {
+	return doListReports(*configFlag, a, *devFlag, schemeFromFlags(), *resolvedFlag)
+}
+
+// The 'assign-report' command line action.
+func assignReportFn(a app.Application) error {
+	return doAssignReport(*configFlag, a, *devFlag, schemeFromFlags(), *reportFlag, *usernameFlag)
+}
+
+// The 'resolve-report' command line action.
+func resolveReportFn(a app.Application) error {
+	return doResolveReport(*configFlag, a, *devFlag, schemeFromFlags(), *reportFlag, *resolutionFlag)
+}
+
+// The 'list-quarantined' command line action.
+func listQuarantinedFn(a app.Application) error {
+	return doListQuarantined(*configFlag, a, *devFlag, schemeFromFlags())
+}
+
+// The 'approve-quarantined' command line action.
+func approveQuarantinedFn(a app.Application) error {
+	return doApproveQuarantined(*configFlag, a, *devFlag, schemeFromFlags(), *quarantinedFlag)
+}
+
+// The 'reject-quarantined' command line action.
+func rejectQuarantinedFn(a app.Application) error {
+	return doRejectQuarantined(*configFlag, a, *devFlag, schemeFromFlags(), *quarantinedFlag)
+}
+
+// The 'list-clients' command line action.
+func listClientsFn(a app.Application) error {
+	return doListClients(*configFlag, a, *devFlag, schemeFromFlags())
+}
+
+// The 'remove-client' command line action.
+func removeClientFn(a app.Application) error {
+	return doRemoveClient(*configFlag, a, *devFlag, schemeFromFlags(), *clientFlag)
+}
+
+// The 'flag-resource-server' command line action.
+func flagResourceServerFn(a app.Application) error {
+	return doSetResourceServer(*configFlag, a, *devFlag, schemeFromFlags(), *clientFlag, true)
+}
+
+// The 'unflag-resource-server' command line action.
+func unflagResourceServerFn(a app.Application) error {
+	return doSetResourceServer(*configFlag, a, *devFlag, schemeFromFlags(), *clientFlag, false)
+}
+
 // The 'version' command line action.
 func versionFn(a app.Application) error {
 	fmt.Fprintf(os.Stdout, "%s; %s\n", a.Software(), apCoreSoftware())
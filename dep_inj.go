@@ -17,6 +17,7 @@
 package apcore
 
 import (
+	"context"
 	"database/sql"
 	"math/rand"
 	"time"
@@ -32,10 +33,20 @@ import (
 	"github.com/go-fed/apcore/framework/web"
 	"github.com/go-fed/apcore/models"
 	"github.com/go-fed/apcore/services"
+	"github.com/go-fed/apcore/util"
 	"github.com/gorilla/mux"
 )
 
 func newServer(configFileName string, appl app.Application, debug bool) (s *framework.Server, err error) {
+	s, _, _, err = newServerAndFramework(configFileName, appl, debug)
+	return
+}
+
+// newServerAndFramework also returns the Framework, for command line actions
+// that need to federate without serving. Such actions never start the server,
+// so they must call closeFn to release its prepared statements and database
+// connections when done.
+func newServerAndFramework(configFileName string, appl app.Application, debug bool) (s *framework.Server, fw *framework.Framework, closeFn func(), err error) {
 	// Load the configuration
 	c, err := framework.LoadConfigFile(configFileName, appl, debug)
 	if err != nil {
@@ -58,15 +69,32 @@ func newServer(configFileName string, appl app.Application, debug bool) (s *fram
 	if err != nil {
 		return
 	}
+	defer func() {
+		if err != nil {
+			sqldb.Close()
+		}
+	}()
 
 	// Create the models & services for higher-level transformations
-	cryp, data, dAttempts, followers, following, inboxes, liked, oauthSrv, outboxes, policies, pkeys, users, nodeinfo, any, models := createModelsAndServices(c, sqldb, dialect, appl, host, scheme, clock)
+	cryp, data, dAttempts, followers, following, inboxes, liked, featured, objects, blocks, mutes, followRequests, reports, quarantine, oauthSrv, outboxes, policies, pkeys, users, nodeinfo, peers, any, models := createModelsAndServices(c, sqldb, dialect, appl, host, scheme, clock)
 
 	// Ensure the SQL statements are prepared
 	err = prepare(models, sqldb, dialect)
 	if err != nil {
 		return
 	}
+	closeFn = func() {
+		for _, m := range models {
+			m.Close()
+		}
+		sqldb.Close()
+	}
+
+	// Give users that predate featured collections their collection.
+	err = users.BackfillFeatured(util.Context{context.Background()})
+	if err != nil {
+		return
+	}
 
 	// ** Create Misc Helpers **
 
@@ -74,7 +102,7 @@ func newServer(configFileName string, appl app.Application, debug bool) (s *fram
 	//
 	// Creating a placeholder early allows us to inject it into the needed
 	// dependencies, even if *Framework is not yet ready for use.
-	fw := &framework.Framework{}
+	fw = &framework.Framework{}
 	internalErrorHandler := appl.InternalServerErrorHandler(fw)
 
 	// Prepare web sessions behavior
@@ -84,7 +112,11 @@ func newServer(configFileName string, appl app.Application, debug bool) (s *fram
 	}
 
 	// Prepare OAuth2 server
-	oauth, err := oauth2.NewServer(c, scheme, internalErrorHandler, oauthSrv, cryp, sess)
+	scopes, err := oauth2.NewScopes(appl)
+	if err != nil {
+		return
+	}
+	oauth, err := oauth2.NewServer(c, scheme, internalErrorHandler, oauthSrv, cryp, sess, scopes, pkeys, users)
 	if err != nil {
 		return
 	}
@@ -104,13 +136,14 @@ func newServer(configFileName string, appl app.Application, debug bool) (s *fram
 		followers,
 		following,
 		liked,
+		objects,
 		any)
 
 	// Create a pub.Database
 	apdb := ap.NewAPDB(db, appl)
 
 	// Create a controller for outbound messaging.
-	tc, err := conn.NewController(c, appl, clock, httpClient, dAttempts, pkeys)
+	tc, err := conn.NewController(c, appl, scheme, clock, httpClient, dAttempts, pkeys, peers)
 	if err != nil {
 		return
 	}
@@ -126,6 +159,11 @@ func newServer(configFileName string, appl app.Application, debug bool) (s *fram
 		policies,
 		followers,
 		users,
+		featured,
+		blocks,
+		followRequests,
+		reports,
+		quarantine,
 		tc)
 	if err != nil {
 		return
@@ -139,10 +177,13 @@ func newServer(configFileName string, appl app.Application, debug bool) (s *fram
 		followers,
 		tc)
 
+	// Enforce the addressing of local objects when they are fetched.
+	visibility := ap.NewVisibility(scheme, host, appl, oauth, data, followers, pkeys, tc)
+
 	// ** Initialize the Web Server **
 
 	// Build framework for auxiliary behaviors
-	fw = framework.BuildFramework(scheme, host, fw, oauth, sess, data, actor, appl)
+	fw = framework.BuildFramework(scheme, host, c.ServerConfig.AccountDomain, fw, oauth, sess, data, peers, featured, blocks, mutes, followRequests, reports, quarantine, policies, users, pkeys, tc, actor, actorMap, appl)
 
 	// Obtain a normal router and fallback web handlers.
 	mr := mux.NewRouter()
@@ -160,6 +201,7 @@ func newServer(configFileName string, appl app.Application, debug bool) (s *fram
 		actorMap,
 		clock,
 		apdb,
+		visibility.Authorize,
 		host,
 		scheme,
 		internalErrorHandler,
@@ -183,6 +225,7 @@ func newServer(configFileName string, appl app.Application, debug bool) (s *fram
 		following,
 		followers,
 		liked,
+		policies,
 		sqldb,
 		oauth,
 		sess,
@@ -228,7 +271,7 @@ func newModels(configFileName string, appl app.Application, debug bool, scheme s
 		return
 	}
 
-	_, _, _, _, _, _, _, _, _, _, _, _, _, _, m = createModelsAndServices(c, sqldb, dialect, appl, host, scheme, clock)
+	_, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, m = createModelsAndServices(c, sqldb, dialect, appl, host, scheme, clock)
 	return
 }
 
@@ -255,7 +298,152 @@ func newUserService(configFileName string, appl app.Application, debug bool, sch
 	}
 
 	var ml []models.Model
-	_, _, _, _, _, _, _, _, _, _, _, users, _, _, ml = createModelsAndServices(c, sqldb, dialect, appl, host, scheme, clock)
+	_, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, users, _, _, _, ml = createModelsAndServices(c, sqldb, dialect, appl, host, scheme, clock)
+	err = prepare(ml, sqldb, dialect)
+	return
+}
+
+func newPeerService(configFileName string, appl app.Application, debug bool, scheme string) (sqldb *sql.DB, peers *services.Peers, err error) {
+	// Load the configuration
+	var c *config.Config
+	c, err = framework.LoadConfigFile(configFileName, appl, debug)
+	if err != nil {
+		return
+	}
+	host := c.ServerConfig.Host
+
+	// Create a server clock, a pub.Clock
+	var clock pub.Clock
+	clock, err = ap.NewClock(c.ActivityPubConfig.ClockTimezone)
+	if err != nil {
+		return
+	}
+
+	// Create the SQL database
+	var dialect models.SqlDialect
+	sqldb, dialect, err = db.NewDB(c)
+	if err != nil {
+		return
+	}
+
+	var ml []models.Model
+	_, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, peers, _, ml = createModelsAndServices(c, sqldb, dialect, appl, host, scheme, clock)
+	err = prepare(ml, sqldb, dialect)
+	return
+}
+
+func newPolicyService(configFileName string, appl app.Application, debug bool, scheme string) (sqldb *sql.DB, users *services.Users, policies *services.Policies, err error) {
+	// Load the configuration
+	var c *config.Config
+	c, err = framework.LoadConfigFile(configFileName, appl, debug)
+	if err != nil {
+		return
+	}
+	host := c.ServerConfig.Host
+
+	// Create a server clock, a pub.Clock
+	var clock pub.Clock
+	clock, err = ap.NewClock(c.ActivityPubConfig.ClockTimezone)
+	if err != nil {
+		return
+	}
+
+	// Create the SQL database
+	var dialect models.SqlDialect
+	sqldb, dialect, err = db.NewDB(c)
+	if err != nil {
+		return
+	}
+
+	var ml []models.Model
+	_, _, _, _, _, _, _, _, _, _, _, _, _, _, _, _, policies, _, users, _, _, _, ml = createModelsAndServices(c, sqldb, dialect, appl, host, scheme, clock)
+	err = prepare(ml, sqldb, dialect)
+	return
+}
+
+func newReportService(configFileName string, appl app.Application, debug bool, scheme string) (sqldb *sql.DB, users *services.Users, reports *services.Reports, err error) {
+	// Load the configuration
+	var c *config.Config
+	c, err = framework.LoadConfigFile(configFileName, appl, debug)
+	if err != nil {
+		return
+	}
+	host := c.ServerConfig.Host
+
+	// Create a server clock, a pub.Clock
+	var clock pub.Clock
+	clock, err = ap.NewClock(c.ActivityPubConfig.ClockTimezone)
+	if err != nil {
+		return
+	}
+
+	// Create the SQL database
+	var dialect models.SqlDialect
+	sqldb, dialect, err = db.NewDB(c)
+	if err != nil {
+		return
+	}
+
+	var ml []models.Model
+	_, _, _, _, _, _, _, _, _, _, _, _, reports, _, _, _, _, _, users, _, _, _, ml = createModelsAndServices(c, sqldb, dialect, appl, host, scheme, clock)
+	err = prepare(ml, sqldb, dialect)
+	return
+}
+
+func newQuarantineService(configFileName string, appl app.Application, debug bool, scheme string) (sqldb *sql.DB, quarantine *services.Quarantine, err error) {
+	// Load the configuration
+	var c *config.Config
+	c, err = framework.LoadConfigFile(configFileName, appl, debug)
+	if err != nil {
+		return
+	}
+	host := c.ServerConfig.Host
+
+	// Create a server clock, a pub.Clock
+	var clock pub.Clock
+	clock, err = ap.NewClock(c.ActivityPubConfig.ClockTimezone)
+	if err != nil {
+		return
+	}
+
+	// Create the SQL database
+	var dialect models.SqlDialect
+	sqldb, dialect, err = db.NewDB(c)
+	if err != nil {
+		return
+	}
+
+	var ml []models.Model
+	_, _, _, _, _, _, _, _, _, _, _, _, _, quarantine, _, _, _, _, _, _, _, _, ml = createModelsAndServices(c, sqldb, dialect, appl, host, scheme, clock)
+	err = prepare(ml, sqldb, dialect)
+	return
+}
+
+func newOAuth2Service(configFileName string, appl app.Application, debug bool, scheme string) (sqldb *sql.DB, oauth *services.OAuth2, err error) {
+	// Load the configuration
+	var c *config.Config
+	c, err = framework.LoadConfigFile(configFileName, appl, debug)
+	if err != nil {
+		return
+	}
+	host := c.ServerConfig.Host
+
+	// Create a server clock, a pub.Clock
+	var clock pub.Clock
+	clock, err = ap.NewClock(c.ActivityPubConfig.ClockTimezone)
+	if err != nil {
+		return
+	}
+
+	// Create the SQL database
+	var dialect models.SqlDialect
+	sqldb, dialect, err = db.NewDB(c)
+	if err != nil {
+		return
+	}
+
+	var ml []models.Model
+	_, _, _, _, _, _, _, _, _, _, _, _, _, _, oauth, _, _, _, _, _, _, _, ml = createModelsAndServices(c, sqldb, dialect, appl, host, scheme, clock)
 	err = prepare(ml, sqldb, dialect)
 	return
 }
@@ -267,12 +455,20 @@ func createModelsAndServices(c *config.Config, sqldb *sql.DB, d models.SqlDialec
 	following *services.Following,
 	inboxes *services.Inboxes,
 	liked *services.Liked,
+	featured *services.Featured,
+	objects *services.ObjectCollections,
+	blocks *services.Blocks,
+	mutes *services.Mutes,
+	followRequests *services.FollowRequests,
+	reports *services.Reports,
+	quarantine *services.Quarantine,
 	oauth *services.OAuth2,
 	outboxes *services.Outboxes,
 	policies *services.Policies,
 	pkeys *services.PrivateKeys,
 	users *services.Users,
 	nodeinfo *services.NodeInfo,
+	peers *services.Peers,
 	any *services.Any,
 	m []models.Model) {
 	us := &models.Users{}
@@ -288,8 +484,16 @@ func createModelsAndServices(c *config.Config, sqldb *sql.DB, d models.SqlDialec
 	fn := &models.Following{}
 	fr := &models.Followers{}
 	li := &models.Liked{}
+	fe := &models.Featured{}
+	oc := &models.ObjectCollections{}
+	bl := &models.Blocks{}
+	mu := &models.Mutes{}
+	fq := &models.FollowRequests{}
+	rp := &models.Reports{}
 	po := &models.Policies{}
 	rs := &models.Resolutions{}
+	qu := &models.Quarantine{}
+	pe := &models.Peers{}
 	m = []models.Model{
 		us,
 		fd,
@@ -304,8 +508,16 @@ func createModelsAndServices(c *config.Config, sqldb *sql.DB, d models.SqlDialec
 		fn,
 		fr,
 		li,
+		fe,
+		oc,
+		bl,
+		mu,
+		fq,
+		rp,
 		po,
 		rs,
+		qu,
+		pe,
 	}
 	cryp = &services.Crypto{
 		DB:    sqldb,
@@ -331,8 +543,42 @@ func createModelsAndServices(c *config.Config, sqldb *sql.DB, d models.SqlDialec
 		DB:    sqldb,
 		Liked: li,
 	}
+	featured = &services.Featured{
+		DB:       sqldb,
+		Featured: fe,
+	}
+	objects = &services.ObjectCollections{
+		DB:                sqldb,
+		ObjectCollections: oc,
+	}
+	blocks = &services.Blocks{
+		DB:        sqldb,
+		Blocks:    bl,
+		Followers: fr,
+		Following: fn,
+	}
+	mutes = &services.Mutes{
+		DB:    sqldb,
+		Mutes: mu,
+	}
+	followRequests = &services.FollowRequests{
+		DB:             sqldb,
+		FollowRequests: fq,
+		Followers:      fr,
+	}
+	reports = &services.Reports{
+		DB:      sqldb,
+		Reports: rp,
+		Host:    host,
+	}
+	quarantine = &services.Quarantine{
+		DB:         sqldb,
+		Quarantine: qu,
+	}
 	data = &services.Data{
+		App:                   appl,
 		DB:                    sqldb,
+		Clock:                 clock,
 		Hostname:              host,
 		FedData:               fd,
 		LocalData:             ld,
@@ -340,6 +586,8 @@ func createModelsAndServices(c *config.Config, sqldb *sql.DB, d models.SqlDialec
 		Following:             following,
 		Followers:             followers,
 		Liked:                 liked,
+		Featured:              featured,
+		ObjectCollections:     objects,
 		DefaultCollectionSize: c.DatabaseConfig.DefaultCollectionPageSize,
 		MaxCollectionPageSize: c.DatabaseConfig.MaxCollectionPageSize,
 	}
@@ -354,16 +602,24 @@ func createModelsAndServices(c *config.Config, sqldb *sql.DB, d models.SqlDialec
 		Outboxes: ou,
 	}
 	policies = &services.Policies{
-		Clock:       clock,
-		DB:          sqldb,
-		Policies:    po,
-		Resolutions: rs,
+		Scheme:           scheme,
+		Host:             host,
+		Clock:            clock,
+		DB:               sqldb,
+		FedData:          fd,
+		Policies:         po,
+		Resolutions:      rs,
+		CacheInvalidated: time.Second * time.Duration(c.ActivityPubConfig.PolicyCacheSeconds),
+		Logging:          services.ResolutionLogging(c.ActivityPubConfig.PolicyResolutionLogging),
+		SampleRate:       c.ActivityPubConfig.PolicyResolutionSampleRate,
+		Rand:             rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 	pkeys = &services.PrivateKeys{
 		Scheme:      scheme,
 		Host:        host,
 		DB:          sqldb,
 		PrivateKeys: pk,
+		Users:       us,
 	}
 	users = &services.Users{
 		App:         appl,
@@ -375,6 +631,9 @@ func createModelsAndServices(c *config.Config, sqldb *sql.DB, d models.SqlDialec
 		Followers:   fr,
 		Following:   fn,
 		Liked:       li,
+		Featured:    fe,
+		LocalData:   ld,
+		TokenInfos:  ti,
 	}
 	nodeinfo = &services.NodeInfo{
 		DB:               sqldb,
@@ -383,6 +642,10 @@ func createModelsAndServices(c *config.Config, sqldb *sql.DB, d models.SqlDialec
 		Rand:             rand.New(rand.NewSource(time.Now().UnixNano())),
 		CacheInvalidated: time.Second * time.Duration(c.NodeInfoConfig.AnonymizedStatsCacheInvalidatedSeconds),
 	}
+	peers = &services.Peers{
+		DB:    sqldb,
+		Peers: pe,
+	}
 	any = &services.Any{
 		DB:      sqldb,
 		Dialect: d,
@@ -391,6 +654,19 @@ func createModelsAndServices(c *config.Config, sqldb *sql.DB, d models.SqlDialec
 }
 
 func prepare(ml []models.Model, db *sql.DB, d models.SqlDialect) error {
+	// Statements may rely on columns added by a migration, so tables
+	// created by an earlier version are migrated first.
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := models.MigrateTables(tx, d, ml); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 	for _, m := range ml {
 		if err := m.Prepare(db, d); err != nil {
 			return err
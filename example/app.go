@@ -192,7 +192,20 @@ func (a *App) GetLoginWebHandlerFunc(f app.Framework) http.HandlerFunc {
 // for the user to approve in the OAuth2 flow.
 func (a *App) GetAuthWebHandlerFunc(f app.Framework) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		a.getSessionWriteTemplateHelper(w, r, f, http.StatusOK, authTemplate, nil, "GetAuthWebHandlerFunc")
+		client, err := f.RequestingClient(r)
+		if err != nil {
+			a.BadRequestHandler(f).ServeHTTP(w, r)
+			return
+		}
+		scopes, err := f.RequestedScopes(r)
+		if err != nil {
+			a.BadRequestHandler(f).ServeHTTP(w, r)
+			return
+		}
+		a.getSessionWriteTemplateHelper(w, r, f, http.StatusOK, authTemplate, map[string]interface{}{
+			"Client": client,
+			"Scopes": scopes,
+		}, "GetAuthWebHandlerFunc")
 	}
 }
 
@@ -341,7 +354,7 @@ func (a *App) BuildRoutes(r app.Router, db app.Database, f app.Framework) error
 		}
 		// View list of existing public (and maybe private) notes with
 		// pagination.
-		userID, authd, err := f.Validate(w, r)
+		userID, _, authd, err := f.Validate(w, r)
 		if err != nil {
 			util.ErrorLogger.Errorf("error validating token/creds in GET /notes: %s", err)
 			// continue processing request as unauthenticated.
@@ -373,7 +386,7 @@ func (a *App) BuildRoutes(r app.Router, db app.Database, f app.Framework) error
 			return
 		}
 		// Ensure the user is logged in.
-		_, authd, err := f.Validate(w, r)
+		_, _, authd, err := f.Validate(w, r)
 		if err != nil {
 			util.ErrorLogger.Errorf("error validating oauth2 token in GET /notes/create: %s", err)
 			internalErrorHandler.ServeHTTP(w, r)
@@ -391,7 +404,7 @@ func (a *App) BuildRoutes(r app.Router, db app.Database, f app.Framework) error
 	})
 	r.NewRoute().Path("/notes/create").Methods("POST").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Ensure the user is logged in.
-		userID, authd, err := f.Validate(w, r)
+		userID, _, authd, err := f.Validate(w, r)
 		if err != nil {
 			util.ErrorLogger.Errorf("error validating oauth2 token in POST /notes/create: %s", err)
 			internalErrorHandler.ServeHTTP(w, r)
@@ -476,7 +489,7 @@ func (a *App) BuildRoutes(r app.Router, db app.Database, f app.Framework) error
 	// ActivityStreams data.
 	authFn := func(c util.Context, w http.ResponseWriter, r *http.Request, db app.Database) (permit bool, err error) {
 		// Determine who, if any, is logged-in.
-		userID, authd, err := f.Validate(w, r)
+		userID, _, authd, err := f.Validate(w, r)
 		if err != nil {
 			util.ErrorLogger.Errorf("error validating token/creds in GET /notes: %s", err)
 			// continue processing request as unauthenticated.
@@ -572,25 +585,36 @@ func (a *App) ApplySocialCallbacks(swc *pub.SocialWrappedCallbacks) (others []in
 	return
 }
 
-// ScopePermitsPostOutbox ensures the OAuth2 token scope is "loggedin", which
-// is the only permission. Other applications can have more granular
-// authorization systems.
+// Scopes declares the OAuth2 scopes this application understands, in addition
+// to the ones built into apcore. Clients may request any of these, and users
+// are asked to consent to them on the authorization page.
+func (a *App) Scopes() []app.Scope {
+	return []app.Scope{
+		{Name: "postOutbox", Description: "Post activities as you"},
+		{Name: "getInbox", Description: "Read your private inbox"},
+		{Name: "getOutbox", Description: "Read your private outbox"},
+	}
+}
+
+// ScopePermitsPostOutbox ensures the OAuth2 token scope includes
+// "postOutbox". Other applications can have more granular authorization
+// systems.
 func (a *App) ScopePermitsPostOutbox(scope string) (permitted bool, err error) {
-	return scope == "postOutbox" || scope == "all", nil
+	return app.ScopesPermit(app.ParseScopes(scope), "postOutbox"), nil
 }
 
-// ScopePermitsPrivateGetInbox ensures the OAuth2 token scope is "loggedin",
-// which is the only permission. Other applications can have more granular
-// authorization systems.
+// ScopePermitsPrivateGetInbox ensures the OAuth2 token scope includes
+// "getInbox". Other applications can have more granular authorization
+// systems.
 func (a *App) ScopePermitsPrivateGetInbox(scope string) (permitted bool, err error) {
-	return scope == "getInbox" || scope == "all", nil
+	return app.ScopesPermit(app.ParseScopes(scope), "getInbox"), nil
 }
 
-// ScopePermitsPrivateGetOutbox ensures the OAuth2 token scope is "loggedin",
-// which is the only permission. Other applications can have more granular
-// authorization systems.
+// ScopePermitsPrivateGetOutbox ensures the OAuth2 token scope includes
+// "getOutbox". Other applications can have more granular authorization
+// systems.
 func (a *App) ScopePermitsPrivateGetOutbox(scope string) (permitted bool, err error) {
-	return scope == "getOutbox" || scope == "all", nil
+	return app.ScopesPermit(app.ParseScopes(scope), "getOutbox"), nil
 }
 
 // Software describes the current running software, based on the code. This
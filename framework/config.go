@@ -57,8 +57,9 @@ func defaultServerConfig() config.ServerConfig {
 
 func defaultOAuth2Config() config.OAuth2Config {
 	return config.OAuth2Config{
-		AccessTokenExpiry:  3600,
-		RefreshTokenExpiry: 7200,
+		AccessTokenExpiry:        3600,
+		RefreshTokenExpiry:       7200,
+		EnableClientRegistration: true,
 	}
 }
 
@@ -94,6 +95,10 @@ func defaultActivityPubConfig() config.ActivityPubConfig {
 		RetrySleepPeriod:                    300,
 		OutboundRateLimitPrunePeriodSeconds: 60,
 		OutboundRateLimitPruneAgeSeconds:    30,
+		PeerNodeInfoRefreshSeconds:          86400,
+		PolicyCacheSeconds:                  60,
+		PolicyResolutionLogging:             "all",
+		PolicyResolutionSampleRate:          0.01,
 	}
 }
 
@@ -148,6 +153,9 @@ func LoadConfigFile(filename string, a app.Application, debug bool) (c *config.C
 	}
 	if debug {
 		c.ServerConfig.Host = "localhost"
+		c.ServerConfig.AccountDomain = "localhost"
+	} else if len(c.ServerConfig.AccountDomain) == 0 {
+		c.ServerConfig.AccountDomain = c.ServerConfig.Host
 	}
 	return
 }
@@ -28,6 +28,7 @@ type Config struct {
 // Configuration section specifically for the HTTP server.
 type ServerConfig struct {
 	Host                        string `ini:"sr_host" comment:"(required) Host with TLD for this instance (basically, the fully qualified domain or subdomain); ignored in debug mode"`
+	AccountDomain               string `ini:"sr_account_domain" comment:"(default: sr_host) Domain used in user handles and WebFinger acct: URIs (user@account_domain) when it differs from the host serving this instance; the account domain must redirect its /.well-known/webfinger and /.well-known/host-meta requests to sr_host; ignored in debug mode"`
 	CertFile                    string `ini:"sr_cert_file" comment:"(required) Path to the certificate file used to establish TLS connections for HTTPS"`
 	KeyFile                     string `ini:"sr_key_file" comment:"(required) Path to the private key file used to establish TLS connections for HTTPS"`
 	CookieAuthKeyFile           string `ini:"sr_cookie_auth_key_file" comment:"(required) Path to private key file used for cookie authentication"`
@@ -46,8 +47,9 @@ type ServerConfig struct {
 }
 
 type OAuth2Config struct {
-	AccessTokenExpiry  int `ini:"oauth_access_token_expiry" comment:"(default: 3600 seconds) Duration in seconds until an access token expires; zero or negative values are invalid."`
-	RefreshTokenExpiry int `ini:"oauth_refresh_token_expiry" comment:"(default: 7200 seconds) Duration in seconds until a refresh token expires; zero or negative values are invalid."`
+	AccessTokenExpiry        int  `ini:"oauth_access_token_expiry" comment:"(default: 3600 seconds) Duration in seconds until an access token expires; zero or negative values are invalid."`
+	RefreshTokenExpiry       int  `ini:"oauth_refresh_token_expiry" comment:"(default: 7200 seconds) Duration in seconds until a refresh token expires; zero or negative values are invalid."`
+	EnableClientRegistration bool `ini:"oauth_enable_client_registration" comment:"(default: true) Whether third party clients may register themselves at the /oauth2/register endpoint using OAuth 2 Dynamic Client Registration"`
 }
 
 // Configuration section specifically for the database.
@@ -74,6 +76,10 @@ type ActivityPubConfig struct {
 	RetryPageSize                       int                  `ini:"ap_retry_page_size" comment:"(default: 25) The number of retryable deliveries to request from the database at a time; a negative value or zero value is invalid"`
 	RetryAbandonLimit                   int                  `ini:"ap_retry_abandon_limit" comment:"(default: 10) The maximum number of times the app will attempt to deliver an Activity to a federated peer and fail before permanently giving up and abandoning any further attempts to deliver it; a negative value or zero value is invalid"`
 	RetrySleepPeriod                    int                  `ini:"ap_retry_sleep_period_seconds" comment:"(default: 300) The time period to await between making periodic attempts to re-deliver Activities to federated peers that have never been successfully delivered; a 300-second retry sleep period with an abandon limit of 10 results in an exponential backoff of 10 delivery attempts across roughly 3 days; a negative value or zero value is invalid"`
+	PeerNodeInfoRefreshSeconds          int                  `ini:"ap_peer_nodeinfo_refresh_seconds" comment:"(default: 86400) The time period to await between periodically fetching the NodeInfo of known federated peers to update their software name and version; NodeInfo is always fetched upon first contact with a peer; a negative value or zero value is invalid"`
+	PolicyCacheSeconds                  int                  `ini:"ap_policy_cache_seconds" comment:"(default: 60) The time period compiled federation policies are cached in memory before being reloaded from the database; changes made through the running server apply immediately, while changes made from the command line apply within this period; a negative value or zero value is invalid"`
+	PolicyResolutionLogging             string               `ini:"ap_policy_resolution_logging" comment:"(default: \"all\") Which results of applying federation policies to incoming activities are recorded: \"all\", \"matches\" for only those that matched, or \"sampled\" for those that matched and a random sample of the rest"`
+	PolicyResolutionSampleRate          float64              `ini:"ap_policy_resolution_sample_rate" comment:"(default: 0.01) The fraction of federation policy results that did not match which are recorded when ap_policy_resolution_logging is \"sampled\"; must be between 0 and 1"`
 }
 
 // Configuration for HTTP Signatures.
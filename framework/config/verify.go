@@ -19,6 +19,7 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
 )
 
 func (c *Config) Verify() error {
@@ -44,6 +45,11 @@ func (c *ServerConfig) Verify() error {
 	if len(c.Host) == 0 {
 		return errors.New("sr_host is empty, but it is required")
 	}
+	if len(c.AccountDomain) > 0 {
+		if err := verifyDomain(c.AccountDomain); err != nil {
+			return fmt.Errorf("sr_account_domain is invalid: %w", err)
+		}
+	}
 	if len(c.CertFile) == 0 {
 		return errors.New("sr_cert_file is empty, but it is required")
 	}
@@ -116,6 +122,20 @@ func (c *ActivityPubConfig) Verify() error {
 	if c.RetrySleepPeriod <= 0 {
 		return fmt.Errorf("ap_retry_sleep_period_seconds is zero or negative, which is forbidden: %d", c.RetrySleepPeriod)
 	}
+	if c.PeerNodeInfoRefreshSeconds <= 0 {
+		return fmt.Errorf("ap_peer_nodeinfo_refresh_seconds is zero or negative, which is forbidden: %d", c.PeerNodeInfoRefreshSeconds)
+	}
+	if c.PolicyCacheSeconds <= 0 {
+		return fmt.Errorf("ap_policy_cache_seconds is zero or negative, which is forbidden: %d", c.PolicyCacheSeconds)
+	}
+	switch c.PolicyResolutionLogging {
+	case "", "all", "matches", "sampled":
+	default:
+		return fmt.Errorf("ap_policy_resolution_logging is not \"all\", \"matches\", or \"sampled\": %q", c.PolicyResolutionLogging)
+	}
+	if c.PolicyResolutionSampleRate < 0 || c.PolicyResolutionSampleRate > 1 {
+		return fmt.Errorf("ap_policy_resolution_sample_rate is not between 0 and 1: %g", c.PolicyResolutionSampleRate)
+	}
 	if err := c.HttpSignaturesConfig.Verify(); err != nil {
 		return err
 	}
@@ -133,3 +153,16 @@ func (c *PostgresConfig) Verify() error {
 func (c *NodeInfoConfig) Verify() error {
 	return nil
 }
+
+// verifyDomain ensures the value is a bare domain, such as "example.com", and
+// not a URL containing a scheme, port, path, or other components.
+func verifyDomain(d string) error {
+	u, err := url.Parse("//" + d)
+	if err != nil {
+		return err
+	}
+	if u.Host != d || len(u.Port()) > 0 || len(u.Path) > 0 || u.User != nil {
+		return fmt.Errorf("%q must be a bare domain without a scheme, port, or path", d)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package conn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-fed/apcore/app"
+	"github.com/go-fed/apcore/framework/config"
+	"github.com/go-fed/apcore/framework/web"
+	"github.com/go-fed/apcore/services"
+	"github.com/go-fed/apcore/util"
+)
+
+const (
+	nodeInfoWellKnownPath = "/.well-known/nodeinfo"
+	nodeInfoSchemaPrefix  = "http://nodeinfo.diaspora.software/ns/schema/"
+	// Limits the size of NodeInfo documents read from peers.
+	maxNodeInfoBytes = 1 << 20
+)
+
+// peerTracker records the remote hosts this server exchanges traffic with, and
+// discovers the software they run via NodeInfo.
+type peerTracker struct {
+	// Immutable
+	p         *services.Peers
+	a         app.Application
+	client    *http.Client
+	tc        *Controller
+	scheme    string
+	refresh   time.Duration
+	refreshFn *util.SafeStartStop
+	// Mutable
+	mu       sync.Mutex
+	fetching map[string]bool
+}
+
+func newPeerTracker(p *services.Peers, a app.Application, client *http.Client, tc *Controller, scheme string, c *config.Config) *peerTracker {
+	pt := &peerTracker{
+		p:        p,
+		a:        a,
+		client:   client,
+		tc:       tc,
+		scheme:   scheme,
+		refresh:  time.Duration(c.ActivityPubConfig.PeerNodeInfoRefreshSeconds) * time.Second,
+		fetching: make(map[string]bool),
+	}
+	pt.refreshFn = util.NewSafeStartStop(pt.refreshStale, pt.refresh)
+	return pt
+}
+
+func (p *peerTracker) Start() {
+	p.refreshFn.Start()
+}
+
+func (p *peerTracker) Stop() {
+	p.refreshFn.Stop()
+}
+
+// seen records traffic with a host, fetching its NodeInfo in the background
+// upon first contact.
+func (p *peerTracker) seen(c util.Context, host string) {
+	needsNodeInfo, err := p.p.Seen(c, host)
+	if err != nil {
+		util.ErrorLogger.Errorf("failed to record peer %s as seen: %s", host, err)
+		return
+	}
+	if needsNodeInfo {
+		go p.fetchNodeInfo(context.Background(), host)
+	}
+}
+
+// delivered records the outcome of delivering to a host.
+func (p *peerTracker) delivered(c util.Context, host string, ok bool) {
+	p.seen(c, host)
+	var err error
+	if ok {
+		err = p.p.DeliverySucceeded(c, host)
+	} else {
+		err = p.p.DeliveryFailed(c, host)
+	}
+	if err != nil {
+		util.ErrorLogger.Errorf("failed to record delivery health for peer %s: %s", host, err)
+	}
+}
+
+func (p *peerTracker) refreshStale(ctx context.Context) {
+	c := util.Context{ctx}
+	hosts, err := p.p.StaleHosts(c, p.refresh)
+	if err != nil {
+		util.ErrorLogger.Errorf("peer tracker failed to obtain peers with stale NodeInfo: %s", err)
+		return
+	}
+	for _, host := range hosts {
+		if ctx.Err() != nil {
+			return
+		}
+		p.fetchNodeInfo(ctx, host)
+	}
+}
+
+// fetchNodeInfo fetches and stores the software a host reports in its
+// NodeInfo. Only one fetch per host is in flight at a time.
+func (p *peerTracker) fetchNodeInfo(ctx context.Context, host string) {
+	p.mu.Lock()
+	if p.fetching[host] {
+		p.mu.Unlock()
+		return
+	}
+	p.fetching[host] = true
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.fetching, host)
+		p.mu.Unlock()
+	}()
+
+	c := util.Context{ctx}
+	name, version, err := p.getSoftware(ctx, host)
+	if err != nil {
+		util.ErrorLogger.Errorf("failed to fetch NodeInfo for peer %s: %s", host, err)
+		if err = p.p.SoftwareUnavailable(c, host); err != nil {
+			util.ErrorLogger.Errorf("failed to record NodeInfo fetch attempt for peer %s: %s", host, err)
+		}
+		return
+	}
+	if err = p.p.SetSoftware(c, host, name, version); err != nil {
+		util.ErrorLogger.Errorf("failed to record NodeInfo software for peer %s: %s", host, err)
+	}
+}
+
+type nodeInfoLinks struct {
+	Links []struct {
+		Rel  string `json:"rel"`
+		Href string `json:"href"`
+	} `json:"links"`
+}
+
+type nodeInfoSoftware struct {
+	Software struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"software"`
+}
+
+func (p *peerTracker) getSoftware(ctx context.Context, host string) (name, version string, err error) {
+	wellKnown := &url.URL{
+		Scheme: p.scheme,
+		Host:   host,
+		Path:   nodeInfoWellKnownPath,
+	}
+	var l nodeInfoLinks
+	if err = p.getJSON(ctx, wellKnown, &l); err != nil {
+		return
+	}
+	// Prefer the latest schema version that is advertised.
+	var href, best string
+	for _, link := range l.Links {
+		if !strings.HasPrefix(link.Rel, nodeInfoSchemaPrefix) {
+			continue
+		}
+		if v := strings.TrimPrefix(link.Rel, nodeInfoSchemaPrefix); v > best {
+			best = v
+			href = link.Href
+		}
+	}
+	if len(href) == 0 {
+		err = fmt.Errorf("no NodeInfo schema link found at %s", wellKnown)
+		return
+	}
+	var u *url.URL
+	if u, err = wellKnown.Parse(href); err != nil {
+		return
+	}
+	var s nodeInfoSoftware
+	if err = p.getJSON(ctx, u, &s); err != nil {
+		return
+	}
+	name, version = s.Software.Name, s.Software.Version
+	return
+}
+
+func (p *peerTracker) getJSON(ctx context.Context, u *url.URL, v interface{}) (err error) {
+	var req *http.Request
+	req, err = http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return
+	}
+	req = req.WithContext(ctx)
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("User-Agent", web.UserAgent(p.a.Software()))
+	if err = p.tc.wait(ctx, u.Host); err != nil {
+		return
+	}
+	var resp *http.Response
+	resp, err = p.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("fetching [%s] failed with status (%d): %s", u, resp.StatusCode, resp.Status)
+		return
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxNodeInfoBytes)).Decode(v)
+}
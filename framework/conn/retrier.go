@@ -71,6 +71,11 @@ func (r *retrier) Stop() {
 
 func (r *retrier) retry(ctx context.Context) {
 	c := util.Context{ctx}
+	// Keys of deleted users are only kept while their deliveries, such as
+	// their Delete, may still be retried.
+	if err := r.pk.PurgeDeletedUsers(c); err != nil {
+		util.ErrorLogger.Errorf("retrier failed to purge keys of deleted users: %s", err)
+	}
 	now := time.Now()
 	failures, err := r.da.FirstPageRetryableFailures(c, r.pageSize)
 	if err != nil {
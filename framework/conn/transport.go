@@ -67,16 +67,19 @@ type Controller struct {
 	postHeaders []string
 	hl          *hostLimiter
 	rt          *retrier
+	pt          *peerTracker
 	da          *services.DeliveryAttempts
 }
 
 func NewController(
 	c *config.Config,
 	a app.Application,
+	scheme string,
 	clock pub.Clock,
 	client *http.Client,
 	da *services.DeliveryAttempts,
-	pk *services.PrivateKeys) (tc *Controller, err error) {
+	pk *services.PrivateKeys,
+	peers *services.Peers) (tc *Controller, err error) {
 	if c.ActivityPubConfig.OutboundRateLimitQPS <= 0 {
 		err = fmt.Errorf("outbound rate limit qps is <= 0")
 		return
@@ -111,15 +114,18 @@ func NewController(
 		da:          da,
 	}
 	ct.rt = newRetrier(da, pk, ct, c)
+	ct.pt = newPeerTracker(peers, a, client, ct, scheme, c)
 	return ct, err
 }
 
 func (tc *Controller) Start() {
 	tc.hl.Start()
 	tc.rt.Start()
+	tc.pt.Start()
 }
 
 func (tc *Controller) Stop() {
+	tc.pt.Stop()
 	tc.rt.Stop()
 	tc.hl.Stop()
 }
@@ -171,6 +177,20 @@ func (tc *Controller) markFailure(c util.Context, id string) (err error) {
 	return
 }
 
+func (tc *Controller) peerSeen(c util.Context, host string) {
+	tc.pt.seen(c, host)
+}
+
+// PeerSeen records traffic received from a remote host, such as a verified
+// delivery to one of our inboxes.
+func (tc *Controller) PeerSeen(c util.Context, host string) {
+	tc.peerSeen(c, host)
+}
+
+func (tc *Controller) peerDelivered(c util.Context, host string, ok bool) {
+	tc.pt.delivered(c, host, ok)
+}
+
 var _ pub.Transport = &transport{}
 
 type transport struct {
@@ -235,6 +255,7 @@ func (t *transport) Dereference(c context.Context, iri *url.URL) (b []byte, err
 	if err = t.handleDereferenceResponse(resp, iri); err != nil {
 		return
 	}
+	t.tc.peerSeen(util.Context{c}, iri.Host)
 	b, err = ioutil.ReadAll(resp.Body)
 	return
 }
@@ -252,6 +273,14 @@ func (t *transport) Deliver(c context.Context, b []byte, to *url.URL) (err error
 		err = fmt.Errorf("failed to create delivery attempt: %s", err)
 		return
 	}
+	// Once the attempt is recorded, any failure is marked so that the
+	// retrier picks it up.
+	failed := func(err error) error {
+		if err2 := t.tc.markFailure(uc, attemptId); err2 != nil {
+			return fmt.Errorf("failed delivery and failed to mark as failure (%s): [%s, %s]", attemptId, err, err2)
+		}
+		return err
+	}
 
 	byteCopy := make([]byte, len(b))
 	copy(byteCopy, b)
@@ -259,6 +288,7 @@ func (t *transport) Deliver(c context.Context, b []byte, to *url.URL) (err error
 	var req *http.Request
 	req, err = http.NewRequest(http.MethodPost, to.String(), buf)
 	if err != nil {
+		err = failed(err)
 		return
 	}
 	req.WithContext(c)
@@ -270,25 +300,28 @@ func (t *transport) Deliver(c context.Context, b []byte, to *url.URL) (err error
 	err = t.postSigner.SignRequest(t.privKey, t.pubKeyId, req, b)
 	t.postSignerMu.Unlock()
 	if err != nil {
+		err = failed(err)
 		return
 	}
 	if err = t.tc.wait(c, req.URL.Host); err != nil {
+		err = failed(err)
 		return
 	}
 	var resp *http.Response
 	resp, err = t.client.Do(req)
 	if err != nil {
+		t.tc.peerDelivered(uc, to.Host, false)
+		err = failed(err)
 		return
 	}
 	defer resp.Body.Close()
 
 	if err = t.handleDeliverResponse(resp, to); err != nil {
-		err2 := t.tc.markFailure(uc, attemptId)
-		if err2 != nil {
-			err = fmt.Errorf("failed delivery and failed to mark as failure (%s): [%s, %s]", attemptId, err, err2)
-		}
+		t.tc.peerDelivered(uc, to.Host, false)
+		err = failed(err)
 		return
 	}
+	t.tc.peerDelivered(uc, to.Host, true)
 	if err = t.tc.markSuccess(uc, attemptId); err != nil {
 		err = fmt.Errorf("failed to mark delivery as successful (%s): %s", attemptId, err)
 		return
@@ -72,10 +72,18 @@ CREATE TABLE IF NOT EXISTS ` + p.schema + `users
   salt bytea NOT NULL,
   actor jsonb NOT NULL,
   privileges jsonb NOT NULL,
-  preferences jsonb NOT NULL
+  preferences jsonb NOT NULL,
+  suspend_time timestamp with time zone,
+  delete_time timestamp with time zone
 );`
 }
 
+func (p *pgV0) MigrateUsersTable() string {
+	return `ALTER TABLE ` + p.schema + `users
+  ADD COLUMN IF NOT EXISTS suspend_time timestamp with time zone,
+  ADD COLUMN IF NOT EXISTS delete_time timestamp with time zone;`
+}
+
 func (p *pgV0) InsertUser() string {
 	return `INSERT INTO ` + p.schema + `users (email, hashpass, salt, actor, privileges, preferences) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
 }
@@ -85,15 +93,24 @@ func (p *pgV0) UpdateUserActor() string {
 }
 
 func (p *pgV0) SensitiveUserByEmail() string {
-	return "SELECT id, hashpass, salt FROM " + p.schema + "users WHERE email = $1"
+	return "SELECT id, hashpass, salt FROM " + p.schema + "users WHERE email = $1 AND suspend_time IS NULL AND delete_time IS NULL"
 }
 
 func (p *pgV0) UserByID() string {
-	return "SELECT id, email, actor, privileges, preferences FROM " + p.schema + "users WHERE id = $1"
+	return "SELECT id, email, actor, privileges, preferences, suspend_time, delete_time FROM " + p.schema + "users WHERE id = $1"
+}
+
+func (p *pgV0) UserIDsWithoutFeatured() string {
+	return `SELECT u.id FROM ` + p.schema + `users AS u
+WHERE COALESCE(u.privileges->>'InstanceActor', 'false') <> 'true'
+AND (NOT u.actor ? 'featured' OR NOT EXISTS (
+  SELECT 1 FROM ` + p.schema + `featured AS f
+  WHERE f.actor_id = u.actor->>'id'
+))`
 }
 
 func (p *pgV0) UserByPreferredUsername() string {
-	return "SELECT id, email, actor, privileges, preferences FROM " + p.schema + "users WHERE actor->'preferredUsername' ? $1"
+	return "SELECT id, email, actor, privileges, preferences, suspend_time, delete_time FROM " + p.schema + "users WHERE actor->'preferredUsername' ? $1"
 }
 
 func (p *pgV0) ActorIDForOutbox() string {
@@ -115,7 +132,7 @@ func (p *pgV0) UpdateUserPrivileges() string {
 }
 
 func (p *pgV0) InstanceUser() string {
-	return "SELECT id, email, actor, privileges, preferences FROM " + p.schema + "users WHERE privileges->>'InstanceActor' = 'true'"
+	return "SELECT id, email, actor, privileges, preferences, suspend_time, delete_time FROM " + p.schema + "users WHERE privileges->>'InstanceActor' = 'true'"
 }
 
 func (p *pgV0) GetInstanceActorPreferences() string {
@@ -136,7 +153,31 @@ func (p *pgV0) GetUserActivityStats() string {
   COUNT(*) FILTER (WHERE current_timestamp - last_seen < '180 DAY'),
   COUNT(*) FILTER (WHERE current_timestamp - last_seen < '30 DAY'),
   COUNT(*) FILTER (WHERE current_timestamp - last_seen < '7 DAY')
-FROM ` + p.schema + `users`
+FROM ` + p.schema + `users
+WHERE delete_time IS NULL`
+}
+
+func (p *pgV0) SuspendUser() string {
+	return `UPDATE ` + p.schema + `users
+SET suspend_time = COALESCE(suspend_time, current_timestamp)
+WHERE id = $1 AND delete_time IS NULL`
+}
+
+func (p *pgV0) UnsuspendUser() string {
+	return `UPDATE ` + p.schema + `users
+SET suspend_time = NULL
+WHERE id = $1 AND delete_time IS NULL`
+}
+
+func (p *pgV0) MarkUserDeleted() string {
+	return `UPDATE ` + p.schema + `users
+SET
+  email = '',
+  hashpass = '',
+  salt = '',
+  suspend_time = NULL,
+  delete_time = current_timestamp
+WHERE id = $1 AND delete_time IS NULL`
 }
 
 func (p *pgV0) CreateFedDataTable() string {
@@ -153,6 +194,10 @@ func (p *pgV0) CreateIndexIDFedDataTable() string {
 	return `CREATE INDEX IF NOT EXISTS fed_data_id_index ON ` + p.schema + `fed_data USING GIN ((payload->'id'));`
 }
 
+func (p *pgV0) CreateIndexCreateTimeFedDataTable() string {
+	return `CREATE INDEX IF NOT EXISTS fed_data_create_time_index ON ` + p.schema + `fed_data (create_time);`
+}
+
 func (p *pgV0) FedExists() string {
 	return `SELECT EXISTS (
   SELECT 1
@@ -168,6 +213,12 @@ FROM ` + p.schema + `fed_data
 WHERE payload->'id' ? $1`
 }
 
+func (p *pgV0) FedGetMany() string {
+	return `SELECT payload->>'id', payload
+FROM ` + p.schema + `fed_data
+WHERE payload->'id' ?| ARRAY(SELECT jsonb_array_elements_text($1::jsonb))`
+}
+
 func (p *pgV0) FedCreate() string {
 	return `INSERT INTO ` + p.schema + `fed_data (payload) VALUES ($1)`
 }
@@ -180,6 +231,24 @@ func (p *pgV0) FedDelete() string {
 	return `DELETE FROM ` + p.schema + `fed_data WHERE payload->>'id' = $1`
 }
 
+func (p *pgV0) FedDeleteForActor() string {
+	return `DELETE FROM ` + p.schema + `fed_data
+WHERE payload->>'id' = $1 OR payload->'actor' ? $1 OR payload->'attributedTo' ? $1`
+}
+
+func (p *pgV0) FedSharedInboxes() string {
+	return `SELECT DISTINCT payload->'endpoints'->>'sharedInbox'
+FROM ` + p.schema + `fed_data
+WHERE payload->'endpoints' ? 'sharedInbox'`
+}
+
+func (p *pgV0) FedGetRecent() string {
+	return `SELECT COALESCE(payload->>'id', ''), payload
+FROM ` + p.schema + `fed_data
+ORDER BY create_time DESC
+LIMIT $1`
+}
+
 func (p *pgV0) CreateLocalDataTable() string {
 	return `
 CREATE TABLE IF NOT EXISTS ` + p.schema + `local_data
@@ -209,6 +278,12 @@ FROM ` + p.schema + `local_data
 WHERE payload->'id' ? $1`
 }
 
+func (p *pgV0) LocalGetMany() string {
+	return `SELECT payload->>'id', payload
+FROM ` + p.schema + `local_data
+WHERE payload->'id' ?| ARRAY(SELECT jsonb_array_elements_text($1::jsonb))`
+}
+
 func (p *pgV0) LocalCreate() string {
 	return `INSERT INTO ` + p.schema + `local_data (payload) VALUES ($1)`
 }
@@ -221,6 +296,11 @@ func (p *pgV0) LocalDelete() string {
 	return `DELETE FROM ` + p.schema + `local_data WHERE payload->>'id' = $1`
 }
 
+func (p *pgV0) LocalDeleteForActor() string {
+	return `DELETE FROM ` + p.schema + `local_data
+WHERE payload->'actor' ? $1 OR payload->'attributedTo' ? $1`
+}
+
 func (p *pgV0) LocalStats() string {
 	return `SELECT
   COUNT(*) FILTER (WHERE (payload->'inReplyTo') IS NULL),
@@ -855,10 +935,24 @@ CREATE TABLE IF NOT EXISTS ` + p.schema + `private_keys
 );`
 }
 
+func (p *pgV0) DuplicatePrivateKeys() string {
+	return `SELECT user_id, purpose FROM ` + p.schema + `private_keys
+GROUP BY user_id, purpose HAVING COUNT(*) > 1`
+}
+
+func (p *pgV0) CreateIndexUserIDPurposePrivateKeysTable() string {
+	return `CREATE UNIQUE INDEX IF NOT EXISTS private_keys_user_id_purpose_index ON ` + p.schema + `private_keys (user_id, purpose);`
+}
+
 func (p *pgV0) CreatePrivateKey() string {
 	return `INSERT INTO ` + p.schema + `private_keys (user_id, purpose, priv_key) VALUES ($1, $2, $3)`
 }
 
+func (p *pgV0) CreatePrivateKeyIfNotExists() string {
+	return `INSERT INTO ` + p.schema + `private_keys (user_id, purpose, priv_key) VALUES ($1, $2, $3)
+ON CONFLICT (user_id, purpose) DO NOTHING`
+}
+
 func (p *pgV0) GetPrivateKeyByUserID() string {
 	return `SELECT priv_key FROM ` + p.schema + `private_keys WHERE user_id = $1 AND purpose = $2`
 }
@@ -872,6 +966,16 @@ ON u.id = pk.user_id
 WHERE u.privileges->>'InstanceActor' = 'true' AND purpose = $1`
 }
 
+func (p *pgV0) DeletePrivateKeysForDeletedUsers() string {
+	return `DELETE FROM ` + p.schema + `private_keys AS pk
+USING ` + p.schema + `users AS u
+WHERE pk.user_id = u.id AND u.delete_time IS NOT NULL
+AND NOT EXISTS (
+  SELECT 1 FROM ` + p.schema + `delivery_attempts AS da
+  WHERE da.from_id = u.id AND (da.state = $1 OR da.state = $2)
+)`
+}
+
 func (p *pgV0) CreateClientInfosTable() string {
 	return `
 CREATE TABLE IF NOT EXISTS ` + p.schema + `oauth_clients
@@ -879,16 +983,54 @@ CREATE TABLE IF NOT EXISTS ` + p.schema + `oauth_clients
   id text PRIMARY KEY,
   secret text,
   domain text NOT NULL,
-  user_id uuid REFERENCES ` + p.schema + `users(id) ON DELETE CASCADE NOT NULL
+  user_id uuid REFERENCES ` + p.schema + `users(id) ON DELETE CASCADE,
+  name text NOT NULL DEFAULT '',
+  website text NOT NULL DEFAULT '',
+  logo_uri text NOT NULL DEFAULT '',
+  redirect_uris jsonb NOT NULL DEFAULT '[]',
+  create_time timestamp with time zone NOT NULL DEFAULT current_timestamp,
+  resource_server boolean NOT NULL DEFAULT false
 );`
 }
 
+func (p *pgV0) MigrateClientInfosTable() string {
+	return `ALTER TABLE ` + p.schema + `oauth_clients
+  ALTER COLUMN user_id DROP NOT NULL,
+  ADD COLUMN IF NOT EXISTS name text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS website text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS logo_uri text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS redirect_uris jsonb NOT NULL DEFAULT '[]',
+  ADD COLUMN IF NOT EXISTS create_time timestamp with time zone NOT NULL DEFAULT current_timestamp,
+  ADD COLUMN IF NOT EXISTS resource_server boolean NOT NULL DEFAULT false;`
+}
+
+func (p *pgV0) MigrateTokenInfosTable() string {
+	return `ALTER TABLE ` + p.schema + `oauth_tokens
+  ADD COLUMN IF NOT EXISTS nonce text;`
+}
+
 func (p *pgV0) CreateClientInfo() string {
 	return `INSERT INTO ` + p.schema + `oauth_clients (id, secret, domain, user_id) VALUES ($1, $2, $3, $4) RETURNING id`
 }
 
 func (p *pgV0) GetClientInfoByID() string {
-	return `SELECT id, secret, domain, user_id FROM ` + p.schema + `oauth_clients WHERE id = $1`
+	return `SELECT id, secret, domain, user_id, name, website, logo_uri, redirect_uris, create_time, resource_server FROM ` + p.schema + `oauth_clients WHERE id = $1`
+}
+
+func (p *pgV0) RegisterClientInfo() string {
+	return `INSERT INTO ` + p.schema + `oauth_clients (id, domain, name, website, logo_uri, redirect_uris) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+}
+
+func (p *pgV0) GetRegisteredClientInfos() string {
+	return `SELECT id, secret, domain, user_id, name, website, logo_uri, redirect_uris, create_time, resource_server FROM ` + p.schema + `oauth_clients WHERE user_id IS NULL ORDER BY create_time`
+}
+
+func (p *pgV0) SetRegisteredClientInfoResourceServer() string {
+	return `UPDATE ` + p.schema + `oauth_clients SET resource_server = $2 WHERE id = $1 AND user_id IS NULL`
+}
+
+func (p *pgV0) DeleteRegisteredClientInfo() string {
+	return `DELETE FROM ` + p.schema + `oauth_clients WHERE id = $1 AND user_id IS NULL`
 }
 
 func (p *pgV0) CreateTokenInfosTable() string {
@@ -910,7 +1052,8 @@ CREATE TABLE IF NOT EXISTS ` + p.schema + `oauth_tokens
   access_expires_in bigint,
   refresh text,
   refresh_create_at timestamp with time zone,
-  refresh_expires_in bigint
+  refresh_expires_in bigint,
+  nonce text
 )`
 }
 
@@ -931,7 +1074,8 @@ func (p *pgV0) CreateTokenInfo() string {
   access_expires_in,
   refresh,
   refresh_create_at,
-  refresh_expires_in
+  refresh_expires_in,
+  nonce
 ) VALUES
 (
   $1,
@@ -948,7 +1092,8 @@ func (p *pgV0) CreateTokenInfo() string {
   $12,
   $13,
   $14,
-  $15
+  $15,
+  $16
 ) RETURNING id`
 }
 
@@ -964,6 +1109,32 @@ func (p *pgV0) RemoveTokenInfoByRefresh() string {
 	return `DELETE FROM ` + p.schema + `oauth_tokens WHERE refresh = $1`
 }
 
+func (p *pgV0) RemoveTokenInfosForUser() string {
+	return `DELETE FROM ` + p.schema + `oauth_tokens WHERE user_id = $1`
+}
+
+func (p *pgV0) RemoveTokenInfosForUserAndClient() string {
+	return `DELETE FROM ` + p.schema + `oauth_tokens WHERE user_id = $1 AND client_id = $2`
+}
+
+func (p *pgV0) GetTokenInfosForUser() string {
+	return `SELECT
+  ti.id,
+  ti.client_id,
+  c.name,
+  c.user_id IS NOT NULL,
+  ti.scope,
+  ti.access_create_at,
+  ti.access_expires_in,
+  ti.refresh_create_at,
+  ti.refresh_expires_in
+FROM ` + p.schema + `oauth_tokens AS ti
+INNER JOIN ` + p.schema + `oauth_clients AS c
+ON ti.client_id = c.id
+WHERE ti.user_id = $1 AND COALESCE(ti.access, '') <> ''
+ORDER BY ti.access_create_at DESC`
+}
+
 func (p *pgV0) GetTokenInfoByCode() string {
 	return `SELECT
   client_id,
@@ -980,7 +1151,8 @@ func (p *pgV0) GetTokenInfoByCode() string {
   access_expires_in,
   refresh,
   refresh_create_at,
-  refresh_expires_in
+  refresh_expires_in,
+  nonce
 FROM ` + p.schema + "oauth_tokens WHERE code = $1"
 }
 
@@ -1000,7 +1172,8 @@ func (p *pgV0) GetTokenInfoByAccess() string {
   access_expires_in,
   refresh,
   refresh_create_at,
-  refresh_expires_in
+  refresh_expires_in,
+  nonce
 FROM ` + p.schema + "oauth_tokens WHERE access = $1"
 }
 
@@ -1020,12 +1193,25 @@ func (p *pgV0) GetTokenInfoByRefresh() string {
   access_expires_in,
   refresh,
   refresh_create_at,
-  refresh_expires_in
+  refresh_expires_in,
+  nonce
 FROM ` + p.schema + "oauth_tokens WHERE refresh = $1"
 }
 
 /* Collection prototype queries */
 
+// collectionKind describes the JSON layout differences between a Collection
+// and an OrderedCollection stored by the collection prototype queries.
+type collectionKind struct {
+	items    string
+	pageType string
+}
+
+var (
+	v0Unordered = collectionKind{items: "items", pageType: "CollectionPage"}
+	v0Ordered   = collectionKind{items: "orderedItems", pageType: "OrderedCollectionPage"}
+)
+
 func (p *pgV0) createCollectionTable(name string) string {
 	return `
 CREATE TABLE IF NOT EXISTS ` + p.schema + name + `
@@ -1044,54 +1230,54 @@ func (p *pgV0) insertCollection(name string) string {
 	return `INSERT INTO ` + p.schema + name + ` (actor_id, ` + name + `) VALUES ($1, $2)`
 }
 
-func (p *pgV0) collectionContainsForActor(name string) string {
+func (p *pgV0) collectionContainsForActor(name string, k collectionKind) string {
 	return `SELECT EXISTS (
   SELECT 1
   FROM ` + p.schema + name + `
-  WHERE actor_id = $1 AND ` + name + `->'items' ? $2
+  WHERE actor_id = $1 AND ` + name + `->'` + k.items + `' ? $2
   LIMIT 1
 )`
 }
 
-func (p *pgV0) collectionContains(name string) string {
+func (p *pgV0) collectionContains(name string, k collectionKind) string {
 	return `SELECT EXISTS (
   SELECT 1
   FROM ` + p.schema + name + `
-  WHERE ` + name + `->'id' ? $1 AND ` + name + `->'items' ? $2
+  WHERE ` + name + `->'id' ? $1 AND ` + name + `->'` + k.items + `' ? $2
   LIMIT 1
 )`
 }
 
-func (p *pgV0) getCollection(name string) string {
+func (p *pgV0) getCollection(name string, k collectionKind) string {
 	return `WITH page AS (
   SELECT
     ` + name + `,
     jsonb_path_query_array(
       ` + name + `,
-      '$.items[$min to $max]',
+      '$.` + k.items + `[$min to $max]',
       jsonb_build_object(
         'min',
 	$2::jsonb,
         'max',
 	$3::jsonb)) AS page,
-    $3::integer + 1 >= jsonb_path_query(` + name + `, '$.items.size()')::numeric AS isEnd
+    $3::integer + 1 >= jsonb_path_query(` + name + `, '$.` + k.items + `.size()')::numeric AS isEnd
   FROM ` + p.schema + name + `
   WHERE ` + name + `->'id' ? $1
 ),
 single_page AS (
   SELECT
     jsonb_build_object(
-      'items',
+      '` + k.items + `',
       page,
       'totalItems',
       jsonb_path_query(page, '$.size()'),
       'type',
-      'CollectionPage') AS page,
+      '` + k.pageType + `') AS page,
     isEnd AS isEnd
   FROM page
   UNION ALL
   SELECT
-    '{"items":[],"totalItems":0,"type":"CollectionPage"}'::jsonb AS page,
+    '{"` + k.items + `":[],"totalItems":0,"type":"` + k.pageType + `"}'::jsonb AS page,
     true AS isEnd
   LIMIT 1
 )
@@ -1101,12 +1287,12 @@ SELECT
   FROM page, single_page AS sp`
 }
 
-func (p *pgV0) getCollectionLastPage(name string) string {
+func (p *pgV0) getCollectionLastPage(name string, k collectionKind) string {
 	return `WITH stats AS (
   SELECT
     ` + name + `,
     GREATEST(0,
-      jsonb_path_query(` + name + `, '$.items.size()')::numeric - $2) AS startIndex
+      jsonb_path_query(` + name + `, '$.` + k.items + `.size()')::numeric - $2) AS startIndex
   FROM ` + p.schema + name + `
   WHERE ` + name + `->'id' ? $1
 ),
@@ -1116,7 +1302,7 @@ page AS (
     startIndex,
     jsonb_path_query_array(
       ` + name + `,
-      '$.items[$min to last]',
+      '$.` + k.items + `[$min to last]',
       jsonb_build_object(
         'min',
         startIndex)) AS page
@@ -1125,17 +1311,17 @@ page AS (
 single_page AS (
   SELECT
     jsonb_build_object(
-      'items',
+      '` + k.items + `',
       page,
       'totalItems',
       jsonb_path_query(page, '$.size()'),
       'type',
-      'CollectionPage') AS page,
+      '` + k.pageType + `') AS page,
     startIndex
   FROM page
   UNION ALL
   SELECT
-    '{"items":[],"totalItems":0,"type":"CollectionPage"}'::jsonb AS page,
+    '{"` + k.items + `":[],"totalItems":0,"type":"` + k.pageType + `"}'::jsonb AS page,
     0 AS startIndex
   LIMIT 1
 )
@@ -1145,28 +1331,37 @@ SELECT
 FROM page, single_page AS sp`
 }
 
-func (p *pgV0) prependCollectionItem(name string) string {
+func (p *pgV0) prependCollectionItem(name string, k collectionKind) string {
 	return `UPDATE ` + p.schema + name + `
 SET ` + name + ` = ` + name + ` || jsonb_build_object(
-  'items',
-  jsonb_build_array($2::text) || COALESCE(` + name + `->'items', '[]'::jsonb),
+  '` + k.items + `',
+  jsonb_build_array($2::text) || COALESCE(` + name + `->'` + k.items + `', '[]'::jsonb),
   'totalItems',
   (COALESCE(` + name + `->>'totalItems','0')::int + 1)::text::jsonb)
 WHERE ` + name + `->'id' ? $1`
 }
 
-func (p *pgV0) deleteCollectionItem(name string) string {
+func (p *pgV0) deleteCollectionItem(name string, k collectionKind) string {
 	return `UPDATE ` + p.schema + name + `
 SET ` + name + `= jsonb_set(
   ` + name + `,
-  '{items}',
-  (` + name + `->'items') - $2) ||
+  '{` + k.items + `}',
+  (` + name + `->'` + k.items + `') - $2) ||
   jsonb_build_object(
   'totalItems',
   (COALESCE(` + name + `->>'totalItems','0')::int - 1)::text::jsonb)
 WHERE ` + name + `->'id' ? $1`
 }
 
+func (p *pgV0) collectionExists(name string) string {
+	return `SELECT EXISTS (
+  SELECT 1
+  FROM ` + p.schema + name + `
+  WHERE ` + name + `->'id' ? $1
+  LIMIT 1
+)`
+}
+
 func (p *pgV0) getAllCollectionForActor(name string) string {
 	return `SELECT ` + name + `
 FROM ` + p.schema + name + `
@@ -1179,6 +1374,7 @@ const (
 	v0Followers = "followers"
 	v0Following = "following"
 	v0Liked     = "liked"
+	v0Featured  = "featured"
 )
 
 func (p *pgV0) CreateFollowersTable() string {
@@ -1194,27 +1390,27 @@ func (p *pgV0) InsertFollowers() string {
 }
 
 func (p *pgV0) FollowersContainsForActor() string {
-	return p.collectionContainsForActor(v0Followers)
+	return p.collectionContainsForActor(v0Followers, v0Unordered)
 }
 
 func (p *pgV0) FollowersContains() string {
-	return p.collectionContains(v0Followers)
+	return p.collectionContains(v0Followers, v0Unordered)
 }
 
 func (p *pgV0) GetFollowers() string {
-	return p.getCollection(v0Followers)
+	return p.getCollection(v0Followers, v0Unordered)
 }
 
 func (p *pgV0) GetFollowersLastPage() string {
-	return p.getCollectionLastPage(v0Followers)
+	return p.getCollectionLastPage(v0Followers, v0Unordered)
 }
 
 func (p *pgV0) PrependFollowersItem() string {
-	return p.prependCollectionItem(v0Followers)
+	return p.prependCollectionItem(v0Followers, v0Unordered)
 }
 
 func (p *pgV0) DeleteFollowersItem() string {
-	return p.deleteCollectionItem(v0Followers)
+	return p.deleteCollectionItem(v0Followers, v0Unordered)
 }
 
 func (p *pgV0) GetAllFollowersForActor() string {
@@ -1234,27 +1430,27 @@ func (p *pgV0) InsertFollowing() string {
 }
 
 func (p *pgV0) FollowingContainsForActor() string {
-	return p.collectionContainsForActor(v0Following)
+	return p.collectionContainsForActor(v0Following, v0Unordered)
 }
 
 func (p *pgV0) FollowingContains() string {
-	return p.collectionContains(v0Following)
+	return p.collectionContains(v0Following, v0Unordered)
 }
 
 func (p *pgV0) GetFollowing() string {
-	return p.getCollection(v0Following)
+	return p.getCollection(v0Following, v0Unordered)
 }
 
 func (p *pgV0) GetFollowingLastPage() string {
-	return p.getCollectionLastPage(v0Following)
+	return p.getCollectionLastPage(v0Following, v0Unordered)
 }
 
 func (p *pgV0) PrependFollowingItem() string {
-	return p.prependCollectionItem(v0Following)
+	return p.prependCollectionItem(v0Following, v0Unordered)
 }
 
 func (p *pgV0) DeleteFollowingItem() string {
-	return p.deleteCollectionItem(v0Following)
+	return p.deleteCollectionItem(v0Following, v0Unordered)
 }
 
 func (p *pgV0) GetAllFollowingForActor() string {
@@ -1274,53 +1470,127 @@ func (p *pgV0) InsertLiked() string {
 }
 
 func (p *pgV0) LikedContainsForActor() string {
-	return p.collectionContainsForActor(v0Liked)
+	return p.collectionContainsForActor(v0Liked, v0Unordered)
 }
 
 func (p *pgV0) LikedContains() string {
-	return p.collectionContains(v0Liked)
+	return p.collectionContains(v0Liked, v0Unordered)
 }
 
 func (p *pgV0) GetLiked() string {
-	return p.getCollection(v0Liked)
+	return p.getCollection(v0Liked, v0Unordered)
 }
 
 func (p *pgV0) GetLikedLastPage() string {
-	return p.getCollectionLastPage(v0Liked)
+	return p.getCollectionLastPage(v0Liked, v0Unordered)
 }
 
 func (p *pgV0) PrependLikedItem() string {
-	return p.prependCollectionItem(v0Liked)
+	return p.prependCollectionItem(v0Liked, v0Unordered)
 }
 
 func (p *pgV0) DeleteLikedItem() string {
-	return p.deleteCollectionItem(v0Liked)
+	return p.deleteCollectionItem(v0Liked, v0Unordered)
 }
 
 func (p *pgV0) GetAllLikedForActor() string {
 	return p.getAllCollectionForActor(v0Liked)
 }
 
+func (p *pgV0) CreateFeaturedTable() string {
+	return p.createCollectionTable(v0Featured)
+}
+
+func (p *pgV0) CreateIndexIDFeaturedTable() string {
+	return p.createCollectionIDIndex(v0Featured)
+}
+
+func (p *pgV0) InsertFeatured() string {
+	return p.insertCollection(v0Featured)
+}
+
+func (p *pgV0) FeaturedExists() string {
+	return p.collectionExists(v0Featured)
+}
+
+func (p *pgV0) FeaturedContainsForActor() string {
+	return p.collectionContainsForActor(v0Featured, v0Ordered)
+}
+
+func (p *pgV0) FeaturedContains() string {
+	return p.collectionContains(v0Featured, v0Ordered)
+}
+
+func (p *pgV0) GetFeatured() string {
+	return p.getCollection(v0Featured, v0Ordered)
+}
+
+func (p *pgV0) GetFeaturedLastPage() string {
+	return p.getCollectionLastPage(v0Featured, v0Ordered)
+}
+
+func (p *pgV0) PrependFeaturedItem() string {
+	return p.prependCollectionItem(v0Featured, v0Ordered)
+}
+
+func (p *pgV0) DeleteFeaturedItem() string {
+	return p.deleteCollectionItem(v0Featured, v0Ordered)
+}
+
+func (p *pgV0) GetAllFeaturedForActor() string {
+	return p.getAllCollectionForActor(v0Featured)
+}
+
 func (p *pgV0) CreatePoliciesTable() string {
 	return `CREATE TABLE IF NOT EXISTS ` + p.schema + `policies
 (
   id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
   actor_id text NOT NULL,
   purpose text NOT NULL,
-  policy jsonb NOT NULL
+  policy jsonb NOT NULL,
+  enabled boolean NOT NULL DEFAULT true,
+  monitor boolean NOT NULL DEFAULT false
 )`
 }
 
+func (p *pgV0) MigratePoliciesTable() string {
+	return `ALTER TABLE ` + p.schema + `policies
+  ADD COLUMN IF NOT EXISTS enabled boolean NOT NULL DEFAULT true,
+  ADD COLUMN IF NOT EXISTS monitor boolean NOT NULL DEFAULT false;`
+}
+
 func (p *pgV0) CreatePolicy() string {
-	return `INSERT INTO ` + p.schema + `policies (actor_id, purpose, policy) VALUES ($1, $2, $3) RETURNING id`
+	return `INSERT INTO ` + p.schema + `policies (actor_id, purpose, policy, enabled, monitor) VALUES ($1, $2, $3, $4, $5) RETURNING id`
 }
 
 func (p *pgV0) GetPoliciesForActor() string {
-	return `SELECT id, purpose, policy FROM ` + p.schema + `policies WHERE actor_id = $1`
+	return `SELECT id, purpose, policy, enabled, monitor FROM ` + p.schema + `policies
+WHERE actor_id = $1
+ORDER BY purpose, policy->>'name', id`
 }
 
 func (p *pgV0) GetPoliciesForActorAndPurpose() string {
-	return `SELECT id, policy FROM ` + p.schema + `policies WHERE actor_id = $1 AND purpose = $2`
+	return `SELECT id, policy, monitor FROM ` + p.schema + `policies WHERE actor_id = $1 AND purpose = $2 AND enabled`
+}
+
+func (p *pgV0) GetPolicyForActor() string {
+	return `SELECT purpose, policy, enabled, monitor FROM ` + p.schema + `policies WHERE id = $1 AND actor_id = $2`
+}
+
+func (p *pgV0) UpdatePolicy() string {
+	return `UPDATE ` + p.schema + `policies SET policy = $3 WHERE id = $1 AND actor_id = $2`
+}
+
+func (p *pgV0) SetPolicyEnabled() string {
+	return `UPDATE ` + p.schema + `policies SET enabled = $3 WHERE id = $1 AND actor_id = $2`
+}
+
+func (p *pgV0) SetPolicyMonitor() string {
+	return `UPDATE ` + p.schema + `policies SET monitor = $3 WHERE id = $1 AND actor_id = $2`
+}
+
+func (p *pgV0) DeletePolicy() string {
+	return `DELETE FROM ` + p.schema + `policies WHERE id = $1 AND actor_id = $2`
 }
 
 func (p *pgV0) CreateResolutionsTable() string {
@@ -1328,13 +1598,27 @@ func (p *pgV0) CreateResolutionsTable() string {
 (
   id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
   policy_id uuid REFERENCES ` + p.schema + `policies(id) ON DELETE CASCADE NOT NULL,
+  create_time timestamp with time zone DEFAULT current_timestamp,
   data_iri text NOT NULL,
   resolution jsonb NOT NULL
 )`
 }
 
+func (p *pgV0) MigrateResolutionsTable() string {
+	return `ALTER TABLE ` + p.schema + `resolutions
+  ADD COLUMN IF NOT EXISTS create_time timestamp with time zone DEFAULT current_timestamp;`
+}
+
 func (p *pgV0) CreateResolution() string {
-	return `INSERT INTO ` + p.schema + `resolutions (policy_id, data_iri, resolution) VALUES ($1, $2, $3)`
+	return `INSERT INTO ` + p.schema + `resolutions (policy_id, data_iri, resolution) VALUES ($1, $2, $3) RETURNING id`
+}
+
+func (p *pgV0) GetResolutionsForPolicy() string {
+	return `SELECT id, data_iri, resolution FROM ` + p.schema + `resolutions
+WHERE policy_id = $1
+ORDER BY create_time DESC, id
+OFFSET $2
+LIMIT $3`
 }
 
 func (p *pgV0) CreateFirstPartyCredentialsTable() string {
@@ -1413,9 +1697,422 @@ func (p *pgV0) GetTokenInfoForCredentialID() string {
   ti.access_expires_in,
   ti.refresh,
   ti.refresh_create_at,
-  ti.refresh_expires_in
+  ti.refresh_expires_in,
+  ti.nonce
 FROM ` + p.schema + `first_party_creds AS fpc
 INNER JOIN ` + p.schema + `oauth_tokens AS ti
 ON fpc.token_id = ti.id
 WHERE fpc.id = $1`
 }
+
+func (p *pgV0) CreatePeersTable() string {
+	return `CREATE TABLE IF NOT EXISTS ` + p.schema + `peers
+(
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  host text UNIQUE NOT NULL,
+  first_seen timestamp with time zone NOT NULL DEFAULT current_timestamp,
+  last_seen timestamp with time zone NOT NULL DEFAULT current_timestamp,
+  software_name text,
+  software_version text,
+  nodeinfo_fetched timestamp with time zone,
+  n_delivery_successes bigint NOT NULL DEFAULT 0,
+  n_delivery_failures bigint NOT NULL DEFAULT 0,
+  last_delivery_success timestamp with time zone,
+  last_delivery_failure timestamp with time zone
+)`
+}
+
+func (p *pgV0) PeerSeen() string {
+	return `INSERT INTO ` + p.schema + `peers (host) VALUES ($1)
+ON CONFLICT (host) DO UPDATE SET last_seen = current_timestamp
+RETURNING nodeinfo_fetched`
+}
+
+func (p *pgV0) MarkPeerDeliverySuccess() string {
+	return `UPDATE ` + p.schema + `peers
+SET
+  n_delivery_successes = n_delivery_successes + 1,
+  last_delivery_success = current_timestamp
+WHERE host = $1`
+}
+
+func (p *pgV0) MarkPeerDeliveryFailure() string {
+	return `UPDATE ` + p.schema + `peers
+SET
+  n_delivery_failures = n_delivery_failures + 1,
+  last_delivery_failure = current_timestamp
+WHERE host = $1`
+}
+
+func (p *pgV0) UpdatePeerSoftware() string {
+	return `UPDATE ` + p.schema + `peers
+SET
+  software_name = COALESCE($2, software_name),
+  software_version = COALESCE($3, software_version),
+  nodeinfo_fetched = current_timestamp
+WHERE host = $1`
+}
+
+func (p *pgV0) GetPeer() string {
+	return `SELECT id, host, first_seen, last_seen, software_name, software_version, nodeinfo_fetched, n_delivery_successes, n_delivery_failures, last_delivery_success, last_delivery_failure
+FROM ` + p.schema + `peers
+WHERE host = $1`
+}
+
+func (p *pgV0) GetPeers() string {
+	return `SELECT id, host, first_seen, last_seen, software_name, software_version, nodeinfo_fetched, n_delivery_successes, n_delivery_failures, last_delivery_success, last_delivery_failure
+FROM ` + p.schema + `peers
+ORDER BY host`
+}
+
+func (p *pgV0) GetPeersWithStaleNodeInfo() string {
+	return `SELECT host
+FROM ` + p.schema + `peers
+WHERE nodeinfo_fetched IS NULL OR nodeinfo_fetched < $1`
+}
+
+func (p *pgV0) CreateObjectCollectionsTable() string {
+	return `
+CREATE TABLE IF NOT EXISTS ` + p.schema + `object_collections
+(
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  object_id text NOT NULL,
+  kind text NOT NULL,
+  collection jsonb NOT NULL,
+  UNIQUE (object_id, kind)
+)`
+}
+
+func (p *pgV0) CreateIndexIDObjectCollectionsTable() string {
+	return `CREATE INDEX IF NOT EXISTS object_collections_id_index ON ` + p.schema + `object_collections USING GIN ((collection->'id'));`
+}
+
+func (p *pgV0) InsertObjectCollection() string {
+	return `INSERT INTO ` + p.schema + `object_collections (object_id, kind, collection) VALUES ($1, $2, $3)
+ON CONFLICT (object_id, kind) DO NOTHING`
+}
+
+func (p *pgV0) ObjectCollectionContains() string {
+	return `SELECT EXISTS (
+  SELECT 1
+  FROM ` + p.schema + `object_collections
+  WHERE collection->'id' ? $1 AND collection->'items' ? $2
+  LIMIT 1
+)`
+}
+
+func (p *pgV0) GetObjectCollection() string {
+	return `WITH page AS (
+  SELECT
+    collection,
+    jsonb_path_query_array(
+      collection,
+      '$.items[$min to $max]',
+      jsonb_build_object(
+        'min',
+	$2::jsonb,
+        'max',
+	$3::jsonb)) AS page,
+    $3::integer + 1 >= jsonb_path_query(collection, '$.items.size()')::numeric AS isEnd
+  FROM ` + p.schema + `object_collections
+  WHERE collection->'id' ? $1
+),
+single_page AS (
+  SELECT
+    jsonb_build_object(
+      'items',
+      page,
+      'totalItems',
+      jsonb_path_query(page, '$.size()'),
+      'type',
+      'CollectionPage') AS page,
+    isEnd AS isEnd
+  FROM page
+  UNION ALL
+  SELECT
+    '{"items":[],"totalItems":0,"type":"CollectionPage"}'::jsonb AS page,
+    true AS isEnd
+  LIMIT 1
+)
+SELECT
+  collection || sp.page,
+  sp.isEnd
+  FROM page, single_page AS sp`
+}
+
+func (p *pgV0) GetObjectCollectionLastPage() string {
+	return `WITH stats AS (
+  SELECT
+    collection,
+    GREATEST(0,
+      jsonb_path_query(collection, '$.items.size()')::numeric - $2) AS startIndex
+  FROM ` + p.schema + `object_collections
+  WHERE collection->'id' ? $1
+),
+page AS (
+  SELECT
+    collection,
+    startIndex,
+    jsonb_path_query_array(
+      collection,
+      '$.items[$min to last]',
+      jsonb_build_object(
+        'min',
+        startIndex)) AS page
+  FROM stats
+),
+single_page AS (
+  SELECT
+    jsonb_build_object(
+      'items',
+      page,
+      'totalItems',
+      jsonb_path_query(page, '$.size()'),
+      'type',
+      'CollectionPage') AS page,
+    startIndex
+  FROM page
+  UNION ALL
+  SELECT
+    '{"items":[],"totalItems":0,"type":"CollectionPage"}'::jsonb AS page,
+    0 AS startIndex
+  LIMIT 1
+)
+SELECT
+  collection || sp.page,
+  sp.startIndex
+FROM page, single_page AS sp`
+}
+
+func (p *pgV0) PrependObjectCollectionItem() string {
+	return `UPDATE ` + p.schema + `object_collections
+SET collection = collection || jsonb_build_object(
+  'items',
+  jsonb_build_array($2::text) || COALESCE(collection->'items', '[]'::jsonb),
+  'totalItems',
+  (COALESCE(collection->>'totalItems','0')::int + 1)::text::jsonb)
+WHERE collection->'id' ? $1`
+}
+
+func (p *pgV0) DeleteObjectCollectionItem() string {
+	return `UPDATE ` + p.schema + `object_collections
+SET collection = jsonb_set(
+  collection,
+  '{items}',
+  (collection->'items') - $2) ||
+  jsonb_build_object(
+  'totalItems',
+  (COALESCE(collection->>'totalItems','0')::int - 1)::text::jsonb)
+WHERE collection->'id' ? $1`
+}
+
+func (p *pgV0) CreateBlocksTable() string {
+	return `CREATE TABLE IF NOT EXISTS ` + p.schema + `blocks
+(
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  create_time timestamp with time zone NOT NULL DEFAULT current_timestamp,
+  actor_id text NOT NULL,
+  blocked_id text NOT NULL,
+  activity_id text,
+  UNIQUE (actor_id, blocked_id)
+)`
+}
+
+func (p *pgV0) InsertBlock() string {
+	return `INSERT INTO ` + p.schema + `blocks (actor_id, blocked_id, activity_id) VALUES ($1, $2, $3)
+ON CONFLICT (actor_id, blocked_id) DO NOTHING`
+}
+
+func (p *pgV0) UpdateBlockActivity() string {
+	return `UPDATE ` + p.schema + `blocks
+SET activity_id = $3
+WHERE actor_id = $1 AND blocked_id = $2`
+}
+
+func (p *pgV0) DeleteBlock() string {
+	return `DELETE FROM ` + p.schema + `blocks
+WHERE actor_id = $1 AND blocked_id = $2
+RETURNING activity_id`
+}
+
+func (p *pgV0) DeleteBlockByActivity() string {
+	return `DELETE FROM ` + p.schema + `blocks
+WHERE actor_id = $1 AND activity_id = $2
+RETURNING blocked_id`
+}
+
+func (p *pgV0) GetBlocksForActor() string {
+	return `SELECT id, create_time, actor_id, blocked_id, activity_id
+FROM ` + p.schema + `blocks
+WHERE actor_id = $1
+ORDER BY create_time DESC`
+}
+
+func (p *pgV0) CreateMutesTable() string {
+	return `CREATE TABLE IF NOT EXISTS ` + p.schema + `mutes
+(
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  create_time timestamp with time zone NOT NULL DEFAULT current_timestamp,
+  actor_id text NOT NULL,
+  kind text NOT NULL,
+  target text NOT NULL,
+  expires timestamp with time zone,
+  UNIQUE (actor_id, kind, target)
+)`
+}
+
+func (p *pgV0) InsertMute() string {
+	return `INSERT INTO ` + p.schema + `mutes (actor_id, kind, target, expires) VALUES ($1, $2, $3, $4)
+ON CONFLICT (actor_id, kind, target) DO UPDATE SET create_time = current_timestamp, expires = EXCLUDED.expires`
+}
+
+func (p *pgV0) DeleteMute() string {
+	return `DELETE FROM ` + p.schema + `mutes WHERE actor_id = $1 AND kind = $2 AND target = $3`
+}
+
+func (p *pgV0) DeleteExpiredMutes() string {
+	return `DELETE FROM ` + p.schema + `mutes WHERE expires < current_timestamp`
+}
+
+func (p *pgV0) GetActiveMutesForActor() string {
+	return `SELECT id, create_time, actor_id, kind, target, expires
+FROM ` + p.schema + `mutes
+WHERE actor_id = $1 AND (expires IS NULL OR expires >= current_timestamp)
+ORDER BY create_time DESC`
+}
+
+func (p *pgV0) CreateFollowRequestsTable() string {
+	return `CREATE TABLE IF NOT EXISTS ` + p.schema + `follow_requests
+(
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  create_time timestamp with time zone NOT NULL DEFAULT current_timestamp,
+  actor_id text NOT NULL,
+  follower_id text NOT NULL,
+  activity_id text NOT NULL,
+  activity jsonb NOT NULL,
+  UNIQUE (actor_id, follower_id)
+)`
+}
+
+func (p *pgV0) InsertFollowRequest() string {
+	return `INSERT INTO ` + p.schema + `follow_requests (actor_id, follower_id, activity_id, activity) VALUES ($1, $2, $3, $4)
+ON CONFLICT (actor_id, follower_id) DO UPDATE SET
+  create_time = current_timestamp,
+  activity_id = EXCLUDED.activity_id,
+  activity = EXCLUDED.activity`
+}
+
+func (p *pgV0) GetFollowRequestsForActor() string {
+	return `SELECT id, create_time, actor_id, follower_id, activity
+FROM ` + p.schema + `follow_requests
+WHERE actor_id = $1
+ORDER BY create_time`
+}
+
+func (p *pgV0) DeleteFollowRequest() string {
+	return `DELETE FROM ` + p.schema + `follow_requests
+WHERE actor_id = $1 AND follower_id = $2
+RETURNING follower_id, activity`
+}
+
+func (p *pgV0) DeleteFollowRequestByActivity() string {
+	return `DELETE FROM ` + p.schema + `follow_requests
+WHERE actor_id = $1 AND activity_id = $2
+RETURNING follower_id, activity`
+}
+
+func (p *pgV0) CreateReportsTable() string {
+	return `CREATE TABLE IF NOT EXISTS ` + p.schema + `reports
+(
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  create_time timestamp with time zone NOT NULL DEFAULT current_timestamp,
+  reporter_id text NOT NULL,
+  target_id text NOT NULL,
+  object_ids jsonb NOT NULL,
+  content text NOT NULL,
+  activity_id text UNIQUE,
+  forwarded boolean NOT NULL DEFAULT false,
+  assignee_id text,
+  resolve_time timestamp with time zone,
+  resolution text NOT NULL DEFAULT ''
+)`
+}
+
+func (p *pgV0) InsertReport() string {
+	return `INSERT INTO ` + p.schema + `reports (reporter_id, target_id, object_ids, content, activity_id) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (activity_id) DO NOTHING
+RETURNING id`
+}
+
+func (p *pgV0) GetReport() string {
+	return `SELECT id, create_time, reporter_id, target_id, object_ids, content, activity_id, forwarded, assignee_id, resolve_time, resolution
+FROM ` + p.schema + `reports
+WHERE id = $1`
+}
+
+func (p *pgV0) GetReports() string {
+	return `SELECT id, create_time, reporter_id, target_id, object_ids, content, activity_id, forwarded, assignee_id, resolve_time, resolution
+FROM ` + p.schema + `reports
+WHERE (resolve_time IS NOT NULL) = $1
+ORDER BY create_time, id
+OFFSET $2
+LIMIT $3`
+}
+
+func (p *pgV0) SetReportForwarded() string {
+	return `UPDATE ` + p.schema + `reports SET forwarded = true, activity_id = $2 WHERE id = $1`
+}
+
+func (p *pgV0) AssignReport() string {
+	return `UPDATE ` + p.schema + `reports SET assignee_id = $2 WHERE id = $1`
+}
+
+func (p *pgV0) ResolveReport() string {
+	return `UPDATE ` + p.schema + `reports SET resolve_time = current_timestamp, resolution = $2
+WHERE id = $1 AND resolve_time IS NULL`
+}
+
+func (p *pgV0) CreateQuarantineTable() string {
+	return `CREATE TABLE IF NOT EXISTS ` + p.schema + `quarantine
+(
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  create_time timestamp with time zone NOT NULL DEFAULT current_timestamp,
+  actor_id text NOT NULL,
+  activity_id text NOT NULL,
+  payload jsonb NOT NULL,
+  status text NOT NULL DEFAULT 'pending',
+  review_time timestamp with time zone,
+  resolution_id uuid REFERENCES ` + p.schema + `resolutions(id) ON DELETE SET NULL
+)`
+}
+
+func (p *pgV0) InsertQuarantined() string {
+	return `INSERT INTO ` + p.schema + `quarantine (actor_id, activity_id, payload, resolution_id) VALUES ($1, $2, $3, $4) RETURNING id`
+}
+
+func (p *pgV0) GetQuarantined() string {
+	return `SELECT q.id, q.create_time, q.actor_id, q.activity_id, q.payload, q.status, q.review_time, q.resolution_id, r.policy_id, r.resolution
+FROM ` + p.schema + `quarantine AS q
+LEFT JOIN ` + p.schema + `resolutions AS r ON q.resolution_id = r.id
+WHERE q.id = $1`
+}
+
+func (p *pgV0) GetQuarantinedByStatus() string {
+	return `SELECT q.id, q.create_time, q.actor_id, q.activity_id, q.payload, q.status, q.review_time, q.resolution_id, r.policy_id, r.resolution
+FROM ` + p.schema + `quarantine AS q
+LEFT JOIN ` + p.schema + `resolutions AS r ON q.resolution_id = r.id
+WHERE q.status = $1
+ORDER BY q.create_time, q.id
+OFFSET $2
+LIMIT $3`
+}
+
+func (p *pgV0) ReviewQuarantined() string {
+	return `UPDATE ` + p.schema + `quarantine SET status = $2, review_time = current_timestamp
+WHERE id = $1 AND status = 'pending'`
+}
+
+func (p *pgV0) ReopenQuarantined() string {
+	return `UPDATE ` + p.schema + `quarantine SET status = 'pending', review_time = NULL
+WHERE id = $1 AND status = $2`
+}
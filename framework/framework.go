@@ -17,15 +17,22 @@
 package framework
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"time"
 
 	"github.com/go-fed/activity/pub"
+	"github.com/go-fed/activity/streams"
 	"github.com/go-fed/activity/streams/vocab"
 	"github.com/go-fed/apcore/app"
+	"github.com/go-fed/apcore/framework/conn"
 	"github.com/go-fed/apcore/framework/oauth2"
 	"github.com/go-fed/apcore/framework/web"
+	"github.com/go-fed/apcore/models"
 	"github.com/go-fed/apcore/paths"
 	"github.com/go-fed/apcore/services"
 	"github.com/go-fed/apcore/util"
@@ -36,28 +43,67 @@ var _ app.Framework = &Framework{}
 type Framework struct {
 	scheme            string
 	host              string
+	accountDomain     string
 	o                 *oauth2.Server
 	s                 *web.Sessions
 	data              *services.Data
+	peers             *services.Peers
+	featured          *services.Featured
+	blocks            *services.Blocks
+	mutes             *services.Mutes
+	followRequests    *services.FollowRequests
+	reports           *services.Reports
+	quarantine        *services.Quarantine
+	policies          *services.Policies
+	users             *services.Users
+	pk                *services.PrivateKeys
+	tc                *conn.Controller
 	actor             pub.Actor
+	actorMap          map[paths.Actor]pub.Actor
 	federationEnabled bool
 }
 
 func BuildFramework(scheme string,
 	host string,
+	accountDomain string,
 	fw *Framework,
 	o *oauth2.Server,
 	s *web.Sessions,
 	data *services.Data,
+	peers *services.Peers,
+	featured *services.Featured,
+	blocks *services.Blocks,
+	mutes *services.Mutes,
+	followRequests *services.FollowRequests,
+	reports *services.Reports,
+	quarantine *services.Quarantine,
+	policies *services.Policies,
+	users *services.Users,
+	pk *services.PrivateKeys,
+	tc *conn.Controller,
 	actor pub.Actor,
+	actorMap map[paths.Actor]pub.Actor,
 	a app.Application) *Framework {
 	_, isS2S := a.(app.S2SApplication)
 	fw.scheme = scheme
 	fw.host = host
+	fw.accountDomain = accountDomain
 	fw.o = o
 	fw.s = s
 	fw.data = data
+	fw.peers = peers
+	fw.featured = featured
+	fw.blocks = blocks
+	fw.mutes = mutes
+	fw.followRequests = followRequests
+	fw.reports = reports
+	fw.quarantine = quarantine
+	fw.policies = policies
+	fw.users = users
+	fw.pk = pk
+	fw.tc = tc
 	fw.actor = actor
+	fw.actorMap = actorMap
 	fw.federationEnabled = isS2S
 	return fw
 }
@@ -70,20 +116,60 @@ func (f *Framework) UserIRI(userUUID paths.UUID) *url.URL {
 	return paths.UUIDIRIFor(f.scheme, f.host, paths.UserPathKey, userUUID)
 }
 
-func (f *Framework) Validate(w http.ResponseWriter, r *http.Request) (userID paths.UUID, authenticated bool, err error) {
+func (f *Framework) AccountDomain() string {
+	return f.accountDomain
+}
+
+func (f *Framework) Validate(w http.ResponseWriter, r *http.Request) (userID paths.UUID, scopes []string, authenticated bool, err error) {
 	var suID string
-	suID, authenticated, err = f.o.Validate(w, r)
+	suID, scopes, authenticated, err = f.o.Validate(w, r)
 	userID = paths.UUID(suID)
 	return
 }
 
+func (f *Framework) RequestedScopes(r *http.Request) ([]app.Scope, error) {
+	return f.o.RequestedScopes(r)
+}
+
+func (f *Framework) RequestingClient(r *http.Request) (*app.OAuth2Client, error) {
+	return f.o.RequestingClient(r)
+}
+
+func (f *Framework) OAuth2Clients(c util.Context) ([]app.OAuth2Client, error) {
+	return f.o.Clients(c)
+}
+
+func (f *Framework) RemoveOAuth2Client(c util.Context, id string) error {
+	return f.o.RemoveClient(c, id)
+}
+
+func (f *Framework) OAuth2Grants(c util.Context, userID paths.UUID) ([]app.OAuth2Grant, error) {
+	return f.o.UserGrants(c, string(userID))
+}
+
+func (f *Framework) RevokeOAuth2Grant(c util.Context, userID paths.UUID, clientID string) error {
+	return f.o.RevokeUserGrant(c, string(userID), clientID)
+}
+
 func (f *Framework) Send(c util.Context, userID paths.UUID, t vocab.Type) error {
 	c.WithUserPathUUID(userID)
+	c.WithActorIRI(f.UserIRI(userID))
 	if !f.federationEnabled {
 		return fmt.Errorf("cannot Send: Framework.Send called when federation is not enabled")
 	} else if fa, ok := f.actor.(pub.FederatingActor); !ok {
 		return fmt.Errorf("cannot Send: pub.Actor is not a pub.FederatingActor with federation enabled")
+	} else if u, err := f.users.UserByID(c, userID); err != nil {
+		return err
+	} else if u == nil || !u.Suspended.IsZero() || !u.Deleted.IsZero() {
+		return fmt.Errorf("cannot Send: user %s is suspended, deleted, or does not exist", userID)
 	} else {
+		// Do not deliver to actors the user has blocked, except for
+		// the Block itself.
+		if _, isBlock := t.(vocab.ActivityStreamsBlock); !isBlock {
+			if err := f.blocks.RemoveBlockedAddressees(c, f.UserIRI(userID), t); err != nil {
+				return err
+			}
+		}
 		outboxIRI := paths.UUIDIRIFor(f.scheme, f.host, paths.OutboxPathKey, userID)
 		_, err := fa.Send(c.Context, outboxIRI, t)
 		return err
@@ -97,3 +183,687 @@ func (f *Framework) Session(r *http.Request) (app.Session, error) {
 func (f *Framework) GetByIRI(c util.Context, id *url.URL) (vocab.Type, error) {
 	return f.data.Get(c, id)
 }
+
+func (f *Framework) GetByIRIForUser(c util.Context, userID paths.UUID, id *url.URL) (vocab.Type, error) {
+	t, err := f.data.Get(c, id)
+	if err != nil || f.data.Owns(id) {
+		return t, err
+	}
+	if err := f.policies.Rewrite(c, f.UserIRI(userID), t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (f *Framework) Peers(c util.Context) (ps []app.Peer, err error) {
+	var sp []*services.Peer
+	sp, err = f.peers.AllPeers(c)
+	if err != nil {
+		return
+	}
+	for _, p := range sp {
+		ps = append(ps, app.Peer{
+			Host:                p.Host,
+			FirstSeen:           p.FirstSeen,
+			LastSeen:            p.LastSeen,
+			SoftwareName:        p.SoftwareName,
+			SoftwareVersion:     p.SoftwareVersion,
+			NodeInfoFetched:     p.NodeInfoFetched,
+			DeliverySuccesses:   p.DeliverySuccesses,
+			DeliveryFailures:    p.DeliveryFailures,
+			LastDeliverySuccess: p.LastDeliverySuccess,
+			LastDeliveryFailure: p.LastDeliveryFailure,
+		})
+	}
+	return
+}
+
+func (f *Framework) Pin(c util.Context, userID paths.UUID, object *url.URL) error {
+	actorIRI := f.UserIRI(userID)
+	if !f.data.Owns(object) {
+		return fmt.Errorf("cannot pin %s: object is not local", object)
+	}
+	t, err := f.data.Get(c, object)
+	if err != nil {
+		return err
+	}
+	owners, err := ownersOf(t)
+	if err != nil {
+		return err
+	} else if !containsIRI(owners, actorIRI) {
+		return fmt.Errorf("cannot pin %s: object is not attributed to %s", object, actorIRI)
+	}
+	pinned, err := f.featured.Pin(c, actorIRI, object)
+	if err != nil || !pinned || !f.federationEnabled {
+		return err
+	}
+	add := streams.NewActivityStreamsAdd()
+	f.addressFeaturedActivity(add, userID, object)
+	return f.Send(c, userID, add)
+}
+
+func (f *Framework) Unpin(c util.Context, userID paths.UUID, object *url.URL) error {
+	actorIRI := f.UserIRI(userID)
+	unpinned, err := f.featured.Unpin(c, actorIRI, object)
+	if err != nil || !unpinned || !f.federationEnabled {
+		return err
+	}
+	remove := streams.NewActivityStreamsRemove()
+	f.addressFeaturedActivity(remove, userID, object)
+	return f.Send(c, userID, remove)
+}
+
+func (f *Framework) Featured(c util.Context, actorIRI *url.URL) (vocab.ActivityStreamsOrderedCollection, error) {
+	return f.featured.GetAllForActor(c, actorIRI)
+}
+
+func (f *Framework) Block(c util.Context, userID paths.UUID, actor *url.URL) error {
+	actorIRI := f.UserIRI(userID)
+	blocked, err := f.blocks.Block(c, actorIRI, actor, nil)
+	if err != nil || !blocked || !f.federationEnabled {
+		return err
+	}
+	block := f.newBlock(actorIRI, actor, nil)
+	if err := f.Send(c, userID, block); err != nil {
+		return err
+	}
+	// The Block's id is generated when it is sent.
+	id, err := pub.GetId(block)
+	if err != nil {
+		return err
+	}
+	return f.blocks.SetActivity(c, actorIRI, actor, id)
+}
+
+func (f *Framework) Unblock(c util.Context, userID paths.UUID, actor *url.URL) error {
+	actorIRI := f.UserIRI(userID)
+	unblocked, activity, err := f.blocks.Unblock(c, actorIRI, actor)
+	if err != nil || !unblocked || !f.federationEnabled {
+		return err
+	}
+	undo := streams.NewActivityStreamsUndo()
+	actorProp := streams.NewActivityStreamsActorProperty()
+	actorProp.AppendIRI(actorIRI)
+	undo.SetActivityStreamsActor(actorProp)
+
+	objProp := streams.NewActivityStreamsObjectProperty()
+	objProp.AppendActivityStreamsBlock(f.newBlock(actorIRI, actor, activity))
+	undo.SetActivityStreamsObject(objProp)
+
+	toProp := streams.NewActivityStreamsToProperty()
+	toProp.AppendIRI(actor)
+	undo.SetActivityStreamsTo(toProp)
+	return f.Send(c, userID, undo)
+}
+
+func (f *Framework) Blocks(c util.Context, userID paths.UUID) (bs []app.Block, err error) {
+	var sb []*services.Block
+	sb, err = f.blocks.ForActor(c, f.UserIRI(userID))
+	if err != nil {
+		return
+	}
+	for _, b := range sb {
+		bs = append(bs, app.Block{
+			Blocked: b.Blocked,
+			Created: b.Created,
+		})
+	}
+	return
+}
+
+func (f *Framework) MuteActor(c util.Context, userID paths.UUID, actor *url.URL, expires time.Time) error {
+	return f.mutes.MuteActor(c, f.UserIRI(userID), actor, expires)
+}
+
+func (f *Framework) MuteDomain(c util.Context, userID paths.UUID, domain string, expires time.Time) error {
+	return f.mutes.MuteDomain(c, f.UserIRI(userID), domain, expires)
+}
+
+func (f *Framework) UnmuteActor(c util.Context, userID paths.UUID, actor *url.URL) error {
+	return f.mutes.UnmuteActor(c, f.UserIRI(userID), actor)
+}
+
+func (f *Framework) UnmuteDomain(c util.Context, userID paths.UUID, domain string) error {
+	return f.mutes.UnmuteDomain(c, f.UserIRI(userID), domain)
+}
+
+func (f *Framework) Mutes(c util.Context, userID paths.UUID) (ms []app.Mute, err error) {
+	var sm []*services.Mute
+	sm, err = f.mutes.ForActor(c, f.UserIRI(userID))
+	if err != nil {
+		return
+	}
+	for _, m := range sm {
+		ms = append(ms, app.Mute{
+			Actor:   m.Actor,
+			Domain:  m.Domain,
+			Created: m.Created,
+			Expires: m.Expires,
+		})
+	}
+	return
+}
+
+func (f *Framework) FilterMuted(c util.Context, userID paths.UUID, page vocab.ActivityStreamsOrderedCollectionPage) error {
+	if page == nil {
+		return nil
+	}
+	items := page.GetActivityStreamsOrderedItems()
+	if items == nil {
+		return nil
+	}
+	muted, err := f.mutes.Matcher(c, f.UserIRI(userID))
+	if err != nil {
+		return err
+	}
+	// Items referred to by IRI are fetched together. Items that cannot be
+	// fetched are kept, as it cannot be determined whether they are muted.
+	var ids []*url.URL
+	for iter := items.Begin(); iter != items.End(); iter = iter.Next() {
+		if iter.IsIRI() && !muted(iter.GetIRI()) {
+			ids = append(ids, iter.GetIRI())
+		}
+	}
+	var fetched map[string]vocab.Type
+	if len(ids) > 0 {
+		if fetched, err = f.data.GetMany(c, ids); err != nil {
+			return err
+		}
+	}
+	// Iterate backwards so removals do not disturb the indices yet to be
+	// visited. The page's totalItems is that of the whole collection, so
+	// it is left unchanged.
+	for i := items.Len() - 1; i >= 0; i-- {
+		iter := items.At(i)
+		t := iter.GetType()
+		if iter.IsIRI() {
+			if muted(iter.GetIRI()) {
+				items.Remove(i)
+				continue
+			}
+			t = fetched[iter.GetIRI().String()]
+		}
+		if t == nil {
+			continue
+		}
+		if iris, err := mutableIRIs(t); err != nil {
+			return err
+		} else if muted(iris...) {
+			items.Remove(i)
+		}
+	}
+	return nil
+}
+
+func (f *Framework) FollowRequests(c util.Context, userID paths.UUID) (fr []app.FollowRequest, err error) {
+	var sfr []*services.FollowRequest
+	sfr, err = f.followRequests.ForActor(c, f.UserIRI(userID))
+	if err != nil {
+		return
+	}
+	for _, r := range sfr {
+		fr = append(fr, app.FollowRequest{
+			Follower: r.Follower,
+			Follow:   r.Follow,
+			Created:  r.Created,
+		})
+	}
+	return
+}
+
+func (f *Framework) AcceptFollowRequest(c util.Context, userID paths.UUID, follower *url.URL) error {
+	follow, err := f.followRequests.Accept(c, f.UserIRI(userID), follower)
+	if err != nil || follow == nil || !f.federationEnabled {
+		return err
+	}
+	accept := streams.NewActivityStreamsAccept()
+	f.addressFollowResponse(accept, userID, follower, follow)
+	return f.Send(c, userID, accept)
+}
+
+func (f *Framework) RejectFollowRequest(c util.Context, userID paths.UUID, follower *url.URL) error {
+	follow, err := f.followRequests.Reject(c, f.UserIRI(userID), follower)
+	if err != nil || follow == nil || !f.federationEnabled {
+		return err
+	}
+	reject := streams.NewActivityStreamsReject()
+	f.addressFollowResponse(reject, userID, follower, follow)
+	return f.Send(c, userID, reject)
+}
+
+func (f *Framework) SuspendUser(c util.Context, userID paths.UUID) error {
+	return f.users.SuspendUser(c, userID)
+}
+
+func (f *Framework) UnsuspendUser(c util.Context, userID paths.UUID) error {
+	return f.users.UnsuspendUser(c, userID)
+}
+
+func (f *Framework) DeleteUser(c util.Context, userID paths.UUID) error {
+	if f.federationEnabled {
+		if err := f.federateUserDelete(c, userID); err != nil {
+			return err
+		}
+	}
+	return f.users.DeleteUser(c, userID)
+}
+
+// federateUserDelete sends a Delete of the user's actor to its followers, and
+// then to the shared inboxes of all known federated actors so that peers
+// without any followers also learn of the deletion.
+func (f *Framework) federateUserDelete(c util.Context, userID paths.UUID) error {
+	public, err := url.Parse(pub.PublicActivityPubIRI)
+	if err != nil {
+		return err
+	}
+	actorIRI := f.UserIRI(userID)
+	del := streams.NewActivityStreamsDelete()
+	actorProp := streams.NewActivityStreamsActorProperty()
+	actorProp.AppendIRI(actorIRI)
+	del.SetActivityStreamsActor(actorProp)
+
+	objProp := streams.NewActivityStreamsObjectProperty()
+	objProp.AppendIRI(actorIRI)
+	del.SetActivityStreamsObject(objProp)
+
+	toProp := streams.NewActivityStreamsToProperty()
+	toProp.AppendIRI(public)
+	del.SetActivityStreamsTo(toProp)
+
+	ccProp := streams.NewActivityStreamsCcProperty()
+	ccProp.AppendIRI(paths.UUIDIRIFor(f.scheme, f.host, paths.FollowersPathKey, userID))
+	del.SetActivityStreamsCc(ccProp)
+	if err := f.Send(c, userID, del); err != nil {
+		return err
+	}
+
+	inboxes, err := f.data.SharedInboxes(c)
+	if err != nil || len(inboxes) == 0 {
+		return err
+	}
+	m, err := streams.Serialize(del)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	k, keyID, err := f.pk.GetUserHTTPSignatureKey(c, userID)
+	if err != nil {
+		return err
+	}
+	tp, err := f.tc.Get(k, keyID.String())
+	if err != nil {
+		return err
+	}
+	c.WithUserPathUUID(userID)
+	return tp.BatchDeliver(c.Context, b, inboxes)
+}
+
+func (f *Framework) Report(c util.Context, userID paths.UUID, actor *url.URL, objects []*url.URL, content string, forward bool) (string, error) {
+	id, err := f.reports.File(c, f.UserIRI(userID), actor, objects, content)
+	if err != nil || !forward || !f.federationEnabled || actor.Host == f.host {
+		return id, err
+	}
+	// The report is already filed, so returning an error would only lead
+	// the caller to file it again.
+	if err := f.forwardReport(c, id, actor, objects, content); err != nil {
+		util.ErrorLogger.Errorf("failed to forward report %s to %s: %s", id, actor, err)
+	}
+	return id, nil
+}
+
+func (f *Framework) Reports(c util.Context, resolved bool, offset, n int) (rs []app.Report, err error) {
+	var sr []*services.Report
+	sr, err = f.reports.List(c, resolved, offset, n)
+	if err != nil {
+		return
+	}
+	for _, r := range sr {
+		rs = append(rs, app.Report{
+			ID:         r.ID,
+			Created:    r.Created,
+			Reporter:   r.Reporter,
+			Target:     r.Target,
+			Objects:    r.Objects,
+			Content:    r.Content,
+			Forwarded:  r.Forwarded,
+			Assignee:   r.Assignee,
+			Resolved:   r.Resolved,
+			Resolution: r.Resolution,
+		})
+	}
+	return
+}
+
+func (f *Framework) AssignReport(c util.Context, reportID string, adminID paths.UUID) error {
+	p, err := f.users.Privileges(c, string(adminID), nil)
+	if err != nil {
+		return err
+	} else if !p.Admin {
+		return fmt.Errorf("cannot assign report: user %s is not an admin", adminID)
+	}
+	return f.reports.Assign(c, reportID, f.UserIRI(adminID))
+}
+
+func (f *Framework) ResolveReport(c util.Context, reportID, resolution string) error {
+	return f.reports.Resolve(c, reportID, resolution)
+}
+
+func (f *Framework) QuarantinedActivities(c util.Context, offset, n int) (qs []app.QuarantinedActivity, err error) {
+	var sq []*services.Quarantined
+	sq, err = f.quarantine.List(c, models.QuarantinePending, offset, n)
+	if err != nil {
+		return
+	}
+	for _, q := range sq {
+		qa := app.QuarantinedActivity{
+			ID:        q.ID,
+			Created:   q.Created,
+			Recipient: q.Actor,
+			Activity:  q.Activity,
+			PolicyID:  q.PolicyID,
+		}
+		if q.Resolution != nil {
+			qa.ResolutionID = q.Resolution.ID
+			qa.MatchLog = q.Resolution.MatchLog
+		}
+		qs = append(qs, qa)
+	}
+	return
+}
+
+func (f *Framework) ApproveQuarantined(c util.Context, id string) error {
+	q, err := f.quarantine.Get(c, id)
+	if err != nil {
+		return err
+	} else if q == nil || q.Status != models.QuarantinePending {
+		return fmt.Errorf("quarantined activity %s does not exist or was already reviewed", id)
+	} else if !f.federationEnabled {
+		return fmt.Errorf("cannot approve quarantined activity: federation is not enabled")
+	}
+	// Only pending activities can be approved, so of concurrent approvals
+	// only one redelivers the activity.
+	if err := f.quarantine.Review(c, id, models.QuarantineApproved); err != nil {
+		return err
+	}
+	if err := f.redeliverQuarantined(c, q); err != nil {
+		// Return it to the queue so that it can be approved again.
+		if rErr := f.quarantine.Reopen(c, id, models.QuarantineApproved); rErr != nil {
+			util.ErrorLogger.Errorf("failed to reopen quarantined activity %s: %s", id, rErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (f *Framework) RejectQuarantined(c util.Context, id string) error {
+	return f.quarantine.Review(c, id, models.QuarantineRejected)
+}
+
+// redeliverQuarantined posts the approved activity to its recipient's inbox
+// through the actor, skipping the authentication and quarantine it already
+// went through when it was first received.
+func (f *Framework) redeliverQuarantined(c util.Context, q *services.Quarantined) error {
+	// The instance actor has its own actor and inbox, which are not at a
+	// user path.
+	actor := f.actor
+	var uuid paths.UUID
+	var inboxIRI *url.URL
+	if paths.IsInstanceActorPath(q.Actor) {
+		actor = f.actorMap[paths.InstanceActor]
+		uuid = paths.UUID(paths.InstanceActor)
+		inboxIRI = paths.ActorIRIFor(f.scheme, f.host, paths.InboxPathKey, paths.InstanceActor)
+	} else {
+		var err error
+		if uuid, err = paths.UUIDFromUserPath(q.Actor.Path); err != nil {
+			return err
+		}
+		inboxIRI = paths.UUIDIRIFor(f.scheme, f.host, paths.InboxPathKey, uuid)
+	}
+	if actor == nil {
+		return fmt.Errorf("cannot approve quarantined activity: no actor for %s", q.Actor)
+	}
+	m, err := streams.Serialize(q.Activity)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, inboxIRI.String(), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(c.Context)
+	req.Header.Set("Content-Type", "application/activity+json")
+	rc := util.WithUserAPHTTPContext(f.scheme, f.host, req, uuid, "")
+	rc.WithQuarantineApproved()
+	w := httptest.NewRecorder()
+	if isAP, err := actor.PostInboxScheme(rc.Context, w, req, f.scheme); err != nil {
+		return err
+	} else if !isAP || w.Code >= http.StatusBadRequest {
+		return fmt.Errorf("cannot approve quarantined activity: inbox responded with status %d", w.Code)
+	}
+	return nil
+}
+
+// forwardReport sends the report to the inbox of the reported actor as a Flag
+// from the instance actor, preferring the shared inbox of its server.
+func (f *Framework) forwardReport(c util.Context, reportID string, actor *url.URL, objects []*url.URL, content string) error {
+	iaID, err := f.users.InstanceActorID(c)
+	if err != nil {
+		return err
+	}
+	k, keyID, err := f.pk.GetUserHTTPSignatureKeyForInstanceActor(c)
+	if err != nil {
+		return err
+	}
+	tp, err := f.tc.Get(k, keyID.String())
+	if err != nil {
+		return err
+	}
+	ab, err := tp.Dereference(c.Context, actor)
+	if err != nil {
+		return err
+	}
+	inbox, err := actorInbox(ab)
+	if err != nil {
+		return err
+	}
+	iaIRI := paths.ActorIRIFor(f.scheme, f.host, paths.UserPathKey, paths.InstanceActor)
+	id := *iaIRI
+	id.Fragment = "reports/" + reportID
+
+	flag := streams.NewActivityStreamsFlag()
+	idProp := streams.NewJSONLDIdProperty()
+	idProp.Set(&id)
+	flag.SetJSONLDId(idProp)
+
+	actorProp := streams.NewActivityStreamsActorProperty()
+	actorProp.AppendIRI(iaIRI)
+	flag.SetActivityStreamsActor(actorProp)
+
+	objProp := streams.NewActivityStreamsObjectProperty()
+	objProp.AppendIRI(actor)
+	for _, o := range objects {
+		objProp.AppendIRI(o)
+	}
+	flag.SetActivityStreamsObject(objProp)
+
+	if len(content) > 0 {
+		contentProp := streams.NewActivityStreamsContentProperty()
+		contentProp.AppendXMLSchemaString(content)
+		flag.SetActivityStreamsContent(contentProp)
+	}
+
+	toProp := streams.NewActivityStreamsToProperty()
+	toProp.AppendIRI(actor)
+	flag.SetActivityStreamsTo(toProp)
+
+	m, err := streams.Serialize(flag)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	// The Flag is recorded as a delivery attempt by the instance actor
+	// before it is sent, so a failed delivery is retried like any other.
+	c.WithUserPathUUID(iaID)
+	if err := tp.Deliver(c.Context, b, inbox); err != nil {
+		util.ErrorLogger.Errorf("delivery of the Flag forwarding report %s failed: %s", reportID, err)
+	}
+	return f.reports.MarkForwarded(c, reportID, &id)
+}
+
+// actorInbox returns the shared inbox of a dereferenced actor if it has one,
+// and otherwise its inbox.
+func actorInbox(b []byte) (*url.URL, error) {
+	var a struct {
+		Inbox     string `json:"inbox"`
+		Endpoints struct {
+			SharedInbox string `json:"sharedInbox"`
+		} `json:"endpoints"`
+	}
+	if err := json.Unmarshal(b, &a); err != nil {
+		return nil, err
+	}
+	if len(a.Endpoints.SharedInbox) > 0 {
+		return url.Parse(a.Endpoints.SharedInbox)
+	} else if len(a.Inbox) == 0 {
+		return nil, fmt.Errorf("actor has no inbox")
+	}
+	return url.Parse(a.Inbox)
+}
+
+// followResponse is the common interface of the Accept and Reject activities
+// used to respond to a Follow.
+type followResponse interface {
+	SetActivityStreamsActor(vocab.ActivityStreamsActorProperty)
+	SetActivityStreamsObject(vocab.ActivityStreamsObjectProperty)
+	SetActivityStreamsTo(vocab.ActivityStreamsToProperty)
+}
+
+// addressFollowResponse populates an Accept or Reject of the Follow and
+// addresses it to the follower.
+func (f *Framework) addressFollowResponse(a followResponse, userID paths.UUID, follower *url.URL, follow vocab.ActivityStreamsFollow) {
+	actorProp := streams.NewActivityStreamsActorProperty()
+	actorProp.AppendIRI(f.UserIRI(userID))
+	a.SetActivityStreamsActor(actorProp)
+
+	objProp := streams.NewActivityStreamsObjectProperty()
+	objProp.AppendActivityStreamsFollow(follow)
+	a.SetActivityStreamsObject(objProp)
+
+	toProp := streams.NewActivityStreamsToProperty()
+	toProp.AppendIRI(follower)
+	a.SetActivityStreamsTo(toProp)
+}
+
+// mutableIRIs returns the IRIs of an inbox item that a mute may apply to: its
+// id, actors, and attributedTo.
+func mutableIRIs(t vocab.Type) (iris []*url.URL, err error) {
+	if id, err := pub.GetId(t); err == nil {
+		iris = append(iris, id)
+	}
+	var owners []*url.URL
+	owners, err = ownersOf(t)
+	iris = append(iris, owners...)
+	return
+}
+
+// ownersOf returns the actors and attributedTo of an ActivityStreams type.
+func ownersOf(t vocab.Type) (iris []*url.URL, err error) {
+	if a, ok := t.(interface {
+		GetActivityStreamsActor() vocab.ActivityStreamsActorProperty
+	}); ok {
+		if ap := a.GetActivityStreamsActor(); ap != nil {
+			for iter := ap.Begin(); iter != ap.End(); iter = iter.Next() {
+				var id *url.URL
+				if id, err = pub.ToId(iter); err != nil {
+					return
+				}
+				iris = append(iris, id)
+			}
+		}
+	}
+	if a, ok := t.(interface {
+		GetActivityStreamsAttributedTo() vocab.ActivityStreamsAttributedToProperty
+	}); ok {
+		if ap := a.GetActivityStreamsAttributedTo(); ap != nil {
+			for iter := ap.Begin(); iter != ap.End(); iter = iter.Next() {
+				var id *url.URL
+				if id, err = pub.ToId(iter); err != nil {
+					return
+				}
+				iris = append(iris, id)
+			}
+		}
+	}
+	return
+}
+
+func containsIRI(iris []*url.URL, iri *url.URL) bool {
+	for _, i := range iris {
+		if i.String() == iri.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// newBlock creates a Block of the actor addressed to the actor. The id is
+// only set if it is known.
+func (f *Framework) newBlock(actorIRI, blocked, id *url.URL) vocab.ActivityStreamsBlock {
+	block := streams.NewActivityStreamsBlock()
+	if id != nil {
+		idProp := streams.NewJSONLDIdProperty()
+		idProp.Set(id)
+		block.SetJSONLDId(idProp)
+	}
+	actorProp := streams.NewActivityStreamsActorProperty()
+	actorProp.AppendIRI(actorIRI)
+	block.SetActivityStreamsActor(actorProp)
+
+	objProp := streams.NewActivityStreamsObjectProperty()
+	objProp.AppendIRI(blocked)
+	block.SetActivityStreamsObject(objProp)
+
+	toProp := streams.NewActivityStreamsToProperty()
+	toProp.AppendIRI(blocked)
+	block.SetActivityStreamsTo(toProp)
+	return block
+}
+
+// featuredActivity is the common interface of the Add and Remove activities
+// used to announce changes to a user's featured collection.
+type featuredActivity interface {
+	SetActivityStreamsActor(vocab.ActivityStreamsActorProperty)
+	SetActivityStreamsObject(vocab.ActivityStreamsObjectProperty)
+	SetActivityStreamsTarget(vocab.ActivityStreamsTargetProperty)
+	SetActivityStreamsTo(vocab.ActivityStreamsToProperty)
+}
+
+// addressFeaturedActivity populates an Add or Remove targeting the user's
+// featured collection and addresses it to the user's followers.
+func (f *Framework) addressFeaturedActivity(a featuredActivity, userID paths.UUID, object *url.URL) {
+	actorProp := streams.NewActivityStreamsActorProperty()
+	actorProp.AppendIRI(f.UserIRI(userID))
+	a.SetActivityStreamsActor(actorProp)
+
+	objProp := streams.NewActivityStreamsObjectProperty()
+	objProp.AppendIRI(object)
+	a.SetActivityStreamsObject(objProp)
+
+	targetProp := streams.NewActivityStreamsTargetProperty()
+	targetProp.AppendIRI(paths.UUIDIRIFor(f.scheme, f.host, paths.FeaturedPathKey, userID))
+	a.SetActivityStreamsTarget(targetProp)
+
+	toProp := streams.NewActivityStreamsToProperty()
+	toProp.AppendIRI(paths.UUIDIRIFor(f.scheme, f.host, paths.FollowersPathKey, userID))
+	a.SetActivityStreamsTo(toProp)
+}
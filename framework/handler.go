@@ -61,6 +61,7 @@ func BuildHandler(r *Router,
 	following *services.Following,
 	followers *services.Followers,
 	liked *services.Liked,
+	policies *services.Policies,
 	sqldb *sql.DB,
 	oauth *oauth2.Server,
 	sl *web.Sessions,
@@ -86,10 +87,10 @@ func BuildHandler(r *Router,
 
 	// Webfinger
 	r.WebOnlyHandleFunc("/.well-known/webfinger",
-		webfingerHandler(scheme, c.ServerConfig.Host, badRequestHandler, internalErrorHandler, users))
+		webfingerHandler(scheme, c.ServerConfig.Host, c.ServerConfig.AccountDomain, badRequestHandler, internalErrorHandler, users))
 
 	// Node-info
-	for _, ph := range nodeinfo.GetNodeInfoHandlers(c.NodeInfoConfig, scheme, c.ServerConfig.Host, ni, users, sw, apcore) {
+	for _, ph := range nodeinfo.GetNodeInfoHandlers(c.NodeInfoConfig, scheme, c.ServerConfig.Host, ni, users, a, sw, apcore) {
 		r.WebOnlyHandleFunc(ph.Path, ph.Handler)
 	}
 
@@ -101,6 +102,7 @@ func BuildHandler(r *Router,
 	// - Followers
 	// - Following
 	// - Liked
+	// - Featured
 	if sa, isS2S := a.(app.S2SApplication); isS2S {
 		r.userActorPostInbox()
 		r.userActorGetInbox(sa.GetInboxWebHandlerFunc(fr))
@@ -143,6 +145,9 @@ func BuildHandler(r *Router,
 		a.GetLikedWebHandlerFunc,
 		liked.GetPage,
 		liked.GetLastPage)
+	r.ActivityPubOnlyHandleFunc(paths.Route(paths.FeaturedPathKey), nil)
+	// The replies, likes, and shares of local objects
+	r.objectCollections()
 	addVocabTypeWebFn := func(path string,
 		f func(app.Framework) (app.VocabHandlerFunc, app.AuthorizeFunc),
 		get func(util.Context) (vocab.Type, error)) {
@@ -190,22 +195,65 @@ func BuildHandler(r *Router,
 		HandlerFunc(
 			getLogoutFn(oauth, sl, internalErrorHandler))
 	r.NewRoute().
-		Path("/oauth2/authorize").
+		Path(paths.OAuth2AuthorizePath).
 		Methods("GET").
 		HandlerFunc(
 			getAuthFn(getAuthWebHandler))
 	r.NewRoute().
-		Path("/oauth2/authorize").
+		Path(paths.OAuth2AuthorizePath).
 		Methods("POST").
 		HandlerFunc(
 			postAuthFn(oauth, sl, db, badRequestHandler, internalErrorHandler, cy))
 	r.NewRoute().
-		Path("/oauth2/token").
-		Methods("GET").
+		Path(paths.OAuth2TokenPath).
+		Methods("POST").
 		HandlerFunc(
 			func(w http.ResponseWriter, r *http.Request) {
 				oauth.HandleAccessTokenRequest(w, r)
 			})
+	r.NewRoute().
+		Path(paths.OAuth2RevokePath).
+		Methods("POST").
+		HandlerFunc(oauth.HandleRevocationRequest)
+	r.NewRoute().
+		Path(paths.OAuth2IntrospectPath).
+		Methods("POST").
+		HandlerFunc(oauth.HandleIntrospectionRequest)
+	r.NewRoute().
+		Path(paths.OAuth2MetadataPath).
+		Methods("GET").
+		HandlerFunc(oauth.HandleMetadataRequest)
+	r.NewRoute().
+		Path(paths.OpenIDConfigPath).
+		Methods("GET").
+		HandlerFunc(oauth.HandleMetadataRequest)
+	r.NewRoute().
+		Path(paths.OpenIDUserInfoPath).
+		Methods("GET", "POST").
+		HandlerFunc(oauth.HandleUserInfoRequest)
+	r.NewRoute().
+		Path(paths.OpenIDJWKSPath).
+		Methods("GET").
+		HandlerFunc(oauth.HandleJWKSRequest)
+	if c.OAuthConfig.EnableClientRegistration {
+		r.NewRoute().
+			Path(paths.OAuth2RegisterPath).
+			Methods("POST").
+			HandlerFunc(oauth.HandleClientRegistrationRequest)
+	}
+
+	// Policy management API
+	pa := &policyAPI{
+		scheme:               scheme,
+		host:                 c.ServerConfig.Host,
+		oauth:                oauth,
+		policies:             policies,
+		badRequestHandler:    badRequestHandler,
+		internalErrorHandler: internalErrorHandler,
+		defaultPageSize:      defaultCollectionSize,
+		maxPageSize:          maxCollectionPageSize,
+	}
+	pa.addRoutes(r)
 
 	// Application-specific routes
 	err = a.BuildRoutes(r, db, fw)
@@ -321,7 +369,13 @@ func hostMetaHandler(scheme, host string) func(w http.ResponseWriter, r *http.Re
 	}
 }
 
-func webfingerHandler(scheme, host string, badRequestHandler, internalErrorHandler http.Handler, users *services.Users) func(http.ResponseWriter, *http.Request) {
+// webfingerHandler serves WebFinger lookups for local users.
+//
+// Resources may use either the account domain or the serving host, so that
+// peers resolving a handle via a delegated account domain and peers
+// verifying the handle of an actor served on this host both succeed. The
+// subject returned always uses the account domain.
+func webfingerHandler(scheme, host, accountDomain string, badRequestHandler, internalErrorHandler http.Handler, users *services.Users) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vals := r.URL.Query()
 		userAccts := strings.Split(
@@ -332,6 +386,11 @@ func webfingerHandler(scheme, host string, badRequestHandler, internalErrorHandl
 			badRequestHandler.ServeHTTP(w, r)
 			return
 		}
+		if domain := strings.ToLower(userAccts[1]); domain != strings.ToLower(accountDomain) && domain != strings.ToLower(host) {
+			util.ErrorLogger.Errorf("error serving webfinger: resource domain is neither %s nor %s: %s", accountDomain, host, vals.Get("resource"))
+			badRequestHandler.ServeHTTP(w, r)
+			return
+		}
 		username := userAccts[0]
 		s, err := users.UserByUsername(util.Context{r.Context()}, username)
 		if err != nil {
@@ -340,7 +399,7 @@ func webfingerHandler(scheme, host string, badRequestHandler, internalErrorHandl
 			return
 		}
 		uuid := paths.UUID(s.ID)
-		wf, err := webfinger.ToWebfinger(scheme, host, username, paths.UUIDPathFor(paths.UserPathKey, uuid))
+		wf, err := webfinger.ToWebfinger(scheme, host, accountDomain, username, paths.UUIDPathFor(paths.UserPathKey, uuid))
 		if err != nil {
 			util.ErrorLogger.Errorf("error serving webfinger: %s", err)
 			internalErrorHandler.ServeHTTP(w, r)
@@ -28,15 +28,19 @@ import (
 	"github.com/go-fed/apcore/util"
 )
 
-// This file contains the NodeInfo v2.1 implementation.
+// This file contains the NodeInfo v2.1 implementation, which is also served as
+// v2.0 for peers that have not adopted v2.1.
 //
 // NodeInfo is infamous for being uncompromising in its dictatorial content
 // requirements of the fields presented below.
 
 const (
 	nodeInfoVersion        = "2.1"
+	nodeInfo20Version      = "2.0"
 	nodeInfoWellKnownPath  = "/.well-known/nodeinfo"
 	nodeInfoPath           = "/nodeinfo/" + nodeInfoVersion
+	nodeInfo20Path         = "/nodeinfo/" + nodeInfo20Version
+	nodeInfoSchemaRel      = "http://nodeinfo.diaspora.software/ns/schema/"
 	validSoftwareNameChars = "abcdefghijklmnopqrstuvwxyz0123456789-"
 )
 
@@ -53,7 +57,8 @@ type nodeInfo struct {
 type software struct {
 	Name       string `json:"name"`
 	Version    string `json:"version"`
-	Repository string `json:"repository"`
+	Repository string `json:"repository,omitempty"`
+	Homepage   string `json:"homepage,omitempty"`
 }
 
 type services struct {
@@ -88,26 +93,70 @@ func sanitizeSoftwareName(name string) string {
 	return b.String()
 }
 
-func toNodeInfo(s, apcore app.Software, t *srv.NodeInfoStats, p srv.ServerPreferences) nodeInfo {
+// protocols lists the protocols the application supports, derived from
+// whether it is an S2S or C2S application. Both are parts of ActivityPub, so
+// a C2S-only application lists it too; the apcore metadata tells them apart.
+func protocols(a app.Application) []string {
+	_, isS2S := a.(app.S2SApplication)
+	_, isC2S := a.(app.C2SApplication)
+	if isS2S || isC2S {
+		return []string{"activitypub"}
+	}
+	return []string{}
+}
+
+// toMetadata builds the NodeInfo metadata from the server preferences, apcore
+// information, and any application-provided entries.
+func toMetadata(c util.Context, a app.Application, apcore app.Software, p srv.ServerPreferences) (m map[string]interface{}, err error) {
+	_, isS2S := a.(app.S2SApplication)
+	_, isC2S := a.(app.C2SApplication)
+	m = map[string]interface{}{
+		"nodeName":        p.ServerName,
+		"nodeDescription": p.ServerDescription,
+		"organization": map[string]interface{}{
+			"name":    p.OrgName,
+			"contact": p.OrgContact,
+			"account": p.OrgAccount,
+		},
+		apcore.Name: map[string]interface{}{
+			"version":    apcore.Version(),
+			"repository": apcore.Repository,
+			"federating": isS2S,
+			"social":     isC2S,
+		},
+	}
+	if na, ok := a.(app.NodeInfoApplication); ok {
+		var am map[string]interface{}
+		am, err = na.NodeInfoMetadata(c.Context)
+		if err != nil {
+			return
+		}
+		for k, v := range am {
+			m[k] = v
+		}
+	}
+	return
+}
+
+func toNodeInfo(version string, s app.Software, a app.Application, t *srv.NodeInfoStats, p srv.ServerPreferences, metadata map[string]interface{}) nodeInfo {
 	n := nodeInfo{
-		Version: nodeInfoVersion,
+		Version: version,
 		Software: software{
-			Name:       sanitizeSoftwareName(s.Name),
-			Version:    s.Version(),
-			Repository: s.Repository,
+			Name:    sanitizeSoftwareName(s.Name),
+			Version: s.Version(),
 		},
-		Protocols: []string{"activitypub"},
+		Protocols: protocols(a),
 		Services: services{
 			Inbound:  []string{},
 			Outbound: []string{},
 		},
 		OpenRegistrations: p.OpenRegistrations,
-		Metadata: map[string]interface{}{
-			apcore.Name: map[string]interface{}{
-				"version":    apcore.Version(),
-				"repository": apcore.Repository,
-			},
-		},
+		Metadata:          metadata,
+	}
+	// The repository and homepage were introduced in v2.1.
+	if version != nodeInfo20Version {
+		n.Software.Repository = s.Repository
+		n.Software.Homepage = s.Homepage
 	}
 	if t != nil {
 		n.Usage = usage{
@@ -127,12 +176,23 @@ func nodeInfoWellKnownHandler(scheme, host string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/jrd+json")
 		var b bytes.Buffer
-		b.WriteString(`{"links":[{"rel": "http://nodeinfo.diaspora.software/ns/schema/2.1","href": "`)
-		b.WriteString(scheme)
-		b.WriteString(`://`)
-		b.WriteString(host)
-		b.WriteString(nodeInfoPath)
-		b.WriteString(`"}]}`)
+		b.WriteString(`{"links":[`)
+		for i, v := range []string{nodeInfo20Version, nodeInfoVersion} {
+			if i > 0 {
+				b.WriteString(`,`)
+			}
+			b.WriteString(`{"rel": "`)
+			b.WriteString(nodeInfoSchemaRel)
+			b.WriteString(v)
+			b.WriteString(`","href": "`)
+			b.WriteString(scheme)
+			b.WriteString(`://`)
+			b.WriteString(host)
+			b.WriteString(`/nodeinfo/`)
+			b.WriteString(v)
+			b.WriteString(`"}`)
+		}
+		b.WriteString(`]}`)
 		bt := b.Bytes()
 		n, err := w.Write(bt)
 		if err != nil {
@@ -143,9 +203,9 @@ func nodeInfoWellKnownHandler(scheme, host string) http.HandlerFunc {
 	}
 }
 
-func nodeInfoHandler(ni *srv.NodeInfo, u *srv.Users, s, apcore app.Software, useStats bool) http.HandlerFunc {
+func nodeInfoHandler(version string, ni *srv.NodeInfo, u *srv.Users, a app.Application, s, apcore app.Software, useStats bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", `application/json; profile="http://nodeinfo.diaspora.software/ns/schema/2.1#"`)
+		w.Header().Set("Content-Type", fmt.Sprintf(`application/json; profile="%s%s#"`, nodeInfoSchemaRel, version))
 
 		ctx := util.Context{r.Context()}
 		var t *srv.NodeInfoStats
@@ -166,7 +226,14 @@ func nodeInfoHandler(ni *srv.NodeInfo, u *srv.Users, s, apcore app.Software, use
 			return
 		}
 
-		ni := toNodeInfo(s, apcore, t, p)
+		m, err := toMetadata(ctx, a, apcore, p)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error serving nodeinfo response"), http.StatusInternalServerError)
+			util.ErrorLogger.Errorf("error in getting metadata for nodeinfo response: %s", err)
+			return
+		}
+
+		ni := toNodeInfo(version, s, a, t, p, m)
 		b, err := json.Marshal(ni)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("error serving nodeinfo response"), http.StatusInternalServerError)
@@ -81,7 +81,7 @@ type feature2 struct {
 	Version string `json:"version"`
 }
 
-func toNodeInfo2(s, apcore app.Software, t *srv.NodeInfoStats, p srv.ServerPreferences) nodeInfo2 {
+func toNodeInfo2(s, apcore app.Software, a app.Application, t *srv.NodeInfoStats, p srv.ServerPreferences) nodeInfo2 {
 	n := nodeInfo2{
 		Version: nodeInfo2Version,
 		Server: server2{
@@ -95,7 +95,7 @@ func toNodeInfo2(s, apcore app.Software, t *srv.NodeInfoStats, p srv.ServerPrefe
 			Contact: p.OrgContact,
 			Account: p.OrgAccount,
 		},
-		Protocols: []string{"activitypub"},
+		Protocols: protocols(a),
 		Services: services2{
 			Inbound:  []string{},
 			Outbound: []string{},
@@ -124,7 +124,7 @@ func toNodeInfo2(s, apcore app.Software, t *srv.NodeInfoStats, p srv.ServerPrefe
 	return n
 }
 
-func nodeInfo2WellKnownHandler(ni *srv.NodeInfo, u *srv.Users, s, apcore app.Software, useStats bool) http.HandlerFunc {
+func nodeInfo2WellKnownHandler(ni *srv.NodeInfo, u *srv.Users, a app.Application, s, apcore app.Software, useStats bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", `application/json`)
 
@@ -147,7 +147,7 @@ func nodeInfo2WellKnownHandler(ni *srv.NodeInfo, u *srv.Users, s, apcore app.Sof
 			return
 		}
 
-		ni := toNodeInfo2(s, apcore, t, p)
+		ni := toNodeInfo2(s, apcore, a, t, p)
 		b, err := json.Marshal(ni)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("error serving nodeinfo2 response"), http.StatusInternalServerError)
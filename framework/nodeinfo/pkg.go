@@ -48,7 +48,7 @@ type PathHandler struct {
 	Handler http.HandlerFunc
 }
 
-func GetNodeInfoHandlers(c config.NodeInfoConfig, scheme, host string, ni *srv.NodeInfo, u *srv.Users, s, apcore app.Software) []PathHandler {
+func GetNodeInfoHandlers(c config.NodeInfoConfig, scheme, host string, ni *srv.NodeInfo, u *srv.Users, a app.Application, s, apcore app.Software) []PathHandler {
 	var ph []PathHandler
 	if c.EnableNodeInfo {
 		ph = append(ph, PathHandler{
@@ -57,13 +57,17 @@ func GetNodeInfoHandlers(c config.NodeInfoConfig, scheme, host string, ni *srv.N
 		})
 		ph = append(ph, PathHandler{
 			Path:    nodeInfoPath,
-			Handler: nodeInfoHandler(ni, u, s, apcore, c.EnableAnonymousStatsSharing),
+			Handler: nodeInfoHandler(nodeInfoVersion, ni, u, a, s, apcore, c.EnableAnonymousStatsSharing),
+		})
+		ph = append(ph, PathHandler{
+			Path:    nodeInfo20Path,
+			Handler: nodeInfoHandler(nodeInfo20Version, ni, u, a, s, apcore, c.EnableAnonymousStatsSharing),
 		})
 	}
 	if c.EnableNodeInfo2 {
 		ph = append(ph, PathHandler{
 			Path:    nodeInfo2WellKnownPath,
-			Handler: nodeInfo2WellKnownHandler(ni, u, s, apcore, c.EnableAnonymousStatsSharing),
+			Handler: nodeInfo2WellKnownHandler(ni, u, a, s, apcore, c.EnableAnonymousStatsSharing),
 		})
 	}
 	return ph
@@ -0,0 +1,89 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package oauth2
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-fed/apcore/paths"
+	"github.com/go-fed/apcore/util"
+	"github.com/go-fed/oauth2"
+)
+
+// metadata is the OAuth 2.0 Authorization Server Metadata of RFC 8414, which
+// is also the OpenID Connect discovery document.
+type metadata struct {
+	Issuer                                 string   `json:"issuer"`
+	AuthorizationEndpoint                  string   `json:"authorization_endpoint"`
+	TokenEndpoint                          string   `json:"token_endpoint"`
+	RegistrationEndpoint                   string   `json:"registration_endpoint,omitempty"`
+	RevocationEndpoint                     string   `json:"revocation_endpoint"`
+	IntrospectionEndpoint                  string   `json:"introspection_endpoint"`
+	UserinfoEndpoint                       string   `json:"userinfo_endpoint"`
+	JWKSURI                                string   `json:"jwks_uri"`
+	ScopesSupported                        []string `json:"scopes_supported"`
+	ResponseTypesSupported                 []string `json:"response_types_supported"`
+	GrantTypesSupported                    []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported      []string `json:"token_endpoint_auth_methods_supported"`
+	RevocationEndpointAuthMethodsSupported []string `json:"revocation_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported          []string `json:"code_challenge_methods_supported"`
+	SubjectTypesSupported                  []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported       []string `json:"id_token_signing_alg_values_supported"`
+	ClaimsSupported                        []string `json:"claims_supported"`
+}
+
+// HandleMetadataRequest serves the authorization server metadata so clients
+// can discover the OAuth2 endpoints, following RFC 8414. It is also served
+// as the OpenID Connect discovery document.
+func (o *Server) HandleMetadataRequest(w http.ResponseWriter, r *http.Request) {
+	iri := func(path string) string {
+		return paths.IRIForPath(o.scheme, o.host, path).String()
+	}
+	md := metadata{
+		Issuer:                                 o.issuer(),
+		AuthorizationEndpoint:                  iri(paths.OAuth2AuthorizePath),
+		TokenEndpoint:                          iri(paths.OAuth2TokenPath),
+		RevocationEndpoint:                     iri(paths.OAuth2RevokePath),
+		IntrospectionEndpoint:                  iri(paths.OAuth2IntrospectPath),
+		UserinfoEndpoint:                       iri(paths.OpenIDUserInfoPath),
+		JWKSURI:                                iri(paths.OpenIDJWKSPath),
+		ResponseTypesSupported:                 []string{oauth2.Code.String()},
+		GrantTypesSupported:                    []string{oauth2.AuthorizationCode.String(), oauth2.Refreshing.String()},
+		TokenEndpointAuthMethodsSupported:      []string{tokenEndpointAuthNone, tokenEndpointAuthBasic},
+		RevocationEndpointAuthMethodsSupported: []string{tokenEndpointAuthNone},
+		CodeChallengeMethodsSupported:          []string{oauth2.CodeChallengeS256.String()},
+		SubjectTypesSupported:                  []string{"public"},
+		IDTokenSigningAlgValuesSupported:       []string{idTokenSigningAlg},
+		ClaimsSupported:                        []string{"iss", "sub", "aud", "exp", "iat", "nonce", "preferred_username", "profile"},
+	}
+	if o.registration {
+		md.RegistrationEndpoint = iri(paths.OAuth2RegisterPath)
+	}
+	for _, s := range o.o.All() {
+		md.ScopesSupported = append(md.ScopesSupported, s.Name)
+	}
+	b, err := json.Marshal(md)
+	if err != nil {
+		util.ErrorLogger.Errorf("error serializing OAuth2 authorization server metadata: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
@@ -26,6 +26,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/go-fed/apcore/app"
 	"github.com/go-fed/apcore/framework/config"
 	"github.com/go-fed/apcore/framework/web"
 	"github.com/go-fed/apcore/services"
@@ -48,6 +49,13 @@ type Server struct {
 	k *web.Sessions
 	m *manage.Manager
 	s *oaserver.Server
+	o *Scopes
+	p *services.PrivateKeys
+	u *services.Users
+	// OpenID Connect state, see openid.go.
+	oidc *openID
+	// registration is whether dynamic client registration is enabled.
+	registration bool
 	// First-party support:
 	clientIDBase                string
 	host                        string
@@ -60,7 +68,7 @@ type Server struct {
 	cleanupFn                   *util.SafeStartStop
 }
 
-func NewServer(c *config.Config, scheme string, internalErrorHandler http.Handler, d *services.OAuth2, y *services.Crypto, k *web.Sessions) (s *Server, err error) {
+func NewServer(c *config.Config, scheme string, internalErrorHandler http.Handler, d *services.OAuth2, y *services.Crypto, k *web.Sessions, scopes *Scopes, p *services.PrivateKeys, u *services.Users) (s *Server, err error) {
 	m := manage.NewDefaultManager()
 	// Configure Access token and Refresh token refresh.
 	if c.OAuthConfig.AccessTokenExpiry <= 0 {
@@ -87,7 +95,19 @@ func NewServer(c *config.Config, scheme string, internalErrorHandler http.Handle
 		IsRemoveRefreshing: true,
 	})
 	m.MapTokenStorage(d)
-	m.MapClientStorage(d)
+	m.MapClientStorage(redirectURIClients{d})
+	// Registered clients may have several redirect URIs on different
+	// hosts, which redirectURIClients passes here in place of a domain.
+	// Redirect URIs are matched exactly against them, both when
+	// authorizing and when exchanging a code.
+	m.SetValidateURIHandler(func(registered, redirectURI string) error {
+		for _, u := range strings.Fields(registered) {
+			if u == redirectURI {
+				return nil
+			}
+		}
+		return oaerrors.ErrInvalidRedirectURI
+	})
 	// OAuth2 server: PKCE + Authorization Code
 	srv := oaserver.NewServer(&oaserver.Config{
 		TokenType: "Bearer",
@@ -106,7 +126,20 @@ func NewServer(c *config.Config, scheme string, internalErrorHandler http.Handle
 			oauth2.AuthorizationCode,
 			oauth2.Refreshing,
 		},
+		// PKCE only with the SHA-256 challenge.
+		AllowedCodeChallengeMethods: []oauth2.CodeChallengeMethod{
+			oauth2.CodeChallengeS256,
+		},
 	}, m)
+	// Registered clients are public clients that identify themselves with
+	// the client_id form value instead of HTTP basic authentication.
+	srv.SetClientInfoHandler(func(r *http.Request) (clientID, clientSecret string, err error) {
+		var ok bool
+		if clientID, clientSecret, ok = r.BasicAuth(); ok {
+			return
+		}
+		return oaserver.ClientFormHandler(r)
+	})
 	// Determines the user to use when granting an authorization token. If
 	// no user is present, then they have not yet logged in and need to do
 	// so. Note that an empty string userID plus no error will magically
@@ -127,6 +160,22 @@ func NewServer(c *config.Config, scheme string, internalErrorHandler http.Handle
 		// User is already logged in
 		return
 	})
+	// Clients may only request registered scopes, and the user may grant
+	// fewer scopes than were requested on the authorization page.
+	srv.SetClientScopeHandler(func(clientID, scope string) (allowed bool, err error) {
+		allowed = scopes.Known(app.ParseScopes(scope))
+		return
+	})
+	srv.SetAuthorizeScopeHandler(func(w http.ResponseWriter, r *http.Request) (scope string, err error) {
+		scope = strings.Join(narrow(app.ParseScopes(r.FormValue("scope")), r.Form), " ")
+		return
+	})
+	// Refreshing a token may only keep or narrow its scopes.
+	srv.SetRefreshingScopeHandler(func(newScope, oldScope string) (allowed bool, err error) {
+		ns := app.ParseScopes(newScope)
+		allowed = scopes.Known(ns) && app.ScopesPermit(app.ParseScopes(oldScope), ns...)
+		return
+	})
 	srv.SetInternalErrorHandler(func(err error) (re *oaerrors.Response) {
 		re = &oaerrors.Response{
 			Error:       oaerrors.ErrServerError,
@@ -152,6 +201,11 @@ func NewServer(c *config.Config, scheme string, internalErrorHandler http.Handle
 		k:                           k,
 		m:                           m,
 		s:                           srv,
+		o:                           scopes,
+		p:                           p,
+		u:                           u,
+		oidc:                        newOpenID(c.ServerConfig.RSAKeySize),
+		registration:                c.OAuthConfig.EnableClientRegistration,
 		clientIDBase:                fmt.Sprintf("%s.%s", b64ClientPart, c.ServerConfig.Host),
 		host:                        c.ServerConfig.Host,
 		scheme:                      scheme,
@@ -161,21 +215,70 @@ func NewServer(c *config.Config, scheme string, internalErrorHandler http.Handle
 		proxyRefreshAccessDuration:  time.Second * time.Duration(c.OAuthConfig.AccessTokenExpiry) / 2,
 		proxyRefreshRefreshDuration: time.Second * time.Duration(c.OAuthConfig.RefreshTokenExpiry) / 2,
 	}
+	// ID tokens are issued alongside access tokens granted the openid
+	// scope.
+	srv.SetExtensionFieldsHandler(s.idTokenFields)
 	s.cleanupFn = util.NewSafeStartStop(s.cleanup, time.Hour*1)
 	return
 }
 
-// TODO: Scopes
-
 func (o *Server) HandleAuthorizationRequest(w http.ResponseWriter, r *http.Request) {
-	if err := o.s.HandleAuthorizeRequest(w, r); err != nil {
+	// Only registered clients using one of their redirect URIs may be
+	// authorized. Reject unknown scopes, and treat granting none of the
+	// requested scopes as the user denying the request.
+	if req, err := o.s.ValidationAuthorizeRequest(r); err == nil {
+		ci, err := o.authorizingClient(util.Context{r.Context()}, req)
+		if err != nil {
+			util.ErrorLogger.Errorf("oauth2 authorization request rejected: %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requested := app.ParseScopes(req.Scope)
+		if len(ci.GetSecret()) == 0 && len(req.CodeChallenge) == 0 {
+			// Public clients cannot keep a secret, so only PKCE
+			// binds the code to the client that requested it.
+			o.authorizeError(w, r, req, oaerrors.ErrCodeChallengeRquired)
+			return
+		} else if !o.o.Known(requested) {
+			o.authorizeError(w, r, req, oaerrors.ErrInvalidScope)
+			return
+		} else if len(requested) > 0 && len(narrow(requested, r.Form)) == 0 {
+			o.authorizeError(w, r, req, oaerrors.ErrAccessDenied)
+			return
+		}
+	}
+	// The nonce is stored with the authorization code.
+	if err := o.s.HandleAuthorizeRequest(w, withOpenIDNonce(r, r.FormValue(nonceFormKey))); err != nil {
 		// oauth2 library would already have written headers by now.
 		util.ErrorLogger.Errorf("oauth2 HandleAuthorizeRequest error: %s", err)
 	}
 }
 
+func (o *Server) authorizeError(w http.ResponseWriter, r *http.Request, req *oaserver.AuthorizeRequest, err error) {
+	data, _, _ := o.s.GetErrorData(err)
+	uri, err := o.s.GetRedirectURI(req, data)
+	if err != nil {
+		util.ErrorLogger.Errorf("oauth2 error redirect URI error: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, uri, http.StatusFound)
+}
+
+// Scopes returns the registry of OAuth2 scopes.
+func (o *Server) Scopes() *Scopes {
+	return o.o
+}
+
+// RequestedScopes returns the scopes requested in an authorization request,
+// for rendering on the consent page.
+func (o *Server) RequestedScopes(r *http.Request) ([]app.Scope, error) {
+	return o.o.Describe(app.ParseScopes(r.FormValue("scope")))
+}
+
 func (o *Server) HandleAccessTokenRequest(w http.ResponseWriter, r *http.Request) {
-	if err := o.s.HandleTokenRequest(w, r); err != nil {
+	// The nonce of an exchanged code is stored with its tokens.
+	if err := o.s.HandleTokenRequest(w, withOpenIDNonce(r, "")); err != nil {
 		// oauth2 library would already have written headers by now.
 		util.ErrorLogger.Errorf("oauth2 HandleTokenRequest error: %s", err)
 	}
@@ -195,7 +298,7 @@ func (o *Server) RemoveByAccess(ctx util.Context, t oauth2.TokenInfo) error {
 	return o.m.RemoveAccessToken(ctx.Context, t.GetAccess())
 }
 
-func (o *Server) Validate(w http.ResponseWriter, r *http.Request) (userID string, auth bool, err error) {
+func (o *Server) Validate(w http.ResponseWriter, r *http.Request) (userID string, scopes []string, auth bool, err error) {
 	var sn *web.Session
 	sn, err = o.k.Get(r)
 	if err != nil {
@@ -204,7 +307,9 @@ func (o *Server) Validate(w http.ResponseWriter, r *http.Request) (userID string
 	var uid string
 	_, uid, auth, err = o.ValidateFirstPartyProxyAccessToken(util.Context{r.Context()}, sn)
 	if err == nil && auth {
+		// First party credentials are granted every scope.
 		userID = uid
+		scopes = []string{app.AllScope}
 		return
 	} else if err != nil {
 		sn.Clear()
@@ -217,6 +322,7 @@ func (o *Server) Validate(w http.ResponseWriter, r *http.Request) (userID string
 	ti, auth, err = o.ValidateOAuth2AccessToken(w, r)
 	if err == nil && auth {
 		userID = ti.GetUserID()
+		scopes = app.ParseScopes(ti.GetScope())
 	} else {
 		sn.Clear()
 		if err2 := sn.Save(r, w); err2 != nil {
@@ -237,7 +343,7 @@ func (o *Server) CreateProxyCredentials(ctx util.Context, userID string) (id str
 		ClientID:            clientID,
 		UserID:              userID,
 		RedirectURI:         (&url.URL{Scheme: o.scheme, Host: o.host, Path: "/"}).String(),
-		Scope:               "all", // TODO: Hardcoded scope here
+		Scope:               app.AllScope,
 		Code:                "",
 		CodeCreateAt:        now,
 		CodeExpiresIn:       0,
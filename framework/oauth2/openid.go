@@ -0,0 +1,305 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package oauth2
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-fed/activity/streams/vocab"
+	"github.com/go-fed/apcore/app"
+	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/paths"
+	"github.com/go-fed/apcore/util"
+	"github.com/go-fed/oauth2"
+)
+
+const (
+	// idTokenSigningAlg is the only JWS algorithm used to sign ID tokens.
+	idTokenSigningAlg = "RS256"
+	// Form keys and claims of OpenID Connect Core.
+	nonceFormKey = "nonce"
+	idTokenField = "id_token"
+)
+
+// openID caches the key that signs ID tokens. The nonce of an authorization
+// is stored with its code, and then with the tokens it is exchanged for, by
+// the token storage.
+type openID struct {
+	keySize int
+	mu      sync.Mutex
+	key     *rsa.PrivateKey
+	kid     string
+}
+
+func newOpenID(keySize int) *openID {
+	return &openID{keySize: keySize}
+}
+
+// withOpenIDNonce prepares the request so the token storage can store or pass
+// on the OpenID Connect nonce, which starts as the given value.
+func withOpenIDNonce(r *http.Request, nonce string) *http.Request {
+	c := util.Context{r.Context()}
+	c.WithOpenIDNonce(&nonce)
+	return r.WithContext(c.Context)
+}
+
+func isOpenID(ti oauth2.TokenInfo) bool {
+	return ti != nil && containsString(app.ParseScopes(ti.GetScope()), app.OpenIDScope)
+}
+
+// idTokenFields adds an ID token to the response of a token request granted
+// the openid scope. The nonce is the one stored with the access token.
+func (o *Server) idTokenFields(ti oauth2.TokenInfo) map[string]interface{} {
+	if !isOpenID(ti) {
+		return nil
+	}
+	c := util.Context{context.Background()}
+	st, err := o.d.GetByAccess(c, ti.GetAccess())
+	if err != nil {
+		util.ErrorLogger.Errorf("error fetching token for OpenID ID token: %s", err)
+		return nil
+	}
+	var nonce string
+	if t, ok := st.(*models.TokenInfo); ok {
+		nonce = t.GetNonce()
+	}
+	idt, err := o.idToken(c, ti, ti.GetAccessCreateAt(), nonce)
+	if err != nil {
+		util.ErrorLogger.Errorf("error minting OpenID ID token: %s", err)
+		return nil
+	}
+	return map[string]interface{}{idTokenField: idt}
+}
+
+// idTokenClaims are the claims of an ID token. The subject is the user's
+// actor IRI.
+type idTokenClaims struct {
+	Iss               string `json:"iss"`
+	Sub               string `json:"sub"`
+	Aud               string `json:"aud"`
+	Exp               int64  `json:"exp"`
+	Iat               int64  `json:"iat"`
+	Nonce             string `json:"nonce,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+}
+
+func (o *Server) idToken(c util.Context, ti oauth2.TokenInfo, now time.Time, nonce string) (string, error) {
+	name, err := o.preferredUsername(c, ti.GetUserID())
+	if err != nil {
+		return "", err
+	}
+	return o.sign(c, idTokenClaims{
+		Iss:               o.issuer(),
+		Sub:               o.subject(ti.GetUserID()),
+		Aud:               ti.GetClientID(),
+		Exp:               now.Add(o.accessExpiryDuration).Unix(),
+		Iat:               now.Unix(),
+		Nonce:             nonce,
+		PreferredUsername: name,
+	})
+}
+
+// sign serializes the claims as a JWS in compact form, signed with RS256.
+func (o *Server) sign(c util.Context, claims interface{}) (string, error) {
+	k, kid, err := o.signingKey(c)
+	if err != nil {
+		return "", err
+	}
+	h, err := json.Marshal(map[string]string{
+		"alg": idTokenSigningAlg,
+		"typ": "JWT",
+		"kid": kid,
+	})
+	if err != nil {
+		return "", err
+	}
+	p, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := b64URL(h) + "." + b64URL(p)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return input + "." + b64URL(sig), nil
+}
+
+// signingKey loads the instance's ID token signing key once, along with its
+// key ID. The lock is not held while the key is loaded or generated; the key
+// is the same no matter which concurrent load stores it.
+func (o *Server) signingKey(c util.Context) (*rsa.PrivateKey, string, error) {
+	o.oidc.mu.Lock()
+	k, kid := o.oidc.key, o.oidc.kid
+	o.oidc.mu.Unlock()
+	if k != nil {
+		return k, kid, nil
+	}
+	k, err := o.p.GetOrCreateOpenIDSigningKey(c, o.oidc.keySize)
+	if err != nil {
+		return nil, "", err
+	}
+	kid, err = thumbprint(&k.PublicKey)
+	if err != nil {
+		return nil, "", err
+	}
+	o.oidc.mu.Lock()
+	defer o.oidc.mu.Unlock()
+	o.oidc.key, o.oidc.kid = k, kid
+	return k, kid, nil
+}
+
+// jwk is an RSA public JSON Web Key of RFC 7517.
+type jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func publicJWK(k *rsa.PublicKey) jwk {
+	return jwk{
+		Kty: "RSA",
+		N:   b64URL(k.N.Bytes()),
+		E:   b64URL(big.NewInt(int64(k.E)).Bytes()),
+	}
+}
+
+// thumbprint computes the RFC 7638 thumbprint of the key, used as its key ID.
+func thumbprint(k *rsa.PublicKey) (string, error) {
+	// Only the required members, in lexicographic order.
+	j := publicJWK(k)
+	b, err := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{j.E, j.Kty, j.N})
+	if err != nil {
+		return "", err
+	}
+	d := sha256.Sum256(b)
+	return b64URL(d[:]), nil
+}
+
+func b64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (o *Server) issuer() string {
+	return paths.IRIForPath(o.scheme, o.host, "").String()
+}
+
+func (o *Server) subject(userID string) string {
+	return paths.UUIDIRIFor(o.scheme, o.host, paths.UserPathKey, paths.UUID(userID)).String()
+}
+
+type preferredUsernamer interface {
+	GetActivityStreamsPreferredUsername() vocab.ActivityStreamsPreferredUsernameProperty
+}
+
+func (o *Server) preferredUsername(c util.Context, userID string) (string, error) {
+	u, err := o.u.UserByID(c, paths.UUID(userID))
+	if err != nil {
+		return "", err
+	}
+	pu, ok := u.Actor.(preferredUsernamer)
+	if !ok {
+		return "", nil
+	}
+	if p := pu.GetActivityStreamsPreferredUsername(); p != nil && p.IsXMLSchemaString() {
+		return p.GetXMLSchemaString(), nil
+	}
+	return "", nil
+}
+
+// userInfo is the OpenID Connect userinfo response.
+type userInfo struct {
+	Sub               string `json:"sub"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Profile           string `json:"profile"`
+}
+
+// HandleUserInfoRequest describes the user that granted the bearer token,
+// which must have the openid scope.
+func (o *Server) HandleUserInfoRequest(w http.ResponseWriter, r *http.Request) {
+	ti, err := o.s.ValidationBearerToken(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer error=%q", errInvalidToken))
+		writeError(w, http.StatusUnauthorized, errInvalidToken, "a valid bearer token is required")
+		return
+	} else if !isOpenID(ti) {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer error=%q, scope=%q", errInsufficientScope, app.OpenIDScope))
+		writeError(w, http.StatusForbidden, errInsufficientScope, "the openid scope is required")
+		return
+	}
+	name, err := o.preferredUsername(util.Context{r.Context()}, ti.GetUserID())
+	if err != nil {
+		util.ErrorLogger.Errorf("error fetching user for OpenID userinfo: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	sub := o.subject(ti.GetUserID())
+	writeJSON(w, userInfo{
+		Sub:               sub,
+		PreferredUsername: name,
+		Profile:           sub,
+	})
+}
+
+// HandleJWKSRequest serves the public key that verifies ID tokens as a JSON
+// Web Key Set.
+func (o *Server) HandleJWKSRequest(w http.ResponseWriter, r *http.Request) {
+	k, kid, err := o.signingKey(util.Context{r.Context()})
+	if err != nil {
+		util.ErrorLogger.Errorf("error fetching OpenID signing key: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	j := publicJWK(&k.PublicKey)
+	j.Use = "sig"
+	j.Alg = idTokenSigningAlg
+	j.Kid = kid
+	writeJSON(w, struct {
+		Keys []jwk `json:"keys"`
+	}{[]jwk{j}})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		util.ErrorLogger.Errorf("error serializing OpenID response: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
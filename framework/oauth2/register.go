@@ -0,0 +1,330 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package oauth2
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-fed/apcore/app"
+	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/services"
+	"github.com/go-fed/apcore/util"
+	"github.com/go-fed/oauth2"
+	oaserver "github.com/go-fed/oauth2/server"
+)
+
+const (
+	// Registered clients are public clients: they hold no secret and must
+	// use PKCE instead.
+	tokenEndpointAuthNone = "none"
+	// Confidential clients, such as first-party clients, authenticate to
+	// the token endpoint with HTTP Basic auth.
+	tokenEndpointAuthBasic = "client_secret_basic"
+	// Client registration error codes from RFC 7591.
+	errInvalidRedirectURI    = "invalid_redirect_uri"
+	errInvalidClientMetadata = "invalid_client_metadata"
+	// maxRegistrationBytes limits the size of a registration request body.
+	maxRegistrationBytes = 1 << 16
+)
+
+// clientMetadata is the client metadata of RFC 7591 that apcore understands.
+type clientMetadata struct {
+	RedirectURIs            []string `json:"redirect_uris"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string `json:"grant_types,omitempty"`
+	ResponseTypes           []string `json:"response_types,omitempty"`
+	ClientName              string   `json:"client_name,omitempty"`
+	ClientURI               string   `json:"client_uri,omitempty"`
+	LogoURI                 string   `json:"logo_uri,omitempty"`
+}
+
+// clientInformation is the successful registration response of RFC 7591.
+type clientInformation struct {
+	ClientID         string `json:"client_id"`
+	ClientIDIssuedAt int64  `json:"client_id_issued_at"`
+	clientMetadata
+}
+
+type errorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// HandleClientRegistrationRequest registers a third party client following
+// OAuth 2.0 Dynamic Client Registration, RFC 7591.
+func (o *Server) HandleClientRegistrationRequest(w http.ResponseWriter, r *http.Request) {
+	var md clientMetadata
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRegistrationBytes)).Decode(&md); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidClientMetadata, "request body is not a JSON object of client metadata")
+		return
+	}
+	if code, err := validateClientMetadata(&md); err != nil {
+		writeError(w, http.StatusBadRequest, code, err.Error())
+		return
+	}
+	id, err := generateClientID()
+	if err != nil {
+		util.ErrorLogger.Errorf("error generating OAuth2 client id: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	ci := &models.ClientInfo{
+		ID: id,
+		// The first redirect URI is used when an authorization request
+		// omits one.
+		Domain:       md.RedirectURIs[0],
+		Name:         md.ClientName,
+		Website:      md.ClientURI,
+		LogoURI:      md.LogoURI,
+		RedirectURIs: md.RedirectURIs,
+	}
+	if err := o.d.RegisterClient(util.Context{r.Context()}, ci); err != nil {
+		util.ErrorLogger.Errorf("error registering OAuth2 client: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	b, err := json.Marshal(clientInformation{
+		ClientID:         id,
+		ClientIDIssuedAt: time.Now().Unix(),
+		clientMetadata:   md,
+	})
+	if err != nil {
+		util.ErrorLogger.Errorf("error serializing OAuth2 client registration: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(b)
+}
+
+// writeError writes an OAuth2 JSON error response.
+func writeError(w http.ResponseWriter, status int, code, description string) {
+	b, err := json.Marshal(errorResponse{Error: code, Description: description})
+	if err != nil {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// validateClientMetadata checks the metadata and fills in defaults, returning
+// the RFC 7591 error code if it is not acceptable.
+func validateClientMetadata(md *clientMetadata) (code string, err error) {
+	if len(md.RedirectURIs) == 0 {
+		return errInvalidRedirectURI, fmt.Errorf("at least one redirect_uri is required")
+	}
+	for _, u := range md.RedirectURIs {
+		if err = validateRedirectURI(u); err != nil {
+			return errInvalidRedirectURI, err
+		}
+	}
+	if md.TokenEndpointAuthMethod == "" {
+		md.TokenEndpointAuthMethod = tokenEndpointAuthNone
+	} else if md.TokenEndpointAuthMethod != tokenEndpointAuthNone {
+		return errInvalidClientMetadata, fmt.Errorf("only the %q token_endpoint_auth_method is supported", tokenEndpointAuthNone)
+	}
+	if len(md.GrantTypes) == 0 {
+		md.GrantTypes = []string{oauth2.AuthorizationCode.String(), oauth2.Refreshing.String()}
+	}
+	for _, g := range md.GrantTypes {
+		if g != oauth2.AuthorizationCode.String() && g != oauth2.Refreshing.String() {
+			return errInvalidClientMetadata, fmt.Errorf("unsupported grant_type: %q", g)
+		}
+	}
+	if len(md.ResponseTypes) == 0 {
+		md.ResponseTypes = []string{oauth2.Code.String()}
+	}
+	for _, t := range md.ResponseTypes {
+		if t != oauth2.Code.String() {
+			return errInvalidClientMetadata, fmt.Errorf("unsupported response_type: %q", t)
+		}
+	}
+	for _, u := range []string{md.ClientURI, md.LogoURI} {
+		if len(u) == 0 {
+			continue
+		} else if pu, err := url.Parse(u); err != nil || !pu.IsAbs() || (pu.Scheme != "https" && pu.Scheme != "http") || len(pu.Host) == 0 {
+			return errInvalidClientMetadata, fmt.Errorf("client_uri and logo_uri must be absolute web URLs: %q", u)
+		}
+	}
+	return "", nil
+}
+
+// validateRedirectURI follows the recommendations of RFC 6749 and RFC 8252:
+// redirect URIs must be absolute and without a fragment, and use https,
+// http on a loopback interface, or a private-use scheme in reverse domain
+// name notation for native applications.
+func validateRedirectURI(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("redirect_uri is not a URI: %q", s)
+	} else if !u.IsAbs() {
+		return fmt.Errorf("redirect_uri is not absolute: %q", s)
+	} else if len(u.Fragment) > 0 || strings.Contains(s, "#") {
+		return fmt.Errorf("redirect_uri must not contain a fragment: %q", s)
+	}
+	switch u.Scheme {
+	case "https":
+		if len(u.Host) == 0 {
+			return fmt.Errorf("redirect_uri has no host: %q", s)
+		}
+	case "http":
+		if !isLoopback(u.Hostname()) {
+			return fmt.Errorf("http redirect_uri must use a loopback host: %q", s)
+		}
+	default:
+		if !strings.Contains(u.Scheme, ".") {
+			return fmt.Errorf("redirect_uri private-use scheme must be a reverse domain name: %q", s)
+		}
+	}
+	return nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func generateClientID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// redirectURIClients is the client storage of the OAuth2 manager. The manager
+// only passes a client's domain to its ValidateURIHandler, so registered
+// clients report their redirect URIs as their domain, separated by spaces.
+type redirectURIClients struct {
+	*services.OAuth2
+}
+
+func (r redirectURIClients) GetByID(ctx context.Context, id string) (oauth2.ClientInfo, error) {
+	ci, err := r.OAuth2.GetByID(ctx, id)
+	if mci, ok := ci.(*models.ClientInfo); ok && mci != nil && len(mci.RedirectURIs) > 0 {
+		return redirectURIClient{mci}, err
+	}
+	return ci, err
+}
+
+type redirectURIClient struct {
+	*models.ClientInfo
+}
+
+func (r redirectURIClient) GetDomain() string {
+	return strings.Join(r.RedirectURIs, " ")
+}
+
+// authorizingClient returns the registered client making the authorization
+// request. An error is returned if the client is not registered or the
+// request's redirect URI is not one the client registered, in which case the
+// user agent must not be redirected.
+func (o *Server) authorizingClient(c util.Context, req *oaserver.AuthorizeRequest) (*models.ClientInfo, error) {
+	ci, err := o.d.RegisteredClient(c, req.ClientID)
+	if err != nil {
+		return nil, err
+	} else if ci == nil {
+		return nil, fmt.Errorf("client is not registered: %q", req.ClientID)
+	}
+	if len(req.RedirectURI) == 0 {
+		if len(ci.RedirectURIs) != 1 {
+			return nil, fmt.Errorf("redirect_uri is required for a client with several redirect URIs")
+		}
+		return ci, nil
+	}
+	for _, u := range ci.RedirectURIs {
+		if u == req.RedirectURI {
+			return ci, nil
+		}
+	}
+	return nil, fmt.Errorf("redirect_uri is not registered for the client: %q", req.RedirectURI)
+}
+
+// RequestingClient returns the registered client making an authorization
+// request, for showing its metadata on the authorization page.
+func (o *Server) RequestingClient(r *http.Request) (*app.OAuth2Client, error) {
+	req, err := o.s.ValidationAuthorizeRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	ci, err := o.authorizingClient(util.Context{r.Context()}, req)
+	if err != nil {
+		return nil, err
+	}
+	return toAppClient(ci), nil
+}
+
+// Clients lists the dynamically registered clients.
+func (o *Server) Clients(c util.Context) ([]app.OAuth2Client, error) {
+	cs, err := o.d.RegisteredClients(c)
+	if err != nil {
+		return nil, err
+	}
+	ac := make([]app.OAuth2Client, 0, len(cs))
+	for _, ci := range cs {
+		ac = append(ac, *toAppClient(ci))
+	}
+	return ac, nil
+}
+
+// RemoveClient deletes a dynamically registered client, revoking its tokens.
+func (o *Server) RemoveClient(c util.Context, id string) error {
+	return o.d.RemoveRegisteredClient(c, id)
+}
+
+func toAppClient(ci *models.ClientInfo) *app.OAuth2Client {
+	optURL := func(s string) *url.URL {
+		if len(s) == 0 {
+			return nil
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil
+		}
+		return u
+	}
+	ac := &app.OAuth2Client{
+		ID:      ci.ID,
+		Name:    ci.Name,
+		Website: optURL(ci.Website),
+		Logo:    optURL(ci.LogoURI),
+		Created: ci.Created,
+	}
+	for _, s := range ci.RedirectURIs {
+		if u := optURL(s); u != nil {
+			ac.RedirectURIs = append(ac.RedirectURIs, u)
+		}
+	}
+	return ac
+}
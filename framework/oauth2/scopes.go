@@ -0,0 +1,124 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package oauth2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-fed/apcore/app"
+)
+
+// Scopes is the registry of OAuth2 scopes declared by apcore and the
+// application. Clients may only request scopes found in the registry.
+type Scopes struct {
+	ordered []app.Scope
+	byName  map[string]app.Scope
+}
+
+// NewScopes builds the registry from apcore's built-in scopes and any scopes
+// declared by the application.
+func NewScopes(a app.Application) (*Scopes, error) {
+	s := &Scopes{
+		byName: make(map[string]app.Scope),
+	}
+	builtin := []app.Scope{
+		{
+			Name:        app.AllScope,
+			Description: "Full access to your account",
+		},
+		{
+			Name:        app.PoliciesScope,
+			Description: "View and manage your federation and moderation policies",
+		},
+		{
+			Name:        app.OpenIDScope,
+			Description: "Sign in to other applications with your account",
+		},
+	}
+	for _, sc := range builtin {
+		if err := s.add(sc); err != nil {
+			return nil, err
+		}
+	}
+	if sa, ok := a.(app.ScopeApplication); ok {
+		for _, sc := range sa.Scopes() {
+			if err := s.add(sc); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *Scopes) add(sc app.Scope) error {
+	if len(sc.Name) == 0 || strings.ContainsAny(sc.Name, " \t\r\n\"\\") {
+		return fmt.Errorf("invalid OAuth2 scope name: %q", sc.Name)
+	} else if _, ok := s.byName[sc.Name]; ok {
+		return fmt.Errorf("OAuth2 scope declared more than once: %q", sc.Name)
+	}
+	s.ordered = append(s.ordered, sc)
+	s.byName[sc.Name] = sc
+	return nil
+}
+
+// All returns every registered scope in the order they were declared.
+func (s *Scopes) All() []app.Scope {
+	return append([]app.Scope(nil), s.ordered...)
+}
+
+// Known determines whether all of the named scopes are registered.
+func (s *Scopes) Known(names []string) bool {
+	for _, n := range names {
+		if _, ok := s.byName[n]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Describe returns the registered scopes for the given names, or an error if
+// any of them is unknown.
+func (s *Scopes) Describe(names []string) ([]app.Scope, error) {
+	d := make([]app.Scope, 0, len(names))
+	for _, n := range names {
+		sc, ok := s.byName[n]
+		if !ok {
+			return nil, fmt.Errorf("unknown OAuth2 scope: %q", n)
+		}
+		d = append(d, sc)
+	}
+	return d, nil
+}
+
+// narrow limits the requested scopes to those the user granted on the
+// authorization page. Consent must be given explicitly: if the form did not
+// submit any granted scopes, none of the requested scopes are kept.
+func narrow(requested []string, form map[string][]string) []string {
+	granted := form[app.GrantedScopesFormKey]
+	consent := make(map[string]bool, len(granted))
+	for _, g := range granted {
+		consent[g] = true
+	}
+	var n []string
+	for _, r := range requested {
+		if consent[r] {
+			n = append(n, r)
+		}
+	}
+	return n
+}
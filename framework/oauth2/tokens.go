@@ -0,0 +1,214 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package oauth2
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-fed/apcore/app"
+	"github.com/go-fed/apcore/util"
+	"github.com/go-fed/oauth2"
+)
+
+const (
+	// Error codes from RFC 6749 used by revocation and introspection.
+	errInvalidRequest = "invalid_request"
+	errInvalidClient  = "invalid_client"
+	errInvalidToken   = "invalid_token"
+	// Error code from RFC 6750 for bearer tokens lacking a scope.
+	errInsufficientScope = "insufficient_scope"
+	// Form keys of RFC 7009 and RFC 7662.
+	tokenFormKey     = "token"
+	tokenHintFormKey = "token_type_hint"
+	clientIDFormKey  = "client_id"
+)
+
+// introspection is the RFC 7662 introspection response. Only Active is set for
+// inactive tokens.
+type introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+}
+
+// HandleRevocationRequest revokes an access or refresh token following RFC
+// 7009. Registered clients are public clients, so they identify themselves
+// with their client_id. Revoking either token of a pair revokes both.
+func (o *Server) HandleRevocationRequest(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "request body is not a form")
+		return
+	}
+	token := r.PostForm.Get(tokenFormKey)
+	clientID := r.PostForm.Get(clientIDFormKey)
+	if len(token) == 0 || len(clientID) == 0 {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "token and client_id are required")
+		return
+	}
+	c := util.Context{r.Context()}
+	if ci, err := o.d.RegisteredClient(c, clientID); err != nil {
+		util.ErrorLogger.Errorf("error fetching client for token revocation: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	} else if ci == nil {
+		writeError(w, http.StatusUnauthorized, errInvalidClient, "client is not registered")
+		return
+	}
+	// Unknown tokens and tokens issued to other clients are not an error,
+	// so that clients cannot learn about them.
+	if err := o.d.RevokeToken(c, clientID, token, r.PostForm.Get(tokenHintFormKey)); err != nil {
+		util.ErrorLogger.Errorf("error revoking token: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleIntrospectionRequest describes an access or refresh token following
+// RFC 7662. The caller authenticates with its own bearer token, and may only
+// introspect tokens issued to the same client unless an admin flagged its
+// client as a resource server.
+func (o *Server) HandleIntrospectionRequest(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "request body is not a form")
+		return
+	}
+	caller, err := o.s.ValidationBearerToken(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, errInvalidToken, "a valid bearer token is required")
+		return
+	}
+	token := r.PostForm.Get(tokenFormKey)
+	if len(token) == 0 {
+		writeError(w, http.StatusBadRequest, errInvalidRequest, "token is required")
+		return
+	}
+	c := util.Context{r.Context()}
+	ti, refresh, err := o.d.LookupToken(c, token, r.PostForm.Get(tokenHintFormKey))
+	if err != nil {
+		util.ErrorLogger.Errorf("error looking up token for introspection: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var resp introspection
+	if ti != nil && ti.GetClientID() != caller.GetClientID() {
+		ci, err := o.d.RegisteredClient(c, caller.GetClientID())
+		if err != nil {
+			util.ErrorLogger.Errorf("error fetching client for introspection: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		} else if ci == nil || !ci.ResourceServer {
+			ti = nil
+		}
+	}
+	if ti != nil {
+		resp = o.introspect(ti, refresh, time.Now())
+	}
+	b, err := json.Marshal(resp)
+	if err != nil {
+		util.ErrorLogger.Errorf("error serializing token introspection: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+func (o *Server) introspect(ti oauth2.TokenInfo, refresh bool, now time.Time) introspection {
+	created, expires := ti.GetAccessCreateAt(), ti.GetAccessExpiresIn()
+	if refresh {
+		created, expires = ti.GetRefreshCreateAt(), ti.GetRefreshExpiresIn()
+	}
+	resp := introspection{
+		Active:   true,
+		Scope:    ti.GetScope(),
+		ClientID: ti.GetClientID(),
+		Iat:      created.Unix(),
+		Sub:      o.subject(ti.GetUserID()),
+		Iss:      o.issuer(),
+	}
+	if !refresh {
+		resp.TokenType = "Bearer"
+	}
+	if expires > 0 {
+		exp := created.Add(expires)
+		if !now.Before(exp) {
+			return introspection{}
+		}
+		resp.Exp = exp.Unix()
+	}
+	return resp
+}
+
+// UserGrants lists the active tokens a user granted, grouped by the client
+// they were granted to.
+func (o *Server) UserGrants(c util.Context, userID string) ([]app.OAuth2Grant, error) {
+	ut, err := o.d.ActiveUserTokens(c, userID)
+	if err != nil {
+		return nil, err
+	}
+	var grants []app.OAuth2Grant
+	byClient := make(map[string]int)
+	for _, u := range ut {
+		i, ok := byClient[u.ClientID]
+		if !ok {
+			i = len(grants)
+			byClient[u.ClientID] = i
+			grants = append(grants, app.OAuth2Grant{
+				ClientID:   u.ClientID,
+				ClientName: u.ClientName,
+				FirstParty: u.FirstParty,
+			})
+		}
+		g := &grants[i]
+		g.Tokens++
+		for _, s := range app.ParseScopes(u.Scope) {
+			if !containsString(g.Scopes, s) {
+				g.Scopes = append(g.Scopes, s)
+			}
+		}
+		if u.AccessCreated.Valid && u.AccessCreated.Time.After(g.LastIssued) {
+			g.LastIssued = u.AccessCreated.Time
+		}
+	}
+	return grants, nil
+}
+
+// RevokeUserGrant revokes every token the user granted to the client, such as
+// to sign out a lost device.
+func (o *Server) RevokeUserGrant(c util.Context, userID, clientID string) error {
+	return o.d.RevokeUserClientTokens(c, userID, clientID)
+}
+
+func containsString(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,342 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package framework
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-fed/apcore/app"
+	"github.com/go-fed/apcore/framework/oauth2"
+	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/paths"
+	"github.com/go-fed/apcore/services"
+	"github.com/go-fed/apcore/util"
+	"github.com/google/uuid"
+)
+
+const (
+	policiesPath      = "/policies"
+	policyPath        = "/policies/{policy}"
+	resolutionsPath   = "/policies/{policy}/resolutions"
+	policyVar         = "policy"
+	offsetQueryParam  = "offset"
+	limitQueryParam   = "limit"
+	policyContentType = "application/json"
+)
+
+// policyJSON is a policy as served by the policy API.
+type policyJSON struct {
+	ID      string         `json:"id"`
+	Purpose models.Purpose `json:"purpose"`
+	Enabled bool           `json:"enabled"`
+	Monitor bool           `json:"monitor"`
+	Policy  models.Policy  `json:"policy"`
+}
+
+// policyRequestJSON is the body of requests creating or updating a policy.
+// Omitted fields are left unchanged when updating. When creating, the purpose
+// defaults to federated_block and the policy is enabled and enforced unless
+// stated.
+type policyRequestJSON struct {
+	Purpose models.Purpose `json:"purpose,omitempty"`
+	Enabled *bool          `json:"enabled,omitempty"`
+	Monitor *bool          `json:"monitor,omitempty"`
+	Policy  *models.Policy `json:"policy,omitempty"`
+}
+
+type resolutionJSON struct {
+	ID       string       `json:"id"`
+	IRI      string       `json:"iri"`
+	Time     time.Time    `json:"time"`
+	Matched  bool         `json:"matched"`
+	MatchLog []string     `json:"matchLog"`
+	Scope    models.Scope `json:"scope,omitempty"`
+}
+
+// resolutionsJSON is a page of resolutions. Next is the offset of the
+// following page, if there is one.
+type resolutionsJSON struct {
+	Resolutions []resolutionJSON `json:"resolutions"`
+	Next        *int             `json:"next,omitempty"`
+}
+
+// policyAPI serves the JSON API through which users authenticated with OAuth2
+// manage their own policies:
+//
+//	GET    /policies                   lists the user's policies
+//	POST   /policies                   creates a policy
+//	GET    /policies/{id}              fetches a policy
+//	PUT    /policies/{id}              updates, enables or disables, and/or monitors a policy
+//	DELETE /policies/{id}              deletes a policy
+//	GET    /policies/{id}/resolutions  pages through a policy's resolutions
+type policyAPI struct {
+	scheme               string
+	host                 string
+	oauth                *oauth2.Server
+	policies             *services.Policies
+	badRequestHandler    http.Handler
+	internalErrorHandler http.Handler
+	defaultPageSize      int
+	maxPageSize          int
+}
+
+func (p *policyAPI) addRoutes(r *Router) {
+	r.NewRoute().Path(policiesPath).Methods("GET").HandlerFunc(p.list)
+	r.NewRoute().Path(policiesPath).Methods("POST").HandlerFunc(p.create)
+	r.NewRoute().Path(policyPath).Methods("GET").HandlerFunc(p.get)
+	r.NewRoute().Path(policyPath).Methods("PUT").HandlerFunc(p.update)
+	r.NewRoute().Path(policyPath).Methods("DELETE").HandlerFunc(p.delete)
+	r.NewRoute().Path(resolutionsPath).Methods("GET").HandlerFunc(p.resolutions)
+}
+
+// authenticate returns the IRI of the authenticated user if they granted the
+// policies scope, or writes a response and returns nil.
+func (p *policyAPI) authenticate(w http.ResponseWriter, r *http.Request) *url.URL {
+	userID, scopes, auth, err := p.oauth.Validate(w, r)
+	if err != nil {
+		util.ErrorLogger.Errorf("error validating policy API request: %s", err)
+		p.internalErrorHandler.ServeHTTP(w, r)
+		return nil
+	} else if !auth {
+		w.WriteHeader(http.StatusUnauthorized)
+		return nil
+	} else if !app.ScopesPermit(scopes, app.PoliciesScope) {
+		w.WriteHeader(http.StatusForbidden)
+		return nil
+	}
+	return paths.UUIDIRIFor(p.scheme, p.host, paths.UserPathKey, paths.UUID(userID))
+}
+
+// getPolicy returns the authenticated user's policy named in the path, or
+// writes a response and returns nil.
+func (p *policyAPI) getPolicy(w http.ResponseWriter, r *http.Request) (actor *url.URL, po *services.Policy) {
+	actor = p.authenticate(w, r)
+	if actor == nil {
+		return
+	}
+	id := Vars(r)[policyVar]
+	if _, err := uuid.Parse(id); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	po, err := p.policies.Get(util.Context{r.Context()}, actor, id)
+	if err != nil {
+		util.ErrorLogger.Errorf("error getting policy %s: %s", id, err)
+		p.internalErrorHandler.ServeHTTP(w, r)
+	} else if po == nil {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	return
+}
+
+func (p *policyAPI) list(w http.ResponseWriter, r *http.Request) {
+	actor := p.authenticate(w, r)
+	if actor == nil {
+		return
+	}
+	ps, err := p.policies.ForActor(util.Context{r.Context()}, actor)
+	if err != nil {
+		util.ErrorLogger.Errorf("error listing policies: %s", err)
+		p.internalErrorHandler.ServeHTTP(w, r)
+		return
+	}
+	pj := make([]policyJSON, 0, len(ps))
+	for _, po := range ps {
+		pj = append(pj, toPolicyJSON(po))
+	}
+	p.writeJSON(w, r, http.StatusOK, pj)
+}
+
+func (p *policyAPI) create(w http.ResponseWriter, r *http.Request) {
+	actor := p.authenticate(w, r)
+	if actor == nil {
+		return
+	}
+	req, ok := p.readRequest(w, r)
+	if !ok {
+		return
+	}
+	if len(req.Purpose) == 0 {
+		req.Purpose = models.FederatedBlockPurpose
+	}
+	if req.Policy == nil || services.ValidatePolicy(req.Purpose, *req.Policy) != nil {
+		p.badRequestHandler.ServeHTTP(w, r)
+		return
+	}
+	c := util.Context{r.Context()}
+	enabled := req.Enabled == nil || *req.Enabled
+	monitor := req.Monitor != nil && *req.Monitor
+	id, err := p.policies.Create(c, actor, req.Purpose, *req.Policy, enabled, monitor)
+	var po *services.Policy
+	if err == nil {
+		po, err = p.policies.Get(c, actor, id)
+	}
+	if err != nil {
+		util.ErrorLogger.Errorf("error creating policy: %s", err)
+		p.internalErrorHandler.ServeHTTP(w, r)
+		return
+	}
+	p.writeJSON(w, r, http.StatusCreated, toPolicyJSON(po))
+}
+
+func (p *policyAPI) get(w http.ResponseWriter, r *http.Request) {
+	_, po := p.getPolicy(w, r)
+	if po == nil {
+		return
+	}
+	p.writeJSON(w, r, http.StatusOK, toPolicyJSON(po))
+}
+
+func (p *policyAPI) update(w http.ResponseWriter, r *http.Request) {
+	actor, po := p.getPolicy(w, r)
+	if po == nil {
+		return
+	}
+	req, ok := p.readRequest(w, r)
+	if !ok {
+		return
+	}
+	if (len(req.Purpose) > 0 && req.Purpose != po.Purpose) ||
+		(req.Policy != nil && services.ValidatePolicy(po.Purpose, *req.Policy) != nil) {
+		p.badRequestHandler.ServeHTTP(w, r)
+		return
+	}
+	c := util.Context{r.Context()}
+	id := po.ID
+	var err error
+	if req.Policy != nil {
+		err = p.policies.Update(c, actor, id, *req.Policy)
+	}
+	if err == nil && req.Enabled != nil {
+		err = p.policies.SetEnabled(c, actor, id, *req.Enabled)
+	}
+	if err == nil && req.Monitor != nil {
+		err = p.policies.SetMonitor(c, actor, id, *req.Monitor)
+	}
+	if err == nil {
+		po, err = p.policies.Get(c, actor, id)
+	}
+	if err != nil {
+		util.ErrorLogger.Errorf("error updating policy %s: %s", id, err)
+		p.internalErrorHandler.ServeHTTP(w, r)
+		return
+	}
+	p.writeJSON(w, r, http.StatusOK, toPolicyJSON(po))
+}
+
+func (p *policyAPI) delete(w http.ResponseWriter, r *http.Request) {
+	actor, po := p.getPolicy(w, r)
+	if po == nil {
+		return
+	}
+	if err := p.policies.Delete(util.Context{r.Context()}, actor, po.ID); err != nil {
+		util.ErrorLogger.Errorf("error deleting policy %s: %s", po.ID, err)
+		p.internalErrorHandler.ServeHTTP(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *policyAPI) resolutions(w http.ResponseWriter, r *http.Request) {
+	actor, po := p.getPolicy(w, r)
+	if po == nil {
+		return
+	}
+	offset, limit := 0, p.defaultPageSize
+	var err error
+	q := r.URL.Query()
+	if v := q.Get(offsetQueryParam); len(v) > 0 {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			p.badRequestHandler.ServeHTTP(w, r)
+			return
+		}
+	}
+	if v := q.Get(limitQueryParam); len(v) > 0 {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			p.badRequestHandler.ServeHTTP(w, r)
+			return
+		}
+	}
+	if limit > p.maxPageSize {
+		limit = p.maxPageSize
+	}
+	rs, isEnd, err := p.policies.ResolutionsFor(util.Context{r.Context()}, actor, po.ID, offset, limit)
+	if err != nil {
+		util.ErrorLogger.Errorf("error getting resolutions of policy %s: %s", po.ID, err)
+		p.internalErrorHandler.ServeHTTP(w, r)
+		return
+	}
+	page := resolutionsJSON{
+		Resolutions: make([]resolutionJSON, 0, len(rs)),
+	}
+	for _, res := range rs {
+		page.Resolutions = append(page.Resolutions, resolutionJSON{
+			ID:       res.ID,
+			IRI:      res.IRI.String(),
+			Time:     res.Time,
+			Matched:  res.Matched,
+			MatchLog: res.MatchLog,
+			Scope:    res.Scope,
+		})
+	}
+	if !isEnd {
+		next := offset + len(rs)
+		page.Next = &next
+	}
+	p.writeJSON(w, r, http.StatusOK, page)
+}
+
+func (p *policyAPI) readRequest(w http.ResponseWriter, r *http.Request) (req policyRequestJSON, ok bool) {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		util.ErrorLogger.Errorf("error decoding policy API request: %s", err)
+		p.badRequestHandler.ServeHTTP(w, r)
+		return
+	}
+	ok = true
+	return
+}
+
+func (p *policyAPI) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		util.ErrorLogger.Errorf("error serving policy API while marshalling: %s", err)
+		p.internalErrorHandler.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", policyContentType)
+	w.WriteHeader(status)
+	n, err := w.Write(b)
+	if err != nil {
+		util.ErrorLogger.Errorf("error writing policy API response: %s", err)
+	} else if n != len(b) {
+		util.ErrorLogger.Errorf("error writing policy API response: wrote %d of %d bytes", n, len(b))
+	}
+}
+
+func toPolicyJSON(po *services.Policy) policyJSON {
+	return policyJSON{
+		ID:      po.ID,
+		Purpose: po.Purpose,
+		Enabled: po.Enabled,
+		Monitor: po.Monitor,
+		Policy:  po.Policy,
+	}
+}
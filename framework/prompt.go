@@ -91,6 +91,13 @@ func PromptOverwriteExistingFile(path string) (b bool, err error) {
 			path))
 }
 
+func PromptDeleteUser(username string) (b bool, err error) {
+	return promptYN(
+		fmt.Sprintf(
+			"Permanently delete the account %q? This cannot be undone.",
+			username))
+}
+
 func promptString(display string) (s string, err error) {
 	s, err = promptStringWithDefault(display, "")
 	return
@@ -232,6 +239,11 @@ func PromptServerProfile(scheme, host string) (sp services.ServerPreferences, er
 	if err != nil {
 		return
 	}
+	sp.ServerDescription, err = promptString(
+		"Please enter a short description of this server, which may be publicly shared")
+	if err != nil {
+		return
+	}
 	sp.OpenRegistrations, err = promptYN("Are registrations on this server open to the general public?")
 	if err != nil {
 		return
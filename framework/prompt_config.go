@@ -54,6 +54,12 @@ Let's go!`)))
 	if err != nil {
 		return
 	}
+	c.ServerConfig.AccountDomain, err = promptStringWithDefault(
+		"Enter the domain for user handles like user@domain; it may differ from the host if that domain redirects its /.well-known paths here (ignored in debug mode)",
+		c.ServerConfig.Host)
+	if err != nil {
+		return
+	}
 	c.ServerConfig.CertFile, err = promptString(
 		"Enter the path to the file containing the certificate used in HTTPS connections")
 	if err != nil {
@@ -18,14 +18,18 @@ package framework
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/go-fed/activity/pub"
 	"github.com/go-fed/activity/streams/vocab"
 	"github.com/go-fed/apcore/app"
 	"github.com/go-fed/apcore/framework/oauth2"
 	"github.com/go-fed/apcore/paths"
+	"github.com/go-fed/apcore/services"
 	"github.com/go-fed/apcore/util"
 	"github.com/gorilla/mux"
 )
@@ -46,6 +50,7 @@ type Router struct {
 	actorMap          map[paths.Actor]pub.Actor
 	clock             pub.Clock
 	db                RoutingDatabase
+	visibility        app.AuthorizeFunc
 	host              string
 	scheme            string
 	errorHandler      http.Handler
@@ -58,6 +63,7 @@ func NewRouter(router *mux.Router,
 	actorMap map[paths.Actor]pub.Actor,
 	clock pub.Clock,
 	db RoutingDatabase,
+	visibility app.AuthorizeFunc,
 	host string,
 	scheme string,
 	errorHandler http.Handler,
@@ -69,6 +75,7 @@ func NewRouter(router *mux.Router,
 		actorMap:          actorMap,
 		clock:             clock,
 		db:                db,
+		visibility:        visibility,
 		host:              host,
 		scheme:            scheme,
 		errorHandler:      errorHandler,
@@ -84,6 +91,7 @@ func (r *Router) wrap(route *mux.Route) *Route {
 		actorMap:          r.actorMap,
 		clock:             r.clock,
 		db:                r.db,
+		visibility:        r.visibility,
 		host:              r.host,
 		scheme:            r.scheme,
 		errorHandler:      r.errorHandler,
@@ -96,6 +104,10 @@ func (r *Router) knownActor(c paths.Actor) app.Route {
 	return r.wrap(r.router.NewRoute()).knownActor(c)
 }
 
+func (r *Router) objectCollections() *Route {
+	return r.wrap(r.router.NewRoute()).objectCollections()
+}
+
 func (r *Router) userActorPostInbox() *Route {
 	return r.wrap(r.router.NewRoute()).userActorPostInbox()
 }
@@ -170,6 +182,14 @@ func (r *Router) WebOnlyHandleFunc(path string, f func(http.ResponseWriter, *htt
 	return r.wrap(r.router.HandleFunc(path, f))
 }
 
+func (r *Router) ScopedHandle(path string, handler http.Handler, scopes ...string) app.Route {
+	return r.wrap(r.router.NewRoute()).ScopedHandler(path, handler, scopes...)
+}
+
+func (r *Router) ScopedHandleFunc(path string, f func(http.ResponseWriter, *http.Request), scopes ...string) app.Route {
+	return r.wrap(r.router.NewRoute()).ScopedHandlerFunc(path, f, scopes...)
+}
+
 func (r *Router) Handle(path string, handler http.Handler) app.Route {
 	return r.wrap(r.router.Handle(path, handler))
 }
@@ -231,6 +251,7 @@ type Route struct {
 	actorMap          map[paths.Actor]pub.Actor
 	clock             pub.Clock
 	db                RoutingDatabase
+	visibility        app.AuthorizeFunc
 	host              string
 	scheme            string
 	errorHandler      http.Handler
@@ -253,7 +274,7 @@ func (r *Route) knownActorPostInbox(c paths.Actor) *Route {
 func (r *Route) actorPostInbox(actor pub.Actor, path string) *Route {
 	r.route = r.route.Path(path).Schemes(r.scheme).Methods("POST").HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {
-			userID, _, err := r.oauth.Validate(w, req)
+			userID, _, _, err := r.oauth.Validate(w, req)
 			if err != nil {
 				userID = ""
 				util.ErrorLogger.Errorf("Error validating for ActorPostInbox: %s", err)
@@ -266,7 +287,12 @@ func (r *Route) actorPostInbox(actor pub.Actor, path string) *Route {
 			}
 			c := util.WithUserAPHTTPContext(r.scheme, r.host, req, uuid, userID)
 			isApRequest, err := actor.PostInboxScheme(c.Context, w, req, r.scheme)
-			if err != nil {
+			if errors.Is(err, services.ErrQuarantined) {
+				// Held for moderation, which is not revealed to
+				// the sender.
+				w.WriteHeader(http.StatusAccepted)
+				return
+			} else if err != nil {
 				util.ErrorLogger.Errorf("Error in ActorPostInbox: %s", err)
 				r.errorHandler.ServeHTTP(w, req)
 				return
@@ -290,7 +316,7 @@ func (r *Route) knownActorPostOutbox(c paths.Actor) *Route {
 func (r *Route) actorPostOutbox(actor pub.Actor, path string) *Route {
 	r.route = r.route.Path(path).Schemes(r.scheme).Methods("POST").HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {
-			userID, _, err := r.oauth.Validate(w, req)
+			userID, _, _, err := r.oauth.Validate(w, req)
 			if err != nil {
 				userID = ""
 				util.ErrorLogger.Errorf("Error validating for ActorPostInbox: %s", err)
@@ -327,7 +353,7 @@ func (r *Route) knownActorGetInbox(c paths.Actor, web func(w http.ResponseWriter
 func (r *Route) actorGetInbox(actor pub.Actor, path string, web func(w http.ResponseWriter, r *http.Request, inbox vocab.ActivityStreamsOrderedCollectionPage)) *Route {
 	r.route = r.route.Path(path).Schemes(r.scheme).Methods("GET").HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {
-			userID, _, err := r.oauth.Validate(w, req)
+			userID, _, _, err := r.oauth.Validate(w, req)
 			if err != nil {
 				userID = ""
 				util.ErrorLogger.Errorf("Error validating for ActorPostInbox: %s", err)
@@ -378,7 +404,7 @@ func (r *Route) knownActorGetOutbox(c paths.Actor, web func(w http.ResponseWrite
 func (r *Route) actorGetOutbox(actor pub.Actor, path string, web func(w http.ResponseWriter, r *http.Request, outbox vocab.ActivityStreamsOrderedCollectionPage)) *Route {
 	r.route = r.route.Path(path).Schemes(r.scheme).Methods("GET").HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {
-			userID, _, err := r.oauth.Validate(w, req)
+			userID, _, _, err := r.oauth.Validate(w, req)
 			if err != nil {
 				userID = ""
 				util.ErrorLogger.Errorf("Error validating for ActorPostInbox: %s", err)
@@ -419,37 +445,60 @@ func (r *Route) actorGetOutbox(actor pub.Actor, path string, web func(w http.Res
 }
 
 func (r *Route) ActivityPubOnlyHandleFunc(path string, authFn app.AuthorizeFunc) app.Route {
+	r.route = r.route.Path(path).Schemes(r.scheme).HandlerFunc(r.activityPubOnlyHandler(authFn))
+	return r
+}
+
+func (r *Route) objectCollections() *Route {
+	r.route = r.route.MatcherFunc(func(req *http.Request, rm *mux.RouteMatch) bool {
+		_, _, ok := paths.ObjectCollectionFor(req.URL)
+		return ok
+	}).Methods("GET").Schemes(r.scheme).HandlerFunc(r.activityPubOnlyHandler(nil))
+	return r
+}
+
+func (r *Route) activityPubOnlyHandler(authFn app.AuthorizeFunc) http.HandlerFunc {
 	apHandler := pub.NewActivityStreamsHandlerScheme(r.db, r.clock, r.scheme)
-	r.route = r.route.Path(path).Schemes(r.scheme).HandlerFunc(
-		func(w http.ResponseWriter, req *http.Request) {
-			c := util.WithAPHTTPContext(r.scheme, r.host, req)
-			permit := true
-			if authFn != nil {
-				var err error
-				permit, err = authFn(c, w, req, r.db)
-				if err != nil {
-					util.ErrorLogger.Errorf("Error in ActivityPubOnlyHandleFunc authFn: %s", err)
-					r.errorHandler.ServeHTTP(w, req)
-					return
-				}
-			}
-			if !permit {
-				r.notFoundHandler.ServeHTTP(w, req)
-				return
-			}
-			isASRequest, err := apHandler(c, w, req)
-			if err != nil {
-				util.ErrorLogger.Errorf("Error in ActivityPubOnlyHandleFunc: %s", err)
-				r.errorHandler.ServeHTTP(w, req)
-				return
-			}
-			if !isASRequest && r.notFoundHandler != nil {
-				r.notFoundHandler.ServeHTTP(w, req)
-				return
-			}
+	return func(w http.ResponseWriter, req *http.Request) {
+		c := util.WithAPHTTPContext(r.scheme, r.host, req)
+		permit, err := r.authorize(c, w, req, authFn)
+		if err != nil {
+			util.ErrorLogger.Errorf("Error in ActivityPubOnlyHandleFunc authFn: %s", err)
+			r.errorHandler.ServeHTTP(w, req)
 			return
-		})
-	return r
+		}
+		if !permit {
+			r.notFoundHandler.ServeHTTP(w, req)
+			return
+		}
+		isASRequest, err := apHandler(c, w, req)
+		if err != nil {
+			util.ErrorLogger.Errorf("Error in ActivityPubOnlyHandleFunc: %s", err)
+			r.errorHandler.ServeHTTP(w, req)
+			return
+		}
+		if !isASRequest && r.notFoundHandler != nil {
+			r.notFoundHandler.ServeHTTP(w, req)
+			return
+		}
+		return
+	}
+}
+
+// authorize first enforces the visibility of local objects, then applies the
+// application's authFn, if any.
+func (r *Route) authorize(c util.Context, w http.ResponseWriter, req *http.Request, authFn app.AuthorizeFunc) (permit bool, err error) {
+	permit = true
+	if r.visibility != nil {
+		permit, err = r.visibility(c, w, req, r.db)
+		if err != nil || !permit {
+			return
+		}
+	}
+	if authFn != nil {
+		permit, err = authFn(c, w, req, r.db)
+	}
+	return
 }
 
 func (r *Route) ActivityPubAndWebHandleFunc(path string, authFn app.AuthorizeFunc, f func(http.ResponseWriter, *http.Request)) app.Route {
@@ -457,15 +506,11 @@ func (r *Route) ActivityPubAndWebHandleFunc(path string, authFn app.AuthorizeFun
 	r.route = r.route.Path(path).Schemes(r.scheme).HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {
 			c := util.WithAPHTTPContext(r.scheme, r.host, req)
-			permit := true
-			if authFn != nil {
-				var err error
-				permit, err = authFn(c, w, req, r.db)
-				if err != nil {
-					util.ErrorLogger.Errorf("Error in ActivityPubAndWebHandleFunc authFn: %s", err)
-					r.errorHandler.ServeHTTP(w, req)
-					return
-				}
+			permit, err := r.authorize(c, w, req, authFn)
+			if err != nil {
+				util.ErrorLogger.Errorf("Error in ActivityPubAndWebHandleFunc authFn: %s", err)
+				r.errorHandler.ServeHTTP(w, req)
+				return
 			}
 			if !permit {
 				r.notFoundHandler.ServeHTTP(w, req)
@@ -493,7 +538,7 @@ func (r *Route) apWebCollectionPageFetchingHandleFunc(path string,
 	apHandler := pub.NewActivityStreamsHandlerScheme(r.db, r.clock, r.scheme)
 	r.route = r.route.Path(path).Schemes(r.scheme).HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {
-			userID, _, err := r.oauth.Validate(w, req)
+			userID, _, _, err := r.oauth.Validate(w, req)
 			if err != nil {
 				userID = ""
 				util.ErrorLogger.Errorf("Error validating for apWebCollectionPageFetchingHandleFunc: %s", err)
@@ -550,7 +595,7 @@ func (r *Route) apWebVocabFetchingHandleFunc(path string,
 	apHandler := pub.NewActivityStreamsHandlerScheme(r.db, r.clock, r.scheme)
 	r.route = r.route.Path(path).Schemes(r.scheme).HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {
-			userID, _, err := r.oauth.Validate(w, req)
+			userID, _, _, err := r.oauth.Validate(w, req)
 			if err != nil {
 				userID = ""
 				util.ErrorLogger.Errorf("Error validating for apWebVocabFetchingHandleFunc: %s", err)
@@ -620,6 +665,41 @@ func (r *Route) WebOnlyHandlerFunc(path string, f func(http.ResponseWriter, *htt
 	return r
 }
 
+func (r *Route) ScopedHandler(path string, handler http.Handler, scopes ...string) app.Route {
+	r.route = r.route.Path(path).Handler(r.requireScopes(handler, scopes))
+	return r
+}
+
+func (r *Route) ScopedHandlerFunc(path string, f func(http.ResponseWriter, *http.Request), scopes ...string) app.Route {
+	r.route = r.route.Path(path).Handler(r.requireScopes(http.HandlerFunc(f), scopes))
+	return r
+}
+
+// requireScopes wraps the handler so it only serves requests whose OAuth2
+// token or first party credential was granted all of the scopes.
+func (r *Route) requireScopes(handler http.Handler, scopes []string) http.Handler {
+	if !r.oauth.Scopes().Known(scopes) {
+		util.ErrorLogger.Errorf("route requires unregistered OAuth2 scopes: %v", scopes)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, granted, auth, err := r.oauth.Validate(w, req)
+		if err != nil {
+			util.ErrorLogger.Errorf("error validating scoped request: %s", err)
+			r.errorHandler.ServeHTTP(w, req)
+			return
+		} else if !auth {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		} else if !app.ScopesPermit(granted, scopes...) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer error=%q, scope=%q", "insufficient_scope", strings.Join(scopes, " ")))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
 func (r *Route) Handler(handler http.Handler) app.Route {
 	r.route = r.route.Handler(handler)
 	return r
@@ -33,9 +33,12 @@ type Webfinger struct {
 	Links   []Link   `json:"links,omitempty"`
 }
 
-func ToWebfinger(scheme, host, username, idPath string) (w Webfinger, err error) {
+// ToWebfinger builds the WebFinger response for a user. The acct: subject uses
+// the account domain, while the actor links point to the serving host. The two
+// are the same unless the account domain is delegated to this host.
+func ToWebfinger(scheme, host, accountDomain, username, idPath string) (w Webfinger, err error) {
 	w = Webfinger{
-		Subject: fmt.Sprintf("acct:%s@%s", username, host),
+		Subject: fmt.Sprintf("acct:%s@%s", username, accountDomain),
 		Aliases: []string{
 			fmt.Sprintf("%s://%s%s", scheme, host, idPath),
 		},
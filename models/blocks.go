@@ -0,0 +1,155 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql"
+	"net/url"
+	"time"
+
+	"github.com/go-fed/apcore/util"
+)
+
+var _ Model = &Blocks{}
+
+// Block is an actor's block of another actor.
+type Block struct {
+	ID        string
+	Created   time.Time
+	ActorID   string
+	BlockedID string
+	// ActivityID is the Block activity that created this block, if one is
+	// known.
+	ActivityID sql.NullString
+}
+
+// Blocks is a Model that provides additional database methods for the actors
+// that an actor has blocked.
+type Blocks struct {
+	insert           *sql.Stmt
+	updateActivity   *sql.Stmt
+	deleteBlock      *sql.Stmt
+	deleteByActivity *sql.Stmt
+	getForActor      *sql.Stmt
+}
+
+func (b *Blocks) Prepare(db *sql.DB, s SqlDialect) error {
+	return prepareStmtPairs(db,
+		stmtPairs{
+			{&(b.insert), s.InsertBlock()},
+			{&(b.updateActivity), s.UpdateBlockActivity()},
+			{&(b.deleteBlock), s.DeleteBlock()},
+			{&(b.deleteByActivity), s.DeleteBlockByActivity()},
+			{&(b.getForActor), s.GetBlocksForActor()},
+		})
+}
+
+func (b *Blocks) CreateTable(t *sql.Tx, s SqlDialect) error {
+	_, err := t.Exec(s.CreateBlocksTable())
+	return err
+}
+
+func (b *Blocks) Close() {
+	b.insert.Close()
+	b.updateActivity.Close()
+	b.deleteBlock.Close()
+	b.deleteByActivity.Close()
+	b.getForActor.Close()
+}
+
+// Create records that the actor blocks another actor. It returns false if the
+// actor already blocks the other actor, in which case nothing changes.
+func (b *Blocks) Create(c util.Context, tx *sql.Tx, actor, blocked *url.URL, activity sql.NullString) (created bool, err error) {
+	var r sql.Result
+	r, err = tx.Stmt(b.insert).ExecContext(c,
+		actor.String(),
+		blocked.String(),
+		activity)
+	if err != nil {
+		return
+	}
+	var n int64
+	n, err = r.RowsAffected()
+	created = n > 0
+	return
+}
+
+// SetActivity sets the Block activity that created an existing block.
+func (b *Blocks) SetActivity(c util.Context, tx *sql.Tx, actor, blocked, activity *url.URL) error {
+	r, err := tx.Stmt(b.updateActivity).ExecContext(c,
+		actor.String(),
+		blocked.String(),
+		activity.String())
+	return mustChangeOneRow(r, err, "Blocks.SetActivity")
+}
+
+// Delete removes the actor's block of another actor, returning the Block
+// activity that created it if one is known. Deleting a block that does not
+// exist returns false.
+func (b *Blocks) Delete(c util.Context, tx *sql.Tx, actor, blocked *url.URL) (deleted bool, activity sql.NullString, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(b.deleteBlock).QueryContext(c,
+		actor.String(),
+		blocked.String())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return deleted, activity, doForRows(rows, "Blocks.Delete", func(r SingleRow) error {
+		deleted = true
+		return r.Scan(&activity)
+	})
+}
+
+// DeleteByActivity removes the actor's block that was created by the given
+// Block activity, returning the actor that is no longer blocked. Deleting a
+// block that does not exist returns an empty string.
+func (b *Blocks) DeleteByActivity(c util.Context, tx *sql.Tx, actor, activity *url.URL) (blocked string, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(b.deleteByActivity).QueryContext(c,
+		actor.String(),
+		activity.String())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return blocked, doForRows(rows, "Blocks.DeleteByActivity", func(r SingleRow) error {
+		return r.Scan(&blocked)
+	})
+}
+
+// GetForActor fetches all the blocks made by an actor, most recent first.
+func (b *Blocks) GetForActor(c util.Context, tx *sql.Tx, actor *url.URL) (bs []Block, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(b.getForActor).QueryContext(c, actor.String())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return bs, doForRows(rows, "Blocks.GetForActor", func(r SingleRow) error {
+		var bl Block
+		if err := r.Scan(&(bl.ID),
+			&(bl.Created),
+			&(bl.ActorID),
+			&(bl.BlockedID),
+			&(bl.ActivityID)); err != nil {
+			return err
+		}
+		bs = append(bs, bl)
+		return nil
+	})
+}
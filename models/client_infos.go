@@ -18,18 +18,60 @@ package models
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
 
 	"github.com/go-fed/apcore/util"
 	"github.com/go-fed/oauth2"
 )
 
 var _ oauth2.ClientInfo = &ClientInfo{}
+var _ driver.Valuer = RedirectURIs{}
+var _ sql.Scanner = &RedirectURIs{}
+var _ Model = &ClientInfos{}
+var _ Migrator = &ClientInfos{}
 
+// RedirectURIs are the exact redirection URIs a registered client may use.
+type RedirectURIs []string
+
+func (r RedirectURIs) Value() (driver.Value, error) {
+	if r == nil {
+		r = RedirectURIs{}
+	}
+	return json.Marshal(r)
+}
+
+func (r *RedirectURIs) Scan(src interface{}) error {
+	return unmarshal(src, r)
+}
+
+// ClientInfo is an OAuth2 client. First party proxy clients belong to a user,
+// while clients registered dynamically have no user and carry the metadata
+// they registered with.
 type ClientInfo struct {
 	ID     string
 	Secret sql.NullString
 	Domain string
 	UserID string
+	// Metadata of dynamically registered clients.
+	Name         string
+	Website      string
+	LogoURI      string
+	RedirectURIs RedirectURIs
+	Created      time.Time
+	// ResourceServer is set by an admin on registered clients that may
+	// introspect the tokens issued to other clients.
+	ResourceServer bool
+}
+
+func (c *ClientInfo) scan(r SingleRow) error {
+	var uid sql.NullString
+	if err := r.Scan(&(c.ID), &(c.Secret), &(c.Domain), &uid, &(c.Name), &(c.Website), &(c.LogoURI), &(c.RedirectURIs), &(c.Created), &(c.ResourceServer)); err != nil {
+		return err
+	}
+	c.UserID = uid.String
+	return nil
 }
 
 func (c *ClientInfo) GetID() string {
@@ -54,15 +96,23 @@ func (c *ClientInfo) GetUserID() string {
 // ClientInfos is a Model that provides additional database methods for OAuth2
 // client information.
 type ClientInfos struct {
-	create  *sql.Stmt
-	getByID *sql.Stmt
+	create           *sql.Stmt
+	register         *sql.Stmt
+	getByID          *sql.Stmt
+	getRegistered    *sql.Stmt
+	setResourceSrv   *sql.Stmt
+	deleteRegistered *sql.Stmt
 }
 
 func (c *ClientInfos) Prepare(db *sql.DB, s SqlDialect) error {
 	return prepareStmtPairs(db,
 		stmtPairs{
 			{&(c.create), s.CreateClientInfo()},
+			{&(c.register), s.RegisterClientInfo()},
 			{&(c.getByID), s.GetClientInfoByID()},
+			{&(c.getRegistered), s.GetRegisteredClientInfos()},
+			{&(c.setResourceSrv), s.SetRegisteredClientInfoResourceServer()},
+			{&(c.deleteRegistered), s.DeleteRegisteredClientInfo()},
 		})
 }
 
@@ -71,9 +121,18 @@ func (c *ClientInfos) CreateTable(t *sql.Tx, s SqlDialect) error {
 	return err
 }
 
+func (c *ClientInfos) Migrate(t *sql.Tx, s SqlDialect) error {
+	_, err := t.Exec(s.MigrateClientInfosTable())
+	return err
+}
+
 func (c *ClientInfos) Close() {
 	c.create.Close()
+	c.register.Close()
 	c.getByID.Close()
+	c.getRegistered.Close()
+	c.setResourceSrv.Close()
+	c.deleteRegistered.Close()
 }
 
 // Create adds a ClientInfo into the database.
@@ -93,6 +152,26 @@ func (c *ClientInfos) Create(ctx util.Context, tx *sql.Tx, info oauth2.ClientInf
 	})
 }
 
+// Register adds a dynamically registered client, which has no user, into the
+// database.
+func (c *ClientInfos) Register(ctx util.Context, tx *sql.Tx, info *ClientInfo) (id string, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(c.register).QueryContext(ctx,
+		info.ID,
+		info.Domain,
+		info.Name,
+		info.Website,
+		info.LogoURI,
+		info.RedirectURIs)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return id, enforceOneRow(rows, "ClientInfos.Register", func(r SingleRow) error {
+		return r.Scan(&(id))
+	})
+}
+
 // GetByID fetches ClientInfo based on its id.
 func (c *ClientInfos) GetByID(ctx util.Context, tx *sql.Tx, id string) (oauth2.ClientInfo, error) {
 	rows, err := tx.Stmt(c.getByID).QueryContext(ctx, id)
@@ -102,6 +181,38 @@ func (c *ClientInfos) GetByID(ctx util.Context, tx *sql.Tx, id string) (oauth2.C
 	defer rows.Close()
 	ci := &ClientInfo{}
 	return ci, enforceOneRow(rows, "ClientInfos.GetByID", func(r SingleRow) error {
-		return r.Scan(&(ci.ID), &(ci.Secret), &(ci.Domain), &(ci.UserID))
+		return ci.scan(r)
+	})
+}
+
+// GetRegistered lists dynamically registered clients, oldest first.
+func (c *ClientInfos) GetRegistered(ctx util.Context, tx *sql.Tx) (cs []*ClientInfo, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(c.getRegistered).QueryContext(ctx)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return cs, doForRows(rows, "ClientInfos.GetRegistered", func(r SingleRow) error {
+		ci := &ClientInfo{}
+		if err := ci.scan(r); err != nil {
+			return err
+		}
+		cs = append(cs, ci)
+		return nil
 	})
 }
+
+// SetResourceServer sets whether a dynamically registered client is a
+// resource server.
+func (c *ClientInfos) SetResourceServer(ctx util.Context, tx *sql.Tx, id string, b bool) error {
+	r, err := tx.Stmt(c.setResourceSrv).ExecContext(ctx, id, b)
+	return mustChangeOneRow(r, err, "ClientInfos.SetResourceServer")
+}
+
+// DeleteRegistered removes a dynamically registered client along with its
+// tokens.
+func (c *ClientInfos) DeleteRegistered(ctx util.Context, tx *sql.Tx, id string) error {
+	r, err := tx.Stmt(c.deleteRegistered).ExecContext(ctx, id)
+	return mustChangeOneRow(r, err, "ClientInfos.DeleteRegistered")
+}
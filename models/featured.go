@@ -0,0 +1,177 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql"
+	"net/url"
+
+	"github.com/go-fed/apcore/util"
+)
+
+var _ Model = &Featured{}
+
+// Featured is a Model that provides additional database methods for the
+// featured, or pinned, collections of both local and federated actors.
+type Featured struct {
+	insert           *sql.Stmt
+	exists           *sql.Stmt
+	containsForActor *sql.Stmt
+	contains         *sql.Stmt
+	get              *sql.Stmt
+	getLastPage      *sql.Stmt
+	prependItem      *sql.Stmt
+	deleteItem       *sql.Stmt
+	getAllForActor   *sql.Stmt
+}
+
+func (i *Featured) Prepare(db *sql.DB, s SqlDialect) error {
+	return prepareStmtPairs(db,
+		stmtPairs{
+			{&(i.insert), s.InsertFeatured()},
+			{&(i.exists), s.FeaturedExists()},
+			{&(i.containsForActor), s.FeaturedContainsForActor()},
+			{&(i.contains), s.FeaturedContains()},
+			{&(i.get), s.GetFeatured()},
+			{&(i.getLastPage), s.GetFeaturedLastPage()},
+			{&(i.prependItem), s.PrependFeaturedItem()},
+			{&(i.deleteItem), s.DeleteFeaturedItem()},
+			{&(i.getAllForActor), s.GetAllFeaturedForActor()},
+		})
+}
+
+func (i *Featured) CreateTable(t *sql.Tx, s SqlDialect) error {
+	if _, err := t.Exec(s.CreateFeaturedTable()); err != nil {
+		return err
+	}
+	_, err := t.Exec(s.CreateIndexIDFeaturedTable())
+	return err
+}
+
+func (i *Featured) Close() {
+	i.insert.Close()
+	i.exists.Close()
+	i.containsForActor.Close()
+	i.contains.Close()
+	i.get.Close()
+	i.getLastPage.Close()
+	i.prependItem.Close()
+	i.deleteItem.Close()
+	i.getAllForActor.Close()
+}
+
+// Create a new featured entry for the given actor.
+func (i *Featured) Create(c util.Context, tx *sql.Tx, actor *url.URL, featured ActivityStreamsOrderedCollection) error {
+	r, err := tx.Stmt(i.insert).ExecContext(c,
+		actor.String(),
+		featured)
+	return mustChangeOneRow(r, err, "Featured.Create")
+}
+
+// Exists returns true if the featured collection is stored.
+func (i *Featured) Exists(c util.Context, tx *sql.Tx, featured *url.URL) (b bool, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(i.exists).QueryContext(c, featured.String())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return b, enforceOneRow(rows, "Featured.Exists", func(r SingleRow) error {
+		return r.Scan(&b)
+	})
+}
+
+// ContainsForActor returns true if the item is in the actor's featured
+// collection.
+func (i *Featured) ContainsForActor(c util.Context, tx *sql.Tx, actor, item *url.URL) (b bool, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(i.containsForActor).QueryContext(c, actor.String(), item.String())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return b, enforceOneRow(rows, "Featured.ContainsForActor", func(r SingleRow) error {
+		return r.Scan(&b)
+	})
+}
+
+// Contains returns true if the item is in the featured collection.
+func (i *Featured) Contains(c util.Context, tx *sql.Tx, featured, item *url.URL) (b bool, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(i.contains).QueryContext(c, featured.String(), item.String())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return b, enforceOneRow(rows, "Featured.Contains", func(r SingleRow) error {
+		return r.Scan(&b)
+	})
+}
+
+// GetPage returns an OrderedCollectionPage of the Featured collection.
+//
+// The range of elements retrieved are [min, max).
+func (i *Featured) GetPage(c util.Context, tx *sql.Tx, featured *url.URL, min, max int) (page ActivityStreamsOrderedCollectionPage, isEnd bool, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(i.get).QueryContext(c, featured.String(), min, max-1)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return page, isEnd, enforceOneRow(rows, "Featured.GetPage", func(r SingleRow) error {
+		return r.Scan(&page, &isEnd)
+	})
+}
+
+// GetLastPage returns the last OrderedCollectionPage of the Featured
+// collection.
+func (i *Featured) GetLastPage(c util.Context, tx *sql.Tx, featured *url.URL, n int) (page ActivityStreamsOrderedCollectionPage, startIdx int, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(i.getLastPage).QueryContext(c, featured.String(), n)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return page, startIdx, enforceOneRow(rows, "Featured.GetLastPage", func(r SingleRow) error {
+		return r.Scan(&page, &startIdx)
+	})
+}
+
+// PrependItem prepends the item to the featured's ordered items list.
+func (i *Featured) PrependItem(c util.Context, tx *sql.Tx, featured, item *url.URL) error {
+	r, err := tx.Stmt(i.prependItem).ExecContext(c, featured.String(), item.String())
+	return mustChangeOneRow(r, err, "Featured.PrependItem")
+}
+
+// DeleteItem removes the item from the featured's ordered items list.
+func (i *Featured) DeleteItem(c util.Context, tx *sql.Tx, featured, item *url.URL) error {
+	r, err := tx.Stmt(i.deleteItem).ExecContext(c, featured.String(), item.String())
+	return mustChangeOneRow(r, err, "Featured.DeleteItem")
+}
+
+// GetAllForActor returns the entire Featured OrderedCollection.
+func (i *Featured) GetAllForActor(c util.Context, tx *sql.Tx, actor *url.URL) (col ActivityStreamsOrderedCollection, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(i.getAllForActor).QueryContext(c, actor.String())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return col, enforceOneRow(rows, "Featured.GetAllForActor", func(r SingleRow) error {
+		return r.Scan(&col)
+	})
+}
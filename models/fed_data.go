@@ -24,15 +24,20 @@ import (
 )
 
 var _ Model = &FedData{}
+var _ Migrator = &FedData{}
 
 // FedData is a Model that provides additional database methods for
 // ActivityStreams data received from federated peers.
 type FedData struct {
-	exists    *sql.Stmt
-	get       *sql.Stmt
-	fedCreate *sql.Stmt
-	fedUpdate *sql.Stmt
-	fedDelete *sql.Stmt
+	exists            *sql.Stmt
+	get               *sql.Stmt
+	getMany           *sql.Stmt
+	fedCreate         *sql.Stmt
+	fedUpdate         *sql.Stmt
+	fedDelete         *sql.Stmt
+	fedDeleteForActor *sql.Stmt
+	sharedInboxes     *sql.Stmt
+	getRecent         *sql.Stmt
 }
 
 func (f *FedData) Prepare(db *sql.DB, s SqlDialect) error {
@@ -40,9 +45,13 @@ func (f *FedData) Prepare(db *sql.DB, s SqlDialect) error {
 		stmtPairs{
 			{&(f.exists), s.FedExists()},
 			{&(f.get), s.FedGet()},
+			{&(f.getMany), s.FedGetMany()},
 			{&(f.fedCreate), s.FedCreate()},
 			{&(f.fedUpdate), s.FedUpdate()},
 			{&(f.fedDelete), s.FedDelete()},
+			{&(f.fedDeleteForActor), s.FedDeleteForActor()},
+			{&(f.sharedInboxes), s.FedSharedInboxes()},
+			{&(f.getRecent), s.FedGetRecent()},
 		})
 }
 
@@ -54,12 +63,21 @@ func (f *FedData) CreateTable(t *sql.Tx, s SqlDialect) error {
 	return err
 }
 
+func (f *FedData) Migrate(t *sql.Tx, s SqlDialect) error {
+	_, err := t.Exec(s.CreateIndexCreateTimeFedDataTable())
+	return err
+}
+
 func (f *FedData) Close() {
 	f.exists.Close()
 	f.get.Close()
+	f.getMany.Close()
 	f.fedCreate.Close()
 	f.fedUpdate.Close()
 	f.fedDelete.Close()
+	f.fedDeleteForActor.Close()
+	f.sharedInboxes.Close()
+	f.getRecent.Close()
 }
 
 // Exists determines if the ID is stored in the federated table.
@@ -90,6 +108,26 @@ func (f *FedData) Get(c util.Context, tx *sql.Tx, id *url.URL) (v ActivityStream
 	return
 }
 
+// GetMany retrieves the ids found in the federated table, keyed by id.
+func (f *FedData) GetMany(c util.Context, tx *sql.Tx, ids []*url.URL) (m map[string]ActivityStreams, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(f.getMany).QueryContext(c, IRIs(ids))
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	m = make(map[string]ActivityStreams, len(ids))
+	return m, doForRows(rows, "FedData.GetMany", func(r SingleRow) error {
+		var id string
+		var v ActivityStreams
+		if err := r.Scan(&id, &v); err != nil {
+			return err
+		}
+		m[id] = v
+		return nil
+	})
+}
+
 // Create inserts the federated data into the table.
 func (f *FedData) Create(c util.Context, tx *sql.Tx, v ActivityStreams) error {
 	r, err := tx.Stmt(f.fedCreate).ExecContext(c, v)
@@ -107,3 +145,53 @@ func (f *FedData) Delete(c util.Context, tx *sql.Tx, fedIDIRI *url.URL) error {
 	r, err := tx.Stmt(f.fedDelete).ExecContext(c, fedIDIRI.String())
 	return mustChangeOneRow(r, err, "FedData.Delete")
 }
+
+// DeleteForActor removes the actor along with the federated data it authored.
+func (f *FedData) DeleteForActor(c util.Context, tx *sql.Tx, actor *url.URL) error {
+	_, err := tx.Stmt(f.fedDeleteForActor).ExecContext(c, actor.String())
+	return err
+}
+
+// SharedInboxes returns the shared inboxes of the federated actors known to
+// this server.
+func (f *FedData) SharedInboxes(c util.Context, tx *sql.Tx) (inboxes []*url.URL, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(f.sharedInboxes).QueryContext(c)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return inboxes, doForRows(rows, "FedData.SharedInboxes", func(r SingleRow) error {
+		var u URL
+		if err := r.Scan(&u); err != nil {
+			return err
+		}
+		inboxes = append(inboxes, u.URL)
+		return nil
+	})
+}
+
+// FedPayload is the raw JSON of federated data, as policies are matched
+// against it.
+type FedPayload struct {
+	ID      string
+	Payload []byte
+}
+
+// GetRecent returns up to n of the most recently received federated data.
+func (f *FedData) GetRecent(c util.Context, tx *sql.Tx, n int) (fp []FedPayload, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(f.getRecent).QueryContext(c, n)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return fp, doForRows(rows, "FedData.GetRecent", func(r SingleRow) error {
+		var p FedPayload
+		if err := r.Scan(&(p.ID), &(p.Payload)); err != nil {
+			return err
+		}
+		fp = append(fp, p)
+		return nil
+	})
+}
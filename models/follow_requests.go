@@ -0,0 +1,135 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql"
+	"net/url"
+	"time"
+
+	"github.com/go-fed/apcore/util"
+)
+
+var _ Model = &FollowRequests{}
+
+// FollowRequest is a pending Follow of a local actor that has been neither
+// accepted nor rejected.
+type FollowRequest struct {
+	ID         string
+	Created    time.Time
+	ActorID    string
+	FollowerID string
+	Follow     ActivityStreams
+}
+
+// FollowRequests is a Model that provides additional database methods for
+// Follows awaiting approval.
+type FollowRequests struct {
+	insert           *sql.Stmt
+	getForActor      *sql.Stmt
+	deleteRequest    *sql.Stmt
+	deleteByActivity *sql.Stmt
+}
+
+func (f *FollowRequests) Prepare(db *sql.DB, s SqlDialect) error {
+	return prepareStmtPairs(db,
+		stmtPairs{
+			{&(f.insert), s.InsertFollowRequest()},
+			{&(f.getForActor), s.GetFollowRequestsForActor()},
+			{&(f.deleteRequest), s.DeleteFollowRequest()},
+			{&(f.deleteByActivity), s.DeleteFollowRequestByActivity()},
+		})
+}
+
+func (f *FollowRequests) CreateTable(t *sql.Tx, s SqlDialect) error {
+	_, err := t.Exec(s.CreateFollowRequestsTable())
+	return err
+}
+
+func (f *FollowRequests) Close() {
+	f.insert.Close()
+	f.getForActor.Close()
+	f.deleteRequest.Close()
+	f.deleteByActivity.Close()
+}
+
+// Create records a pending Follow of the actor. A newer Follow by the same
+// follower replaces the older one.
+func (f *FollowRequests) Create(c util.Context, tx *sql.Tx, actor, follower, activityID *url.URL, follow ActivityStreams) error {
+	r, err := tx.Stmt(f.insert).ExecContext(c,
+		actor.String(),
+		follower.String(),
+		activityID.String(),
+		follow)
+	return mustChangeOneRow(r, err, "FollowRequests.Create")
+}
+
+// GetForActor fetches the pending Follows of the actor, oldest first.
+func (f *FollowRequests) GetForActor(c util.Context, tx *sql.Tx, actor *url.URL) (fr []FollowRequest, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(f.getForActor).QueryContext(c, actor.String())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return fr, doForRows(rows, "FollowRequests.GetForActor", func(r SingleRow) error {
+		var req FollowRequest
+		if err := r.Scan(&(req.ID),
+			&(req.Created),
+			&(req.ActorID),
+			&(req.FollowerID),
+			&(req.Follow)); err != nil {
+			return err
+		}
+		fr = append(fr, req)
+		return nil
+	})
+}
+
+// Delete removes the follower's pending Follow of the actor, returning it.
+// The returned bool is false if there was no such Follow.
+func (f *FollowRequests) Delete(c util.Context, tx *sql.Tx, actor, follower *url.URL) (deleted bool, req FollowRequest, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(f.deleteRequest).QueryContext(c,
+		actor.String(),
+		follower.String())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return deleted, req, doForRows(rows, "FollowRequests.Delete", func(r SingleRow) error {
+		deleted = true
+		return r.Scan(&(req.FollowerID), &(req.Follow))
+	})
+}
+
+// DeleteByActivity removes the pending Follow of the actor with the given id,
+// returning it. The returned bool is false if there was no such Follow.
+func (f *FollowRequests) DeleteByActivity(c util.Context, tx *sql.Tx, actor, activityID *url.URL) (deleted bool, req FollowRequest, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(f.deleteByActivity).QueryContext(c,
+		actor.String(),
+		activityID.String())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return deleted, req, doForRows(rows, "FollowRequests.DeleteByActivity", func(r SingleRow) error {
+		deleted = true
+		return r.Scan(&(req.FollowerID), &(req.Follow))
+	})
+}
@@ -28,12 +28,14 @@ var _ Model = &LocalData{}
 // LocalData is a Model that provides additional database methods for
 // ActivityStreams data generated by this instance.
 type LocalData struct {
-	exists      *sql.Stmt
-	get         *sql.Stmt
-	localCreate *sql.Stmt
-	localUpdate *sql.Stmt
-	localDelete *sql.Stmt
-	stats       *sql.Stmt
+	exists              *sql.Stmt
+	get                 *sql.Stmt
+	getMany             *sql.Stmt
+	localCreate         *sql.Stmt
+	localUpdate         *sql.Stmt
+	localDelete         *sql.Stmt
+	localDeleteForActor *sql.Stmt
+	stats               *sql.Stmt
 }
 
 func (f *LocalData) Prepare(db *sql.DB, s SqlDialect) error {
@@ -41,9 +43,11 @@ func (f *LocalData) Prepare(db *sql.DB, s SqlDialect) error {
 		stmtPairs{
 			{&(f.exists), s.LocalExists()},
 			{&(f.get), s.LocalGet()},
+			{&(f.getMany), s.LocalGetMany()},
 			{&(f.localCreate), s.LocalCreate()},
 			{&(f.localUpdate), s.LocalUpdate()},
 			{&(f.localDelete), s.LocalDelete()},
+			{&(f.localDeleteForActor), s.LocalDeleteForActor()},
 			{&(f.stats), s.LocalStats()},
 		})
 }
@@ -59,9 +63,11 @@ func (f *LocalData) CreateTable(t *sql.Tx, s SqlDialect) error {
 func (f *LocalData) Close() {
 	f.exists.Close()
 	f.get.Close()
+	f.getMany.Close()
 	f.localCreate.Close()
 	f.localUpdate.Close()
 	f.localDelete.Close()
+	f.localDeleteForActor.Close()
 	f.stats.Close()
 }
 
@@ -93,6 +99,26 @@ func (f *LocalData) Get(c util.Context, tx *sql.Tx, id *url.URL) (v ActivityStre
 	return
 }
 
+// GetMany retrieves the ids found in the local table, keyed by id.
+func (f *LocalData) GetMany(c util.Context, tx *sql.Tx, ids []*url.URL) (m map[string]ActivityStreams, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(f.getMany).QueryContext(c, IRIs(ids))
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	m = make(map[string]ActivityStreams, len(ids))
+	return m, doForRows(rows, "LocalData.GetMany", func(r SingleRow) error {
+		var id string
+		var v ActivityStreams
+		if err := r.Scan(&id, &v); err != nil {
+			return err
+		}
+		m[id] = v
+		return nil
+	})
+}
+
 // Create inserts the local data into the table.
 func (f *LocalData) Create(c util.Context, tx *sql.Tx, v ActivityStreams) error {
 	r, err := tx.Stmt(f.localCreate).ExecContext(c, v)
@@ -127,3 +153,9 @@ func (f *LocalData) Stats(c util.Context, tx *sql.Tx) (la LocalDataActivity, err
 		return r.Scan(&(la.NLocalPosts), &(la.NLocalComments))
 	})
 }
+
+// DeleteForActor removes the local data authored by the actor.
+func (f *LocalData) DeleteForActor(c util.Context, tx *sql.Tx, actor *url.URL) error {
+	_, err := tx.Stmt(f.localDeleteForActor).ExecContext(c, actor.String())
+	return err
+}
@@ -27,6 +27,28 @@ type Model interface {
 	Close()
 }
 
+// Migrator is a Model whose table may have been created by an earlier version
+// of apcore, and so needs to be brought up to date.
+//
+// Migrate must be idempotent, as it is run every time the tables are created
+// and before statements are prepared.
+type Migrator interface {
+	Migrate(*sql.Tx, SqlDialect) error
+}
+
+// MigrateTables brings the tables of all Models that are also Migrators up to
+// date.
+func MigrateTables(t *sql.Tx, s SqlDialect, ms []Model) error {
+	for _, m := range ms {
+		if mg, ok := m.(Migrator); ok {
+			if err := mg.Migrate(t, s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // stmtPair make a pair of **sql.Stmt and its associated SQL string.
 //
 // The goal is to populate *stmt based on the associated sqlStr.
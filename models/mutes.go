@@ -0,0 +1,130 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql"
+	"net/url"
+	"time"
+
+	"github.com/go-fed/apcore/util"
+)
+
+var _ Model = &Mutes{}
+
+// MuteKind is the kind of thing being muted.
+type MuteKind string
+
+const (
+	// ActorMute mutes a single actor, identified by its IRI.
+	ActorMute MuteKind = "actor"
+	// DomainMute mutes every actor on a domain, identified by its host.
+	DomainMute MuteKind = "domain"
+)
+
+// Mute is an actor's mute of another actor or of a domain.
+type Mute struct {
+	ID      string
+	Created time.Time
+	ActorID string
+	Kind    MuteKind
+	Target  string
+	// Expires is not valid if the mute does not expire.
+	Expires sql.NullTime
+}
+
+// Mutes is a Model that provides additional database methods for the actors
+// and domains that an actor has muted.
+type Mutes struct {
+	insert         *sql.Stmt
+	deleteMute     *sql.Stmt
+	deleteExpired  *sql.Stmt
+	getActiveMutes *sql.Stmt
+}
+
+func (m *Mutes) Prepare(db *sql.DB, s SqlDialect) error {
+	return prepareStmtPairs(db,
+		stmtPairs{
+			{&(m.insert), s.InsertMute()},
+			{&(m.deleteMute), s.DeleteMute()},
+			{&(m.deleteExpired), s.DeleteExpiredMutes()},
+			{&(m.getActiveMutes), s.GetActiveMutesForActor()},
+		})
+}
+
+func (m *Mutes) CreateTable(t *sql.Tx, s SqlDialect) error {
+	_, err := t.Exec(s.CreateMutesTable())
+	return err
+}
+
+func (m *Mutes) Close() {
+	m.insert.Close()
+	m.deleteMute.Close()
+	m.deleteExpired.Close()
+	m.getActiveMutes.Close()
+}
+
+// Create mutes the target for the actor. Muting a target that is already
+// muted replaces its expiry.
+func (m *Mutes) Create(c util.Context, tx *sql.Tx, actor *url.URL, kind MuteKind, target string, expires sql.NullTime) error {
+	r, err := tx.Stmt(m.insert).ExecContext(c,
+		actor.String(),
+		kind,
+		target,
+		expires)
+	return mustChangeOneRow(r, err, "Mutes.Create")
+}
+
+// Delete unmutes the target for the actor. Nothing happens if the target is
+// not muted.
+func (m *Mutes) Delete(c util.Context, tx *sql.Tx, actor *url.URL, kind MuteKind, target string) error {
+	_, err := tx.Stmt(m.deleteMute).ExecContext(c,
+		actor.String(),
+		kind,
+		target)
+	return err
+}
+
+// DeleteExpired removes all mutes that have expired.
+func (m *Mutes) DeleteExpired(c util.Context, tx *sql.Tx) error {
+	_, err := tx.Stmt(m.deleteExpired).ExecContext(c)
+	return err
+}
+
+// GetActiveForActor fetches the actor's mutes that have not expired, most
+// recent first.
+func (m *Mutes) GetActiveForActor(c util.Context, tx *sql.Tx, actor *url.URL) (ms []Mute, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(m.getActiveMutes).QueryContext(c, actor.String())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return ms, doForRows(rows, "Mutes.GetActiveForActor", func(r SingleRow) error {
+		var mu Mute
+		if err := r.Scan(&(mu.ID),
+			&(mu.Created),
+			&(mu.ActorID),
+			&(mu.Kind),
+			&(mu.Target),
+			&(mu.Expires)); err != nil {
+			return err
+		}
+		ms = append(ms, mu)
+		return nil
+	})
+}
@@ -0,0 +1,129 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql"
+	"net/url"
+
+	"github.com/go-fed/apcore/util"
+)
+
+var _ Model = &ObjectCollections{}
+
+// ObjectCollections is a Model that provides additional database methods for
+// the replies, likes, and shares collections of local objects.
+type ObjectCollections struct {
+	insert      *sql.Stmt
+	contains    *sql.Stmt
+	get         *sql.Stmt
+	getLastPage *sql.Stmt
+	prependItem *sql.Stmt
+	deleteItem  *sql.Stmt
+}
+
+func (o *ObjectCollections) Prepare(db *sql.DB, s SqlDialect) error {
+	return prepareStmtPairs(db,
+		stmtPairs{
+			{&(o.insert), s.InsertObjectCollection()},
+			{&(o.contains), s.ObjectCollectionContains()},
+			{&(o.get), s.GetObjectCollection()},
+			{&(o.getLastPage), s.GetObjectCollectionLastPage()},
+			{&(o.prependItem), s.PrependObjectCollectionItem()},
+			{&(o.deleteItem), s.DeleteObjectCollectionItem()},
+		})
+}
+
+func (o *ObjectCollections) CreateTable(t *sql.Tx, s SqlDialect) error {
+	if _, err := t.Exec(s.CreateObjectCollectionsTable()); err != nil {
+		return err
+	}
+	_, err := t.Exec(s.CreateIndexIDObjectCollectionsTable())
+	return err
+}
+
+func (o *ObjectCollections) Close() {
+	o.insert.Close()
+	o.contains.Close()
+	o.get.Close()
+	o.getLastPage.Close()
+	o.prependItem.Close()
+	o.deleteItem.Close()
+}
+
+// Create a new collection of the given kind for the object. Nothing happens if
+// the object already has a collection of that kind.
+func (o *ObjectCollections) Create(c util.Context, tx *sql.Tx, object *url.URL, kind string, col ActivityStreamsCollection) error {
+	_, err := tx.Stmt(o.insert).ExecContext(c,
+		object.String(),
+		kind,
+		col)
+	return err
+}
+
+// Contains returns true if the item is in the collection.
+func (o *ObjectCollections) Contains(c util.Context, tx *sql.Tx, col, item *url.URL) (b bool, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(o.contains).QueryContext(c, col.String(), item.String())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return b, enforceOneRow(rows, "ObjectCollections.Contains", func(r SingleRow) error {
+		return r.Scan(&b)
+	})
+}
+
+// GetPage returns a CollectionPage of the collection.
+//
+// The range of elements retrieved are [min, max).
+func (o *ObjectCollections) GetPage(c util.Context, tx *sql.Tx, col *url.URL, min, max int) (page ActivityStreamsCollectionPage, isEnd bool, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(o.get).QueryContext(c, col.String(), min, max-1)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return page, isEnd, enforceOneRow(rows, "ObjectCollections.GetPage", func(r SingleRow) error {
+		return r.Scan(&page, &isEnd)
+	})
+}
+
+// GetLastPage returns the last CollectionPage of the collection.
+func (o *ObjectCollections) GetLastPage(c util.Context, tx *sql.Tx, col *url.URL, n int) (page ActivityStreamsCollectionPage, startIdx int, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(o.getLastPage).QueryContext(c, col.String(), n)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return page, startIdx, enforceOneRow(rows, "ObjectCollections.GetLastPage", func(r SingleRow) error {
+		return r.Scan(&page, &startIdx)
+	})
+}
+
+// PrependItem prepends the item to the collection's items list.
+func (o *ObjectCollections) PrependItem(c util.Context, tx *sql.Tx, col, item *url.URL) error {
+	r, err := tx.Stmt(o.prependItem).ExecContext(c, col.String(), item.String())
+	return mustChangeOneRow(r, err, "ObjectCollections.PrependItem")
+}
+
+// DeleteItem removes the item from the collection's items list.
+func (o *ObjectCollections) DeleteItem(c util.Context, tx *sql.Tx, col, item *url.URL) error {
+	r, err := tx.Stmt(o.deleteItem).ExecContext(c, col.String(), item.String())
+	return mustChangeOneRow(r, err, "ObjectCollections.DeleteItem")
+}
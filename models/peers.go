@@ -0,0 +1,180 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/go-fed/apcore/util"
+)
+
+var _ Model = &Peers{}
+
+// Peer is a remote host this server has exchanged traffic with.
+type Peer struct {
+	ID                  string
+	Host                string
+	FirstSeen           time.Time
+	LastSeen            time.Time
+	SoftwareName        sql.NullString
+	SoftwareVersion     sql.NullString
+	NodeInfoFetched     sql.NullTime
+	NDeliverySuccesses  int
+	NDeliveryFailures   int
+	LastDeliverySuccess sql.NullTime
+	LastDeliveryFailure sql.NullTime
+}
+
+// Peers is a Model that provides additional database methods for remote
+// hosts that this server federates with.
+type Peers struct {
+	peerSeen            *sql.Stmt
+	markDeliverySuccess *sql.Stmt
+	markDeliveryFailure *sql.Stmt
+	updateSoftware      *sql.Stmt
+	getPeer             *sql.Stmt
+	getPeers            *sql.Stmt
+	getStalePeers       *sql.Stmt
+}
+
+func (p *Peers) Prepare(db *sql.DB, s SqlDialect) error {
+	return prepareStmtPairs(db,
+		stmtPairs{
+			{&(p.peerSeen), s.PeerSeen()},
+			{&(p.markDeliverySuccess), s.MarkPeerDeliverySuccess()},
+			{&(p.markDeliveryFailure), s.MarkPeerDeliveryFailure()},
+			{&(p.updateSoftware), s.UpdatePeerSoftware()},
+			{&(p.getPeer), s.GetPeer()},
+			{&(p.getPeers), s.GetPeers()},
+			{&(p.getStalePeers), s.GetPeersWithStaleNodeInfo()},
+		})
+}
+
+func (p *Peers) CreateTable(t *sql.Tx, s SqlDialect) error {
+	_, err := t.Exec(s.CreatePeersTable())
+	return err
+}
+
+func (p *Peers) Close() {
+	p.peerSeen.Close()
+	p.markDeliverySuccess.Close()
+	p.markDeliveryFailure.Close()
+	p.updateSoftware.Close()
+	p.getPeer.Close()
+	p.getPeers.Close()
+	p.getStalePeers.Close()
+}
+
+// Seen records traffic with a host, creating it if it is not yet known. It
+// returns when the host's NodeInfo was last fetched, which is not valid if it
+// has never been fetched.
+func (p *Peers) Seen(c util.Context, tx *sql.Tx, host string) (fetched sql.NullTime, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(p.peerSeen).QueryContext(c, host)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return fetched, enforceOneRow(rows, "Peers.Seen", func(r SingleRow) error {
+		return r.Scan(&(fetched))
+	})
+}
+
+// MarkDeliverySuccess records a successful delivery to a host.
+func (p *Peers) MarkDeliverySuccess(c util.Context, tx *sql.Tx, host string) error {
+	r, err := tx.Stmt(p.markDeliverySuccess).ExecContext(c, host)
+	return mustChangeOneRow(r, err, "Peers.MarkDeliverySuccess")
+}
+
+// MarkDeliveryFailure records a failed delivery to a host.
+func (p *Peers) MarkDeliveryFailure(c util.Context, tx *sql.Tx, host string) error {
+	r, err := tx.Stmt(p.markDeliveryFailure).ExecContext(c, host)
+	return mustChangeOneRow(r, err, "Peers.MarkDeliveryFailure")
+}
+
+// UpdateSoftware sets the software name and version that a host reported in
+// its NodeInfo, and marks the NodeInfo as fetched. Null values retain the
+// previously known software, which is useful when a fetch fails.
+func (p *Peers) UpdateSoftware(c util.Context, tx *sql.Tx, host string, name, version sql.NullString) error {
+	r, err := tx.Stmt(p.updateSoftware).ExecContext(c, host, name, version)
+	return mustChangeOneRow(r, err, "Peers.UpdateSoftware")
+}
+
+// Get fetches a single peer by its host.
+func (p *Peers) Get(c util.Context, tx *sql.Tx, host string) (pr Peer, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(p.getPeer).QueryContext(c, host)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return pr, enforceOneRow(rows, "Peers.Get", func(r SingleRow) error {
+		return scanPeer(r, &pr)
+	})
+}
+
+// GetAll fetches all known peers.
+func (p *Peers) GetAll(c util.Context, tx *sql.Tx) (ps []Peer, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(p.getPeers).QueryContext(c)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return ps, doForRows(rows, "Peers.GetAll", func(r SingleRow) error {
+		var pr Peer
+		if err := scanPeer(r, &pr); err != nil {
+			return err
+		}
+		ps = append(ps, pr)
+		return nil
+	})
+}
+
+// GetStale fetches the hosts whose NodeInfo has never been fetched, or was
+// last fetched before the given time.
+func (p *Peers) GetStale(c util.Context, tx *sql.Tx, before time.Time) (hosts []string, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(p.getStalePeers).QueryContext(c, before)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return hosts, doForRows(rows, "Peers.GetStale", func(r SingleRow) error {
+		var h string
+		if err := r.Scan(&h); err != nil {
+			return err
+		}
+		hosts = append(hosts, h)
+		return nil
+	})
+}
+
+func scanPeer(r SingleRow, pr *Peer) error {
+	return r.Scan(&(pr.ID),
+		&(pr.Host),
+		&(pr.FirstSeen),
+		&(pr.LastSeen),
+		&(pr.SoftwareName),
+		&(pr.SoftwareVersion),
+		&(pr.NodeInfoFetched),
+		&(pr.NDeliverySuccesses),
+		&(pr.NDeliveryFailures),
+		&(pr.LastDeliverySuccess),
+		&(pr.LastDeliveryFailure))
+}
@@ -24,7 +24,9 @@ import (
 	"fmt"
 	"net/url"
 	"reflect"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/go-fed/apcore/util"
 	"github.com/tidwall/gjson"
@@ -32,10 +34,37 @@ import (
 
 const (
 	FederatedBlockPurpose Purpose = "federated_block"
+	// The following purposes rewrite matching incoming activities instead
+	// of rejecting them.
+	ForceSensitivePurpose Purpose = "force_sensitive"
+	StripMediaPurpose     Purpose = "strip_media"
+	DropPublicPurpose     Purpose = "drop_public"
+	DelistPurpose         Purpose = "delist"
+	// QuarantinePurpose holds matching incoming activities for moderators
+	// to approve or reject.
+	QuarantinePurpose Purpose = "quarantine"
 )
 
+// RewritePurposes are the purposes that rewrite incoming activities, in the
+// order they are applied.
+var RewritePurposes = []Purpose{
+	ForceSensitivePurpose,
+	StripMediaPurpose,
+	DropPublicPurpose,
+	DelistPurpose,
+}
+
 type Purpose string
 
+const (
+	InstanceScope Scope = "instance"
+	UserScope     Scope = "user"
+)
+
+// Scope is whether a resolved policy is owned by the instance actor, applying
+// to every local recipient, or by the recipient themselves.
+type Scope string
+
 var _ driver.Valuer = Policy{}
 var _ sql.Scanner = &Policy{}
 
@@ -65,6 +94,19 @@ func (p Policy) Validate() error {
 	return nil
 }
 
+// Compile validates the policy and prepares it to be resolved repeatedly,
+// compiling its regular expressions and parsing its durations and sets once
+// instead of on every match.
+func (p *Policy) Compile() error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	for _, m := range p.Matchers {
+		m.ValueMatcher.compile()
+	}
+	return nil
+}
+
 func (p Policy) Resolve(json []byte, r *Resolution) error {
 	r.Logf("applying policy %q", p.Name)
 	var err error
@@ -101,10 +143,15 @@ func (k KVMatcher) Resolve(json []byte, r *Resolution) (err error) {
 		r.Logf("resolution already found match, skipping examining %q", k.KeyPathQuery)
 		return
 	}
+	r.Matched, err = k.Match(json, r)
+	return
+}
+
+// Match applies the ValueMatcher to the value at the KeyPathQuery.
+func (k KVMatcher) Match(json []byte, r *Resolution) (bool, error) {
 	r.Logf("examining value of %q", k.KeyPathQuery)
 	result := gjson.GetBytes(json, k.KeyPathQuery)
-	r.Matched, err = k.ValueMatcher.Match(result, json, r)
-	return
+	return k.ValueMatcher.Match(result, json, r)
 }
 
 type UnaryMatcher struct {
@@ -113,6 +160,9 @@ type UnaryMatcher struct {
 	Or    *BinaryMatcher `json:"or,omitempty"`
 	Value *Value         `json:"value,omitempty"`
 	Empty bool           `json:"empty,omitempty"`
+	// At matches the value at another key path of the data, so that one
+	// matcher can combine conditions on several properties.
+	At *KVMatcher `json:"at,omitempty"`
 }
 
 func (u UnaryMatcher) Validate() error {
@@ -132,14 +182,46 @@ func (u UnaryMatcher) Validate() error {
 	if u.Empty {
 		n++
 	}
+	if u.At != nil {
+		n++
+	}
 	if n > 1 {
 		return errors.New("unary matcher has >1 field set")
 	} else if n == 0 {
 		return errors.New("unary matcher has no fields set")
 	}
+	switch {
+	case u.Not != nil:
+		return u.Not.Validate()
+	case u.And != nil:
+		return u.And.Validate()
+	case u.Or != nil:
+		return u.Or.Validate()
+	case u.Value != nil:
+		return u.Value.Validate()
+	case u.At != nil:
+		return u.At.Validate()
+	}
 	return nil
 }
 
+func (u *UnaryMatcher) compile() {
+	switch {
+	case u.Not != nil:
+		u.Not.compile()
+	case u.And != nil:
+		u.And.L.compile()
+		u.And.R.compile()
+	case u.Or != nil:
+		u.Or.L.compile()
+		u.Or.R.compile()
+	case u.At != nil:
+		u.At.ValueMatcher.compile()
+	case u.Value != nil:
+		u.Value.compile()
+	}
+}
+
 func (u UnaryMatcher) Match(res gjson.Result, json []byte, r *Resolution) (bool, error) {
 	if u.Not != nil {
 		in, err := u.Not.Match(res, json, r)
@@ -172,6 +254,9 @@ func (u UnaryMatcher) Match(res gjson.Result, json []byte, r *Resolution) (bool,
 		v := !res.Exists()
 		r.Logf("apply EMPTY=>%v", v)
 		return v, nil
+	} else if u.At != nil {
+		v, err := u.At.Match(json, r)
+		return v, err
 	}
 	r.Log("error: Match called with invalid UnaryMatcher")
 	return false, errors.New("Match called with invalid UnaryMatcher")
@@ -212,6 +297,44 @@ type Value struct {
 	LenEquals      *int   `json:"lenEquals,omitempty"`
 	LenGreater     *int   `json:"lenGreater,omitempty"`
 	LenLess        *int   `json:"lenLess,omitempty"`
+	// MatchesRegexp is a regular expression in RE2 syntax.
+	MatchesRegexp string `json:"matchesRegexp,omitempty"`
+	// EqualsStringFold and ContainsStringFold compare case-insensitively.
+	EqualsStringFold   string `json:"equalsStringFold,omitempty"`
+	ContainsStringFold string `json:"containsStringFold,omitempty"`
+	// NumberGreater and NumberLess compare the value as a number.
+	NumberGreater *float64 `json:"numberGreater,omitempty"`
+	NumberLess    *float64 `json:"numberLess,omitempty"`
+	// NewerThan and OlderThan compare the value as an RFC 3339 date
+	// against the time of the Resolution. They are durations such as
+	// "24h", so that a date within the last day is NewerThan "24h".
+	NewerThan string `json:"newerThan,omitempty"`
+	OlderThan string `json:"olderThan,omitempty"`
+	// HostEquals compares the host of the value as an IRI,
+	// case-insensitively.
+	HostEquals string `json:"hostEquals,omitempty"`
+	// In is the set of strings the value must be one of.
+	In []string `json:"in,omitempty"`
+
+	// The following are set by compiling a validated Value.
+	compiled  bool
+	re        *regexp.Regexp
+	newerThan time.Duration
+	olderThan time.Duration
+	in        map[string]bool
+}
+
+func (u *Value) compile() {
+	u.re, _ = regexp.Compile(u.MatchesRegexp)
+	u.newerThan, _ = time.ParseDuration(u.NewerThan)
+	u.olderThan, _ = time.ParseDuration(u.OlderThan)
+	if len(u.In) > 0 {
+		u.in = make(map[string]bool, len(u.In))
+		for _, s := range u.In {
+			u.in[s] = true
+		}
+	}
+	u.compiled = true
 }
 
 func (u Value) Validate() error {
@@ -234,6 +357,42 @@ func (u Value) Validate() error {
 	if u.LenLess != nil {
 		n++
 	}
+	if len(u.MatchesRegexp) > 0 {
+		n++
+		if _, err := regexp.Compile(u.MatchesRegexp); err != nil {
+			return fmt.Errorf("invalid matchesRegexp: %w", err)
+		}
+	}
+	if len(u.EqualsStringFold) > 0 {
+		n++
+	}
+	if len(u.ContainsStringFold) > 0 {
+		n++
+	}
+	if u.NumberGreater != nil {
+		n++
+	}
+	if u.NumberLess != nil {
+		n++
+	}
+	if len(u.NewerThan) > 0 {
+		n++
+		if _, err := time.ParseDuration(u.NewerThan); err != nil {
+			return fmt.Errorf("invalid newerThan: %w", err)
+		}
+	}
+	if len(u.OlderThan) > 0 {
+		n++
+		if _, err := time.ParseDuration(u.OlderThan); err != nil {
+			return fmt.Errorf("invalid olderThan: %w", err)
+		}
+	}
+	if len(u.HostEquals) > 0 {
+		n++
+	}
+	if len(u.In) > 0 {
+		n++
+	}
 	if n > 1 {
 		return errors.New("value has >1 field set")
 	} else if n == 0 {
@@ -271,6 +430,80 @@ func (u Value) Match(res gjson.Result, json []byte, r *Resolution) (bool, error)
 		v := l < *u.LenLess
 		r.Logf("apply LESS(LEN(), %d)=>%v", *u.LenLess, v)
 		return v, nil
+	} else if len(u.MatchesRegexp) > 0 {
+		re := u.re
+		if !u.compiled {
+			var err error
+			if re, err = regexp.Compile(u.MatchesRegexp); err != nil {
+				r.Logf("error: invalid regexp %s: %s", u.MatchesRegexp, err)
+				return false, err
+			}
+		}
+		v := re.MatchString(res.String())
+		r.Logf("apply MATCHES(%s)=>%v", u.MatchesRegexp, v)
+		return v, nil
+	} else if len(u.EqualsStringFold) > 0 {
+		v := strings.EqualFold(res.String(), u.EqualsStringFold)
+		r.Logf("apply EQUALS(FOLD(), FOLD(%s))=>%v", u.EqualsStringFold, v)
+		return v, nil
+	} else if len(u.ContainsStringFold) > 0 {
+		v := strings.Contains(strings.ToLower(res.String()), strings.ToLower(u.ContainsStringFold))
+		r.Logf("apply CONTAINS(FOLD(), FOLD(%s))=>%v", u.ContainsStringFold, v)
+		return v, nil
+	} else if u.NumberGreater != nil {
+		v := res.Type == gjson.Number && res.Float() > *u.NumberGreater
+		r.Logf("apply GREATER(NUMBER(), %g)=>%v", *u.NumberGreater, v)
+		return v, nil
+	} else if u.NumberLess != nil {
+		v := res.Type == gjson.Number && res.Float() < *u.NumberLess
+		r.Logf("apply LESS(NUMBER(), %g)=>%v", *u.NumberLess, v)
+		return v, nil
+	} else if len(u.NewerThan) > 0 {
+		d := u.newerThan
+		if !u.compiled {
+			var err error
+			if d, err = time.ParseDuration(u.NewerThan); err != nil {
+				r.Logf("error: invalid duration %s: %s", u.NewerThan, err)
+				return false, err
+			}
+		}
+		t, ok := resultTime(res)
+		v := ok && t.After(r.Time.Add(-d))
+		r.Logf("apply GREATER(DATE(), %s)=>%v", r.Time.Add(-d).Format(time.RFC3339), v)
+		return v, nil
+	} else if len(u.OlderThan) > 0 {
+		d := u.olderThan
+		if !u.compiled {
+			var err error
+			if d, err = time.ParseDuration(u.OlderThan); err != nil {
+				r.Logf("error: invalid duration %s: %s", u.OlderThan, err)
+				return false, err
+			}
+		}
+		t, ok := resultTime(res)
+		v := ok && t.Before(r.Time.Add(-d))
+		r.Logf("apply LESS(DATE(), %s)=>%v", r.Time.Add(-d).Format(time.RFC3339), v)
+		return v, nil
+	} else if len(u.HostEquals) > 0 {
+		iri, err := url.Parse(res.String())
+		v := err == nil && strings.EqualFold(iri.Hostname(), u.HostEquals)
+		r.Logf("apply EQUALS(HOST(), %s)=>%v", u.HostEquals, v)
+		return v, nil
+	} else if len(u.In) > 0 {
+		v := false
+		s := res.String()
+		if u.compiled {
+			v = u.in[s]
+		} else {
+			for _, in := range u.In {
+				if s == in {
+					v = true
+					break
+				}
+			}
+		}
+		r.Logf("apply IN(%s)=>%v", strings.Join(u.In, ", "), v)
+		return v, nil
 	}
 	r.Log("error: Match called with invalid Value")
 	return false, errors.New("Match called with invalid Value")
@@ -280,6 +513,16 @@ func resultsEqual(lhs, rhs gjson.Result) bool {
 	return reflect.DeepEqual(lhs.Value(), rhs.Value())
 }
 
+// resultTime parses the result as an RFC 3339 date.
+func resultTime(r gjson.Result) (t time.Time, ok bool) {
+	if r.Type != gjson.String {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, r.String())
+	ok = err == nil
+	return
+}
+
 func resultsLen(r gjson.Result) int {
 	l := 0
 	if r.Exists() {
@@ -295,20 +538,26 @@ type CreatePolicy struct {
 	ActorID *url.URL
 	Purpose Purpose
 	Policy  Policy
+	Enabled bool
+	Monitor bool
 }
 
 type PolicyAndPurpose struct {
 	ID      string
 	Purpose Purpose
 	Policy  Policy
+	Enabled bool
+	Monitor bool
 }
 
 type PolicyAndID struct {
-	ID     string
-	Policy Policy
+	ID      string
+	Policy  Policy
+	Monitor bool
 }
 
 var _ Model = &Policies{}
+var _ Migrator = &Policies{}
 
 // Policies is a Model that provides additional database methods for the
 // Policy type.
@@ -316,6 +565,11 @@ type Policies struct {
 	create                *sql.Stmt
 	getForActor           *sql.Stmt
 	getForActorAndPurpose *sql.Stmt
+	get                   *sql.Stmt
+	update                *sql.Stmt
+	setEnabled            *sql.Stmt
+	setMonitor            *sql.Stmt
+	deletePolicy          *sql.Stmt
 }
 
 func (p *Policies) Prepare(db *sql.DB, s SqlDialect) error {
@@ -324,6 +578,11 @@ func (p *Policies) Prepare(db *sql.DB, s SqlDialect) error {
 			{&(p.create), s.CreatePolicy()},
 			{&(p.getForActor), s.GetPoliciesForActor()},
 			{&(p.getForActorAndPurpose), s.GetPoliciesForActorAndPurpose()},
+			{&(p.get), s.GetPolicyForActor()},
+			{&(p.update), s.UpdatePolicy()},
+			{&(p.setEnabled), s.SetPolicyEnabled()},
+			{&(p.setMonitor), s.SetPolicyMonitor()},
+			{&(p.deletePolicy), s.DeletePolicy()},
 		})
 }
 
@@ -332,10 +591,20 @@ func (p *Policies) CreateTable(t *sql.Tx, s SqlDialect) error {
 	return err
 }
 
+func (p *Policies) Migrate(t *sql.Tx, s SqlDialect) error {
+	_, err := t.Exec(s.MigratePoliciesTable())
+	return err
+}
+
 func (p *Policies) Close() {
 	p.create.Close()
 	p.getForActor.Close()
 	p.getForActorAndPurpose.Close()
+	p.get.Close()
+	p.update.Close()
+	p.setEnabled.Close()
+	p.setMonitor.Close()
+	p.deletePolicy.Close()
 }
 
 // Create a new Policy
@@ -344,7 +613,9 @@ func (p *Policies) Create(c util.Context, tx *sql.Tx, cp CreatePolicy) (policyID
 	rows, err = tx.Stmt(p.create).QueryContext(c,
 		cp.ActorID.String(),
 		cp.Purpose,
-		cp.Policy)
+		cp.Policy,
+		cp.Enabled,
+		cp.Monitor)
 	if err != nil {
 		return
 	}
@@ -364,7 +635,7 @@ func (p *Policies) GetForActor(c util.Context, tx *sql.Tx, actorID *url.URL) (po
 	defer rows.Close()
 	return po, doForRows(rows, "Policies.GetForActor", func(r SingleRow) error {
 		var pp PolicyAndPurpose
-		if err := r.Scan(&(pp.ID), &(pp.Purpose), &(pp.Policy)); err != nil {
+		if err := r.Scan(&(pp.ID), &(pp.Purpose), &(pp.Policy), &(pp.Enabled), &(pp.Monitor)); err != nil {
 			return err
 		}
 		po = append(po, pp)
@@ -372,7 +643,7 @@ func (p *Policies) GetForActor(c util.Context, tx *sql.Tx, actorID *url.URL) (po
 	})
 }
 
-// GetForActorAndPurpose obtains all policies for an Actor and Purpose.
+// GetForActorAndPurpose obtains all enabled policies for an Actor and Purpose.
 func (p *Policies) GetForActorAndPurpose(c util.Context, tx *sql.Tx, actorID *url.URL, u Purpose) (po []PolicyAndID, err error) {
 	var rows *sql.Rows
 	rows, err = tx.Stmt(p.getForActorAndPurpose).QueryContext(c, actorID.String(), u)
@@ -382,10 +653,51 @@ func (p *Policies) GetForActorAndPurpose(c util.Context, tx *sql.Tx, actorID *ur
 	defer rows.Close()
 	return po, doForRows(rows, "Policies.GetForActorAndPurpose", func(r SingleRow) error {
 		var pp PolicyAndID
-		if err := r.Scan(&(pp.ID), &(pp.Policy)); err != nil {
+		if err := r.Scan(&(pp.ID), &(pp.Policy), &(pp.Monitor)); err != nil {
 			return err
 		}
 		po = append(po, pp)
 		return nil
 	})
 }
+
+// Get obtains the Actor's policy, which is nil if the Actor has no policy
+// with the id.
+func (p *Policies) Get(c util.Context, tx *sql.Tx, actorID *url.URL, id string) (po *PolicyAndPurpose, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(p.get).QueryContext(c, id, actorID.String())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return po, enforceOneRow(rows, "Policies.Get", func(r SingleRow) error {
+		po = &PolicyAndPurpose{ID: id}
+		return r.Scan(&(po.Purpose), &(po.Policy), &(po.Enabled), &(po.Monitor))
+	})
+}
+
+// Update replaces the Actor's policy.
+func (p *Policies) Update(c util.Context, tx *sql.Tx, actorID *url.URL, id string, po Policy) error {
+	r, err := tx.Stmt(p.update).ExecContext(c, id, actorID.String(), po)
+	return mustChangeOneRow(r, err, "Policies.Update")
+}
+
+// SetEnabled enables or disables the Actor's policy. Disabled policies are
+// not applied.
+func (p *Policies) SetEnabled(c util.Context, tx *sql.Tx, actorID *url.URL, id string, enabled bool) error {
+	r, err := tx.Stmt(p.setEnabled).ExecContext(c, id, actorID.String(), enabled)
+	return mustChangeOneRow(r, err, "Policies.SetEnabled")
+}
+
+// SetMonitor puts the Actor's policy in or out of monitor-only mode. Policies
+// in monitor-only mode record their resolutions without taking effect.
+func (p *Policies) SetMonitor(c util.Context, tx *sql.Tx, actorID *url.URL, id string, monitor bool) error {
+	r, err := tx.Stmt(p.setMonitor).ExecContext(c, id, actorID.String(), monitor)
+	return mustChangeOneRow(r, err, "Policies.SetMonitor")
+}
+
+// Delete removes the Actor's policy along with its resolutions.
+func (p *Policies) Delete(c util.Context, tx *sql.Tx, actorID *url.URL, id string) error {
+	r, err := tx.Stmt(p.deletePolicy).ExecContext(c, id, actorID.String())
+	return mustChangeOneRow(r, err, "Policies.Delete")
+}
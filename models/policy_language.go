@@ -0,0 +1,722 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PolicyDefinition is a policy along with its purpose and state, as written
+// in the policy language.
+//
+// The policy language describes a set of policies in plain text:
+//
+//	// Comments run to the end of the line.
+//	policy "No spam" federated_block {
+//		description "Blocks spam from untrusted servers."
+//		monitor
+//		match object.content icontains "spam" and not actor matches /trusted\.example/
+//		match actor host "spam.example"
+//	}
+//
+// A policy may be marked "disabled" or "monitor". Each "match" is a
+// KVMatcher, and a policy matches if any of them do. A match expression
+// combines predicates with "not", "and", "or", and parentheses. Each predicate
+// begins with a GJSON key path, quoted if it has characters other than
+// letters, digits, and "_.#@*?-+:", followed by one of:
+//
+//	is empty
+//	equals "s"
+//	equals path "other.path"
+//	contains "s"
+//	iequals "s"
+//	icontains "s"
+//	matches /regexp/
+//	length == 1, length > 1, length < 1
+//	> 1.5, < 1.5
+//	newer than 24h, older than 24h
+//	host "example.com"
+//	in ["a", "b"]
+type PolicyDefinition struct {
+	Purpose Purpose
+	Enabled bool
+	Monitor bool
+	Policy  Policy
+}
+
+// ParsePolicies parses the policies written in the policy language.
+func ParsePolicies(src string) (pd []PolicyDefinition, err error) {
+	p := &policyParser{l: policyLexer{src: src, line: 1, col: 1}}
+	if err = p.next(); err != nil {
+		return
+	}
+	for p.tok.kind != tokEOF {
+		var d PolicyDefinition
+		d, err = p.parsePolicy()
+		if err != nil {
+			return
+		}
+		pd = append(pd, d)
+	}
+	return
+}
+
+// FormatPolicies writes the policies in the policy language, such that
+// parsing the result yields equivalent policies.
+func FormatPolicies(pd []PolicyDefinition) string {
+	var b strings.Builder
+	for i, d := range pd {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "policy %s %s {\n", strconv.Quote(d.Policy.Name), formatPath(string(d.Purpose)))
+		if len(d.Policy.Description) > 0 {
+			fmt.Fprintf(&b, "\tdescription %s\n", strconv.Quote(d.Policy.Description))
+		}
+		if !d.Enabled {
+			b.WriteString("\tdisabled\n")
+		}
+		if d.Monitor {
+			b.WriteString("\tmonitor\n")
+		}
+		for _, m := range d.Policy.Matchers {
+			b.WriteString("\tmatch ")
+			formatUnary(&b, m.ValueMatcher, m.KeyPathQuery, precOr)
+			b.WriteString("\n")
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// Operator precedence when formatting, from loosest to tightest binding.
+const (
+	precOr = iota
+	precAnd
+	precNot
+)
+
+func formatUnary(b *strings.Builder, u *UnaryMatcher, path string, prec int) {
+	switch {
+	case u.Not != nil:
+		b.WriteString("not ")
+		formatUnary(b, u.Not, path, precNot)
+	case u.And != nil:
+		formatBinary(b, u.And, "and", path, prec, precAnd)
+	case u.Or != nil:
+		formatBinary(b, u.Or, "or", path, prec, precOr)
+	case u.At != nil:
+		formatUnary(b, u.At.ValueMatcher, u.At.KeyPathQuery, prec)
+	case u.Empty:
+		fmt.Fprintf(b, "%s is empty", formatPath(path))
+	case u.Value != nil:
+		b.WriteString(formatPath(path))
+		b.WriteString(" ")
+		formatValue(b, u.Value)
+	}
+}
+
+func formatBinary(b *strings.Builder, m *BinaryMatcher, op, path string, prec, opPrec int) {
+	if prec > opPrec {
+		b.WriteString("(")
+	}
+	// Operators associate to the left, so a right operand of the same
+	// precedence must be parenthesized.
+	formatUnary(b, m.L, path, opPrec)
+	fmt.Fprintf(b, " %s ", op)
+	formatUnary(b, m.R, path, opPrec+1)
+	if prec > opPrec {
+		b.WriteString(")")
+	}
+}
+
+func formatValue(b *strings.Builder, v *Value) {
+	switch {
+	case len(v.JSONPath) > 0:
+		fmt.Fprintf(b, "equals path %s", strconv.Quote(v.JSONPath))
+	case len(v.EqualsString) > 0:
+		fmt.Fprintf(b, "equals %s", strconv.Quote(v.EqualsString))
+	case len(v.ContainsString) > 0:
+		fmt.Fprintf(b, "contains %s", strconv.Quote(v.ContainsString))
+	case v.LenEquals != nil:
+		fmt.Fprintf(b, "length == %d", *v.LenEquals)
+	case v.LenGreater != nil:
+		fmt.Fprintf(b, "length > %d", *v.LenGreater)
+	case v.LenLess != nil:
+		fmt.Fprintf(b, "length < %d", *v.LenLess)
+	case len(v.MatchesRegexp) > 0:
+		fmt.Fprintf(b, "matches %s", formatRegexp(v.MatchesRegexp))
+	case len(v.EqualsStringFold) > 0:
+		fmt.Fprintf(b, "iequals %s", strconv.Quote(v.EqualsStringFold))
+	case len(v.ContainsStringFold) > 0:
+		fmt.Fprintf(b, "icontains %s", strconv.Quote(v.ContainsStringFold))
+	case v.NumberGreater != nil:
+		fmt.Fprintf(b, "> %s", strconv.FormatFloat(*v.NumberGreater, 'g', -1, 64))
+	case v.NumberLess != nil:
+		fmt.Fprintf(b, "< %s", strconv.FormatFloat(*v.NumberLess, 'g', -1, 64))
+	case len(v.NewerThan) > 0:
+		fmt.Fprintf(b, "newer than %s", formatPath(v.NewerThan))
+	case len(v.OlderThan) > 0:
+		fmt.Fprintf(b, "older than %s", formatPath(v.OlderThan))
+	case len(v.HostEquals) > 0:
+		fmt.Fprintf(b, "host %s", strconv.Quote(v.HostEquals))
+	case len(v.In) > 0:
+		q := make([]string, len(v.In))
+		for i, s := range v.In {
+			q[i] = strconv.Quote(s)
+		}
+		fmt.Fprintf(b, "in [%s]", strings.Join(q, ", "))
+	}
+}
+
+// formatRegexp delimits the regular expression with slashes, escaping any
+// unescaped slash within it.
+func formatRegexp(re string) string {
+	var b strings.Builder
+	b.WriteString("/")
+	for i := 0; i < len(re); i++ {
+		switch re[i] {
+		case '\\':
+			b.WriteByte('\\')
+			if i+1 < len(re) {
+				i++
+				b.WriteByte(re[i])
+			}
+		case '/':
+			b.WriteString("\\/")
+		default:
+			b.WriteByte(re[i])
+		}
+	}
+	b.WriteString("/")
+	return b.String()
+}
+
+// formatPath writes a key path bare when it would be read back as one, and
+// quoted otherwise.
+func formatPath(path string) string {
+	if len(path) == 0 || isPolicyKeyword(path) {
+		return strconv.Quote(path)
+	}
+	for _, r := range path {
+		if !isBareRune(r) {
+			return strconv.Quote(path)
+		}
+	}
+	return path
+}
+
+func isPolicyKeyword(s string) bool {
+	switch s {
+	case "not", "and", "or", "policy", "match", "description", "disabled", "monitor":
+		return true
+	}
+	return false
+}
+
+func isBareRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.#@*?-+:", r)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokRegexp
+	tokSymbol
+)
+
+type token struct {
+	kind      tokenKind
+	text      string
+	line, col int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of input"
+	case tokString:
+		return strconv.Quote(t.text)
+	case tokRegexp:
+		return formatRegexp(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type policyLexer struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+func (l *policyLexer) peek() (rune, int) {
+	if l.pos >= len(l.src) {
+		return 0, 0
+	}
+	return utf8.DecodeRuneInString(l.src[l.pos:])
+}
+
+func (l *policyLexer) advance() rune {
+	r, n := l.peek()
+	l.pos += n
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+func (l *policyLexer) errorf(line, col int, format string, i ...interface{}) error {
+	return fmt.Errorf("policy %d:%d: %s", line, col, fmt.Sprintf(format, i...))
+}
+
+func (l *policyLexer) skipSpaceAndComments() {
+	for l.pos < len(l.src) {
+		r, _ := l.peek()
+		if unicode.IsSpace(r) {
+			l.advance()
+		} else if strings.HasPrefix(l.src[l.pos:], "//") {
+			for l.pos < len(l.src) {
+				if l.advance() == '\n' {
+					break
+				}
+			}
+		} else {
+			return
+		}
+	}
+}
+
+func (l *policyLexer) next() (t token, err error) {
+	l.skipSpaceAndComments()
+	t.line, t.col = l.line, l.col
+	if l.pos >= len(l.src) {
+		t.kind = tokEOF
+		return
+	}
+	r, _ := l.peek()
+	switch {
+	case r == '"':
+		start := l.pos
+		l.advance()
+		for {
+			if l.pos >= len(l.src) {
+				err = l.errorf(t.line, t.col, "unterminated string")
+				return
+			}
+			c := l.advance()
+			if c == '\\' && l.pos < len(l.src) {
+				l.advance()
+			} else if c == '"' {
+				break
+			}
+		}
+		t.kind = tokString
+		t.text, err = strconv.Unquote(l.src[start:l.pos])
+		if err != nil {
+			err = l.errorf(t.line, t.col, "invalid string: %s", err)
+		}
+	case r == '/':
+		l.advance()
+		var b strings.Builder
+		for {
+			if l.pos >= len(l.src) {
+				err = l.errorf(t.line, t.col, "unterminated regular expression")
+				return
+			}
+			c := l.advance()
+			if c == '\\' && l.pos < len(l.src) {
+				if e := l.advance(); e != '/' {
+					b.WriteRune(c)
+					b.WriteRune(e)
+				} else {
+					b.WriteRune(e)
+				}
+			} else if c == '/' {
+				break
+			} else {
+				b.WriteRune(c)
+			}
+		}
+		t.kind = tokRegexp
+		t.text = b.String()
+	case r == '=':
+		l.advance()
+		if c, _ := l.peek(); c != '=' {
+			err = l.errorf(t.line, t.col, "expected \"==\"")
+			return
+		}
+		l.advance()
+		t.kind = tokSymbol
+		t.text = "=="
+	case strings.ContainsRune("(){}[],<>", r):
+		l.advance()
+		t.kind = tokSymbol
+		t.text = string(r)
+	case isBareRune(r):
+		start := l.pos
+		for l.pos < len(l.src) {
+			if c, _ := l.peek(); !isBareRune(c) {
+				break
+			}
+			l.advance()
+		}
+		t.kind = tokWord
+		t.text = l.src[start:l.pos]
+	default:
+		err = l.errorf(t.line, t.col, "unexpected character %q", r)
+	}
+	return
+}
+
+type policyParser struct {
+	l   policyLexer
+	tok token
+}
+
+func (p *policyParser) next() (err error) {
+	p.tok, err = p.l.next()
+	return
+}
+
+func (p *policyParser) errorf(format string, i ...interface{}) error {
+	return p.l.errorf(p.tok.line, p.tok.col, format, i...)
+}
+
+func (p *policyParser) is(kind tokenKind, text string) bool {
+	return p.tok.kind == kind && p.tok.text == text
+}
+
+// expect consumes the word or symbol, or returns an error.
+func (p *policyParser) expect(kind tokenKind, text string) error {
+	if !p.is(kind, text) {
+		return p.errorf("expected %q, found %s", text, p.tok)
+	}
+	return p.next()
+}
+
+// text consumes a token of one of the kinds, returning its text.
+func (p *policyParser) text(what string, kinds ...tokenKind) (s string, err error) {
+	for _, k := range kinds {
+		if p.tok.kind == k {
+			s = p.tok.text
+			err = p.next()
+			return
+		}
+	}
+	err = p.errorf("expected %s, found %s", what, p.tok)
+	return
+}
+
+func (p *policyParser) parsePolicy() (d PolicyDefinition, err error) {
+	d.Enabled = true
+	if err = p.expect(tokWord, "policy"); err != nil {
+		return
+	}
+	if d.Policy.Name, err = p.text("policy name", tokString); err != nil {
+		return
+	}
+	var purpose string
+	if purpose, err = p.text("policy purpose", tokWord, tokString); err != nil {
+		return
+	}
+	d.Purpose = Purpose(purpose)
+	if err = p.expect(tokSymbol, "{"); err != nil {
+		return
+	}
+	for !p.is(tokSymbol, "}") {
+		switch {
+		case p.is(tokWord, "description"):
+			if err = p.next(); err != nil {
+				return
+			}
+			if d.Policy.Description, err = p.text("description", tokString); err != nil {
+				return
+			}
+		case p.is(tokWord, "disabled"):
+			d.Enabled = false
+			err = p.next()
+		case p.is(tokWord, "monitor"):
+			d.Monitor = true
+			err = p.next()
+		case p.is(tokWord, "match"):
+			if err = p.next(); err != nil {
+				return
+			}
+			var m *KVMatcher
+			if m, err = p.parseMatch(); err != nil {
+				return
+			}
+			d.Policy.Matchers = append(d.Policy.Matchers, m)
+		default:
+			err = p.errorf("expected \"description\", \"disabled\", \"monitor\", \"match\", or \"}\", found %s", p.tok)
+		}
+		if err != nil {
+			return
+		}
+	}
+	err = p.next()
+	return
+}
+
+// pathMatcher is a parsed match expression whose predicates each carry their
+// own key path.
+type pathMatcher struct {
+	// op is "not", "and", or "or" with operands l and r, or empty for a
+	// predicate u on the path.
+	op   string
+	l, r *pathMatcher
+	path string
+	u    *UnaryMatcher
+}
+
+// parseMatch parses a match expression into a KVMatcher on the key path of
+// its first predicate. Predicates on other key paths become At matchers.
+func (p *policyParser) parseMatch() (*KVMatcher, error) {
+	pm, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	root := pm.firstPath()
+	return &KVMatcher{
+		KeyPathQuery: root,
+		ValueMatcher: pm.toUnary(root),
+	}, nil
+}
+
+func (pm *pathMatcher) firstPath() string {
+	if len(pm.op) > 0 {
+		return pm.l.firstPath()
+	}
+	return pm.path
+}
+
+func (pm *pathMatcher) toUnary(root string) *UnaryMatcher {
+	switch pm.op {
+	case "not":
+		return &UnaryMatcher{Not: pm.l.toUnary(root)}
+	case "and":
+		return &UnaryMatcher{And: &BinaryMatcher{L: pm.l.toUnary(root), R: pm.r.toUnary(root)}}
+	case "or":
+		return &UnaryMatcher{Or: &BinaryMatcher{L: pm.l.toUnary(root), R: pm.r.toUnary(root)}}
+	}
+	if pm.path == root {
+		return pm.u
+	}
+	return &UnaryMatcher{At: &KVMatcher{KeyPathQuery: pm.path, ValueMatcher: pm.u}}
+}
+
+func (p *policyParser) parseOr() (*pathMatcher, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.is(tokWord, "or") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &pathMatcher{op: "or", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *policyParser) parseAnd() (*pathMatcher, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.is(tokWord, "and") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l = &pathMatcher{op: "and", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *policyParser) parseNot() (*pathMatcher, error) {
+	if p.is(tokWord, "not") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		n, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &pathMatcher{op: "not", l: n}, nil
+	} else if p.is(tokSymbol, "(") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		pm, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return pm, p.expect(tokSymbol, ")")
+	}
+	return p.parsePredicate()
+}
+
+func (p *policyParser) parsePredicate() (pm *pathMatcher, err error) {
+	pm = &pathMatcher{}
+	if pm.path, err = p.text("key path", tokWord, tokString); err != nil {
+		return
+	}
+	v := &Value{}
+	pm.u = &UnaryMatcher{Value: v}
+	op := p.tok
+	if err = p.next(); err != nil {
+		return
+	}
+	switch {
+	case op.kind == tokWord && op.text == "is":
+		if err = p.expect(tokWord, "empty"); err != nil {
+			return
+		}
+		pm.u = &UnaryMatcher{Empty: true}
+	case op.kind == tokWord && op.text == "equals":
+		if p.is(tokWord, "path") {
+			if err = p.next(); err != nil {
+				return
+			}
+			v.JSONPath, err = p.text("key path", tokWord, tokString)
+		} else {
+			v.EqualsString, err = p.text("string", tokString)
+		}
+	case op.kind == tokWord && op.text == "contains":
+		v.ContainsString, err = p.text("string", tokString)
+	case op.kind == tokWord && op.text == "iequals":
+		v.EqualsStringFold, err = p.text("string", tokString)
+	case op.kind == tokWord && op.text == "icontains":
+		v.ContainsStringFold, err = p.text("string", tokString)
+	case op.kind == tokWord && op.text == "matches":
+		v.MatchesRegexp, err = p.text("regular expression", tokRegexp)
+	case op.kind == tokWord && op.text == "host":
+		v.HostEquals, err = p.text("host", tokString, tokWord)
+	case op.kind == tokWord && op.text == "length":
+		cmp := p.tok
+		if err = p.next(); err != nil {
+			return
+		}
+		var n int
+		if n, err = p.parseInt(); err != nil {
+			return
+		}
+		switch {
+		case cmp.kind == tokSymbol && cmp.text == "==":
+			v.LenEquals = &n
+		case cmp.kind == tokSymbol && cmp.text == ">":
+			v.LenGreater = &n
+		case cmp.kind == tokSymbol && cmp.text == "<":
+			v.LenLess = &n
+		default:
+			err = p.l.errorf(cmp.line, cmp.col, "expected \"==\", \">\", or \"<\", found %s", cmp)
+		}
+	case op.kind == tokSymbol && (op.text == ">" || op.text == "<"):
+		var f float64
+		if f, err = p.parseFloat(); err != nil {
+			return
+		}
+		if op.text == ">" {
+			v.NumberGreater = &f
+		} else {
+			v.NumberLess = &f
+		}
+	case op.kind == tokWord && (op.text == "newer" || op.text == "older"):
+		if err = p.expect(tokWord, "than"); err != nil {
+			return
+		}
+		var d string
+		if d, err = p.text("duration", tokWord, tokString); err != nil {
+			return
+		}
+		if op.text == "newer" {
+			v.NewerThan = d
+		} else {
+			v.OlderThan = d
+		}
+	case op.kind == tokWord && op.text == "in":
+		if err = p.expect(tokSymbol, "["); err != nil {
+			return
+		}
+		for !p.is(tokSymbol, "]") {
+			if len(v.In) > 0 {
+				if err = p.expect(tokSymbol, ","); err != nil {
+					return
+				}
+			}
+			var s string
+			if s, err = p.text("string", tokString); err != nil {
+				return
+			}
+			v.In = append(v.In, s)
+		}
+		err = p.next()
+	default:
+		err = p.l.errorf(op.line, op.col, "expected a predicate after key path %q, found %s", pm.path, op)
+	}
+	if err == nil && pm.u.Value != nil {
+		err = v.Validate()
+		if err != nil {
+			err = p.l.errorf(op.line, op.col, "%s", err)
+		}
+	}
+	return
+}
+
+func (p *policyParser) parseInt() (n int, err error) {
+	if p.tok.kind != tokWord {
+		err = p.errorf("expected an integer, found %s", p.tok)
+		return
+	}
+	if n, err = strconv.Atoi(p.tok.text); err != nil {
+		err = p.errorf("expected an integer, found %s", p.tok)
+		return
+	}
+	err = p.next()
+	return
+}
+
+func (p *policyParser) parseFloat() (f float64, err error) {
+	if p.tok.kind != tokWord {
+		err = p.errorf("expected a number, found %s", p.tok)
+		return
+	}
+	if f, err = strconv.ParseFloat(p.tok.text, 64); err != nil {
+		err = p.errorf("expected a number, found %s", p.tok)
+		return
+	}
+	err = p.next()
+	return
+}
@@ -18,26 +18,33 @@ package models
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
 
 	"github.com/go-fed/apcore/util"
 )
 
 var _ Model = &PrivateKeys{}
+var _ Migrator = &PrivateKeys{}
 
 // PrivateKeys is a Model that provides additional database methods for the
 // PrivateKey type.
 type PrivateKeys struct {
 	createPrivateKey *sql.Stmt
+	createIfNotExist *sql.Stmt
 	getByUserID      *sql.Stmt
 	getInstanceActor *sql.Stmt
+	deleteForDeleted *sql.Stmt
 }
 
 func (p *PrivateKeys) Prepare(db *sql.DB, s SqlDialect) error {
 	return prepareStmtPairs(db,
 		stmtPairs{
 			{&(p.createPrivateKey), s.CreatePrivateKey()},
+			{&(p.createIfNotExist), s.CreatePrivateKeyIfNotExists()},
 			{&(p.getByUserID), s.GetPrivateKeyByUserID()},
 			{&(p.getInstanceActor), s.GetPrivateKeyForInstanceActor()},
+			{&(p.deleteForDeleted), s.DeletePrivateKeysForDeletedUsers()},
 		})
 }
 
@@ -46,10 +53,40 @@ func (p *PrivateKeys) CreateTable(t *sql.Tx, s SqlDialect) error {
 	return err
 }
 
+// Migrate keeps one key per user and purpose, so that the unique index on
+// them can be created.
+// Migrate adds the unique index on a user's keys per purpose. Rather than
+// guess which duplicate key a user's actor advertises, it fails if any exist
+// so the admin can resolve them.
+func (p *PrivateKeys) Migrate(t *sql.Tx, s SqlDialect) error {
+	r, err := t.Query(s.DuplicatePrivateKeys())
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	var dups []string
+	if err := doForRows(r, "PrivateKeys.Migrate", func(r SingleRow) error {
+		var userID, purpose string
+		if err := r.Scan(&userID, &purpose); err != nil {
+			return err
+		}
+		dups = append(dups, fmt.Sprintf("user %s (%s)", userID, purpose))
+		return nil
+	}); err != nil {
+		return err
+	} else if len(dups) > 0 {
+		return fmt.Errorf("cannot migrate private_keys: more than one key exists for %s; delete all but the key matching each actor's publicKey and restart", strings.Join(dups, ", "))
+	}
+	_, err = t.Exec(s.CreateIndexUserIDPurposePrivateKeysTable())
+	return err
+}
+
 func (p *PrivateKeys) Close() {
 	p.createPrivateKey.Close()
+	p.createIfNotExist.Close()
 	p.getByUserID.Close()
 	p.getInstanceActor.Close()
+	p.deleteForDeleted.Close()
 }
 
 // Create a new private key entry in the database.
@@ -58,6 +95,13 @@ func (p *PrivateKeys) Create(c util.Context, tx *sql.Tx, userID, purpose string,
 	return mustChangeOneRow(r, err, "PrivateKeys.Create")
 }
 
+// CreateIfNotExists creates a new private key entry in the database, unless
+// the user already has a key for the purpose.
+func (p *PrivateKeys) CreateIfNotExists(c util.Context, tx *sql.Tx, userID, purpose string, privKey []byte) error {
+	_, err := tx.Stmt(p.createIfNotExist).ExecContext(c, userID, purpose, privKey)
+	return err
+}
+
 // GetByUserID fetches a private key by the userID and purpose of the key.
 func (p *PrivateKeys) GetByUserID(c util.Context, tx *sql.Tx, userID, purpose string) (b []byte, err error) {
 	var rows *sql.Rows
@@ -83,3 +127,12 @@ func (p *PrivateKeys) GetInstanceActor(c util.Context, tx *sql.Tx, purpose strin
 		return r.Scan(&(b))
 	})
 }
+
+// DeleteForDeletedUsers removes the private keys of deleted users that have
+// no deliveries left in flight or awaiting a retry.
+func (p *PrivateKeys) DeleteForDeletedUsers(c util.Context, tx *sql.Tx) error {
+	_, err := tx.Stmt(p.deleteForDeleted).ExecContext(c,
+		failedDeliveryAttempt,
+		newDeliveryAttempt)
+	return err
+}
@@ -0,0 +1,194 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/go-fed/apcore/util"
+)
+
+var _ Model = &Quarantine{}
+
+// QuarantineStatus is whether a quarantined activity has been reviewed.
+type QuarantineStatus string
+
+const (
+	QuarantinePending  QuarantineStatus = "pending"
+	QuarantineApproved QuarantineStatus = "approved"
+	QuarantineRejected QuarantineStatus = "rejected"
+)
+
+// CreateQuarantined is an incoming activity held by a quarantine policy
+// instead of being delivered to the actor's inbox.
+type CreateQuarantined struct {
+	ActorID      *url.URL
+	ActivityID   *url.URL
+	Activity     ActivityStreams
+	ResolutionID string
+}
+
+// Quarantined is a held activity, along with the Resolution of the policy
+// that quarantined it. The policy and Resolution are not valid if the policy
+// has since been deleted.
+type Quarantined struct {
+	ID           string
+	Created      time.Time
+	ActorID      string
+	ActivityID   string
+	Activity     ActivityStreams
+	Status       QuarantineStatus
+	Reviewed     sql.NullTime
+	ResolutionID sql.NullString
+	PolicyID     sql.NullString
+	Resolution   *Resolution
+}
+
+func (q *Quarantined) scan(row SingleRow) error {
+	var res []byte
+	if err := row.Scan(&(q.ID),
+		&(q.Created),
+		&(q.ActorID),
+		&(q.ActivityID),
+		&(q.Activity),
+		&(q.Status),
+		&(q.Reviewed),
+		&(q.ResolutionID),
+		&(q.PolicyID),
+		&res); err != nil {
+		return err
+	}
+	if res != nil {
+		q.Resolution = &Resolution{}
+		return json.Unmarshal(res, q.Resolution)
+	}
+	return nil
+}
+
+// Quarantine is a Model that provides additional database methods for the
+// moderation queue of quarantined activities.
+type Quarantine struct {
+	insert      *sql.Stmt
+	get         *sql.Stmt
+	getByStatus *sql.Stmt
+	review      *sql.Stmt
+	reopen      *sql.Stmt
+}
+
+func (q *Quarantine) Prepare(db *sql.DB, s SqlDialect) error {
+	return prepareStmtPairs(db,
+		stmtPairs{
+			{&(q.insert), s.InsertQuarantined()},
+			{&(q.get), s.GetQuarantined()},
+			{&(q.getByStatus), s.GetQuarantinedByStatus()},
+			{&(q.review), s.ReviewQuarantined()},
+			{&(q.reopen), s.ReopenQuarantined()},
+		})
+}
+
+func (q *Quarantine) CreateTable(t *sql.Tx, s SqlDialect) error {
+	_, err := t.Exec(s.CreateQuarantineTable())
+	return err
+}
+
+func (q *Quarantine) Close() {
+	q.insert.Close()
+	q.get.Close()
+	q.getByStatus.Close()
+	q.review.Close()
+	q.reopen.Close()
+}
+
+// Create holds a pending activity, returning its id in the queue.
+func (q *Quarantine) Create(c util.Context, tx *sql.Tx, cq CreateQuarantined) (id string, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(q.insert).QueryContext(c,
+		cq.ActorID.String(),
+		cq.ActivityID.String(),
+		cq.Activity,
+		cq.ResolutionID)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return id, enforceOneRow(rows, "Quarantine.Create", func(r SingleRow) error {
+		return r.Scan(&id)
+	})
+}
+
+// Get fetches a held activity. The returned bool is false if there is no such
+// activity.
+func (q *Quarantine) Get(c util.Context, tx *sql.Tx, id string) (found bool, qa Quarantined, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(q.get).QueryContext(c, id)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return found, qa, enforceOneRow(rows, "Quarantine.Get", func(r SingleRow) error {
+		found = true
+		return qa.scan(r)
+	})
+}
+
+// GetByStatus fetches a page of the held activities with the status, oldest
+// first.
+func (q *Quarantine) GetByStatus(c util.Context, tx *sql.Tx, status QuarantineStatus, offset, n int) (qs []Quarantined, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(q.getByStatus).QueryContext(c, string(status), offset, n)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return qs, doForRows(rows, "Quarantine.GetByStatus", func(r SingleRow) error {
+		var qa Quarantined
+		if err := qa.scan(r); err != nil {
+			return err
+		}
+		qs = append(qs, qa)
+		return nil
+	})
+}
+
+// Review sets the status of a pending activity. The returned bool is false if
+// the activity does not exist or was already reviewed.
+func (q *Quarantine) Review(c util.Context, tx *sql.Tx, id string, status QuarantineStatus) (reviewed bool, err error) {
+	var res sql.Result
+	res, err = tx.Stmt(q.review).ExecContext(c, id, string(status))
+	if err != nil {
+		return
+	}
+	var n int64
+	n, err = res.RowsAffected()
+	return n == 1, err
+}
+
+// Reopen returns a reviewed activity with the status to the pending queue,
+// returning false if it does not exist or does not have the status.
+func (q *Quarantine) Reopen(c util.Context, tx *sql.Tx, id string, status QuarantineStatus) (reopened bool, err error) {
+	var res sql.Result
+	res, err = tx.Stmt(q.reopen).ExecContext(c, id, string(status))
+	if err != nil {
+		return
+	}
+	var n int64
+	n, err = res.RowsAffected()
+	return n == 1, err
+}
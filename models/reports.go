@@ -0,0 +1,215 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/go-fed/apcore/util"
+)
+
+var _ Model = &Reports{}
+
+var _ driver.Valuer = ReportObjects{}
+var _ sql.Scanner = &ReportObjects{}
+
+// ReportObjects are the ids of the objects a report is about, such as
+// offending notes.
+type ReportObjects []string
+
+func (r ReportObjects) Value() (driver.Value, error) {
+	if r == nil {
+		r = ReportObjects{}
+	}
+	return json.Marshal(r)
+}
+
+func (r *ReportObjects) Scan(src interface{}) error {
+	return unmarshal(src, r)
+}
+
+// CreateReport is a report about an actor for moderators to review, either
+// received in a Flag or filed by a local user.
+type CreateReport struct {
+	ReporterID *url.URL
+	TargetID   *url.URL
+	ObjectIDs  []*url.URL
+	Content    string
+	ActivityID *url.URL
+}
+
+// Report is a stored report about an actor.
+type Report struct {
+	ID         string
+	Created    time.Time
+	ReporterID string
+	TargetID   string
+	ObjectIDs  ReportObjects
+	Content    string
+	ActivityID sql.NullString
+	Forwarded  bool
+	AssigneeID sql.NullString
+	Resolved   sql.NullTime
+	Resolution string
+}
+
+func (r *Report) scan(row SingleRow) error {
+	return row.Scan(&(r.ID),
+		&(r.Created),
+		&(r.ReporterID),
+		&(r.TargetID),
+		&(r.ObjectIDs),
+		&(r.Content),
+		&(r.ActivityID),
+		&(r.Forwarded),
+		&(r.AssigneeID),
+		&(r.Resolved),
+		&(r.Resolution))
+}
+
+// Reports is a Model that provides additional database methods for
+// moderation reports.
+type Reports struct {
+	insert       *sql.Stmt
+	get          *sql.Stmt
+	getReports   *sql.Stmt
+	setForwarded *sql.Stmt
+	assign       *sql.Stmt
+	resolve      *sql.Stmt
+}
+
+func (r *Reports) Prepare(db *sql.DB, s SqlDialect) error {
+	return prepareStmtPairs(db,
+		stmtPairs{
+			{&(r.insert), s.InsertReport()},
+			{&(r.get), s.GetReport()},
+			{&(r.getReports), s.GetReports()},
+			{&(r.setForwarded), s.SetReportForwarded()},
+			{&(r.assign), s.AssignReport()},
+			{&(r.resolve), s.ResolveReport()},
+		})
+}
+
+func (r *Reports) CreateTable(t *sql.Tx, s SqlDialect) error {
+	_, err := t.Exec(s.CreateReportsTable())
+	return err
+}
+
+func (r *Reports) Close() {
+	r.insert.Close()
+	r.get.Close()
+	r.getReports.Close()
+	r.setForwarded.Close()
+	r.assign.Close()
+	r.resolve.Close()
+}
+
+// Create stores a new open report, returning its id. The id is empty if a
+// report with the same activity was already stored.
+func (r *Reports) Create(c util.Context, tx *sql.Tx, cr CreateReport) (id string, err error) {
+	objs := make(ReportObjects, 0, len(cr.ObjectIDs))
+	for _, o := range cr.ObjectIDs {
+		objs = append(objs, o.String())
+	}
+	var activity sql.NullString
+	if cr.ActivityID != nil {
+		activity = sql.NullString{String: cr.ActivityID.String(), Valid: true}
+	}
+	var rows *sql.Rows
+	rows, err = tx.Stmt(r.insert).QueryContext(c,
+		cr.ReporterID.String(),
+		cr.TargetID.String(),
+		objs,
+		cr.Content,
+		activity)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return id, enforceOneRow(rows, "Reports.Create", func(r SingleRow) error {
+		return r.Scan(&id)
+	})
+}
+
+// Get fetches a report. The returned bool is false if there is no such
+// report.
+func (r *Reports) Get(c util.Context, tx *sql.Tx, id string) (found bool, rep Report, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(r.get).QueryContext(c, id)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return found, rep, enforceOneRow(rows, "Reports.Get", func(r SingleRow) error {
+		found = true
+		return rep.scan(r)
+	})
+}
+
+// List fetches a page of either the open or the resolved reports, oldest
+// first.
+func (r *Reports) List(c util.Context, tx *sql.Tx, resolved bool, offset, n int) (rs []Report, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(r.getReports).QueryContext(c, resolved, offset, n)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return rs, doForRows(rows, "Reports.List", func(r SingleRow) error {
+		var rep Report
+		if err := rep.scan(r); err != nil {
+			return err
+		}
+		rs = append(rs, rep)
+		return nil
+	})
+}
+
+// MarkForwarded records the Flag that forwarded the report to the server of
+// the reported actor.
+func (r *Reports) MarkForwarded(c util.Context, tx *sql.Tx, id string, activityID *url.URL) error {
+	res, err := tx.Stmt(r.setForwarded).ExecContext(c, id, activityID.String())
+	return mustChangeOneRow(res, err, "Reports.MarkForwarded")
+}
+
+// Assign sets the moderator handling the report. A nil assignee unassigns
+// it.
+func (r *Reports) Assign(c util.Context, tx *sql.Tx, id string, assignee *url.URL) error {
+	var a sql.NullString
+	if assignee != nil {
+		a = sql.NullString{String: assignee.String(), Valid: true}
+	}
+	res, err := tx.Stmt(r.assign).ExecContext(c, id, a)
+	return mustChangeOneRow(res, err, "Reports.Assign")
+}
+
+// Resolve closes an open report with the moderator's resolution. The returned
+// bool is false if the report does not exist or was already resolved.
+func (r *Reports) Resolve(c util.Context, tx *sql.Tx, id, resolution string) (resolved bool, err error) {
+	var res sql.Result
+	res, err = tx.Stmt(r.resolve).ExecContext(c, id, resolution)
+	if err != nil {
+		return
+	}
+	var n int64
+	n, err = res.RowsAffected()
+	return n == 1, err
+}
@@ -18,6 +18,8 @@ package models
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"time"
@@ -31,16 +33,45 @@ type Resolution struct {
 	// The following are used by Policies
 	Matched  bool     `json:"matched",omitempty`
 	MatchLog []string `json:"matchLog",omitempty`
+	Scope    Scope    `json:"scope,omitempty"`
+	// DiscardLog skips building the MatchLog, for resolutions that will
+	// not be recorded.
+	DiscardLog bool `json:"-"`
+}
+
+var _ driver.Valuer = Resolution{}
+var _ sql.Scanner = &Resolution{}
+
+func (r Resolution) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+func (r *Resolution) Scan(src interface{}) error {
+	return unmarshal(src, r)
 }
 
 func (r *Resolution) Logf(s string, i ...interface{}) {
+	if r.DiscardLog {
+		return
+	}
 	r.Log(fmt.Sprintf(s, i...))
 }
 
 func (r *Resolution) Log(s string) {
+	if r.DiscardLog {
+		return
+	}
 	r.MatchLog = append(r.MatchLog, s)
 }
 
+// PolicyResolution is the Resolution of applying a policy to the data at the
+// IRI.
+type PolicyResolution struct {
+	ID  string
+	IRI *url.URL
+	R   Resolution
+}
+
 type CreateResolution struct {
 	PolicyID string
 	IRI      *url.URL
@@ -48,17 +79,20 @@ type CreateResolution struct {
 }
 
 var _ Model = &Resolutions{}
+var _ Migrator = &Resolutions{}
 
 // Resolutions is a Model that provides additional database methods for the
 // Resolution type.
 type Resolutions struct {
-	create *sql.Stmt
+	create       *sql.Stmt
+	getForPolicy *sql.Stmt
 }
 
 func (r *Resolutions) Prepare(db *sql.DB, s SqlDialect) error {
 	return prepareStmtPairs(db,
 		stmtPairs{
 			{&(r.create), s.CreateResolution()},
+			{&(r.getForPolicy), s.GetResolutionsForPolicy()},
 		})
 }
 
@@ -67,15 +101,49 @@ func (r *Resolutions) CreateTable(t *sql.Tx, s SqlDialect) error {
 	return err
 }
 
+func (r *Resolutions) Migrate(t *sql.Tx, s SqlDialect) error {
+	_, err := t.Exec(s.MigrateResolutionsTable())
+	return err
+}
+
 func (r *Resolutions) Close() {
 	r.create.Close()
+	r.getForPolicy.Close()
 }
 
-// Create a new Resolution
-func (r *Resolutions) Create(c util.Context, tx *sql.Tx, cr CreateResolution) error {
-	rows, err := tx.Stmt(r.create).ExecContext(c,
+// Create a new Resolution, returning its id.
+func (r *Resolutions) Create(c util.Context, tx *sql.Tx, cr CreateResolution) (id string, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(r.create).QueryContext(c,
 		cr.PolicyID,
 		cr.IRI.String(),
 		cr.R)
-	return mustChangeOneRow(rows, err, "Resolutions.Create")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return id, enforceOneRow(rows, "Resolutions.Create", func(r SingleRow) error {
+		return r.Scan(&id)
+	})
+}
+
+// GetForPolicy obtains at most n of the policy's resolutions, most recent
+// first, skipping the first offset ones.
+func (r *Resolutions) GetForPolicy(c util.Context, tx *sql.Tx, policyID string, offset, n int) (rs []PolicyResolution, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(r.getForPolicy).QueryContext(c, policyID, offset, n)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return rs, doForRows(rows, "Resolutions.GetForPolicy", func(row SingleRow) error {
+		var pr PolicyResolution
+		var iri URL
+		if err := row.Scan(&(pr.ID), &iri, &(pr.R)); err != nil {
+			return err
+		}
+		pr.IRI = iri.URL
+		rs = append(rs, pr)
+		return nil
+	})
 }
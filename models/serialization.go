@@ -181,6 +181,9 @@ type InstanceActorPreferences struct {
 	ServerBaseURL string
 	// ServerName contains the name of this particular server.
 	ServerName string
+	// ServerDescription contains a short description of this particular
+	// server.
+	ServerDescription string
 	// OrgName contains the name of the wider organization this server
 	// belongs to.
 	OrgName string
@@ -492,3 +495,16 @@ func (u *URL) Scan(src interface{}) error {
 	u.URL, err = url.Parse(s)
 	return err
 }
+
+var _ driver.Valuer = IRIs{}
+
+// IRIs is a list of ids passed to a query as a JSON array.
+type IRIs []*url.URL
+
+func (l IRIs) Value() (driver.Value, error) {
+	s := make([]string, len(l))
+	for i, u := range l {
+		s[i] = u.String()
+	}
+	return json.Marshal(s)
+}
@@ -53,18 +53,62 @@ type SqlDialect interface {
 	CreateFollowingTable() string
 	// CreateLikedTable for the Liked model.
 	CreateLikedTable() string
+	// CreateFeaturedTable for the Featured model.
+	CreateFeaturedTable() string
 	// CreatePoliciesTable for the Policies model.
 	CreatePoliciesTable() string
 	// CreateResolutionsTable for the Resolutions model.
 	CreateResolutionsTable() string
 	// CreateFirstPartyCredentialsTable for first party credentials model.
 	CreateFirstPartyCredentialsTable() string
+	// CreatePeersTable for the Peers model.
+	CreatePeersTable() string
+	// CreateObjectCollectionsTable for the ObjectCollections model.
+	CreateObjectCollectionsTable() string
+	// CreateBlocksTable for the Blocks model.
+	CreateBlocksTable() string
+	// CreateMutesTable for the Mutes model.
+	CreateMutesTable() string
+	// CreateFollowRequestsTable for the FollowRequests model.
+	CreateFollowRequestsTable() string
+	// CreateReportsTable for the Reports model.
+	CreateReportsTable() string
+	// CreateQuarantineTable for the Quarantine model.
+	CreateQuarantineTable() string
+
+	/* Table Migration Statements */
+
+	// MigrateUsersTable brings a Users table created by an earlier
+	// version up to date.
+	MigrateUsersTable() string
+	// MigrateClientInfosTable brings a ClientInfos table created by an
+	// earlier version up to date.
+	MigrateClientInfosTable() string
+	// MigrateTokenInfosTable brings a TokenInfos table created by an
+	// earlier version up to date.
+	MigrateTokenInfosTable() string
+	// MigratePoliciesTable brings a Policies table created by an earlier
+	// version up to date.
+	MigratePoliciesTable() string
+	// MigrateResolutionsTable brings a Resolutions table created by an
+	// earlier version up to date.
+	MigrateResolutionsTable() string
+	// DuplicatePrivateKeys finds users with more than one private key for
+	// the same purpose, which must be resolved before the PrivateKeys table
+	// can be migrated.
+	//
+	// Returns:
+	// - user_id, purpose
+	DuplicatePrivateKeys() string
 
 	/* Indexes */
 
 	// CreateIndexIDFedDataTable creates an index on the `id` of a federated
 	// data payload.
 	CreateIndexIDFedDataTable() string
+	// CreateIndexCreateTimeFedDataTable creates an index on the time
+	// federated data was received.
+	CreateIndexCreateTimeFedDataTable() string
 	// CreateIndexIDLocalDataTable creates an index on the `id` of a local
 	// data payload.
 	CreateIndexIDLocalDataTable() string
@@ -81,6 +125,15 @@ type SqlDialect interface {
 	// CreateIndexIDLikedTable creates an index on the `id` of a liked
 	// collection.
 	CreateIndexIDLikedTable() string
+	// CreateIndexIDFeaturedTable creates an index on the `id` of a
+	// featured collection.
+	CreateIndexIDFeaturedTable() string
+	// CreateIndexIDObjectCollectionsTable creates an index on the `id` of
+	// an object's replies, likes, or shares collection.
+	CreateIndexIDObjectCollectionsTable() string
+	// CreateIndexUserIDPurposePrivateKeysTable creates a unique index on
+	// the user and purpose of a private key.
+	CreateIndexUserIDPurposePrivateKeysTable() string
 
 	/* Queries */
 
@@ -118,7 +171,14 @@ type SqlDialect interface {
 	//   Actor       []byte
 	//   Privileges  []byte
 	//   Preferences []byte
+	//   Suspended   sql.NullTime
+	//   Deleted     sql.NullTime
 	UserByID() string
+	// UserIDsWithoutFeatured:
+	//  Params
+	//  Returns (Multiple)
+	//   ID          string
+	UserIDsWithoutFeatured() string
 	// UserByPreferredUsername:
 	//  Params
 	//   Name        string
@@ -128,6 +188,8 @@ type SqlDialect interface {
 	//   Actor       []byte
 	//   Privileges  []byte
 	//   Preferences []byte
+	//   Suspended   sql.NullTime
+	//   Deleted     sql.NullTime
 	UserByPreferredUsername() string
 	// ActorIDForOutbox:
 	//  Params
@@ -161,6 +223,8 @@ type SqlDialect interface {
 	//   Actor       []byte
 	//   Privileges  []byte
 	//   Preferences []byte
+	//   Suspended   sql.NullTime
+	//   Deleted     sql.NullTime
 	InstanceUser() string
 	// GetInstanceActorProfile:
 	//  Params
@@ -180,6 +244,21 @@ type SqlDialect interface {
 	//   ActiveMonth    int
 	//   ActiveWeek     int
 	GetUserActivityStats() string
+	// SuspendUser:
+	//  Params
+	//   ID          string
+	//  Returns
+	SuspendUser() string
+	// UnsuspendUser:
+	//  Params
+	//   ID          string
+	//  Returns
+	UnsuspendUser() string
+	// MarkUserDeleted:
+	//  Params
+	//   ID          string
+	//  Returns
+	MarkUserDeleted() string
 
 	// FedExists:
 	//  Params
@@ -193,6 +272,13 @@ type SqlDialect interface {
 	//  Returns
 	//   Payload     []byte
 	FedGet() string
+	// FedGetMany:
+	//  Params
+	//   IDs         []byte
+	//  Returns (Multiple)
+	//   ID          string
+	//   Payload     []byte
+	FedGetMany() string
 	// FedCreate:
 	//  Params
 	//   Payload     []byte
@@ -209,6 +295,23 @@ type SqlDialect interface {
 	//   ID          string
 	//  Returns
 	FedDelete() string
+	// FedDeleteForActor:
+	//  Params
+	//   ActorID     string
+	//  Returns
+	FedDeleteForActor() string
+	// FedSharedInboxes:
+	//  Params
+	//  Returns (Multiple)
+	//   SharedInbox string
+	FedSharedInboxes() string
+	// FedGetRecent returns the most recent first:
+	//  Params
+	//   Limit       int
+	//  Returns (Multiple)
+	//   ID          string
+	//   Payload     []byte
+	FedGetRecent() string
 
 	// LocalExists:
 	//  Params
@@ -222,6 +325,13 @@ type SqlDialect interface {
 	//  Returns
 	//   Payload     []byte
 	LocalGet() string
+	// LocalGetMany:
+	//  Params
+	//   IDs         []byte
+	//  Returns (Multiple)
+	//   ID          string
+	//   Payload     []byte
+	LocalGetMany() string
 	// LocalCreate:
 	//  Params
 	//   Payload     []byte
@@ -238,6 +348,11 @@ type SqlDialect interface {
 	//   ID          string
 	//  Returns
 	LocalDelete() string
+	// LocalDeleteForActor:
+	//  Params
+	//   ActorID     string
+	//  Returns
+	LocalDeleteForActor() string
 	// LocalStats:
 	//  Params
 	//  Returns
@@ -444,6 +559,13 @@ type SqlDialect interface {
 	//   PrivKey     []byte
 	//  Returns
 	CreatePrivateKey() string
+	// CreatePrivateKeyIfNotExists:
+	//  Params
+	//   UserID      string
+	//   Purpose     string
+	//   PrivKey     []byte
+	//  Returns
+	CreatePrivateKeyIfNotExists() string
 	// GetPrivateKeyByUserID:
 	//  Params
 	//   UserID      string
@@ -457,6 +579,12 @@ type SqlDialect interface {
 	//  Returns
 	//   PrivKey     []byte
 	GetPrivateKeyForInstanceActor() string
+	// DeletePrivateKeysForDeletedUsers:
+	//  Params
+	//   FailedState string
+	//   NewState    string
+	//  Returns
+	DeletePrivateKeysForDeletedUsers() string
 
 	// CreateClientInfo:
 	//  Params
@@ -474,7 +602,49 @@ type SqlDialect interface {
 	//   Secret      string
 	//   Domain      string
 	//   UserID      string
+	//   Name        string
+	//   Website     string
+	//   LogoURI     string
+	//   RedirURIs   []byte
+	//   CreateTime  time.Time
+	//   ResourceSrv bool
 	GetClientInfoByID() string
+	// RegisterClientInfo:
+	//  Params
+	//   ID          string
+	//   Domain      string
+	//   Name        string
+	//   Website     string
+	//   LogoURI     string
+	//   RedirURIs   []byte
+	//  Returns
+	//   ID          string
+	RegisterClientInfo() string
+	// GetRegisteredClientInfos:
+	//  Params
+	//  Returns (Multiple)
+	//   ID          string
+	//   Secret      string
+	//   Domain      string
+	//   UserID      string
+	//   Name        string
+	//   Website     string
+	//   LogoURI     string
+	//   RedirURIs   []byte
+	//   CreateTime  time.Time
+	//   ResourceSrv bool
+	GetRegisteredClientInfos() string
+	// SetRegisteredClientInfoResourceServer:
+	//  Params
+	//   ID          string
+	//   ResourceSrv bool
+	//  Returns
+	SetRegisteredClientInfoResourceServer() string
+	// DeleteRegisteredClientInfo:
+	//  Params
+	//   ID          string
+	//  Returns
+	DeleteRegisteredClientInfo() string
 
 	// CreateTokenInfo:
 	//  Params
@@ -493,6 +663,7 @@ type SqlDialect interface {
 	//   Refresh     string
 	//   RefrCreated time.Time
 	//   RefrExpires time.Duration
+	//   Nonce       sql.NullString
 	//  Returns
 	//   ID          string
 	CreateTokenInfo() string
@@ -511,6 +682,31 @@ type SqlDialect interface {
 	//   Refresh     string
 	//  Returns
 	RemoveTokenInfoByRefresh() string
+	// RemoveTokenInfosForUser:
+	//  Params
+	//   UserID      string
+	//  Returns
+	RemoveTokenInfosForUser() string
+	// RemoveTokenInfosForUserAndClient:
+	//  Params
+	//   UserID      string
+	//   ClientID    string
+	//  Returns
+	RemoveTokenInfosForUserAndClient() string
+	// GetTokenInfosForUser:
+	//  Params
+	//   UserID      string
+	//  Returns (Multiple)
+	//   ID          string
+	//   ClientID    string
+	//   ClientName  string
+	//   FirstParty  bool
+	//   Scope       string
+	//   AccessCtd   time.Time
+	//   AccessExp   time.Duration
+	//   RefrCreated time.Time
+	//   RefrExpires time.Duration
+	GetTokenInfosForUser() string
 	// GetTokenInfoByCode:
 	//  Params
 	//   Code        string
@@ -530,6 +726,7 @@ type SqlDialect interface {
 	//   Refresh     string
 	//   RefrCreated time.Time
 	//   RefrExpires time.Duration
+	//   Nonce       sql.NullString
 	GetTokenInfoByCode() string
 	// GetTokenInfoByAccess:
 	//  Params
@@ -550,6 +747,7 @@ type SqlDialect interface {
 	//   Refresh     string
 	//   RefrCreated time.Time
 	//   RefrExpires time.Duration
+	//   Nonce       sql.NullString
 	GetTokenInfoByAccess() string
 	// GetTokenInfoByRefresh:
 	//  Params
@@ -570,6 +768,7 @@ type SqlDialect interface {
 	//   Refresh     string
 	//   RefrCreated time.Time
 	//   RefrExpires time.Duration
+	//   Nonce       sql.NullString
 	GetTokenInfoByRefresh() string
 
 	// InsertFollowers:
@@ -740,30 +939,134 @@ type SqlDialect interface {
 	//   Liked       []byte
 	GetAllLikedForActor() string
 
+	// InsertFeatured:
+	//  Params
+	//   ActorID     string
+	//   Featured    []byte
+	//  Returns
+	InsertFeatured() string
+	// FeaturedExists:
+	//  Params
+	//   Featured    string
+	//  Returns
+	//   Exists      bool
+	FeaturedExists() string
+	// FeaturedContainsForActor:
+	//  Params
+	//   ActorID     string
+	//   Item        string
+	//  Returns
+	//   Contains    bool
+	FeaturedContainsForActor() string
+	// FeaturedContains:
+	//  Params
+	//   Featured    string
+	//   Item        string
+	//  Returns
+	//   Contains    bool
+	FeaturedContains() string
+	// GetFeatured:
+	//  Params
+	//   Featured    string
+	//   Min         int
+	//   Max         int
+	//  Returns
+	//   Page        []byte
+	//   IsEnd       bool
+	GetFeatured() string
+	// GetFeaturedLastPage:
+	//  Params
+	//   Featured    string
+	//   N           int
+	//  Returns
+	//   Page        []byte
+	//   StartIndex  int
+	GetFeaturedLastPage() string
+	// PrependFeaturedItem:
+	//  Params
+	//   Featured    string
+	//   Item        string
+	//  Returns
+	PrependFeaturedItem() string
+	// DeleteFeaturedItem:
+	//  Params
+	//   Featured    string
+	//   Item        string
+	//  Returns
+	DeleteFeaturedItem() string
+	// GetAllFeaturedForActor:
+	//  Params
+	//   ActorID     string
+	//  Returns
+	//   Featured    []byte
+	GetAllFeaturedForActor() string
+
 	// CreatePolicy:
 	//  Params
 	//   ActorID     string
 	//   Purpose     string
 	//   Payload     []byte
+	//   Enabled     bool
+	//   Monitor     bool
 	//  Returns
 	//   ID          string
 	CreatePolicy() string
-	// GetPoliciesForActor:
+	// GetPoliciesForActor orders by purpose, then name:
 	//  Params
 	//   ActorID     string
 	//  Returns (Multiple)
 	//   ID          string
 	//   Purpose     string
 	//   Payload     []byte
+	//   Enabled     bool
+	//   Monitor     bool
 	GetPoliciesForActor() string
-	// GetPoliciesForActorAndPurpose:
+	// GetPoliciesForActorAndPurpose returns only enabled policies:
 	//  Params
 	//   ActorID     string
 	//   Purpose     string
 	//  Returns (Multiple)
 	//   ID          string
 	//   Payload     []byte
+	//   Monitor     bool
 	GetPoliciesForActorAndPurpose() string
+	// GetPolicyForActor:
+	//  Params
+	//   ID          string
+	//   ActorID     string
+	//  Returns (Zero or one)
+	//   Purpose     string
+	//   Payload     []byte
+	//   Enabled     bool
+	//   Monitor     bool
+	GetPolicyForActor() string
+	// UpdatePolicy:
+	//  Params
+	//   ID          string
+	//   ActorID     string
+	//   Payload     []byte
+	//  Returns
+	UpdatePolicy() string
+	// SetPolicyEnabled:
+	//  Params
+	//   ID          string
+	//   ActorID     string
+	//   Enabled     bool
+	//  Returns
+	SetPolicyEnabled() string
+	// SetPolicyMonitor:
+	//  Params
+	//   ID          string
+	//   ActorID     string
+	//   Monitor     bool
+	//  Returns
+	SetPolicyMonitor() string
+	// DeletePolicy also deletes its resolutions:
+	//  Params
+	//   ID          string
+	//   ActorID     string
+	//  Returns
+	DeletePolicy() string
 
 	// CreateResolution:
 	//  Params
@@ -771,7 +1074,18 @@ type SqlDialect interface {
 	//   DataIRI     string
 	//   Payload     []byte
 	//  Returns
+	//   ID          string
 	CreateResolution() string
+	// GetResolutionsForPolicy returns the most recent first:
+	//  Params
+	//   PolicyID    string
+	//   Offset      int
+	//   Limit       int
+	//  Returns (Multiple)
+	//   ID          string
+	//   DataIRI     string
+	//   Payload     []byte
+	GetResolutionsForPolicy() string
 
 	// CreateFirstPartyCredential:
 	//  Params
@@ -835,5 +1149,317 @@ type SqlDialect interface {
 	//   Refresh     string
 	//   RefrCreated time.Time
 	//   RefrExpires time.Duration
+	//   Nonce       sql.NullString
 	GetTokenInfoForCredentialID() string
+
+	// PeerSeen:
+	//  Params
+	//   Host        string
+	//  Returns
+	//   NodeInfoFetched sql.NullTime
+	PeerSeen() string
+	// MarkPeerDeliverySuccess:
+	//  Params
+	//   Host        string
+	//  Returns
+	MarkPeerDeliverySuccess() string
+	// MarkPeerDeliveryFailure:
+	//  Params
+	//   Host        string
+	//  Returns
+	MarkPeerDeliveryFailure() string
+	// UpdatePeerSoftware:
+	//  Params
+	//   Host        string
+	//   Name        sql.NullString
+	//   Version     sql.NullString
+	//  Returns
+	UpdatePeerSoftware() string
+	// GetPeer:
+	//  Params
+	//   Host        string
+	//  Returns
+	//   ID          string
+	//   Host        string
+	//   FirstSeen   time.Time
+	//   LastSeen    time.Time
+	//   SwName      sql.NullString
+	//   SwVersion   sql.NullString
+	//   NIFetched   sql.NullTime
+	//   NSuccesses  int
+	//   NFailures   int
+	//   LastSuccess sql.NullTime
+	//   LastFailure sql.NullTime
+	GetPeer() string
+	// GetPeers:
+	//  Params
+	//  Returns (Multiple)
+	//   ID          string
+	//   Host        string
+	//   FirstSeen   time.Time
+	//   LastSeen    time.Time
+	//   SwName      sql.NullString
+	//   SwVersion   sql.NullString
+	//   NIFetched   sql.NullTime
+	//   NSuccesses  int
+	//   NFailures   int
+	//   LastSuccess sql.NullTime
+	//   LastFailure sql.NullTime
+	GetPeers() string
+	// GetPeersWithStaleNodeInfo:
+	//  Params
+	//   Before      time.Time
+	//  Returns (Multiple)
+	//   Host        string
+	GetPeersWithStaleNodeInfo() string
+
+	// InsertObjectCollection:
+	//  Params
+	//   ObjectID    string
+	//   Kind        string
+	//   Collection  []byte
+	//  Returns
+	InsertObjectCollection() string
+	// ObjectCollectionContains:
+	//  Params
+	//   Collection  string
+	//   Item        string
+	//  Returns
+	//   Contains    bool
+	ObjectCollectionContains() string
+	// GetObjectCollection:
+	//  Params
+	//   Collection  string
+	//   Min         int
+	//   Max         int
+	//  Returns
+	//   Page        []byte
+	//   IsEnd       bool
+	GetObjectCollection() string
+	// GetObjectCollectionLastPage:
+	//  Params
+	//   Collection  string
+	//   N           int
+	//  Returns
+	//   Page        []byte
+	//   StartIndex  int
+	GetObjectCollectionLastPage() string
+	// PrependObjectCollectionItem:
+	//  Params
+	//   Collection  string
+	//   Item        string
+	//  Returns
+	PrependObjectCollectionItem() string
+	// DeleteObjectCollectionItem:
+	//  Params
+	//   Collection  string
+	//   Item        string
+	//  Returns
+	DeleteObjectCollectionItem() string
+
+	// InsertBlock:
+	//  Params
+	//   ActorID     string
+	//   BlockedID   string
+	//   ActivityID  sql.NullString
+	//  Returns
+	InsertBlock() string
+	// UpdateBlockActivity:
+	//  Params
+	//   ActorID     string
+	//   BlockedID   string
+	//   ActivityID  sql.NullString
+	//  Returns
+	UpdateBlockActivity() string
+	// DeleteBlock:
+	//  Params
+	//   ActorID     string
+	//   BlockedID   string
+	//  Returns (Zero or one)
+	//   ActivityID  sql.NullString
+	DeleteBlock() string
+	// DeleteBlockByActivity:
+	//  Params
+	//   ActorID     string
+	//   ActivityID  string
+	//  Returns (Zero or one)
+	//   BlockedID   string
+	DeleteBlockByActivity() string
+	// GetBlocksForActor:
+	//  Params
+	//   ActorID     string
+	//  Returns (Multiple)
+	//   ID          string
+	//   Created     time.Time
+	//   ActorID     string
+	//   BlockedID   string
+	//   ActivityID  sql.NullString
+	GetBlocksForActor() string
+
+	// InsertMute:
+	//  Params
+	//   ActorID     string
+	//   Kind        string
+	//   Target      string
+	//   Expires     sql.NullTime
+	//  Returns
+	InsertMute() string
+	// DeleteMute:
+	//  Params
+	//   ActorID     string
+	//   Kind        string
+	//   Target      string
+	//  Returns
+	DeleteMute() string
+	// DeleteExpiredMutes:
+	//  Params
+	//  Returns
+	DeleteExpiredMutes() string
+	// GetActiveMutesForActor:
+	//  Params
+	//   ActorID     string
+	//  Returns (Multiple)
+	//   ID          string
+	//   Created     time.Time
+	//   ActorID     string
+	//   Kind        string
+	//   Target      string
+	//   Expires     sql.NullTime
+	GetActiveMutesForActor() string
+
+	// InsertFollowRequest:
+	//  Params
+	//   ActorID     string
+	//   FollowerID  string
+	//   ActivityID  string
+	//   Activity    []byte
+	//  Returns
+	InsertFollowRequest() string
+	// GetFollowRequestsForActor:
+	//  Params
+	//   ActorID     string
+	//  Returns (Multiple)
+	//   ID          string
+	//   Created     time.Time
+	//   ActorID     string
+	//   FollowerID  string
+	//   Activity    []byte
+	GetFollowRequestsForActor() string
+	// DeleteFollowRequest:
+	//  Params
+	//   ActorID     string
+	//   FollowerID  string
+	//  Returns (Zero or one)
+	//   FollowerID  string
+	//   Activity    []byte
+	DeleteFollowRequest() string
+	// DeleteFollowRequestByActivity:
+	//  Params
+	//   ActorID     string
+	//   ActivityID  string
+	//  Returns (Zero or one)
+	//   FollowerID  string
+	//   Activity    []byte
+	DeleteFollowRequestByActivity() string
+
+	// InsertReport does nothing if a report with the activity already
+	// exists:
+	//  Params
+	//   ReporterID  string
+	//   TargetID    string
+	//   ObjectIDs   []byte
+	//   Content     string
+	//   ActivityID  sql.NullString
+	//  Returns (Zero or one)
+	//   ID          string
+	InsertReport() string
+	// GetReport:
+	//  Params
+	//   ID          string
+	//  Returns (Zero or one)
+	//   ID          string
+	//   Created     time.Time
+	//   ReporterID  string
+	//   TargetID    string
+	//   ObjectIDs   []byte
+	//   Content     string
+	//   ActivityID  sql.NullString
+	//   Forwarded   bool
+	//   AssigneeID  sql.NullString
+	//   Resolved    sql.NullTime
+	//   Resolution  string
+	GetReport() string
+	// GetReports returns either the open or resolved reports, oldest
+	// first:
+	//  Params
+	//   Resolved    bool
+	//   Offset      int
+	//   Limit       int
+	//  Returns (Multiple)
+	//   (Same as GetReport)
+	GetReports() string
+	// SetReportForwarded:
+	//  Params
+	//   ID          string
+	//   ActivityID  string
+	//  Returns
+	SetReportForwarded() string
+	// AssignReport:
+	//  Params
+	//   ID          string
+	//   AssigneeID  sql.NullString
+	//  Returns
+	AssignReport() string
+	// ResolveReport only resolves open reports:
+	//  Params
+	//   ID          string
+	//   Resolution  string
+	//  Returns
+	ResolveReport() string
+
+	// InsertQuarantined:
+	//  Params
+	//   ActorID      string
+	//   ActivityID   string
+	//   Payload      []byte
+	//   ResolutionID string
+	//  Returns
+	//   ID           string
+	InsertQuarantined() string
+	// GetQuarantined also returns the linked Resolution, if it still
+	// exists:
+	//  Params
+	//   ID           string
+	//  Returns (Zero or one)
+	//   ID           string
+	//   Created      time.Time
+	//   ActorID      string
+	//   ActivityID   string
+	//   Payload      []byte
+	//   Status       string
+	//   Reviewed     sql.NullTime
+	//   ResolutionID sql.NullString
+	//   PolicyID     sql.NullString
+	//   Resolution   []byte
+	GetQuarantined() string
+	// GetQuarantinedByStatus returns the oldest first:
+	//  Params
+	//   Status       string
+	//   Offset       int
+	//   Limit        int
+	//  Returns (Multiple)
+	//   (Same as GetQuarantined)
+	GetQuarantinedByStatus() string
+	// ReviewQuarantined only reviews pending activities:
+	//  Params
+	//   ID           string
+	//   Status       string
+	//  Returns
+	ReviewQuarantined() string
+	// ReopenQuarantined returns a reviewed activity to pending:
+	//  Params
+	//   ID           string
+	//   Status       string
+	//  Returns
+	ReopenQuarantined() string
 }
@@ -23,6 +23,7 @@ import (
 	"flag"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/go-fed/activity/pub"
@@ -30,6 +31,7 @@ import (
 	"github.com/go-fed/activity/streams/vocab"
 	"github.com/go-fed/apcore/framework/db"
 	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/services"
 	"github.com/go-fed/apcore/util"
 	"github.com/go-fed/oauth2"
 	_ "github.com/jackc/pgx/v4/stdlib"
@@ -51,8 +53,16 @@ var credentials = &models.Credentials{}
 var following = &models.Following{}
 var followers = &models.Followers{}
 var liked = &models.Liked{}
+var featured = &models.Featured{}
+var objectCollections = &models.ObjectCollections{}
 var policies = &models.Policies{}
 var resolutions = &models.Resolutions{}
+var peers = &models.Peers{}
+var blocks = &models.Blocks{}
+var mutes = &models.Mutes{}
+var followRequests = &models.FollowRequests{}
+var reports = &models.Reports{}
+var quarantine = &models.Quarantine{}
 var testModels []models.Model
 
 func init() {
@@ -70,8 +80,16 @@ func init() {
 		following,
 		followers,
 		liked,
+		featured,
+		objectCollections,
 		policies,
 		resolutions,
+		peers,
+		blocks,
+		mutes,
+		followRequests,
+		reports,
+		quarantine,
 	}
 }
 
@@ -151,13 +169,50 @@ func main() {
 	if err = runLikedCalls(ctx, db); err != nil {
 		panic(err)
 	}
+	fmt.Println("Running Featured calls...")
+	if err = runFeaturedCalls(ctx, db); err != nil {
+		panic(err)
+	}
+	fmt.Println("Running ObjectCollections calls...")
+	if err = runObjectCollectionsCalls(ctx, db); err != nil {
+		panic(err)
+	}
 	fmt.Println("Running Policies calls...")
 	policyID, err := runPoliciesCalls(ctx, db)
 	if err != nil {
 		panic(err)
 	}
 	fmt.Println("Running Resolutions calls...")
-	if err = runResolutionsCalls(ctx, db, policyID); err != nil {
+	resolutionID, err := runResolutionsCalls(ctx, db, policyID)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("Running Quarantine calls...")
+	if err = runQuarantineCalls(ctx, db, resolutionID); err != nil {
+		panic(err)
+	}
+	fmt.Println("Running Peers calls...")
+	if err = runPeersCalls(ctx, db); err != nil {
+		panic(err)
+	}
+	fmt.Println("Running Blocks calls...")
+	if err = runBlocksCalls(ctx, db); err != nil {
+		panic(err)
+	}
+	fmt.Println("Running Mutes calls...")
+	if err = runMutesCalls(ctx, db); err != nil {
+		panic(err)
+	}
+	fmt.Println("Running FollowRequests calls...")
+	if err = runFollowRequestsCalls(ctx, db); err != nil {
+		panic(err)
+	}
+	fmt.Println("Running Reports calls...")
+	if err = runReportsCalls(ctx, db); err != nil {
+		panic(err)
+	}
+	fmt.Println("Running user suspension and deletion calls...")
+	if err = runUserDeletionCalls(ctx, db); err != nil {
 		panic(err)
 	}
 	fmt.Println("Close models...")
@@ -167,16 +222,433 @@ func main() {
 	fmt.Println("done")
 }
 
-/* Resolutions */
+/* User suspension and deletion */
+
+func runUserDeletionCalls(ctx util.Context, db *sql.DB) error {
+	cu := &models.CreateUser{
+		Email:       testEmail2,
+		Hashpass:    []byte{1, 2, 3},
+		Salt:        []byte{4, 5, 6},
+		Actor:       models.ActivityStreamsPerson{streams.NewActivityStreamsPerson()},
+		Privileges:  models.Privileges{},
+		Preferences: models.Preferences{},
+	}
+	var id string
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		id, err = users.Create(ctx, tx, cu)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> Create(): %s\n", id)
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		if err := users.Suspend(ctx, tx, id); err != nil {
+			return err
+		}
+		return users.Suspend(ctx, tx, id)
+	}); err != nil {
+		return err
+	}
+	var u *models.User
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		u, err = users.UserByID(ctx, tx, id)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> Suspend: %v\n", u.Suspended)
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return users.Unsuspend(ctx, tx, id)
+	}); err != nil {
+		return err
+	}
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		if err := tokenInfos.RemoveForUser(ctx, tx, id); err != nil {
+			return err
+		} else if err := localData.DeleteForActor(ctx, tx, mustParse(testActor3IRI)); err != nil {
+			return err
+		}
+		return fedData.DeleteForActor(ctx, tx, mustParse(testActor3IRI))
+	}); err != nil {
+		return err
+	}
+	var inboxes []*url.URL
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		inboxes, err = fedData.SharedInboxes(ctx, tx)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> SharedInboxes: %v\n", inboxes)
+	var recent []models.FedPayload
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		recent, err = fedData.GetRecent(ctx, tx, 10)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> GetRecent: %d\n", len(recent))
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return users.MarkDeleted(ctx, tx, id)
+	}); err != nil {
+		return err
+	}
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		u, err = users.UserByID(ctx, tx, id)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> MarkDeleted: %v\n", u.Deleted)
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return privateKeys.DeleteForDeletedUsers(ctx, tx)
+	}); err != nil {
+		return err
+	}
+	return nil
+}
 
-func runResolutionsCalls(ctx util.Context, db *sql.DB, policyID string) error {
-	if err := runResolutionsCreate(ctx, db, policyID); err != nil {
+/* Blocks */
+
+// checkRemoveBlockedAddressees ensures that a Create whose Note addresses a
+// blocked actor is not delivered to it, as the Note's addressing is copied
+// onto the Create when it is delivered.
+func checkRemoveBlockedAddressees(ctx util.Context, db *sql.DB, actor, blocked *url.URL) error {
+	other := mustParse(testActor3IRI)
+	note := streams.NewActivityStreamsNote()
+	noteTo := streams.NewActivityStreamsToProperty()
+	noteTo.AppendIRI(blocked)
+	noteTo.AppendIRI(other)
+	note.SetActivityStreamsTo(noteTo)
+	noteCc := streams.NewActivityStreamsCcProperty()
+	noteCc.AppendIRI(blocked)
+	note.SetActivityStreamsCc(noteCc)
+	create := streams.NewActivityStreamsCreate()
+	createTo := streams.NewActivityStreamsToProperty()
+	createTo.AppendIRI(other)
+	create.SetActivityStreamsTo(createTo)
+	obj := streams.NewActivityStreamsObjectProperty()
+	obj.AppendActivityStreamsNote(note)
+	create.SetActivityStreamsObject(obj)
+
+	bl := &services.Blocks{DB: db, Blocks: blocks}
+	if err := bl.RemoveBlockedAddressees(ctx, actor, create); err != nil {
+		return err
+	}
+	var addressed []string
+	for _, p := range []interface {
+		Len() int
+		At(int) vocab.ActivityStreamsToPropertyIterator
+	}{create.GetActivityStreamsTo(), note.GetActivityStreamsTo()} {
+		for i := 0; i < p.Len(); i++ {
+			addressed = append(addressed, p.At(i).GetIRI().String())
+		}
+	}
+	for i := 0; i < note.GetActivityStreamsCc().Len(); i++ {
+		addressed = append(addressed, note.GetActivityStreamsCc().At(i).GetIRI().String())
+	}
+	fmt.Printf("> RemoveBlockedAddressees (Create of Note): %v\n", addressed)
+	for _, a := range addressed {
+		if a == blocked.String() {
+			return fmt.Errorf("RemoveBlockedAddressees: blocked actor %s is still addressed", blocked)
+		}
+	}
+	if len(addressed) != 2 {
+		return fmt.Errorf("RemoveBlockedAddressees: expected the 2 unblocked addressees to remain, got %v", addressed)
+	}
+	return nil
+}
+
+func runBlocksCalls(ctx util.Context, db *sql.DB) error {
+	actor := mustParse(testActor1IRI)
+	blocked := mustParse(testActor2IRI)
+	var created bool
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		created, err = blocks.Create(ctx, tx, actor, blocked, sql.NullString{})
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> Create (first): %v\n", created)
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		created, err = blocks.Create(ctx, tx, actor, blocked, sql.NullString{})
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> Create (again): %v\n", created)
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return blocks.SetActivity(ctx, tx, actor, blocked, mustParse(testActivity5IRI))
+	}); err != nil {
+		return err
+	}
+	var bs []models.Block
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		bs, err = blocks.GetForActor(ctx, tx, actor)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> GetForActor: %v\n", bs)
+	if err := checkRemoveBlockedAddressees(ctx, db, actor, blocked); err != nil {
+		return err
+	}
+	var deleted bool
+	var activity sql.NullString
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		deleted, activity, err = blocks.Delete(ctx, tx, actor, blocked)
+		return
+	}); err != nil {
 		return err
 	}
+	fmt.Printf("> Delete: %v %v\n", deleted, activity)
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		_, err = blocks.Create(ctx, tx, blocked, actor, sql.NullString{String: testActivity6IRI, Valid: true})
+		return
+	}); err != nil {
+		return err
+	}
+	var unblocked string
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		unblocked, err = blocks.DeleteByActivity(ctx, tx, blocked, mustParse(testActivity6IRI))
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> DeleteByActivity: %s\n", unblocked)
 	return nil
 }
 
-func runResolutionsCreate(ctx util.Context, db *sql.DB, policyID string) error {
+/* Mutes */
+
+func runMutesCalls(ctx util.Context, db *sql.DB) error {
+	actor := mustParse(testActor1IRI)
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return mutes.Create(ctx, tx, actor, models.ActorMute, testActor2IRI, sql.NullTime{})
+	}); err != nil {
+		return err
+	}
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return mutes.Create(ctx, tx, actor, models.DomainMute, "fed.example.com", sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true})
+	}); err != nil {
+		return err
+	}
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return mutes.Create(ctx, tx, actor, models.ActorMute, testActor3IRI, sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true})
+	}); err != nil {
+		return err
+	}
+	var ms []models.Mute
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		ms, err = mutes.GetActiveForActor(ctx, tx, actor)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> GetActiveForActor: %v\n", ms)
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return mutes.DeleteExpired(ctx, tx)
+	}); err != nil {
+		return err
+	}
+	return doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return mutes.Delete(ctx, tx, actor, models.ActorMute, testActor2IRI)
+	})
+}
+
+/* FollowRequests */
+
+func runFollowRequestsCalls(ctx util.Context, db *sql.DB) error {
+	actor := mustParse(testActor1IRI)
+	follower := mustParse(testActor2IRI)
+	follow := streams.NewActivityStreamsFollow()
+	idP := streams.NewJSONLDIdProperty()
+	idP.SetIRI(mustParse(testActivity7IRI))
+	follow.SetJSONLDId(idP)
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return followRequests.Create(ctx, tx, actor, follower, mustParse(testActivity7IRI), models.ActivityStreams{follow})
+	}); err != nil {
+		return err
+	}
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return followRequests.Create(ctx, tx, actor, mustParse(testActor3IRI), mustParse(testActivity8IRI), models.ActivityStreams{follow})
+	}); err != nil {
+		return err
+	}
+	var fr []models.FollowRequest
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		fr, err = followRequests.GetForActor(ctx, tx, actor)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> GetForActor: %v\n", fr)
+	var deleted bool
+	var req models.FollowRequest
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		deleted, req, err = followRequests.Delete(ctx, tx, actor, follower)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> Delete: %v %v\n", deleted, req)
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		deleted, req, err = followRequests.DeleteByActivity(ctx, tx, actor, mustParse(testActivity8IRI))
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> DeleteByActivity: %v %v\n", deleted, req)
+	return nil
+}
+
+/* Reports */
+
+func runReportsCalls(ctx util.Context, db *sql.DB) error {
+	cr := models.CreateReport{
+		ReporterID: mustParse(testActor1IRI),
+		TargetID:   mustParse(testActor2IRI),
+		ObjectIDs:  []*url.URL{mustParse(testActivity7IRI)},
+		Content:    "spam",
+	}
+	var id string
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		id, err = reports.Create(ctx, tx, cr)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> Create: %s\n", id)
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		if err := reports.MarkForwarded(ctx, tx, id, mustParse(testActivity8IRI)); err != nil {
+			return err
+		}
+		return reports.Assign(ctx, tx, id, mustParse(testActor3IRI))
+	}); err != nil {
+		return err
+	}
+	var rs []models.Report
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		rs, err = reports.List(ctx, tx, false, 0, 10)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> List (open): %v\n", rs)
+	var resolved bool
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		resolved, err = reports.Resolve(ctx, tx, id, "suspended")
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> Resolve: %v\n", resolved)
+	var found bool
+	var rep models.Report
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		found, rep, err = reports.Get(ctx, tx, id)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> Get: %v %v\n", found, rep)
+	return nil
+}
+
+/* Peers */
+
+func runPeersCalls(ctx util.Context, db *sql.DB) error {
+	host := mustParse(testPeerActor1InboxIRI).Host
+	fetched, err := runPeersSeen(ctx, db, host)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("> Seen (first contact): %v\n", fetched)
+	if err = doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return peers.MarkDeliverySuccess(ctx, tx, host)
+	}); err != nil {
+		return err
+	}
+	if err = doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return peers.MarkDeliveryFailure(ctx, tx, host)
+	}); err != nil {
+		return err
+	}
+	stale, err := runPeersGetStale(ctx, db)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("> GetStale: %v\n", stale)
+	if err = doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return peers.UpdateSoftware(ctx, tx, host,
+			sql.NullString{String: "testsoftware", Valid: true},
+			sql.NullString{String: "1.2.3", Valid: true})
+	}); err != nil {
+		return err
+	}
+	if err = doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return peers.UpdateSoftware(ctx, tx, host, sql.NullString{}, sql.NullString{})
+	}); err != nil {
+		return err
+	}
+	fetched, err = runPeersSeen(ctx, db, host)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("> Seen (after NodeInfo): %v\n", fetched)
+	var p models.Peer
+	if err = doWithTx(ctx, db, func(tx *sql.Tx) error {
+		p, err = peers.Get(ctx, tx, host)
+		return err
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> Get: %v\n", p)
+	var ps []models.Peer
+	if err = doWithTx(ctx, db, func(tx *sql.Tx) error {
+		ps, err = peers.GetAll(ctx, tx)
+		return err
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> GetAll: %v\n", ps)
+	return nil
+}
+
+func runPeersSeen(ctx util.Context, db *sql.DB, host string) (fetched sql.NullTime, err error) {
+	return fetched, doWithTx(ctx, db, func(tx *sql.Tx) error {
+		fetched, err = peers.Seen(ctx, tx, host)
+		return err
+	})
+}
+
+func runPeersGetStale(ctx util.Context, db *sql.DB) (hosts []string, err error) {
+	return hosts, doWithTx(ctx, db, func(tx *sql.Tx) error {
+		hosts, err = peers.GetStale(ctx, tx, time.Now())
+		return err
+	})
+}
+
+/* Resolutions */
+
+func runResolutionsCalls(ctx util.Context, db *sql.DB, policyID string) (resolutionID string, err error) {
+	resolutionID, err = runResolutionsCreate(ctx, db, policyID)
+	if err != nil {
+		return
+	}
+	fmt.Printf("> Create: %s\n", resolutionID)
+	var rs []models.PolicyResolution
+	if err = doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		rs, err = resolutions.GetForPolicy(ctx, tx, policyID, 0, 10)
+		return
+	}); err != nil {
+		return
+	}
+	fmt.Printf("> GetForPolicy: %v\n", rs)
+	return
+}
+
+func runResolutionsCreate(ctx util.Context, db *sql.DB, policyID string) (id string, err error) {
 	cr := models.CreateResolution{
 		PolicyID: policyID,
 		IRI:      mustParse(testActivity1IRI),
@@ -190,11 +662,73 @@ func runResolutionsCreate(ctx util.Context, db *sql.DB, policyID string) error {
 			},
 		},
 	}
-	return doWithTx(ctx, db, func(tx *sql.Tx) error {
-		return resolutions.Create(ctx, tx, cr)
+	return id, doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		id, err = resolutions.Create(ctx, tx, cr)
+		return
 	})
 }
 
+/* Quarantine */
+
+func runQuarantineCalls(ctx util.Context, db *sql.DB, resolutionID string) error {
+	note := streams.NewActivityStreamsCreate()
+	idP := streams.NewJSONLDIdProperty()
+	idP.SetIRI(mustParse(testActivity1IRI))
+	note.SetJSONLDId(idP)
+	cq := models.CreateQuarantined{
+		ActorID:      mustParse(testActor1IRI),
+		ActivityID:   mustParse(testActivity1IRI),
+		Activity:     models.ActivityStreams{note},
+		ResolutionID: resolutionID,
+	}
+	var id string
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		id, err = quarantine.Create(ctx, tx, cq)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> Create: %s\n", id)
+	var qs []models.Quarantined
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		qs, err = quarantine.GetByStatus(ctx, tx, models.QuarantinePending, 0, 10)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> GetByStatus: %v\n", qs)
+	var reviewed bool
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		reviewed, err = quarantine.Review(ctx, tx, id, models.QuarantineRejected)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> Review: %v\n", reviewed)
+	var reopened bool
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		reopened, err = quarantine.Reopen(ctx, tx, id, models.QuarantineRejected)
+		if err != nil || !reopened {
+			return
+		}
+		reviewed, err = quarantine.Review(ctx, tx, id, models.QuarantineRejected)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> Reopen: %v %v\n", reopened, reviewed)
+	var found bool
+	var qa models.Quarantined
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		found, qa, err = quarantine.Get(ctx, tx, id)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> Get: %v %v\n", found, qa)
+	return nil
+}
+
 /* Policies */
 
 func runPoliciesCalls(ctx util.Context, db *sql.DB) (policyID string, err error) {
@@ -214,13 +748,127 @@ func runPoliciesCalls(ctx util.Context, db *sql.DB) (policyID string, err error)
 		return
 	}
 	fmt.Printf("> GetForActorAndPurpose: %v\n", pd)
+	if err = runPoliciesUpdateCalls(ctx, db, policyID); err != nil {
+		return
+	}
+	var toDelete string
+	toDelete, err = runPoliciesCreate(ctx, db)
+	if err != nil {
+		return
+	}
+	err = doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return policies.Delete(ctx, tx, mustParse(testActor1IRI), toDelete)
+	})
 	return
 }
 
+func richerMatchers() []*models.KVMatcher {
+	lessThan := 10.0
+	kv := func(key string, v *models.Value) *models.KVMatcher {
+		return &models.KVMatcher{
+			KeyPathQuery: key,
+			ValueMatcher: &models.UnaryMatcher{Value: v},
+		}
+	}
+	return []*models.KVMatcher{
+		kv("actor", &models.Value{MatchesRegexp: `^https://.*/actor`}),
+		kv("actor", &models.Value{EqualsStringFold: strings.ToUpper(testActor3IRI)}),
+		kv("actor", &models.Value{ContainsStringFold: "ACTOR"}),
+		kv("score", &models.Value{NumberLess: &lessThan}),
+		kv("published", &models.Value{NewerThan: "24h"}),
+		kv("published", &models.Value{OlderThan: "24h"}),
+		kv("actor", &models.Value{HostEquals: mustParse(testActor3IRI).Host}),
+		kv("actor", &models.Value{In: []string{testActor1IRI, testActor3IRI}}),
+	}
+}
+
+// checkPolicyRoundTrip ensures that formatting policies in the policy language
+// and parsing them back yields the same policies.
+func checkPolicyRoundTrip(pd []models.PolicyDefinition) error {
+	src := models.FormatPolicies(pd)
+	parsed, err := models.ParsePolicies(src)
+	if err != nil {
+		return fmt.Errorf("ParsePolicies(FormatPolicies) failed: %w\n%s", err, src)
+	}
+	// Compare serialized forms, as matchers cache unexported compiled
+	// state.
+	want, err := json.Marshal(pd)
+	if err != nil {
+		return err
+	}
+	got, err := json.Marshal(parsed)
+	if err != nil {
+		return err
+	}
+	if string(want) != string(got) {
+		return fmt.Errorf("ParsePolicies(FormatPolicies) mismatch:\nwant %s\ngot  %s\nsource:\n%s", want, got, src)
+	}
+	fmt.Printf("> ParsePolicies(FormatPolicies): round trip ok\n")
+	return nil
+}
+
+func runPoliciesUpdateCalls(ctx util.Context, db *sql.DB, policyID string) error {
+	actor := mustParse(testActor1IRI)
+	return doWithTx(ctx, db, func(tx *sql.Tx) error {
+		p, err := policies.Get(ctx, tx, actor, policyID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("> Get: %v\n", p)
+		fmt.Printf("> FormatPolicies:\n%s", models.FormatPolicies([]models.PolicyDefinition{{
+			Purpose: p.Purpose,
+			Enabled: p.Enabled,
+			Policy:  p.Policy,
+		}}))
+		p.Policy.Description = "An updated test policy."
+		p.Policy.Matchers = append(p.Policy.Matchers, richerMatchers()...)
+		if err := p.Policy.Validate(); err != nil {
+			return err
+		}
+		if err := checkPolicyRoundTrip([]models.PolicyDefinition{{
+			Purpose: p.Purpose,
+			Enabled: p.Enabled,
+			Monitor: true,
+			Policy:  p.Policy,
+		}}); err != nil {
+			return err
+		}
+		res := models.Resolution{Time: time.Now()}
+		if err := p.Policy.Resolve([]byte(`{"actor":"`+testActor3IRI+`","published":"2020-01-01T00:00:00Z","score":3}`), &res); err != nil {
+			return err
+		}
+		fmt.Printf("> Resolve: %v\n", res)
+		if err := policies.Update(ctx, tx, actor, policyID, p.Policy); err != nil {
+			return err
+		}
+		if err := policies.SetEnabled(ctx, tx, actor, policyID, false); err != nil {
+			return err
+		}
+		pd, err := policies.GetForActorAndPurpose(ctx, tx, actor, models.FederatedBlockPurpose)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("> GetForActorAndPurpose (disabled): %v\n", pd)
+		if err := policies.SetEnabled(ctx, tx, actor, policyID, true); err != nil {
+			return err
+		}
+		if err := policies.SetMonitor(ctx, tx, actor, policyID, true); err != nil {
+			return err
+		}
+		pd, err = policies.GetForActorAndPurpose(ctx, tx, actor, models.FederatedBlockPurpose)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("> GetForActorAndPurpose (monitor): %v\n", pd)
+		return policies.SetMonitor(ctx, tx, actor, policyID, false)
+	})
+}
+
 func runPoliciesCreate(ctx util.Context, db *sql.DB) (policyID string, err error) {
 	cp := models.CreatePolicy{
 		ActorID: mustParse(testActor1IRI),
 		Purpose: models.FederatedBlockPurpose,
+		Enabled: true,
 		Policy: models.Policy{
 			Name:        "Test Policy 1",
 			Description: "A test policy.",
@@ -256,6 +904,139 @@ func runPoliciesGetForActorAndPurpose(ctx util.Context, db *sql.DB) (p []models.
 	})
 }
 
+/* Featured */
+
+func runFeaturedCalls(ctx util.Context, db *sql.DB) error {
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return featured.Create(ctx, tx, mustParse(testActor1IRI), testActor1Featured)
+	}); err != nil {
+		return err
+	}
+	var exists, has bool
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		exists, err = featured.Exists(ctx, tx, mustParse(testActor1FeaturedIRI))
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> Exists: %v\n", exists)
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return featured.PrependItem(ctx, tx, mustParse(testActor1FeaturedIRI), mustParse(testActivity5IRI))
+	}); err != nil {
+		return err
+	}
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		has, err = featured.ContainsForActor(ctx, tx, mustParse(testActor1IRI), mustParse(testActivity5IRI))
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> ContainsForActorTrue: %v\n", has)
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		has, err = featured.Contains(ctx, tx, mustParse(testActor1FeaturedIRI), mustParse(testActivity1IRI))
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> ContainsFalse: %v\n", has)
+	var p models.ActivityStreamsOrderedCollectionPage
+	var isEnd bool
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		p, isEnd, err = featured.GetPage(ctx, tx, mustParse(testActor1FeaturedIRI), 0, 1)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> GetPage(%d, %d): %s %v\n", 0, 1, p, isEnd)
+	if pb, err := toJSON(p); err != nil {
+		return err
+	} else {
+		fmt.Printf("> JSON:\n%s\n", pb)
+	}
+	var startIdx int
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		p, startIdx, err = featured.GetLastPage(ctx, tx, mustParse(testActor1FeaturedIRI), 1)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> GetLastPage(%d): %s %v\n", 1, p, startIdx)
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return featured.DeleteItem(ctx, tx, mustParse(testActor1FeaturedIRI), mustParse(testActivity4IRI))
+	}); err != nil {
+		return err
+	}
+	var c models.ActivityStreamsOrderedCollection
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		c, err = featured.GetAllForActor(ctx, tx, mustParse(testActor1IRI))
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> GetAllForActor: %s\n", c)
+	if pb, err := toJSON(c); err != nil {
+		return err
+	} else {
+		fmt.Printf("> JSON:\n%s\n", pb)
+	}
+	return nil
+}
+
+/* ObjectCollections */
+
+func runObjectCollectionsCalls(ctx util.Context, db *sql.DB) error {
+	object := mustParse(testActivity4IRI)
+	likes := mustParse(testActivity4IRI + "/likes")
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return objectCollections.Create(ctx, tx, object, "likes", testActivity4Likes)
+	}); err != nil {
+		return err
+	}
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return objectCollections.Create(ctx, tx, object, "likes", testActivity4Likes)
+	}); err != nil {
+		return err
+	}
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return objectCollections.PrependItem(ctx, tx, likes, mustParse(testActivity1IRI))
+	}); err != nil {
+		return err
+	}
+	var has bool
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		has, err = objectCollections.Contains(ctx, tx, likes, mustParse(testActivity1IRI))
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> ContainsTrue: %v\n", has)
+	var p models.ActivityStreamsCollectionPage
+	var isEnd bool
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		p, isEnd, err = objectCollections.GetPage(ctx, tx, likes, 0, 1)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> GetPage(%d, %d): %s %v\n", 0, 1, p, isEnd)
+	if pb, err := toJSON(p); err != nil {
+		return err
+	} else {
+		fmt.Printf("> JSON:\n%s\n", pb)
+	}
+	var startIdx int
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		p, startIdx, err = objectCollections.GetLastPage(ctx, tx, likes, 1)
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> GetLastPage(%d): %s %v\n", 1, p, startIdx)
+	return doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return objectCollections.DeleteItem(ctx, tx, likes, mustParse(testActivity1IRI))
+	})
+}
+
 /* Liked */
 
 func runLikedCalls(ctx util.Context, db *sql.DB) error {
@@ -744,7 +1525,7 @@ func runCredentialsCreate(ctx util.Context, db *sql.DB, clientID string) (id str
 	for i, ti := range tis {
 		var tid string
 		if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
-			tid, err = tokenInfos.Create(ctx, tx, ti)
+			tid, err = tokenInfos.Create(ctx, tx, ti, ti.Nonce)
 			return err
 		}); err != nil {
 			return "", err
@@ -805,7 +1586,7 @@ func runCredentialsDelete(ctx util.Context, db *sql.DB, clientID string) error {
 	}
 	var tid string
 	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
-		tid, err = tokenInfos.Create(ctx, tx, ti)
+		tid, err = tokenInfos.Create(ctx, tx, ti, ti.Nonce)
 		return err
 	}); err != nil {
 		return err
@@ -871,7 +1652,7 @@ func runCredentialsDeleteExpired(ctx util.Context, db *sql.DB, clientID string)
 	for i, ti := range tis {
 		var tid string
 		if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
-			tid, err = tokenInfos.Create(ctx, tx, ti)
+			tid, err = tokenInfos.Create(ctx, tx, ti, ti.Nonce)
 			return err
 		}); err != nil {
 			return err
@@ -920,7 +1701,33 @@ func runTokenInfosCalls(ctx util.Context, db *sql.DB, clientID string) error {
 		return err
 	}
 	fmt.Printf("> GetByRefresh: %v\n", ti)
-	return nil
+	ut, err := runTokenInfosGetForUser(ctx, db)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("> GetForUser: %v\n", ut)
+	return runTokenInfosRemoveForUserAndClient(ctx, db, clientID)
+}
+
+func runTokenInfosGetForUser(ctx util.Context, db *sql.DB) (ut []models.UserToken, err error) {
+	uid, err := getUserID(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	return ut, doWithTx(ctx, db, func(tx *sql.Tx) error {
+		ut, err = tokenInfos.GetForUser(ctx, tx, uid)
+		return err
+	})
+}
+
+func runTokenInfosRemoveForUserAndClient(ctx util.Context, db *sql.DB, clientID string) error {
+	uid, err := getUserID(ctx, db)
+	if err != nil {
+		return err
+	}
+	return doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return tokenInfos.RemoveForUserAndClient(ctx, tx, uid, clientID)
+	})
 }
 
 func runTokenInfosCreate(ctx util.Context, db *sql.DB, clientID string) (id string, err error) {
@@ -936,9 +1743,10 @@ func runTokenInfosCreate(ctx util.Context, db *sql.DB, clientID string) (id stri
 		Code:        sql.NullString{"code1", true},
 		Access:      sql.NullString{"access1", true},
 		Refresh:     sql.NullString{"refresh1", true},
+		Nonce:       sql.NullString{"nonce1", true},
 	}
 	return id, doWithTx(ctx, db, func(tx *sql.Tx) error {
-		id, err = tokenInfos.Create(ctx, tx, ti)
+		id, err = tokenInfos.Create(ctx, tx, ti, ti.Nonce)
 		return err
 	})
 }
@@ -959,7 +1767,7 @@ func runTokenInfosRemoveByCode(ctx util.Context, db *sql.DB, clientID string) er
 		CodeExpires: models.NullDuration{5 * time.Second, true},
 	}
 	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
-		_, err = tokenInfos.Create(ctx, tx, ti)
+		_, err = tokenInfos.Create(ctx, tx, ti, ti.Nonce)
 		return err
 	}); err != nil {
 		return err
@@ -985,7 +1793,7 @@ func runTokenInfosRemoveByAccess(ctx util.Context, db *sql.DB, clientID string)
 		AccessExpires: models.NullDuration{6 * time.Second, true},
 	}
 	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
-		_, err = tokenInfos.Create(ctx, tx, ti)
+		_, err = tokenInfos.Create(ctx, tx, ti, ti.Nonce)
 		return err
 	}); err != nil {
 		return err
@@ -1011,7 +1819,7 @@ func runTokenInfosRemoveByRefresh(ctx util.Context, db *sql.DB, clientID string)
 		RefreshExpires: models.NullDuration{7 * time.Second, true},
 	}
 	if err := doWithTx(ctx, db, func(tx *sql.Tx) error {
-		_, err = tokenInfos.Create(ctx, tx, ti)
+		_, err = tokenInfos.Create(ctx, tx, ti, ti.Nonce)
 		return err
 	}); err != nil {
 		return err
@@ -1038,7 +1846,7 @@ func runTokenInfosGetByCode(ctx util.Context, db *sql.DB, clientID string) (ti o
 		CodeExpires: models.NullDuration{8 * time.Second, true},
 	}
 	if err = doWithTx(ctx, db, func(tx *sql.Tx) error {
-		_, err = tokenInfos.Create(ctx, tx, pti)
+		_, err = tokenInfos.Create(ctx, tx, pti, pti.Nonce)
 		return err
 	}); err != nil {
 		return
@@ -1066,7 +1874,7 @@ func runTokenInfosGetByAccess(ctx util.Context, db *sql.DB, clientID string) (ti
 		AccessExpires: models.NullDuration{9 * time.Second, true},
 	}
 	if err = doWithTx(ctx, db, func(tx *sql.Tx) error {
-		_, err = tokenInfos.Create(ctx, tx, pti)
+		_, err = tokenInfos.Create(ctx, tx, pti, pti.Nonce)
 		return err
 	}); err != nil {
 		return
@@ -1094,7 +1902,7 @@ func runTokenInfosGetByRefresh(ctx util.Context, db *sql.DB, clientID string) (t
 		RefreshExpires: models.NullDuration{10 * time.Second, true},
 	}
 	if err = doWithTx(ctx, db, func(tx *sql.Tx) error {
-		_, err = tokenInfos.Create(ctx, tx, pti)
+		_, err = tokenInfos.Create(ctx, tx, pti, pti.Nonce)
 		return err
 	}); err != nil {
 		return
@@ -1117,7 +1925,54 @@ func runClientInfosCalls(ctx util.Context, db *sql.DB) (string, error) {
 		return id, err
 	}
 	fmt.Printf("> GetByUserID: %v\n", ci)
-	return id, nil
+	rid, err := runClientInfosRegister(ctx, db)
+	if err != nil {
+		return id, err
+	}
+	if err := runClientInfosSetResourceServer(ctx, db, rid); err != nil {
+		return id, err
+	}
+	cs, err := runClientInfosGetRegistered(ctx, db)
+	if err != nil {
+		return id, err
+	}
+	fmt.Printf("> GetRegistered: %v\n", cs)
+	err = runClientInfosDeleteRegistered(ctx, db, rid)
+	return id, err
+}
+
+func runClientInfosRegister(ctx util.Context, db *sql.DB) (id string, err error) {
+	ci := &models.ClientInfo{
+		ID:           "ci_registered_id",
+		Domain:       "https://client.example.com/callback",
+		Name:         "ci_name",
+		Website:      "https://client.example.com",
+		LogoURI:      "https://client.example.com/logo.png",
+		RedirectURIs: models.RedirectURIs{"https://client.example.com/callback"},
+	}
+	return id, doWithTx(ctx, db, func(tx *sql.Tx) error {
+		id, err = clientInfos.Register(ctx, tx, ci)
+		return err
+	})
+}
+
+func runClientInfosGetRegistered(ctx util.Context, db *sql.DB) (cs []*models.ClientInfo, err error) {
+	return cs, doWithTx(ctx, db, func(tx *sql.Tx) error {
+		cs, err = clientInfos.GetRegistered(ctx, tx)
+		return err
+	})
+}
+
+func runClientInfosSetResourceServer(ctx util.Context, db *sql.DB, id string) error {
+	return doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return clientInfos.SetResourceServer(ctx, tx, id, true)
+	})
+}
+
+func runClientInfosDeleteRegistered(ctx util.Context, db *sql.DB, id string) error {
+	return doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return clientInfos.DeleteRegistered(ctx, tx, id)
+	})
 }
 
 func runClientInfosCreate(ctx util.Context, db *sql.DB) (id string, err error) {
@@ -1151,6 +2006,9 @@ func runPrivateKeysCalls(ctx util.Context, db *sql.DB) error {
 	if err := runPrivateKeysCreate(ctx, db); err != nil {
 		return err
 	}
+	if err := runPrivateKeysCreateIfNotExists(ctx, db); err != nil {
+		return err
+	}
 	b, err := runPrivateKeysGetByUserID(ctx, db)
 	if err != nil {
 		return err
@@ -1174,6 +2032,18 @@ func runPrivateKeysCreate(ctx util.Context, db *sql.DB) error {
 	})
 }
 
+// runPrivateKeysCreateIfNotExists must leave the key from
+// runPrivateKeysCreate in place.
+func runPrivateKeysCreateIfNotExists(ctx util.Context, db *sql.DB) error {
+	id, err := getUserID(ctx, db)
+	if err != nil {
+		return err
+	}
+	return doWithTx(ctx, db, func(tx *sql.Tx) error {
+		return privateKeys.CreateIfNotExists(ctx, tx, id, "test", []byte{0, 0, 0, 0})
+	})
+}
+
 func runPrivateKeysGetByUserID(ctx util.Context, db *sql.DB) (b []byte, err error) {
 	id, err := getUserID(ctx, db)
 	if err != nil {
@@ -1726,6 +2596,14 @@ func runLocalDataCalls(ctx util.Context, db *sql.DB) error {
 	} else {
 		fmt.Printf("> JSON:\n%s\n", pb)
 	}
+	var many map[string]models.ActivityStreams
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		many, err = localData.GetMany(ctx, tx, []*url.URL{mustParse(testActivity4IRI), mustParse(testActivity1IRI)})
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> GetMany: %v\n", many)
 	ex, err := runLocalDataExists(ctx, db, testActivity4IRI)
 	if err != nil {
 		return err
@@ -1823,6 +2701,14 @@ func runFedDataCalls(ctx util.Context, db *sql.DB) error {
 	} else {
 		fmt.Printf("> JSON:\n%s\n", pb)
 	}
+	var many map[string]models.ActivityStreams
+	if err := doWithTx(ctx, db, func(tx *sql.Tx) (err error) {
+		many, err = fedData.GetMany(ctx, tx, []*url.URL{mustParse(testActivity1IRI), mustParse(testActivity4IRI)})
+		return
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("> GetMany: %v\n", many)
 	ex, err := runFedDataExists(ctx, db, testActivity1IRI)
 	if err != nil {
 		return err
@@ -1901,6 +2787,11 @@ func runUserModelCalls(ctx util.Context, db *sql.DB) error {
 		return err
 	}
 	fmt.Printf("> UserByID(%s): %v\n", s.ID, u)
+	ids, err := runUserModelUserIDsWithoutFeatured(ctx, db)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("> UserIDsWithoutFeatured(): %v\n", ids)
 	if pb, err := toJSON(u.Actor); err != nil {
 		return err
 	} else {
@@ -2043,6 +2934,13 @@ func runUserModelUserByID(ctx util.Context, db *sql.DB, id string) (s *models.Us
 	return s, tx.Commit()
 }
 
+func runUserModelUserIDsWithoutFeatured(ctx util.Context, db *sql.DB) (ids []string, err error) {
+	return ids, doWithTx(ctx, db, func(tx *sql.Tx) error {
+		ids, err = users.UserIDsWithoutFeatured(ctx, tx)
+		return err
+	})
+}
+
 func runUserModelActorIDForOutbox(ctx util.Context, db *sql.DB) (id models.URL, err error) {
 	return id, doWithTx(ctx, db, func(tx *sql.Tx) error {
 		id, err = users.ActorIDForOutbox(ctx, tx, mustParse(testActor1OutboxIRI))
@@ -2142,6 +3040,9 @@ func createTables(ctx util.Context, db *sql.DB, d models.SqlDialect) error {
 			return err
 		}
 	}
+	if err := models.MigrateTables(tx, d, testModels); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
@@ -50,11 +50,14 @@ var (
 	testActor1Liked     models.ActivityStreamsCollection
 	testActor2Liked     models.ActivityStreamsCollection
 	testActor3Liked     models.ActivityStreamsCollection
+	testActor1Featured  models.ActivityStreamsOrderedCollection
+	testActivity4Likes  models.ActivityStreamsCollection
 )
 
 const (
 	testActor1PreferredUsername = "testPreferredUsername"
 	testEmail1                  = "test@example.com"
+	testEmail2                  = "deleted@example.com"
 	testActor1IRI               = "https://example.com/actors/test1"
 	testActor2IRI               = "https://example.com/actors/test2"
 	testActor3IRI               = "https://example.com/actors/test3"
@@ -83,6 +86,7 @@ const (
 	testActor1LikedIRI          = "https://example.com/actors/test1/liked"
 	testActor2LikedIRI          = "https://example.com/actors/test2/liked"
 	testActor3LikedIRI          = "https://example.com/actors/test3/liked"
+	testActor1FeaturedIRI       = "https://example.com/actors/test1/featured"
 )
 
 func init() {
@@ -110,6 +114,8 @@ func init() {
 	initTestActor1Liked()
 	initTestActor2Liked()
 	initTestActor3Liked()
+	initTestActor1Featured()
+	initTestActivity4Likes()
 }
 
 func initTestActor1() {
@@ -612,3 +618,30 @@ func initTestActor3Liked() {
 	totalItems.Set(0)
 	testActor3Liked.SetActivityStreamsTotalItems(totalItems)
 }
+
+func initTestActor1Featured() {
+	testActor1Featured = models.ActivityStreamsOrderedCollection{
+		streams.NewActivityStreamsOrderedCollection(),
+	}
+	idP := streams.NewJSONLDIdProperty()
+	idP.SetIRI(mustParse(testActor1FeaturedIRI))
+	testActor1Featured.SetJSONLDId(idP)
+	totalItems := streams.NewActivityStreamsTotalItemsProperty()
+	totalItems.Set(1)
+	testActor1Featured.SetActivityStreamsTotalItems(totalItems)
+	orderedItems := streams.NewActivityStreamsOrderedItemsProperty()
+	orderedItems.AppendIRI(mustParse(testActivity4IRI))
+	testActor1Featured.SetActivityStreamsOrderedItems(orderedItems)
+}
+
+func initTestActivity4Likes() {
+	testActivity4Likes = models.ActivityStreamsCollection{
+		streams.NewActivityStreamsCollection(),
+	}
+	idP := streams.NewJSONLDIdProperty()
+	idP.SetIRI(mustParse(testActivity4IRI + "/likes"))
+	testActivity4Likes.SetJSONLDId(idP)
+	totalItems := streams.NewActivityStreamsTotalItemsProperty()
+	totalItems.Set(0)
+	testActivity4Likes.SetActivityStreamsTotalItems(totalItems)
+}
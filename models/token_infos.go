@@ -25,6 +25,8 @@ import (
 )
 
 var _ oauth2.TokenInfo = &TokenInfo{}
+var _ Model = &TokenInfos{}
+var _ Migrator = &TokenInfos{}
 
 type TokenInfo struct {
 	ClientID            string
@@ -42,6 +44,9 @@ type TokenInfo struct {
 	Refresh             sql.NullString
 	RefreshCreated      sql.NullTime
 	RefreshExpires      NullDuration
+	// Nonce is the OpenID Connect nonce of the authorization, which is
+	// not part of oauth2.TokenInfo.
+	Nonce sql.NullString
 }
 
 func (t *TokenInfo) New() oauth2.TokenInfo {
@@ -212,6 +217,14 @@ func (t *TokenInfo) SetRefreshExpiresIn(s time.Duration) {
 	t.RefreshExpires.Valid = true
 }
 
+// GetNonce returns the OpenID Connect nonce of the authorization, if any.
+func (t *TokenInfo) GetNonce() string {
+	if t.Nonce.Valid {
+		return t.Nonce.String
+	}
+	return ""
+}
+
 func (t *TokenInfo) scanFromSingleRow(r SingleRow) error {
 	return r.Scan(&(t.ClientID),
 		&(t.UserID),
@@ -227,7 +240,34 @@ func (t *TokenInfo) scanFromSingleRow(r SingleRow) error {
 		&(t.AccessExpires),
 		&(t.Refresh),
 		&(t.RefreshCreated),
-		&(t.RefreshExpires))
+		&(t.RefreshExpires),
+		&(t.Nonce))
+}
+
+// UserToken summarizes an OAuth2 token a user granted, along with the client
+// it was granted to.
+type UserToken struct {
+	ID             string
+	ClientID       string
+	ClientName     string
+	FirstParty     bool
+	Scope          string
+	AccessCreated  sql.NullTime
+	AccessExpires  NullDuration
+	RefreshCreated sql.NullTime
+	RefreshExpires NullDuration
+}
+
+// Active determines whether the access or refresh token is still usable at
+// the given time. A zero duration never expires.
+func (u UserToken) Active(now time.Time) bool {
+	active := func(created sql.NullTime, expires NullDuration) bool {
+		if !created.Valid {
+			return false
+		}
+		return !expires.Valid || expires.Duration == 0 || now.Before(created.Time.Add(expires.Duration))
+	}
+	return active(u.AccessCreated, u.AccessExpires) || active(u.RefreshCreated, u.RefreshExpires)
 }
 
 // TokenInfos is a Model that provides additional database methods for OAuth2
@@ -240,6 +280,9 @@ type TokenInfos struct {
 	getByCode       *sql.Stmt
 	getByAccess     *sql.Stmt
 	getByRefresh    *sql.Stmt
+	removeForUser   *sql.Stmt
+	getForUser      *sql.Stmt
+	removeForClient *sql.Stmt
 }
 
 func (t *TokenInfos) Prepare(db *sql.DB, s SqlDialect) error {
@@ -252,6 +295,9 @@ func (t *TokenInfos) Prepare(db *sql.DB, s SqlDialect) error {
 			{&(t.getByCode), s.GetTokenInfoByCode()},
 			{&(t.getByAccess), s.GetTokenInfoByAccess()},
 			{&(t.getByRefresh), s.GetTokenInfoByRefresh()},
+			{&(t.removeForUser), s.RemoveTokenInfosForUser()},
+			{&(t.getForUser), s.GetTokenInfosForUser()},
+			{&(t.removeForClient), s.RemoveTokenInfosForUserAndClient()},
 		})
 }
 
@@ -260,6 +306,11 @@ func (t *TokenInfos) CreateTable(tx *sql.Tx, s SqlDialect) error {
 	return err
 }
 
+func (t *TokenInfos) Migrate(tx *sql.Tx, s SqlDialect) error {
+	_, err := tx.Exec(s.MigrateTokenInfosTable())
+	return err
+}
+
 func (t *TokenInfos) Close() {
 	t.createTokenInfo.Close()
 	t.removeByCode.Close()
@@ -268,10 +319,14 @@ func (t *TokenInfos) Close() {
 	t.getByCode.Close()
 	t.getByAccess.Close()
 	t.getByRefresh.Close()
+	t.removeForUser.Close()
+	t.getForUser.Close()
+	t.removeForClient.Close()
 }
 
-// Create saves the new token information.
-func (t *TokenInfos) Create(c util.Context, tx *sql.Tx, info oauth2.TokenInfo) (id string, err error) {
+// Create saves the new token information, along with the OpenID Connect
+// nonce of its authorization.
+func (t *TokenInfos) Create(c util.Context, tx *sql.Tx, info oauth2.TokenInfo, nonce sql.NullString) (id string, err error) {
 	var rows *sql.Rows
 	rows, err = tx.Stmt(t.createTokenInfo).QueryContext(c,
 		info.GetClientID(),
@@ -289,6 +344,7 @@ func (t *TokenInfos) Create(c util.Context, tx *sql.Tx, info oauth2.TokenInfo) (
 		info.GetRefresh(),
 		info.GetRefreshCreateAt(),
 		info.GetRefreshExpiresIn(),
+		nonce,
 	)
 	if err != nil {
 		return
@@ -355,3 +411,35 @@ func (t *TokenInfos) GetByRefresh(c util.Context, tx *sql.Tx, refresh string) (o
 		return ti.scanFromSingleRow(r)
 	})
 }
+
+// RemoveForUser deletes all of the tokens granted to the user.
+func (t *TokenInfos) RemoveForUser(c util.Context, tx *sql.Tx, userID string) error {
+	_, err := tx.Stmt(t.removeForUser).ExecContext(c, userID)
+	return err
+}
+
+// GetForUser lists the issued access tokens granted by the user, including
+// expired ones.
+func (t *TokenInfos) GetForUser(c util.Context, tx *sql.Tx, userID string) (ut []UserToken, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(t.getForUser).QueryContext(c, userID)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return ut, doForRows(rows, "TokenInfos.GetForUser", func(r SingleRow) error {
+		var u UserToken
+		if err := r.Scan(&(u.ID), &(u.ClientID), &(u.ClientName), &(u.FirstParty), &(u.Scope), &(u.AccessCreated), &(u.AccessExpires), &(u.RefreshCreated), &(u.RefreshExpires)); err != nil {
+			return err
+		}
+		ut = append(ut, u)
+		return nil
+	})
+}
+
+// RemoveForUserAndClient deletes all of the tokens the user granted to the
+// client.
+func (t *TokenInfos) RemoveForUserAndClient(c util.Context, tx *sql.Tx, userID, clientID string) error {
+	_, err := tx.Stmt(t.removeForClient).ExecContext(c, userID, clientID)
+	return err
+}
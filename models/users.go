@@ -39,6 +39,10 @@ type User struct {
 	Actor       ActivityStreams
 	Privileges  Privileges
 	Preferences Preferences
+	// Suspended is not valid if the user is not suspended.
+	Suspended sql.NullTime
+	// Deleted is not valid if the user is not deleted.
+	Deleted sql.NullTime
 }
 
 type SensitiveUser struct {
@@ -48,6 +52,7 @@ type SensitiveUser struct {
 }
 
 var _ Model = &Users{}
+var _ Migrator = &Users{}
 
 // Users is a Model that provides additional database methods for the
 // Users type.
@@ -56,6 +61,7 @@ type Users struct {
 	updateActor                 *sql.Stmt
 	sensitiveUserByEmail        *sql.Stmt
 	userByID                    *sql.Stmt
+	userIDsWithoutFeatured      *sql.Stmt
 	userByPreferredUsername     *sql.Stmt
 	actorIDForOutbox            *sql.Stmt
 	actorIDForInbox             *sql.Stmt
@@ -65,6 +71,9 @@ type Users struct {
 	instanceActorPreferences    *sql.Stmt
 	setInstanceActorPreferences *sql.Stmt
 	activityStats               *sql.Stmt
+	suspend                     *sql.Stmt
+	unsuspend                   *sql.Stmt
+	markDeleted                 *sql.Stmt
 }
 
 func (u *Users) Prepare(db *sql.DB, s SqlDialect) error {
@@ -74,6 +83,7 @@ func (u *Users) Prepare(db *sql.DB, s SqlDialect) error {
 			{&(u.updateActor), s.UpdateUserActor()},
 			{&(u.sensitiveUserByEmail), s.SensitiveUserByEmail()},
 			{&(u.userByID), s.UserByID()},
+			{&(u.userIDsWithoutFeatured), s.UserIDsWithoutFeatured()},
 			{&(u.userByPreferredUsername), s.UserByPreferredUsername()},
 			{&(u.actorIDForOutbox), s.ActorIDForOutbox()},
 			{&(u.actorIDForInbox), s.ActorIDForInbox()},
@@ -83,6 +93,9 @@ func (u *Users) Prepare(db *sql.DB, s SqlDialect) error {
 			{&(u.instanceActorPreferences), s.GetInstanceActorPreferences()},
 			{&(u.setInstanceActorPreferences), s.SetInstanceActorPreferences()},
 			{&(u.activityStats), s.GetUserActivityStats()},
+			{&(u.suspend), s.SuspendUser()},
+			{&(u.unsuspend), s.UnsuspendUser()},
+			{&(u.markDeleted), s.MarkUserDeleted()},
 		})
 }
 
@@ -91,11 +104,17 @@ func (u *Users) CreateTable(t *sql.Tx, s SqlDialect) error {
 	return err
 }
 
+func (u *Users) Migrate(t *sql.Tx, s SqlDialect) error {
+	_, err := t.Exec(s.MigrateUsersTable())
+	return err
+}
+
 func (u *Users) Close() {
 	u.insertUser.Close()
 	u.updateActor.Close()
 	u.sensitiveUserByEmail.Close()
 	u.userByID.Close()
+	u.userIDsWithoutFeatured.Close()
 	u.userByPreferredUsername.Close()
 	u.actorIDForOutbox.Close()
 	u.actorIDForInbox.Close()
@@ -105,6 +124,9 @@ func (u *Users) Close() {
 	u.instanceActorPreferences.Close()
 	u.setInstanceActorPreferences.Close()
 	u.activityStats.Close()
+	u.suspend.Close()
+	u.unsuspend.Close()
+	u.markDeleted.Close()
 }
 
 // Create a User in the database.
@@ -156,7 +178,27 @@ func (u *Users) UserByID(c util.Context, tx *sql.Tx, id string) (s *User, err er
 	defer rows.Close()
 	return s, enforceOneRow(rows, "UserByID", func(r SingleRow) error {
 		s = &User{}
-		return r.Scan(&(s.ID), &(s.Email), &(s.Actor), &(s.Privileges), &(s.Preferences))
+		return r.Scan(&(s.ID), &(s.Email), &(s.Actor), &(s.Privileges), &(s.Preferences), &(s.Suspended), &(s.Deleted))
+	})
+}
+
+// UserIDsWithoutFeatured lists the users whose actor has no featured property
+// or no featured collection, as is the case for users created before featured
+// collections were supported.
+func (u *Users) UserIDsWithoutFeatured(c util.Context, tx *sql.Tx) (ids []string, err error) {
+	var rows *sql.Rows
+	rows, err = tx.Stmt(u.userIDsWithoutFeatured).QueryContext(c)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	return ids, doForRows(rows, "Users.UserIDsWithoutFeatured", func(r SingleRow) error {
+		var id string
+		if err := r.Scan(&id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		return nil
 	})
 }
 
@@ -171,7 +213,7 @@ func (u *Users) UserByPreferredUsername(c util.Context, tx *sql.Tx, name string)
 	defer rows.Close()
 	return s, enforceOneRow(rows, "UserByID", func(r SingleRow) error {
 		s = &User{}
-		return r.Scan(&(s.ID), &(s.Email), &(s.Actor), &(s.Privileges), &(s.Preferences))
+		return r.Scan(&(s.ID), &(s.Email), &(s.Actor), &(s.Privileges), &(s.Preferences), &(s.Suspended), &(s.Deleted))
 	})
 }
 
@@ -185,7 +227,7 @@ func (u *Users) InstanceActorUser(c util.Context, tx *sql.Tx) (s *User, err erro
 	defer rows.Close()
 	return s, enforceOneRow(rows, "Users.InstanceActorUser", func(r SingleRow) error {
 		s = &User{}
-		return r.Scan(&(s.ID), &(s.Email), &(s.Actor), &(s.Privileges), &(s.Preferences))
+		return r.Scan(&(s.ID), &(s.Email), &(s.Actor), &(s.Privileges), &(s.Preferences), &(s.Suspended), &(s.Deleted))
 	})
 }
 
@@ -274,3 +316,22 @@ func (u *Users) ActivityStats(c util.Context, tx *sql.Tx) (uas UserActivityStats
 			&(uas.ActiveWeek))
 	})
 }
+
+// Suspend marks the user as suspended, if it is not already.
+func (u *Users) Suspend(c util.Context, tx *sql.Tx, id string) error {
+	r, err := tx.Stmt(u.suspend).ExecContext(c, id)
+	return mustChangeOneRow(r, err, "Users.Suspend")
+}
+
+// Unsuspend lifts the suspension of the user.
+func (u *Users) Unsuspend(c util.Context, tx *sql.Tx, id string) error {
+	r, err := tx.Stmt(u.unsuspend).ExecContext(c, id)
+	return mustChangeOneRow(r, err, "Users.Unsuspend")
+}
+
+// MarkDeleted marks the user as deleted and erases its credentials. The row
+// and its actor are kept so that the username cannot be reused.
+func (u *Users) MarkDeleted(c util.Context, tx *sql.Tx, id string) error {
+	r, err := tx.Stmt(u.markDeleted).ExecContext(c, id)
+	return mustChangeOneRow(r, err, "Users.MarkDeleted")
+}
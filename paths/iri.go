@@ -45,6 +45,28 @@ const (
 
 var AllActors []Actor = []Actor{InstanceActor}
 
+// Paths of the OAuth2 endpoints served by apcore.
+const (
+	OAuth2AuthorizePath  = "/oauth2/authorize"
+	OAuth2TokenPath      = "/oauth2/token"
+	OAuth2RegisterPath   = "/oauth2/register"
+	OAuth2RevokePath     = "/oauth2/revoke"
+	OAuth2IntrospectPath = "/oauth2/introspect"
+	OAuth2MetadataPath   = "/.well-known/oauth-authorization-server"
+	OpenIDUserInfoPath   = "/oauth2/userinfo"
+	OpenIDJWKSPath       = "/oauth2/jwks"
+	OpenIDConfigPath     = "/.well-known/openid-configuration"
+)
+
+// IRIForPath returns the IRI of a path served by this server.
+func IRIForPath(scheme, host, path string) *url.URL {
+	return &url.URL{
+		Scheme: scheme,
+		Host:   host,
+		Path:   path,
+	}
+}
+
 type PathKey string
 
 const (
@@ -64,6 +86,9 @@ const (
 	LikedPathKey                  = "liked"
 	LikedFirstPathKey             = "likedFirst"
 	LikedLastPathKey              = "likedLast"
+	FeaturedPathKey               = "featured"
+	FeaturedFirstPathKey          = "featuredFirst"
+	FeaturedLastPathKey           = "featuredLast"
 	HttpSigPubKeyKey              = "httpsigPubKey"
 )
 
@@ -84,6 +109,9 @@ var knownPaths map[PathKey]string = map[PathKey]string{
 	LikedPathKey:          "{user}/liked",
 	LikedFirstPathKey:     "{user}/liked",
 	LikedLastPathKey:      "{user}/liked",
+	FeaturedPathKey:       "{user}/featured",
+	FeaturedFirstPathKey:  "{user}/featured",
+	FeaturedLastPathKey:   "{user}/featured",
 	HttpSigPubKeyKey:      "{user}",
 }
 
@@ -130,6 +158,8 @@ var knownUserPathQuery map[PathKey]string = map[PathKey]string{
 	FollowingLastPathKey:  fmt.Sprintf("%s=%s&%s=%s", queryCollectionPage, queryTrue, queryCollectionEnd, queryTrue),
 	LikedFirstPathKey:     fmt.Sprintf("%s=%s", queryCollectionPage, queryTrue),
 	LikedLastPathKey:      fmt.Sprintf("%s=%s&%s=%s", queryCollectionPage, queryTrue, queryCollectionEnd, queryTrue),
+	FeaturedFirstPathKey:  fmt.Sprintf("%s=%s", queryCollectionPage, queryTrue),
+	FeaturedLastPathKey:   fmt.Sprintf("%s=%s&%s=%s", queryCollectionPage, queryTrue, queryCollectionEnd, queryTrue),
 }
 
 var knownUserPathFragment map[PathKey]string = map[PathKey]string{
@@ -200,6 +230,20 @@ func IRIForActorID(k PathKey, actorID *url.URL) (*url.URL, error) {
 	}, nil
 }
 
+// ActorIDForSubPath returns the IRI of the local actor owning a sub-path, such
+// as its featured collection.
+func ActorIDForSubPath(id *url.URL) (*url.URL, error) {
+	s := strings.Split(id.Path, "/")
+	if len(s) < 3 {
+		return nil, fmt.Errorf("path is not an actor sub-path: %s", id.Path)
+	}
+	return &url.URL{
+		Scheme: id.Scheme,
+		Host:   id.Host,
+		Path:   strings.Join(s[:3], "/"),
+	}, nil
+}
+
 func Route(k PathKey) string {
 	return knownUserPaths(k)
 }
@@ -226,9 +270,76 @@ func IsLikedPath(id *url.URL) bool {
 	return isSubPath(id, "liked")
 }
 
+func IsFeaturedPath(id *url.URL) bool {
+	return isSubPath(id, "featured")
+}
+
 func isSubPath(id *url.URL, sub string) bool {
 	s := strings.Split(id.Path, "/")
 	return len(s) > 3 &&
 		(strings.Contains(id.Path, "users") || strings.Contains(id.Path, "actors")) &&
 		strings.Contains(s[3], sub)
 }
+
+// ObjectCollection is a collection that is maintained for every local
+// ActivityStreams object, and served on a sub-path of the object's IRI.
+type ObjectCollection string
+
+const (
+	RepliesCollection ObjectCollection = "replies"
+	LikesCollection   ObjectCollection = "likes"
+	SharesCollection  ObjectCollection = "shares"
+)
+
+var AllObjectCollections []ObjectCollection = []ObjectCollection{
+	RepliesCollection,
+	LikesCollection,
+	SharesCollection,
+}
+
+// ObjectCollectionIRIFor returns the IRI of the object's collection.
+func ObjectCollectionIRIFor(objectID *url.URL, oc ObjectCollection) *url.URL {
+	c := Normalize(objectID)
+	c.Path = strings.TrimSuffix(c.Path, "/") + "/" + string(oc)
+	return c
+}
+
+// ObjectCollectionFirstIRIFor returns the IRI of the first page of the
+// object's collection.
+func ObjectCollectionFirstIRIFor(objectID *url.URL, oc ObjectCollection) *url.URL {
+	c := ObjectCollectionIRIFor(objectID, oc)
+	c.RawQuery = fmt.Sprintf("%s=%s", queryCollectionPage, queryTrue)
+	return c
+}
+
+// ObjectCollectionLastIRIFor returns the IRI of the last page of the object's
+// collection.
+func ObjectCollectionLastIRIFor(objectID *url.URL, oc ObjectCollection) *url.URL {
+	c := ObjectCollectionIRIFor(objectID, oc)
+	c.RawQuery = fmt.Sprintf("%s=%s&%s=%s", queryCollectionPage, queryTrue, queryCollectionEnd, queryTrue)
+	return c
+}
+
+// ObjectCollectionFor determines whether the IRI is one of the collections
+// maintained for an object, returning which one and the object's IRI.
+//
+// Actors and their collections are never considered objects.
+func ObjectCollectionFor(id *url.URL) (oc ObjectCollection, objectID *url.URL, ok bool) {
+	s := strings.Split(id.Path, "/")
+	if len(s) < 3 {
+		return
+	}
+	last := ObjectCollection(s[len(s)-1])
+	for _, known := range AllObjectCollections {
+		if last != known {
+			continue
+		}
+		objectID = Normalize(id)
+		objectID.Path = strings.Join(s[:len(s)-1], "/")
+		if IsUserPath(objectID) || IsInstanceActorPath(objectID) {
+			return "", nil, false
+		}
+		return last, objectID, true
+	}
+	return
+}
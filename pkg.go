@@ -37,5 +37,6 @@ func apCoreSoftware() app.Software {
 		MinorVersion: apcoreMinorVersion,
 		PatchVersion: apcorePatchVersion,
 		Repository:   apcoreRepository,
+		Homepage:     apcoreRepository,
 	}
 }
@@ -17,11 +17,14 @@
 package services
 
 import (
+	"context"
 	"net/url"
+	"time"
 
 	"github.com/go-fed/activity/pub"
 	"github.com/go-fed/activity/streams"
 	"github.com/go-fed/activity/streams/vocab"
+	"github.com/go-fed/apcore/app"
 	"github.com/go-fed/apcore/paths"
 )
 
@@ -115,6 +118,12 @@ func toPersonActor(uuid paths.UUID,
 	likedProp.SetIRI(likedIRI)
 	p.SetActivityStreamsLiked(likedProp)
 
+	// featured
+	featuredProp := streams.NewTootFeaturedProperty()
+	featuredIRI := paths.UUIDIRIFor(scheme, host, paths.FeaturedPathKey, uuid)
+	featuredProp.SetIRI(featuredIRI)
+	p.SetTootFeatured(featuredProp)
+
 	// name
 	nameProp := streams.NewActivityStreamsNameProperty()
 	nameProp.AppendXMLSchemaString(username)
@@ -194,6 +203,22 @@ func emptyOutbox(actorID *url.URL) (vocab.ActivityStreamsOrderedCollection, erro
 	return emptyOrderedCollection(id, first, last), nil
 }
 
+func emptyFeatured(actorID *url.URL) (vocab.ActivityStreamsOrderedCollection, error) {
+	id, err := paths.IRIForActorID(paths.FeaturedPathKey, actorID)
+	if err != nil {
+		return nil, err
+	}
+	first, err := paths.IRIForActorID(paths.FeaturedFirstPathKey, actorID)
+	if err != nil {
+		return nil, err
+	}
+	last, err := paths.IRIForActorID(paths.FeaturedLastPathKey, actorID)
+	if err != nil {
+		return nil, err
+	}
+	return emptyOrderedCollection(id, first, last), nil
+}
+
 func emptyOrderedCollection(id, first, last *url.URL) vocab.ActivityStreamsOrderedCollection {
 	oc := streams.NewActivityStreamsOrderedCollection()
 	// id
@@ -340,6 +365,12 @@ func toApplicationActor(c paths.Actor, scheme, host string,
 	likedProp.SetIRI(likedIRI)
 	p.SetActivityStreamsLiked(likedProp)
 
+	// featured
+	featuredProp := streams.NewTootFeaturedProperty()
+	featuredIRI := paths.ActorIRIFor(scheme, host, paths.FeaturedPathKey, c)
+	featuredProp.SetIRI(featuredIRI)
+	p.SetTootFeatured(featuredProp)
+
 	// name
 	nameProp := streams.NewActivityStreamsNameProperty()
 	nameProp.AppendXMLSchemaString(username)
@@ -381,3 +412,75 @@ func toApplicationActor(c paths.Actor, scheme, host string,
 	p.SetW3IDSecurityV1PublicKey(publicKeyProp)
 	return p, idIRI
 }
+
+// withManuallyApprovesFollowers sets the manuallyApprovesFollowers property of
+// an actor, which tells peers whether its Follows are approved by hand. The
+// property is not part of the ActivityStreams vocabulary, so it is set on the
+// serialized actor.
+func withManuallyApprovesFollowers(c context.Context, actor vocab.Type, onFollow pub.OnFollowBehavior) (vocab.Type, error) {
+	m, err := streams.Serialize(actor)
+	if err != nil {
+		return nil, err
+	}
+	m["manuallyApprovesFollowers"] = onFollow == pub.OnFollowDoNothing
+	return streams.ToType(c, m)
+}
+
+// withEndpoints sets the client endpoints of a local user actor: the OAuth2
+// authorization and token endpoints, plus the proxyUrl and uploadMedia
+// endpoints when the application serves them. Other endpoints, such as a
+// sharedInbox, are kept. Actors other than Persons are returned unchanged.
+func withEndpoints(c context.Context, actor vocab.Type, a app.Application) (vocab.Type, error) {
+	if actor.GetTypeName() != "Person" {
+		return actor, nil
+	}
+	id, err := pub.GetId(actor)
+	if err != nil {
+		return nil, err
+	}
+	m, err := streams.Serialize(actor)
+	if err != nil {
+		return nil, err
+	}
+	ep, ok := m["endpoints"].(map[string]interface{})
+	if !ok {
+		ep = make(map[string]interface{})
+	}
+	set := func(k, path string) {
+		if len(path) == 0 {
+			delete(ep, k)
+		} else {
+			ep[k] = paths.IRIForPath(id.Scheme, id.Host, path).String()
+		}
+	}
+	set("oauthAuthorizationEndpoint", paths.OAuth2AuthorizePath)
+	set("oauthTokenEndpoint", paths.OAuth2TokenPath)
+	var proxy, upload string
+	if ea, ok := a.(app.EndpointsApplication); ok {
+		proxy, upload = ea.ProxyURLPath(), ea.UploadMediaPath()
+	}
+	set("proxyUrl", proxy)
+	set("uploadMedia", upload)
+	m["endpoints"] = ep
+	return streams.ToType(c, m)
+}
+
+// toTombstone replaces the actor or object with a Tombstone, which is served
+// with a 410 Gone status in its place.
+func toTombstone(t vocab.Type, deleted time.Time) (vocab.ActivityStreamsTombstone, error) {
+	id, err := pub.GetId(t)
+	if err != nil {
+		return nil, err
+	}
+	ts := streams.NewActivityStreamsTombstone()
+	idp := streams.NewJSONLDIdProperty()
+	idp.Set(id)
+	ts.SetJSONLDId(idp)
+	ft := streams.NewActivityStreamsFormerTypeProperty()
+	ft.AppendXMLSchemaString(t.GetTypeName())
+	ts.SetActivityStreamsFormerType(ft)
+	dp := streams.NewActivityStreamsDeletedProperty()
+	dp.Set(deleted)
+	ts.SetActivityStreamsDeleted(dp)
+	return ts, nil
+}
@@ -0,0 +1,290 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package services
+
+import (
+	"database/sql"
+	"net/url"
+	"time"
+
+	"github.com/go-fed/activity/pub"
+	"github.com/go-fed/activity/streams/vocab"
+	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/util"
+)
+
+// Block is an actor's block of another actor.
+type Block struct {
+	Actor   *url.URL
+	Blocked *url.URL
+	Created time.Time
+	// Activity is nil if the Block activity is not known.
+	Activity *url.URL
+}
+
+type Blocks struct {
+	DB        *sql.DB
+	Blocks    *models.Blocks
+	Followers *models.Followers
+	Following *models.Following
+}
+
+// Block records that the actor blocks another actor, and removes any follow
+// relationship between them in either direction. It returns false if the
+// actor already blocked the other actor, in which case nothing changes.
+//
+// The activity may be nil if the Block activity is not yet known.
+func (b *Blocks) Block(c util.Context, actor, blocked, activity *url.URL) (created bool, err error) {
+	var aid sql.NullString
+	if activity != nil {
+		aid = sql.NullString{String: activity.String(), Valid: true}
+	}
+	return created, doInTx(c, b.DB, func(tx *sql.Tx) error {
+		created, err = b.Blocks.Create(c, tx, actor, blocked, aid)
+		if err != nil || !created {
+			return err
+		}
+		for _, ac := range []actorCollection{b.Followers, b.Following} {
+			if err := removeFromActorCollection(c, tx, ac, actor, blocked); err != nil {
+				return err
+			}
+			if err := removeFromActorCollection(c, tx, ac, blocked, actor); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetActivity records the Block activity of an existing block.
+func (b *Blocks) SetActivity(c util.Context, actor, blocked, activity *url.URL) error {
+	return doInTx(c, b.DB, func(tx *sql.Tx) error {
+		return b.Blocks.SetActivity(c, tx, actor, blocked, activity)
+	})
+}
+
+// Unblock removes the actor's block of another actor. It returns false if
+// there was no such block. The Block activity is returned if it is known.
+func (b *Blocks) Unblock(c util.Context, actor, blocked *url.URL) (deleted bool, activity *url.URL, err error) {
+	return deleted, activity, doInTx(c, b.DB, func(tx *sql.Tx) error {
+		var aid sql.NullString
+		deleted, aid, err = b.Blocks.Delete(c, tx, actor, blocked)
+		if err != nil || !aid.Valid {
+			return err
+		}
+		activity, err = url.Parse(aid.String)
+		return err
+	})
+}
+
+// UnblockActivity removes the actor's block created by the Block activity. It
+// returns the actor that is no longer blocked, or nil if there was no such
+// block.
+func (b *Blocks) UnblockActivity(c util.Context, actor, activity *url.URL) (blocked *url.URL, err error) {
+	return blocked, doInTx(c, b.DB, func(tx *sql.Tx) error {
+		bid, err := b.Blocks.DeleteByActivity(c, tx, actor, activity)
+		if err != nil || len(bid) == 0 {
+			return err
+		}
+		blocked, err = url.Parse(bid)
+		return err
+	})
+}
+
+// ForActor lists the actors blocked by the actor, most recent first.
+func (b *Blocks) ForActor(c util.Context, actor *url.URL) (bs []*Block, err error) {
+	return bs, doInTx(c, b.DB, func(tx *sql.Tx) error {
+		mbs, err := b.Blocks.GetForActor(c, tx, actor)
+		if err != nil {
+			return err
+		}
+		for _, mb := range mbs {
+			bl, err := toBlock(mb)
+			if err != nil {
+				return err
+			}
+			bs = append(bs, bl)
+		}
+		return nil
+	})
+}
+
+// IsBlocking returns true if the actor blocks any of the other actors.
+func (b *Blocks) IsBlocking(c util.Context, actor *url.URL, others []*url.URL) (blocking bool, err error) {
+	var blocked map[string]bool
+	blocked, err = b.blockedSet(c, actor)
+	if err != nil {
+		return
+	}
+	for _, o := range others {
+		if blocked[o.String()] {
+			return true, nil
+		}
+	}
+	return
+}
+
+// RemoveBlockedAddressees removes the actors blocked by the actor from the
+// to, bto, cc, bcc, and audience of the ActivityStreams type, so that it is
+// not delivered to them. The addressing of embedded objects is stripped as
+// well, since it is copied onto a Create when delivering.
+func (b *Blocks) RemoveBlockedAddressees(c util.Context, actor *url.URL, t vocab.Type) error {
+	blocked, err := b.blockedSet(c, actor)
+	if err != nil || len(blocked) == 0 {
+		return err
+	}
+	if err := removeBlockedAddressing(t, blocked); err != nil {
+		return err
+	}
+	if v, ok := t.(interface {
+		GetActivityStreamsObject() vocab.ActivityStreamsObjectProperty
+	}); ok {
+		if p := v.GetActivityStreamsObject(); p != nil {
+			for iter := p.Begin(); iter != p.End(); iter = iter.Next() {
+				if o := iter.GetType(); o != nil {
+					if err := removeBlockedAddressing(o, blocked); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// removeBlockedAddressing removes the blocked actors from the to, bto, cc,
+// bcc, and audience of the ActivityStreams type.
+func removeBlockedAddressing(t vocab.Type, blocked map[string]bool) error {
+	if v, ok := t.(interface {
+		GetActivityStreamsTo() vocab.ActivityStreamsToProperty
+	}); ok {
+		if p := v.GetActivityStreamsTo(); p != nil {
+			if err := removeAddressees(p.Len(), func(i int) pub.IdProperty { return p.At(i) }, p.Remove, blocked); err != nil {
+				return err
+			}
+		}
+	}
+	if v, ok := t.(interface {
+		GetActivityStreamsBto() vocab.ActivityStreamsBtoProperty
+	}); ok {
+		if p := v.GetActivityStreamsBto(); p != nil {
+			if err := removeAddressees(p.Len(), func(i int) pub.IdProperty { return p.At(i) }, p.Remove, blocked); err != nil {
+				return err
+			}
+		}
+	}
+	if v, ok := t.(interface {
+		GetActivityStreamsCc() vocab.ActivityStreamsCcProperty
+	}); ok {
+		if p := v.GetActivityStreamsCc(); p != nil {
+			if err := removeAddressees(p.Len(), func(i int) pub.IdProperty { return p.At(i) }, p.Remove, blocked); err != nil {
+				return err
+			}
+		}
+	}
+	if v, ok := t.(interface {
+		GetActivityStreamsBcc() vocab.ActivityStreamsBccProperty
+	}); ok {
+		if p := v.GetActivityStreamsBcc(); p != nil {
+			if err := removeAddressees(p.Len(), func(i int) pub.IdProperty { return p.At(i) }, p.Remove, blocked); err != nil {
+				return err
+			}
+		}
+	}
+	if v, ok := t.(interface {
+		GetActivityStreamsAudience() vocab.ActivityStreamsAudienceProperty
+	}); ok {
+		if p := v.GetActivityStreamsAudience(); p != nil {
+			if err := removeAddressees(p.Len(), func(i int) pub.IdProperty { return p.At(i) }, p.Remove, blocked); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *Blocks) blockedSet(c util.Context, actor *url.URL) (blocked map[string]bool, err error) {
+	return blocked, doInTx(c, b.DB, func(tx *sql.Tx) error {
+		mbs, err := b.Blocks.GetForActor(c, tx, actor)
+		if err != nil {
+			return err
+		}
+		blocked = make(map[string]bool, len(mbs))
+		for _, mb := range mbs {
+			blocked[mb.BlockedID] = true
+		}
+		return nil
+	})
+}
+
+// removeAddressees removes the values of an addressing property that are
+// blocked, iterating backwards so removals do not disturb the indices yet to
+// be visited.
+func removeAddressees(n int, at func(int) pub.IdProperty, remove func(int), blocked map[string]bool) error {
+	for i := n - 1; i >= 0; i-- {
+		id, err := pub.ToId(at(i))
+		if err != nil {
+			return err
+		}
+		if blocked[id.String()] {
+			remove(i)
+		}
+	}
+	return nil
+}
+
+// actorCollection is a collection model whose rows are owned by an actor, such
+// as the followers and following collections.
+type actorCollection interface {
+	ContainsForActor(c util.Context, tx *sql.Tx, actor, item *url.URL) (bool, error)
+	GetAllForActor(c util.Context, tx *sql.Tx, actor *url.URL) (models.ActivityStreamsCollection, error)
+	DeleteItem(c util.Context, tx *sql.Tx, col, item *url.URL) error
+}
+
+// removeFromActorCollection removes the item from the actor's collection, if
+// the actor is local and the item is present.
+func removeFromActorCollection(c util.Context, tx *sql.Tx, ac actorCollection, actor, item *url.URL) error {
+	has, err := ac.ContainsForActor(c, tx, actor, item)
+	if err != nil || !has {
+		return err
+	}
+	col, err := ac.GetAllForActor(c, tx, actor)
+	if err != nil {
+		return err
+	}
+	id, err := pub.GetId(col)
+	if err != nil {
+		return err
+	}
+	return ac.DeleteItem(c, tx, id, item)
+}
+
+func toBlock(mb models.Block) (bl *Block, err error) {
+	bl = &Block{
+		Created: mb.Created,
+	}
+	if bl.Actor, err = url.Parse(mb.ActorID); err != nil {
+		return
+	}
+	if bl.Blocked, err = url.Parse(mb.BlockedID); err != nil {
+		return
+	}
+	if mb.ActivityID.Valid {
+		bl.Activity, err = url.Parse(mb.ActivityID.String)
+	}
+	return
+}
@@ -41,7 +41,7 @@ func (c *Crypto) Valid(ctx util.Context, email, pass string) (uuid string, valid
 		su, err = c.Users.SensitiveUserByEmail(ctx, tx, email)
 		return err
 	})
-	if err != nil {
+	if err != nil || su == nil {
 		return
 	}
 	valid = passEquals(pass, su.Salt, su.Hashpass)
@@ -22,13 +22,16 @@ import (
 
 	"github.com/go-fed/activity/pub"
 	"github.com/go-fed/activity/streams/vocab"
+	"github.com/go-fed/apcore/app"
 	"github.com/go-fed/apcore/models"
 	"github.com/go-fed/apcore/paths"
 	"github.com/go-fed/apcore/util"
 )
 
 type Data struct {
+	App                   app.Application
 	DB                    *sql.DB
+	Clock                 pub.Clock
 	Hostname              string
 	FedData               *models.FedData
 	LocalData             *models.LocalData
@@ -36,6 +39,8 @@ type Data struct {
 	Following             *Following
 	Followers             *Followers
 	Liked                 *Liked
+	Featured              *Featured
+	ObjectCollections     *ObjectCollections
 	DefaultCollectionSize int
 	MaxCollectionPageSize int
 }
@@ -93,6 +98,26 @@ func (d *Data) Get(c util.Context, id *url.URL) (v vocab.Type, err error) {
 				d.MaxCollectionPageSize,
 				any,
 				last)
+		} else if paths.IsFeaturedPath(id) {
+			// Users created before featured collections were
+			// supported are backfilled at startup.
+			any := d.Featured.GetPage
+			last := d.Featured.GetLastPage
+			v, err = DoOrderedCollectionPagination(c,
+				id,
+				d.DefaultCollectionSize,
+				d.MaxCollectionPageSize,
+				any,
+				last)
+		} else if _, _, ok := paths.ObjectCollectionFor(id); ok {
+			any := d.ObjectCollections.GetPage
+			last := d.ObjectCollections.GetLastPage
+			v, err = DoCollectionPagination(c,
+				id,
+				d.DefaultCollectionSize,
+				d.MaxCollectionPageSize,
+				any,
+				last)
 		} else if paths.IsInstanceActorPath(id) {
 			err = doInTx(c, d.DB, func(tx *sql.Tx) error {
 				var as *models.User
@@ -114,9 +139,18 @@ func (d *Data) Get(c util.Context, id *url.URL) (v vocab.Type, err error) {
 				as, err = d.Users.UserByID(c, tx, string(uid))
 				if err != nil {
 					return err
+				} else if as.Deleted.Valid {
+					v, err = toTombstone(as.Actor.Type, as.Deleted.Time)
+					return err
+				} else if as.Suspended.Valid {
+					v, err = toTombstone(as.Actor.Type, as.Suspended.Time)
+					return err
 				}
-				v = as.Actor.Type
-				return nil
+				// Endpoints are set when served so that actors
+				// created before them, or before the application's
+				// paths changed, are never stale.
+				v, err = withEndpoints(c, as.Actor.Type, d.App)
+				return err
 			})
 		} else {
 			err = doInTx(c, d.DB, func(tx *sql.Tx) error {
@@ -143,6 +177,66 @@ func (d *Data) Get(c util.Context, id *url.URL) (v vocab.Type, err error) {
 	return
 }
 
+// GetMany obtains the locally stored or federated ActivityStreams data, such
+// as the items of an inbox, keyed by id. Ids that are not found, as well as
+// actors and collections, are absent from the result.
+func (d *Data) GetMany(c util.Context, ids []*url.URL) (m map[string]vocab.Type, err error) {
+	var local, fed []*url.URL
+	for _, id := range ids {
+		if d.Owns(id) {
+			local = append(local, id)
+		} else {
+			fed = append(fed, id)
+		}
+	}
+	m = make(map[string]vocab.Type, len(ids))
+	err = doInTx(c, d.DB, func(tx *sql.Tx) error {
+		for _, q := range []struct {
+			ids []*url.URL
+			fn  func(util.Context, *sql.Tx, []*url.URL) (map[string]models.ActivityStreams, error)
+		}{
+			{local, d.LocalData.GetMany},
+			{fed, d.FedData.GetMany},
+		} {
+			if len(q.ids) == 0 {
+				continue
+			}
+			as, err := q.fn(c, tx, q.ids)
+			if err != nil {
+				return err
+			}
+			for id, v := range as {
+				m[id] = v.Type
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// GetLocalObject obtains the locally stored ActivityStreams object, as opposed
+// to an actor or a collection. A nil value is returned if the id does not
+// refer to such an object.
+func (d *Data) GetLocalObject(c util.Context, id *url.URL) (v vocab.Type, err error) {
+	if !d.Owns(id) {
+		return
+	}
+	err = doInTx(c, d.DB, func(tx *sql.Tx) error {
+		exists, err := d.LocalData.Exists(c, tx, id)
+		if err != nil || !exists {
+			return err
+		}
+		var as models.ActivityStreams
+		as, err = d.LocalData.Get(c, tx, id)
+		if err != nil {
+			return err
+		}
+		v = as.Type
+		return nil
+	})
+	return
+}
+
 // Create stores the ActivityStreams payload locally or federated.
 func (d *Data) Create(c util.Context, v vocab.Type) (err error) {
 	var iri *url.URL
@@ -158,7 +252,10 @@ func (d *Data) Create(c util.Context, v vocab.Type) (err error) {
 	}
 	if d.Owns(iri) {
 		err = doInTx(c, d.DB, func(tx *sql.Tx) error {
-			return d.LocalData.Create(c, tx, models.ActivityStreams{v})
+			if err := d.LocalData.Create(c, tx, models.ActivityStreams{v}); err != nil {
+				return err
+			}
+			return d.ObjectCollections.createForObject(c, tx, iri, v)
 		})
 	} else {
 		err = doInTx(c, d.DB, func(tx *sql.Tx) error {
@@ -187,16 +284,74 @@ func (d *Data) Update(c util.Context, v vocab.Type) (err error) {
 	return
 }
 
-// Delete removes the ActivityStreams payload locally or federated.
+// Delete replaces the ActivityStreams payload, locally or federated, with a
+// Tombstone. This way, fetching it results in a 410 Gone and a repeated
+// delivery of its creation does not bring it back.
 func (d *Data) Delete(c util.Context, iri *url.URL) (err error) {
 	if d.Owns(iri) {
 		err = doInTx(c, d.DB, func(tx *sql.Tx) error {
-			return d.LocalData.Delete(c, tx, iri)
+			as, err := d.LocalData.Get(c, tx, iri)
+			if err != nil {
+				return err
+			}
+			ts, ok, err := d.tombstoneFor(as.Type)
+			if err != nil || !ok {
+				return err
+			}
+			return d.LocalData.Update(c, tx, iri, models.ActivityStreams{ts})
 		})
 	} else {
+		// Federated data may never have been stored, such as a remote
+		// actor deleting itself.
 		err = doInTx(c, d.DB, func(tx *sql.Tx) error {
-			return d.FedData.Delete(c, tx, iri)
+			exists, err := d.FedData.Exists(c, tx, iri)
+			if err != nil || !exists {
+				return err
+			}
+			as, err := d.FedData.Get(c, tx, iri)
+			if err != nil {
+				return err
+			}
+			ts, ok, err := d.tombstoneFor(as.Type)
+			if err != nil || !ok {
+				return err
+			}
+			return d.FedData.Update(c, tx, iri, models.ActivityStreams{ts})
 		})
 	}
 	return
 }
+
+// tombstoneFor returns the Tombstone replacing the deleted value, or false if
+// there is no value or it already is a Tombstone.
+func (d *Data) tombstoneFor(t vocab.Type) (ts vocab.Type, ok bool, err error) {
+	if t == nil {
+		return
+	} else if _, isTombstone := t.(vocab.ActivityStreamsTombstone); isTombstone {
+		return
+	}
+	ts, err = toTombstone(t, d.Clock.Now())
+	ok = err == nil
+	return
+}
+
+// DeleteFederatedActor purges the federated actor along with all federated
+// data it authored.
+func (d *Data) DeleteFederatedActor(c util.Context, actor *url.URL) (err error) {
+	if d.Owns(actor) {
+		return
+	}
+	err = doInTx(c, d.DB, func(tx *sql.Tx) error {
+		return d.FedData.DeleteForActor(c, tx, actor)
+	})
+	return
+}
+
+// SharedInboxes obtains the shared inboxes of all known federated actors.
+func (d *Data) SharedInboxes(c util.Context) (iris []*url.URL, err error) {
+	err = doInTx(c, d.DB, func(tx *sql.Tx) error {
+		iris, err = d.FedData.SharedInboxes(c, tx)
+		return err
+	})
+	return
+}
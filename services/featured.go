@@ -0,0 +1,201 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package services
+
+import (
+	"database/sql"
+	"net/url"
+
+	"github.com/go-fed/activity/streams"
+	"github.com/go-fed/activity/streams/vocab"
+	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/paths"
+	"github.com/go-fed/apcore/util"
+)
+
+// Featured manages the featured, or pinned, collections of local users as
+// well as the copies of federated actors' featured collections that peers
+// keep up to date with Add and Remove activities.
+type Featured struct {
+	DB       *sql.DB
+	Featured *models.Featured
+}
+
+func (f *Featured) ContainsForActor(c util.Context, actor, id *url.URL) (has bool, err error) {
+	return has, doInTx(c, f.DB, func(tx *sql.Tx) error {
+		has, err = f.Featured.ContainsForActor(c, tx, actor, id)
+		return err
+	})
+}
+
+func (f *Featured) Contains(c util.Context, featured, id *url.URL) (has bool, err error) {
+	return has, doInTx(c, f.DB, func(tx *sql.Tx) error {
+		has, err = f.Featured.Contains(c, tx, featured, id)
+		return err
+	})
+}
+
+func (f *Featured) GetPage(c util.Context, featured *url.URL, min, n int) (page vocab.ActivityStreamsOrderedCollectionPage, err error) {
+	err = doInTx(c, f.DB, func(tx *sql.Tx) error {
+		var isEnd bool
+		var mp models.ActivityStreamsOrderedCollectionPage
+		mp, isEnd, err = f.Featured.GetPage(c, tx, featured, min, min+n)
+		if err != nil {
+			return err
+		}
+		page = mp.ActivityStreamsOrderedCollectionPage
+		return addNextPrev(page, min, n, isEnd)
+	})
+	return
+}
+
+func (f *Featured) GetLastPage(c util.Context, featured *url.URL, n int) (page vocab.ActivityStreamsOrderedCollectionPage, err error) {
+	err = doInTx(c, f.DB, func(tx *sql.Tx) error {
+		var startIdx int
+		var mp models.ActivityStreamsOrderedCollectionPage
+		mp, startIdx, err = f.Featured.GetLastPage(c, tx, featured, n)
+		if err != nil {
+			return err
+		}
+		page = mp.ActivityStreamsOrderedCollectionPage
+		return addNextPrev(page, startIdx, n, true)
+	})
+	return
+}
+
+func (f *Featured) GetAllForActor(c util.Context, actor *url.URL) (col vocab.ActivityStreamsOrderedCollection, err error) {
+	err = doInTx(c, f.DB, func(tx *sql.Tx) error {
+		var mc models.ActivityStreamsOrderedCollection
+		mc, err = f.Featured.GetAllForActor(c, tx, actor)
+		if err != nil {
+			return err
+		}
+		col = mc.ActivityStreamsOrderedCollection
+		return err
+	})
+	return
+}
+
+// Pin adds the item to the local actor's featured collection. It returns false
+// if the item was already pinned.
+func (f *Featured) Pin(c util.Context, actorID, item *url.URL) (pinned bool, err error) {
+	var featured *url.URL
+	featured, err = paths.IRIForActorID(paths.FeaturedPathKey, actorID)
+	if err != nil {
+		return
+	}
+	return f.addItem(c, actorID, featured, item, func() (vocab.ActivityStreamsOrderedCollection, error) {
+		return emptyFeatured(actorID)
+	})
+}
+
+// ensureLocalFeatured creates the empty featured collection of a local actor
+// if it does not exist yet, as is the case for users created before featured
+// collections were supported. See Users.BackfillFeatured.
+func ensureLocalFeatured(c util.Context, tx *sql.Tx, m *models.Featured, actorID *url.URL) error {
+	featured, err := paths.IRIForActorID(paths.FeaturedPathKey, actorID)
+	if err != nil {
+		return err
+	}
+	exists, err := m.Exists(c, tx, featured)
+	if err != nil || exists {
+		return err
+	}
+	oc, err := emptyFeatured(actorID)
+	if err != nil {
+		return err
+	}
+	return m.Create(c, tx, actorID, models.ActivityStreamsOrderedCollection{oc})
+}
+
+// Unpin removes the item from the local actor's featured collection. It
+// returns false if the item was not pinned.
+func (f *Featured) Unpin(c util.Context, actorID, item *url.URL) (unpinned bool, err error) {
+	var featured *url.URL
+	featured, err = paths.IRIForActorID(paths.FeaturedPathKey, actorID)
+	if err != nil {
+		return
+	}
+	return f.deleteItem(c, featured, item)
+}
+
+// AddFederated records that a federated actor has added the item to their
+// featured collection.
+func (f *Featured) AddFederated(c util.Context, actorID, featured, item *url.URL) error {
+	_, err := f.addItem(c, actorID, featured, item, func() (vocab.ActivityStreamsOrderedCollection, error) {
+		return federatedFeatured(featured), nil
+	})
+	return err
+}
+
+// RemoveFederated records that a federated actor has removed the item from
+// their featured collection.
+func (f *Featured) RemoveFederated(c util.Context, featured, item *url.URL) error {
+	_, err := f.deleteItem(c, featured, item)
+	return err
+}
+
+func (f *Featured) addItem(c util.Context, actorID, featured, item *url.URL, empty func() (vocab.ActivityStreamsOrderedCollection, error)) (added bool, err error) {
+	return added, doInTx(c, f.DB, func(tx *sql.Tx) error {
+		exists, err := f.Featured.Exists(c, tx, featured)
+		if err != nil {
+			return err
+		} else if !exists {
+			oc, err := empty()
+			if err != nil {
+				return err
+			}
+			err = f.Featured.Create(c, tx, actorID, models.ActivityStreamsOrderedCollection{oc})
+			if err != nil {
+				return err
+			}
+		} else if has, err := f.Featured.Contains(c, tx, featured, item); err != nil {
+			return err
+		} else if has {
+			return nil
+		}
+		added = true
+		return f.Featured.PrependItem(c, tx, featured, item)
+	})
+}
+
+func (f *Featured) deleteItem(c util.Context, featured, item *url.URL) (deleted bool, err error) {
+	return deleted, doInTx(c, f.DB, func(tx *sql.Tx) error {
+		has, err := f.Featured.Contains(c, tx, featured, item)
+		if err != nil {
+			return err
+		} else if !has {
+			return nil
+		}
+		deleted = true
+		return f.Featured.DeleteItem(c, tx, featured, item)
+	})
+}
+
+// federatedFeatured is the bare OrderedCollection used to track a federated
+// actor's featured collection.
+func federatedFeatured(id *url.URL) vocab.ActivityStreamsOrderedCollection {
+	oc := streams.NewActivityStreamsOrderedCollection()
+	idProp := streams.NewJSONLDIdProperty()
+	idProp.SetIRI(id)
+	oc.SetJSONLDId(idProp)
+	tiProp := streams.NewActivityStreamsTotalItemsProperty()
+	tiProp.Set(0)
+	oc.SetActivityStreamsTotalItems(tiProp)
+	oc.SetActivityStreamsOrderedItems(streams.NewActivityStreamsOrderedItemsProperty())
+	return oc
+}
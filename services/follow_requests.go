@@ -0,0 +1,161 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-fed/activity/pub"
+	"github.com/go-fed/activity/streams/vocab"
+	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/util"
+)
+
+// FollowRequest is a pending Follow of a local user.
+type FollowRequest struct {
+	Follower *url.URL
+	Follow   vocab.ActivityStreamsFollow
+	Created  time.Time
+}
+
+type FollowRequests struct {
+	DB             *sql.DB
+	FollowRequests *models.FollowRequests
+	Followers      *models.Followers
+}
+
+// Create records the Follow as pending approval by the actor.
+func (f *FollowRequests) Create(c util.Context, actor *url.URL, follow vocab.ActivityStreamsFollow) error {
+	id, err := pub.GetId(follow)
+	if err != nil {
+		return err
+	}
+	actors := follow.GetActivityStreamsActor()
+	if actors == nil || actors.Len() == 0 {
+		return fmt.Errorf("cannot create follow request: Follow %s has no actor", id)
+	}
+	return doInTx(c, f.DB, func(tx *sql.Tx) error {
+		for iter := actors.Begin(); iter != actors.End(); iter = iter.Next() {
+			follower, err := pub.ToId(iter)
+			if err != nil {
+				return err
+			}
+			if err := f.FollowRequests.Create(c, tx, actor, follower, id, models.ActivityStreams{follow}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ForActor lists the Follows pending approval by the actor, oldest first.
+func (f *FollowRequests) ForActor(c util.Context, actor *url.URL) (fr []*FollowRequest, err error) {
+	return fr, doInTx(c, f.DB, func(tx *sql.Tx) error {
+		mfr, err := f.FollowRequests.GetForActor(c, tx, actor)
+		if err != nil {
+			return err
+		}
+		for _, m := range mfr {
+			req, err := toFollowRequest(m)
+			if err != nil {
+				return err
+			}
+			fr = append(fr, req)
+		}
+		return nil
+	})
+}
+
+// Accept approves the follower's pending Follow of the actor, adding the
+// follower to the actor's followers. The Follow is returned so it can be
+// accepted, and is nil if there was no pending Follow.
+func (f *FollowRequests) Accept(c util.Context, actor, follower *url.URL) (follow vocab.ActivityStreamsFollow, err error) {
+	return f.resolve(c, actor, true, func(tx *sql.Tx) (bool, models.FollowRequest, error) {
+		return f.FollowRequests.Delete(c, tx, actor, follower)
+	})
+}
+
+// AcceptActivity approves the pending Follow of the actor with the given id.
+// It is otherwise the same as Accept.
+func (f *FollowRequests) AcceptActivity(c util.Context, actor, followID *url.URL) (follow vocab.ActivityStreamsFollow, err error) {
+	return f.resolve(c, actor, true, func(tx *sql.Tx) (bool, models.FollowRequest, error) {
+		return f.FollowRequests.DeleteByActivity(c, tx, actor, followID)
+	})
+}
+
+// Reject removes the follower's pending Follow of the actor without adding the
+// follower to the actor's followers. The Follow is returned so it can be
+// rejected, and is nil if there was no pending Follow.
+func (f *FollowRequests) Reject(c util.Context, actor, follower *url.URL) (follow vocab.ActivityStreamsFollow, err error) {
+	return f.resolve(c, actor, false, func(tx *sql.Tx) (bool, models.FollowRequest, error) {
+		return f.FollowRequests.Delete(c, tx, actor, follower)
+	})
+}
+
+// RejectActivity removes the pending Follow of the actor with the given id.
+// It is otherwise the same as Reject.
+func (f *FollowRequests) RejectActivity(c util.Context, actor, followID *url.URL) (follow vocab.ActivityStreamsFollow, err error) {
+	return f.resolve(c, actor, false, func(tx *sql.Tx) (bool, models.FollowRequest, error) {
+		return f.FollowRequests.DeleteByActivity(c, tx, actor, followID)
+	})
+}
+
+func (f *FollowRequests) resolve(c util.Context, actor *url.URL, accept bool, del func(tx *sql.Tx) (bool, models.FollowRequest, error)) (follow vocab.ActivityStreamsFollow, err error) {
+	return follow, doInTx(c, f.DB, func(tx *sql.Tx) error {
+		deleted, m, err := del(tx)
+		if err != nil || !deleted {
+			return err
+		}
+		req, err := toFollowRequest(m)
+		if err != nil {
+			return err
+		}
+		follow = req.Follow
+		if !accept {
+			return nil
+		}
+		has, err := f.Followers.ContainsForActor(c, tx, actor, req.Follower)
+		if err != nil || has {
+			return err
+		}
+		followers, err := f.Followers.GetAllForActor(c, tx, actor)
+		if err != nil {
+			return err
+		}
+		id, err := pub.GetId(followers)
+		if err != nil {
+			return err
+		}
+		return f.Followers.PrependItem(c, tx, id, req.Follower)
+	})
+}
+
+func toFollowRequest(m models.FollowRequest) (req *FollowRequest, err error) {
+	follow, ok := m.Follow.Type.(vocab.ActivityStreamsFollow)
+	if !ok {
+		return nil, fmt.Errorf("follow request is not a Follow: %T", m.Follow.Type)
+	}
+	req = &FollowRequest{
+		Follow:  follow,
+		Created: m.Created,
+	}
+	req.Follower, err = url.Parse(m.FollowerID)
+	return
+}
@@ -0,0 +1,140 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package services
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/util"
+)
+
+// Mute is a user's mute of an actor or a domain. Exactly one of Actor and
+// Domain is set.
+type Mute struct {
+	Actor   *url.URL
+	Domain  string
+	Created time.Time
+	// Expires is the zero time if the mute does not expire.
+	Expires time.Time
+}
+
+// Mutes are not federated: they only affect what a user is shown.
+type Mutes struct {
+	DB    *sql.DB
+	Mutes *models.Mutes
+}
+
+// MuteActor mutes the other actor for the actor until it expires. A zero
+// expiry never expires. Muting an actor already muted replaces its expiry.
+func (m *Mutes) MuteActor(c util.Context, actor, muted *url.URL, expires time.Time) error {
+	return m.mute(c, actor, models.ActorMute, muted.String(), expires)
+}
+
+// MuteDomain mutes all actors on the domain for the actor until it expires. A
+// zero expiry never expires. Muting a domain already muted replaces its
+// expiry.
+func (m *Mutes) MuteDomain(c util.Context, actor *url.URL, domain string, expires time.Time) error {
+	return m.mute(c, actor, models.DomainMute, strings.ToLower(domain), expires)
+}
+
+func (m *Mutes) UnmuteActor(c util.Context, actor, muted *url.URL) error {
+	return doInTx(c, m.DB, func(tx *sql.Tx) error {
+		return m.Mutes.Delete(c, tx, actor, models.ActorMute, muted.String())
+	})
+}
+
+func (m *Mutes) UnmuteDomain(c util.Context, actor *url.URL, domain string) error {
+	return doInTx(c, m.DB, func(tx *sql.Tx) error {
+		return m.Mutes.Delete(c, tx, actor, models.DomainMute, strings.ToLower(domain))
+	})
+}
+
+// ForActor lists the actor's mutes that have not expired, most recent first.
+func (m *Mutes) ForActor(c util.Context, actor *url.URL) (ms []*Mute, err error) {
+	return ms, doInTx(c, m.DB, func(tx *sql.Tx) error {
+		mms, err := m.Mutes.GetActiveForActor(c, tx, actor)
+		if err != nil {
+			return err
+		}
+		for _, mm := range mms {
+			mu := &Mute{
+				Created: mm.Created,
+				Expires: mm.Expires.Time,
+			}
+			switch mm.Kind {
+			case models.ActorMute:
+				if mu.Actor, err = url.Parse(mm.Target); err != nil {
+					return err
+				}
+			case models.DomainMute:
+				mu.Domain = mm.Target
+			}
+			ms = append(ms, mu)
+		}
+		return nil
+	})
+}
+
+// Matcher fetches the actor's active mutes, returning a function that
+// determines whether any of the given IRIs are muted. An IRI is muted if it is
+// a muted actor or is on a muted domain.
+func (m *Mutes) Matcher(c util.Context, actor *url.URL) (muted func(iris ...*url.URL) bool, err error) {
+	actors := make(map[string]bool)
+	domains := make(map[string]bool)
+	err = doInTx(c, m.DB, func(tx *sql.Tx) error {
+		mms, err := m.Mutes.GetActiveForActor(c, tx, actor)
+		if err != nil {
+			return err
+		}
+		for _, mm := range mms {
+			switch mm.Kind {
+			case models.ActorMute:
+				actors[mm.Target] = true
+			case models.DomainMute:
+				domains[mm.Target] = true
+			}
+		}
+		return nil
+	})
+	muted = func(iris ...*url.URL) bool {
+		for _, iri := range iris {
+			if actors[iri.String()] || domains[strings.ToLower(iri.Hostname())] {
+				return true
+			}
+		}
+		return false
+	}
+	return
+}
+
+func (m *Mutes) mute(c util.Context, actor *url.URL, kind models.MuteKind, target string, expires time.Time) error {
+	var exp sql.NullTime
+	if !expires.IsZero() {
+		exp = sql.NullTime{Time: expires, Valid: true}
+	}
+	return doInTx(c, m.DB, func(tx *sql.Tx) error {
+		// Opportunistically clean up, as expired mutes are never shown.
+		if err := m.Mutes.DeleteExpired(c, tx); err != nil {
+			return err
+		}
+		return m.Mutes.Create(c, tx, actor, kind, target, exp)
+	})
+}
@@ -43,6 +43,7 @@ type ServerPreferences struct {
 	OpenRegistrations bool
 	ServerBaseURL     string
 	ServerName        string
+	ServerDescription string
 	OrgName           string
 	OrgContact        string
 	OrgAccount        string
@@ -19,6 +19,7 @@ package services
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/go-fed/apcore/models"
 	"github.com/go-fed/apcore/util"
@@ -44,10 +45,16 @@ func (o *OAuth2) GetByID(ctx context.Context, id string) (ci oauth2.ClientInfo,
 	})
 }
 
+// Create stores the token information along with the OpenID Connect nonce
+// held in the request context, if any.
 func (o *OAuth2) Create(ctx context.Context, info oauth2.TokenInfo) error {
 	c := util.Context{ctx}
+	var nonce sql.NullString
+	if n, ok := c.OpenIDNonce(); ok && len(*n) > 0 {
+		nonce = sql.NullString{String: *n, Valid: true}
+	}
 	return doInTx(c, o.DB, func(tx *sql.Tx) error {
-		_, err := o.Token.Create(c, tx, info)
+		_, err := o.Token.Create(c, tx, info, nonce)
 		return err
 	})
 }
@@ -73,12 +80,21 @@ func (o *OAuth2) RemoveByRefresh(ctx context.Context, refresh string) error {
 	})
 }
 
+// GetByCode fetches the token information of an authorization code. Its
+// OpenID Connect nonce is passed on through the request context, so the
+// tokens the code is exchanged for are stored with it.
 func (o *OAuth2) GetByCode(ctx context.Context, code string) (ti oauth2.TokenInfo, err error) {
 	c := util.Context{ctx}
-	return ti, doInTx(c, o.DB, func(tx *sql.Tx) error {
+	err = doInTx(c, o.DB, func(tx *sql.Tx) error {
 		ti, err = o.Token.GetByCode(c, tx, code)
 		return err
 	})
+	if n, ok := c.OpenIDNonce(); ok && err == nil {
+		if t, ok := ti.(*models.TokenInfo); ok {
+			*n = t.GetNonce()
+		}
+	}
+	return
 }
 
 func (o *OAuth2) GetByAccess(ctx context.Context, access string) (ti oauth2.TokenInfo, err error) {
@@ -109,7 +125,7 @@ func (o *OAuth2) ProxyCreateCredential(ctx context.Context, ti oauth2.TokenInfo)
 		if err != nil {
 			return err
 		}
-		tID, err := o.Token.Create(c, tx, ti)
+		tID, err := o.Token.Create(c, tx, ti, sql.NullString{})
 		if err != nil {
 			return err
 		}
@@ -150,3 +166,130 @@ func (o *OAuth2) DeleteExpiredFirstPartyCredentials(ctx context.Context) error {
 		return o.Creds.DeleteExpired(c, tx)
 	})
 }
+
+// RegisterClient stores a dynamically registered client.
+func (o *OAuth2) RegisterClient(c util.Context, ci *models.ClientInfo) error {
+	return doInTx(c, o.DB, func(tx *sql.Tx) error {
+		_, err := o.Client.Register(c, tx, ci)
+		return err
+	})
+}
+
+// RegisteredClient fetches a dynamically registered client, or returns nil if
+// there is no such client.
+func (o *OAuth2) RegisteredClient(c util.Context, id string) (ci *models.ClientInfo, err error) {
+	return ci, doInTx(c, o.DB, func(tx *sql.Tx) error {
+		var oci oauth2.ClientInfo
+		oci, err = o.Client.GetByID(c, tx, id)
+		if err != nil {
+			return err
+		}
+		if mci, ok := oci.(*models.ClientInfo); ok && len(mci.ID) > 0 && len(mci.UserID) == 0 {
+			ci = mci
+		}
+		return nil
+	})
+}
+
+// RegisteredClients lists the dynamically registered clients.
+func (o *OAuth2) RegisteredClients(c util.Context) (cs []*models.ClientInfo, err error) {
+	return cs, doInTx(c, o.DB, func(tx *sql.Tx) error {
+		cs, err = o.Client.GetRegistered(c, tx)
+		return err
+	})
+}
+
+// SetResourceServer sets whether a dynamically registered client is a resource
+// server, permitted to introspect the tokens issued to other clients.
+func (o *OAuth2) SetResourceServer(c util.Context, id string, b bool) error {
+	return doInTx(c, o.DB, func(tx *sql.Tx) error {
+		return o.Client.SetResourceServer(c, tx, id, b)
+	})
+}
+
+// RemoveRegisteredClient deletes a dynamically registered client and revokes
+// all of its tokens.
+func (o *OAuth2) RemoveRegisteredClient(c util.Context, id string) error {
+	return doInTx(c, o.DB, func(tx *sql.Tx) error {
+		return o.Client.DeleteRegistered(c, tx, id)
+	})
+}
+
+const (
+	// Token type hints of RFC 7009 and RFC 7662.
+	AccessTokenHint  = "access_token"
+	RefreshTokenHint = "refresh_token"
+)
+
+// LookupToken finds the token information for an access or refresh token,
+// trying the kind given by the hint first. It returns nil if the token is
+// unknown.
+func (o *OAuth2) LookupToken(c util.Context, token, hint string) (ti oauth2.TokenInfo, refresh bool, err error) {
+	lookups := []bool{false, true}
+	if hint == RefreshTokenHint {
+		lookups = []bool{true, false}
+	}
+	err = doInTx(c, o.DB, func(tx *sql.Tx) error {
+		for _, r := range lookups {
+			var t oauth2.TokenInfo
+			var err error
+			if r {
+				t, err = o.Token.GetByRefresh(c, tx, token)
+			} else {
+				t, err = o.Token.GetByAccess(c, tx, token)
+			}
+			if err != nil {
+				return err
+			} else if len(t.GetClientID()) > 0 {
+				ti, refresh = t, r
+				return nil
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// RevokeToken deletes the access or refresh token, along with its pair, if it
+// was issued to the client. Unknown tokens and tokens of other clients are
+// ignored.
+func (o *OAuth2) RevokeToken(c util.Context, clientID, token, hint string) error {
+	ti, refresh, err := o.LookupToken(c, token, hint)
+	if err != nil || ti == nil || ti.GetClientID() != clientID {
+		return err
+	}
+	return doInTx(c, o.DB, func(tx *sql.Tx) error {
+		if refresh {
+			return o.Token.RemoveByRefresh(c, tx, token)
+		}
+		return o.Token.RemoveByAccess(c, tx, token)
+	})
+}
+
+// ActiveUserTokens lists the tokens granted by the user that have not yet
+// expired.
+func (o *OAuth2) ActiveUserTokens(c util.Context, userID string) (ut []models.UserToken, err error) {
+	var all []models.UserToken
+	err = doInTx(c, o.DB, func(tx *sql.Tx) error {
+		all, err = o.Token.GetForUser(c, tx, userID)
+		return err
+	})
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, u := range all {
+		if u.Active(now) {
+			ut = append(ut, u)
+		}
+	}
+	return
+}
+
+// RevokeUserClientTokens deletes all of the tokens the user granted to the
+// client.
+func (o *OAuth2) RevokeUserClientTokens(c util.Context, userID, clientID string) error {
+	return doInTx(c, o.DB, func(tx *sql.Tx) error {
+		return o.Token.RemoveForUserAndClient(c, tx, userID, clientID)
+	})
+}
@@ -0,0 +1,120 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package services
+
+import (
+	"database/sql"
+	"net/url"
+
+	"github.com/go-fed/activity/streams/vocab"
+	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/paths"
+	"github.com/go-fed/apcore/util"
+)
+
+// ObjectCollections manages the replies, likes, and shares collections of
+// local objects.
+type ObjectCollections struct {
+	DB                *sql.DB
+	ObjectCollections *models.ObjectCollections
+}
+
+// createForObject stores empty replies, likes, and shares collections for a
+// local object that refers to them, if they do not already exist.
+func (o *ObjectCollections) createForObject(c util.Context, tx *sql.Tx, objectID *url.URL, v vocab.Type) error {
+	r, ok := v.(interface {
+		GetActivityStreamsReplies() vocab.ActivityStreamsRepliesProperty
+	})
+	if !ok {
+		return nil
+	} else if p := r.GetActivityStreamsReplies(); p == nil || !p.IsIRI() ||
+		p.GetIRI().String() != paths.ObjectCollectionIRIFor(objectID, paths.RepliesCollection).String() {
+		return nil
+	}
+	for _, oc := range paths.AllObjectCollections {
+		if err := o.create(c, tx, objectID, oc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *ObjectCollections) GetPage(c util.Context, col *url.URL, min, n int) (page vocab.ActivityStreamsCollectionPage, err error) {
+	err = doInTx(c, o.DB, func(tx *sql.Tx) error {
+		var isEnd bool
+		var mp models.ActivityStreamsCollectionPage
+		mp, isEnd, err = o.ObjectCollections.GetPage(c, tx, col, min, min+n)
+		if err != nil {
+			return err
+		}
+		page = mp.ActivityStreamsCollectionPage
+		return addNextPrevCol(page, min, n, isEnd)
+	})
+	return
+}
+
+func (o *ObjectCollections) GetLastPage(c util.Context, col *url.URL, n int) (page vocab.ActivityStreamsCollectionPage, err error) {
+	err = doInTx(c, o.DB, func(tx *sql.Tx) error {
+		var startIdx int
+		var mp models.ActivityStreamsCollectionPage
+		mp, startIdx, err = o.ObjectCollections.GetLastPage(c, tx, col, n)
+		if err != nil {
+			return err
+		}
+		page = mp.ActivityStreamsCollectionPage
+		return addNextPrevCol(page, startIdx, n, true)
+	})
+	return
+}
+
+// Add prepends the item to the object's collection, creating the collection
+// if the object predates it. Adding an item already in the collection does
+// nothing.
+func (o *ObjectCollections) Add(c util.Context, objectID *url.URL, oc paths.ObjectCollection, item *url.URL) error {
+	col := paths.ObjectCollectionIRIFor(objectID, oc)
+	return doInTx(c, o.DB, func(tx *sql.Tx) error {
+		if err := o.create(c, tx, objectID, oc); err != nil {
+			return err
+		}
+		if has, err := o.ObjectCollections.Contains(c, tx, col, item); err != nil {
+			return err
+		} else if has {
+			return nil
+		}
+		return o.ObjectCollections.PrependItem(c, tx, col, item)
+	})
+}
+
+// Remove deletes the item from the object's collection, if present.
+func (o *ObjectCollections) Remove(c util.Context, objectID *url.URL, oc paths.ObjectCollection, item *url.URL) error {
+	col := paths.ObjectCollectionIRIFor(objectID, oc)
+	return doInTx(c, o.DB, func(tx *sql.Tx) error {
+		if has, err := o.ObjectCollections.Contains(c, tx, col, item); err != nil {
+			return err
+		} else if !has {
+			return nil
+		}
+		return o.ObjectCollections.DeleteItem(c, tx, col, item)
+	})
+}
+
+func (o *ObjectCollections) create(c util.Context, tx *sql.Tx, objectID *url.URL, oc paths.ObjectCollection) error {
+	col := emptyCollection(paths.ObjectCollectionIRIFor(objectID, oc),
+		paths.ObjectCollectionFirstIRIFor(objectID, oc),
+		paths.ObjectCollectionLastIRIFor(objectID, oc))
+	return o.ObjectCollections.Create(c, tx, objectID, string(oc), models.ActivityStreamsCollection{col})
+}
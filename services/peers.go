@@ -0,0 +1,134 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/util"
+)
+
+// Peer is a remote host this server has exchanged traffic with.
+type Peer struct {
+	Host      string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// SoftwareName and SoftwareVersion are those reported by the host's
+	// NodeInfo, and are empty if it has never been successfully fetched.
+	SoftwareName    string
+	SoftwareVersion string
+	// NodeInfoFetched is the zero time if NodeInfo has never been fetched.
+	NodeInfoFetched     time.Time
+	DeliverySuccesses   int
+	DeliveryFailures    int
+	LastDeliverySuccess time.Time
+	LastDeliveryFailure time.Time
+}
+
+type Peers struct {
+	DB    *sql.DB
+	Peers *models.Peers
+}
+
+// Seen records traffic with a host. It returns true if the host's NodeInfo has
+// never been fetched, such as upon first contact.
+func (p *Peers) Seen(c util.Context, host string) (needsNodeInfo bool, err error) {
+	return needsNodeInfo, doInTx(c, p.DB, func(tx *sql.Tx) error {
+		fetched, err := p.Peers.Seen(c, tx, host)
+		needsNodeInfo = !fetched.Valid
+		return err
+	})
+}
+
+func (p *Peers) DeliverySucceeded(c util.Context, host string) error {
+	return doInTx(c, p.DB, func(tx *sql.Tx) error {
+		return p.Peers.MarkDeliverySuccess(c, tx, host)
+	})
+}
+
+func (p *Peers) DeliveryFailed(c util.Context, host string) error {
+	return doInTx(c, p.DB, func(tx *sql.Tx) error {
+		return p.Peers.MarkDeliveryFailure(c, tx, host)
+	})
+}
+
+// SetSoftware records the software a host reports in its NodeInfo.
+func (p *Peers) SetSoftware(c util.Context, host, name, version string) error {
+	return doInTx(c, p.DB, func(tx *sql.Tx) error {
+		return p.Peers.UpdateSoftware(c, tx, host,
+			sql.NullString{String: name, Valid: true},
+			sql.NullString{String: version, Valid: true})
+	})
+}
+
+// SoftwareUnavailable records an attempt to fetch a host's NodeInfo that did
+// not succeed, retaining any software previously reported.
+func (p *Peers) SoftwareUnavailable(c util.Context, host string) error {
+	return doInTx(c, p.DB, func(tx *sql.Tx) error {
+		return p.Peers.UpdateSoftware(c, tx, host, sql.NullString{}, sql.NullString{})
+	})
+}
+
+// StaleHosts returns the hosts whose NodeInfo has not been fetched within the
+// given duration.
+func (p *Peers) StaleHosts(c util.Context, age time.Duration) (hosts []string, err error) {
+	return hosts, doInTx(c, p.DB, func(tx *sql.Tx) error {
+		hosts, err = p.Peers.GetStale(c, tx, time.Now().Add(-age))
+		return err
+	})
+}
+
+func (p *Peers) PeerByHost(c util.Context, host string) (pr *Peer, err error) {
+	return pr, doInTx(c, p.DB, func(tx *sql.Tx) error {
+		mp, err := p.Peers.Get(c, tx, host)
+		if err != nil {
+			return err
+		}
+		pr = toPeer(mp)
+		return nil
+	})
+}
+
+func (p *Peers) AllPeers(c util.Context) (ps []*Peer, err error) {
+	return ps, doInTx(c, p.DB, func(tx *sql.Tx) error {
+		mps, err := p.Peers.GetAll(c, tx)
+		if err != nil {
+			return err
+		}
+		for _, mp := range mps {
+			ps = append(ps, toPeer(mp))
+		}
+		return nil
+	})
+}
+
+func toPeer(mp models.Peer) *Peer {
+	return &Peer{
+		Host:                mp.Host,
+		FirstSeen:           mp.FirstSeen,
+		LastSeen:            mp.LastSeen,
+		SoftwareName:        mp.SoftwareName.String,
+		SoftwareVersion:     mp.SoftwareVersion.String,
+		NodeInfoFetched:     mp.NodeInfoFetched.Time,
+		DeliverySuccesses:   mp.NDeliverySuccesses,
+		DeliveryFailures:    mp.NDeliveryFailures,
+		LastDeliverySuccess: mp.LastDeliverySuccess.Time,
+		LastDeliveryFailure: mp.LastDeliveryFailure.Time,
+	}
+}
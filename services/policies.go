@@ -18,54 +18,496 @@ package services
 
 import (
 	"database/sql"
+	"fmt"
+	"math/rand"
 	"net/url"
+	"sync"
+	"time"
 
 	"github.com/go-fed/activity/pub"
+	"github.com/go-fed/activity/streams/vocab"
 	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/paths"
 	"github.com/go-fed/apcore/util"
 )
 
 type Policies struct {
+	Scheme      string
+	Host        string
 	Clock       pub.Clock
 	DB          *sql.DB
+	FedData     *models.FedData
 	Policies    *models.Policies
 	Resolutions *models.Resolutions
+	// CacheInvalidated is how long compiled policies are cached before
+	// they are reloaded. Changes made through Policies take effect
+	// immediately.
+	CacheInvalidated time.Duration
+	Logging          ResolutionLogging
+	// SampleRate is the fraction of unmatched resolutions recorded when
+	// Logging is LogSampledResolutions.
+	SampleRate float64
+	Rand       *rand.Rand
+	mu         sync.RWMutex
+	gen        uint64
+	cache      map[string]compiledPolicies
 }
 
+// InstanceActorIRI is the IRI of the instance actor, whose policies apply to
+// every local recipient.
+func (p *Policies) InstanceActorIRI() *url.URL {
+	return paths.ActorIRIFor(p.Scheme, p.Host, paths.UserPathKey, paths.InstanceActor)
+}
+
+// scopedActor is an owner of policies applying to a recipient.
+type scopedActor struct {
+	Scope   models.Scope
+	ActorID *url.URL
+}
+
+// scopesFor returns the owners of the policies applying to the recipient, in
+// order of precedence: the instance actor, then the recipient themselves.
+func (p *Policies) scopesFor(actorID *url.URL) []scopedActor {
+	ia := p.InstanceActorIRI()
+	s := []scopedActor{{Scope: models.InstanceScope, ActorID: ia}}
+	if actorID.String() != ia.String() {
+		s = append(s, scopedActor{Scope: models.UserScope, ActorID: actorID})
+	}
+	return s
+}
+
+// IsBlocked determines whether the activity is blocked by the instance's or the
+// actor's federated_block policies. Instance policies take precedence: once one
+// matches, the actor's own policies are not evaluated.
 func (p *Policies) IsBlocked(c util.Context, actorID *url.URL, a pub.Activity) (blocked bool, err error) {
 	var iri *url.URL
-	iri, err = pub.GetId(a)
-	if err != nil {
-		return
+	var jsonb []byte
+	var rs []models.CreateResolution
+	for _, sa := range p.scopesFor(actorID) {
+		if blocked {
+			break
+		}
+		var pd []models.PolicyAndID
+		pd, err = p.enabledFor(c, sa.ActorID, models.FederatedBlockPurpose)
+		if err != nil {
+			return
+		}
+		for _, policy := range pd {
+			// Only serialize the activity once a policy applies.
+			if jsonb == nil {
+				if iri, err = pub.GetId(a); err != nil {
+					return
+				} else if jsonb, err = models.Marshal(a); err != nil {
+					return
+				}
+			}
+			var res models.Resolution
+			var record bool
+			res, record, err = p.resolve(policy, sa.Scope, jsonb)
+			if err != nil {
+				return
+			}
+			if res.Matched && policy.Monitor {
+				res.Log("monitor only: not blocked")
+			}
+			if record {
+				rs = append(rs, models.CreateResolution{
+					PolicyID: policy.ID,
+					IRI:      iri,
+					R:        res,
+				})
+			}
+			blocked = blocked || (res.Matched && !policy.Monitor)
+		}
 	}
+	_, err = p.record(c, rs)
+	return
+}
+
+// Quarantine determines whether the activity is held for moderation by the
+// instance's or the actor's quarantine policies, returning the id of the
+// Resolution of the first policy that quarantined it. Instance policies take
+// precedence: once one matches, the actor's own policies are not evaluated.
+func (p *Policies) Quarantine(c util.Context, actorID *url.URL, a pub.Activity) (quarantined bool, resolutionID string, err error) {
+	var iri *url.URL
 	var jsonb []byte
-	jsonb, err = models.Marshal(a)
+	var rs []models.CreateResolution
+	first := -1
+	for _, sa := range p.scopesFor(actorID) {
+		if first >= 0 {
+			break
+		}
+		var pd []models.PolicyAndID
+		pd, err = p.enabledFor(c, sa.ActorID, models.QuarantinePurpose)
+		if err != nil {
+			return
+		}
+		for _, policy := range pd {
+			if jsonb == nil {
+				if iri, err = pub.GetId(a); err != nil {
+					return
+				} else if jsonb, err = models.Marshal(a); err != nil {
+					return
+				}
+			}
+			var res models.Resolution
+			var record bool
+			res, record, err = p.resolve(policy, sa.Scope, jsonb)
+			if err != nil {
+				return
+			}
+			if res.Matched && policy.Monitor {
+				res.Log("monitor only: not quarantined")
+			}
+			if !record {
+				continue
+			}
+			if first < 0 && res.Matched && !policy.Monitor {
+				first = len(rs)
+			}
+			rs = append(rs, models.CreateResolution{
+				PolicyID: policy.ID,
+				IRI:      iri,
+				R:        res,
+			})
+		}
+	}
+	var ids []string
+	if ids, err = p.record(c, rs); err != nil || first < 0 {
+		return
+	}
+	return true, ids[first], nil
+}
+
+// Rewrite applies the instance's and then the actor's enabled rewrite policies
+// to federated data read on behalf of the actor, modifying it in place without
+// recording Resolutions. Stored federated data is shared by all of its local
+// recipients, so a recipient's policies are applied again when it is read.
+func (p *Policies) Rewrite(c util.Context, actorID *url.URL, t vocab.Type) error {
+	_, err := p.rewrite(c, actorID, t)
+	return err
+}
+
+// ApplyRewrites applies the instance's and then the actor's enabled rewrite
+// policies to an authorized activity received by the actor, modifying it in
+// place, and records their Resolutions. Each inbox request parses its own
+// copy of the activity, so the rewrites apply only to this recipient.
+func (p *Policies) ApplyRewrites(c util.Context, actorID *url.URL, a pub.Activity) error {
+	rs, err := p.rewrite(c, actorID, a)
 	if err != nil {
+		return err
+	}
+	_, err = p.record(c, rs)
+	return err
+}
+
+// rewrite applies the rewrite policies applying to the actor to the data in
+// place, returning the Resolutions to record.
+func (p *Policies) rewrite(c util.Context, actorID *url.URL, t vocab.Type) (rs []models.CreateResolution, err error) {
+	var iri *url.URL
+	var jsonb []byte
+	for _, sa := range p.scopesFor(actorID) {
+		for _, purpose := range models.RewritePurposes {
+			var pd []models.PolicyAndID
+			pd, err = p.enabledFor(c, sa.ActorID, purpose)
+			if err != nil {
+				return
+			}
+			for _, policy := range pd {
+				if jsonb == nil {
+					if iri, err = pub.GetId(t); err != nil {
+						return
+					} else if jsonb, err = models.Marshal(t); err != nil {
+						return
+					}
+				}
+				var res models.Resolution
+				var record bool
+				res, record, err = p.resolve(policy, sa.Scope, jsonb)
+				if err != nil {
+					return
+				}
+				if res.Matched && policy.Monitor {
+					res.Log("monitor only: not rewritten")
+				} else if res.Matched {
+					rewrites[purpose](t, policy.Policy, &res)
+					// Later policies match against the rewritten
+					// data.
+					if jsonb, err = models.Marshal(t); err != nil {
+						return
+					}
+				}
+				if record {
+					rs = append(rs, models.CreateResolution{
+						PolicyID: policy.ID,
+						IRI:      iri,
+						R:        res,
+					})
+				}
+			}
+		}
+	}
+	return
+}
+
+// Simulate applies a candidate policy to up to n of the most recently received
+// federated data without recording anything, returning a resolution for each.
+func (p *Policies) Simulate(c util.Context, purpose models.Purpose, po models.Policy, n int) (rs []*Resolution, err error) {
+	if err = ValidatePolicy(purpose, po); err != nil {
+		return
+	} else if err = po.Compile(); err != nil {
 		return
 	}
 	err = doInTx(c, p.DB, func(tx *sql.Tx) error {
-		pd, err := p.Policies.GetForActorAndPurpose(c, tx, actorID, models.FederatedBlockPurpose)
+		fp, err := p.FedData.GetRecent(c, tx, n)
 		if err != nil {
 			return err
 		}
-		for _, policy := range pd {
+		for _, d := range fp {
 			var res models.Resolution
 			res.Time = p.Clock.Now()
-			err = policy.Policy.Resolve(jsonb, &res)
+			if err := po.Resolve(d.Payload, &res); err != nil {
+				return err
+			}
+			iri, err := url.Parse(d.ID)
 			if err != nil {
 				return err
 			}
-			err = p.Resolutions.Create(c, tx, models.CreateResolution{
-				PolicyID: policy.ID,
+			rs = append(rs, &Resolution{
 				IRI:      iri,
-				R:        res,
+				Time:     res.Time,
+				Matched:  res.Matched,
+				MatchLog: res.MatchLog,
 			})
-			if err != nil {
+		}
+		return nil
+	})
+	return
+}
+
+// Policy is an actor's policy for a purpose, such as blocking federated data.
+type Policy struct {
+	ID      string
+	Purpose models.Purpose
+	Enabled bool
+	// Monitor is whether the policy only records its resolutions, without
+	// blocking or rewriting anything.
+	Monitor bool
+	Policy  models.Policy
+}
+
+// Resolution is the outcome of applying a policy to the data at the IRI.
+type Resolution struct {
+	ID       string
+	IRI      *url.URL
+	Time     time.Time
+	Matched  bool
+	MatchLog []string
+	Scope    models.Scope
+}
+
+// Create adds a policy for the actor, returning its id.
+func (p *Policies) Create(c util.Context, actorID *url.URL, purpose models.Purpose, po models.Policy, enabled, monitor bool) (id string, err error) {
+	defer p.invalidate(actorID)
+	if err = ValidatePolicy(purpose, po); err != nil {
+		return
+	}
+	err = doInTx(c, p.DB, func(tx *sql.Tx) error {
+		id, err = p.Policies.Create(c, tx, models.CreatePolicy{
+			ActorID: actorID,
+			Purpose: purpose,
+			Policy:  po,
+			Enabled: enabled,
+			Monitor: monitor,
+		})
+		return err
+	})
+	return
+}
+
+// ForActor lists all of the actor's policies, enabled or not.
+func (p *Policies) ForActor(c util.Context, actorID *url.URL) (ps []*Policy, err error) {
+	err = doInTx(c, p.DB, func(tx *sql.Tx) error {
+		mp, err := p.Policies.GetForActor(c, tx, actorID)
+		if err != nil {
+			return err
+		}
+		for _, m := range mp {
+			ps = append(ps, &Policy{
+				ID:      m.ID,
+				Purpose: m.Purpose,
+				Enabled: m.Enabled,
+				Monitor: m.Monitor,
+				Policy:  m.Policy,
+			})
+		}
+		return nil
+	})
+	return
+}
+
+// Get obtains the actor's policy, which is nil if the actor has no policy with
+// the id.
+func (p *Policies) Get(c util.Context, actorID *url.URL, id string) (po *Policy, err error) {
+	err = doInTx(c, p.DB, func(tx *sql.Tx) error {
+		m, err := p.Policies.Get(c, tx, actorID, id)
+		if err != nil || m == nil {
+			return err
+		}
+		po = &Policy{
+			ID:      m.ID,
+			Purpose: m.Purpose,
+			Enabled: m.Enabled,
+			Monitor: m.Monitor,
+			Policy:  m.Policy,
+		}
+		return nil
+	})
+	return
+}
+
+// Update replaces the actor's policy, keeping its purpose and whether it is
+// enabled.
+func (p *Policies) Update(c util.Context, actorID *url.URL, id string, po models.Policy) error {
+	defer p.invalidate(actorID)
+	return doInTx(c, p.DB, func(tx *sql.Tx) error {
+		m, err := p.Policies.Get(c, tx, actorID, id)
+		if err != nil {
+			return err
+		} else if m == nil {
+			return fmt.Errorf("no policy %s for %s", id, actorID)
+		} else if err := ValidatePolicy(m.Purpose, po); err != nil {
+			return err
+		}
+		return p.Policies.Update(c, tx, actorID, id, po)
+	})
+}
+
+// SetEnabled enables or disables the actor's policy. Disabled policies are not
+// applied and so produce no resolutions.
+func (p *Policies) SetEnabled(c util.Context, actorID *url.URL, id string, enabled bool) error {
+	defer p.invalidate(actorID)
+	return doInTx(c, p.DB, func(tx *sql.Tx) error {
+		return p.Policies.SetEnabled(c, tx, actorID, id, enabled)
+	})
+}
+
+// Import creates the actor's policies from the definitions, replacing the
+// policy, state, and description of any existing policy with the same name and
+// purpose so that importing a policy set again does not duplicate it.
+func (p *Policies) Import(c util.Context, actorID *url.URL, pd []models.PolicyDefinition) (created, updated int, err error) {
+	defer p.invalidate(actorID)
+	type key struct {
+		Name    string
+		Purpose models.Purpose
+	}
+	seen := make(map[key]bool, len(pd))
+	for _, d := range pd {
+		k := key{d.Policy.Name, d.Purpose}
+		if seen[k] {
+			err = fmt.Errorf("policy %q for %s is defined more than once", k.Name, k.Purpose)
+			return
+		} else if err = ValidatePolicy(d.Purpose, d.Policy); err != nil {
+			err = fmt.Errorf("policy %q: %w", d.Policy.Name, err)
+			return
+		}
+		seen[k] = true
+	}
+	err = doInTx(c, p.DB, func(tx *sql.Tx) error {
+		mp, err := p.Policies.GetForActor(c, tx, actorID)
+		if err != nil {
+			return err
+		}
+		existing := make(map[key]string, len(mp))
+		for _, m := range mp {
+			existing[key{m.Policy.Name, m.Purpose}] = m.ID
+		}
+		for _, d := range pd {
+			id, ok := existing[key{d.Policy.Name, d.Purpose}]
+			if !ok {
+				_, err = p.Policies.Create(c, tx, models.CreatePolicy{
+					ActorID: actorID,
+					Purpose: d.Purpose,
+					Policy:  d.Policy,
+					Enabled: d.Enabled,
+					Monitor: d.Monitor,
+				})
+				if err != nil {
+					return err
+				}
+				created++
+				continue
+			}
+			if err := p.Policies.Update(c, tx, actorID, id, d.Policy); err != nil {
+				return err
+			} else if err := p.Policies.SetEnabled(c, tx, actorID, id, d.Enabled); err != nil {
+				return err
+			} else if err := p.Policies.SetMonitor(c, tx, actorID, id, d.Monitor); err != nil {
 				return err
 			}
-			blocked = blocked || res.Matched
+			updated++
 		}
 		return nil
 	})
 	return
 }
+
+// SetMonitor puts the actor's policy in or out of monitor-only mode, in which
+// its resolutions are recorded but it does not take effect.
+func (p *Policies) SetMonitor(c util.Context, actorID *url.URL, id string, monitor bool) error {
+	defer p.invalidate(actorID)
+	return doInTx(c, p.DB, func(tx *sql.Tx) error {
+		return p.Policies.SetMonitor(c, tx, actorID, id, monitor)
+	})
+}
+
+// Delete removes the actor's policy and its resolutions.
+func (p *Policies) Delete(c util.Context, actorID *url.URL, id string) error {
+	defer p.invalidate(actorID)
+	return doInTx(c, p.DB, func(tx *sql.Tx) error {
+		return p.Policies.Delete(c, tx, actorID, id)
+	})
+}
+
+// ResolutionsFor obtains at most n resolutions of the actor's policy, most recent
+// first, skipping the first offset ones. isEnd is true if there are no more
+// resolutions after these.
+func (p *Policies) ResolutionsFor(c util.Context, actorID *url.URL, id string, offset, n int) (rs []*Resolution, isEnd bool, err error) {
+	err = doInTx(c, p.DB, func(tx *sql.Tx) error {
+		m, err := p.Policies.Get(c, tx, actorID, id)
+		if err != nil {
+			return err
+		} else if m == nil {
+			return fmt.Errorf("no policy %s for %s", id, actorID)
+		}
+		// Fetch one more to determine if this is the end.
+		mr, err := p.Resolutions.GetForPolicy(c, tx, id, offset, n+1)
+		if err != nil {
+			return err
+		}
+		isEnd = len(mr) <= n
+		if !isEnd {
+			mr = mr[:n]
+		}
+		for _, r := range mr {
+			rs = append(rs, &Resolution{
+				ID:       r.ID,
+				IRI:      r.IRI,
+				Time:     r.R.Time,
+				Matched:  r.R.Matched,
+				MatchLog: r.R.MatchLog,
+				Scope:    r.R.Scope,
+			})
+		}
+		return nil
+	})
+	return
+}
+
+// ValidatePolicy determines whether the policy is well formed for the purpose.
+func ValidatePolicy(purpose models.Purpose, po models.Policy) error {
+	if _, isRewrite := rewrites[purpose]; !isRewrite && purpose != models.FederatedBlockPurpose && purpose != models.QuarantinePurpose {
+		return fmt.Errorf("unsupported policy purpose: %q", purpose)
+	}
+	return po.Validate()
+}
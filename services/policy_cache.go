@@ -0,0 +1,146 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package services
+
+import (
+	"database/sql"
+	"net/url"
+	"time"
+
+	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/util"
+)
+
+// ResolutionLogging determines which resolutions of applying policies to
+// incoming activities are recorded.
+type ResolutionLogging string
+
+const (
+	// LogAllResolutions records every resolution.
+	LogAllResolutions ResolutionLogging = "all"
+	// LogMatchedResolutions records only the resolutions that matched.
+	LogMatchedResolutions ResolutionLogging = "matches"
+	// LogSampledResolutions records the resolutions that matched and a
+	// random sample of the rest.
+	LogSampledResolutions ResolutionLogging = "sampled"
+)
+
+// compiledPolicies are an actor's enabled policies by purpose, compiled when
+// they were loaded.
+type compiledPolicies struct {
+	loaded    time.Time
+	byPurpose map[models.Purpose][]models.PolicyAndID
+}
+
+// enabledFor returns the actor's enabled and compiled policies for the
+// purpose, loading them from the database if they are not cached or the cache
+// has expired. Policies that fail to compile are logged and skipped, so they
+// do not stop the actor's other policies from applying.
+func (p *Policies) enabledFor(c util.Context, actorID *url.URL, purpose models.Purpose) ([]models.PolicyAndID, error) {
+	key := actorID.String()
+	now := p.Clock.Now()
+	p.mu.RLock()
+	cp, ok := p.cache[key]
+	gen := p.gen
+	p.mu.RUnlock()
+	if ok && now.Sub(cp.loaded) < p.CacheInvalidated {
+		return cp.byPurpose[purpose], nil
+	}
+	var mp []models.PolicyAndPurpose
+	if err := doInTx(c, p.DB, func(tx *sql.Tx) (err error) {
+		mp, err = p.Policies.GetForActor(c, tx, actorID)
+		return
+	}); err != nil {
+		return nil, err
+	}
+	cp = compiledPolicies{
+		loaded:    now,
+		byPurpose: make(map[models.Purpose][]models.PolicyAndID),
+	}
+	for _, m := range mp {
+		if !m.Enabled {
+			continue
+		}
+		if err := m.Policy.Compile(); err != nil {
+			util.ErrorLogger.Errorf("skipping policy %s of %s: %s", m.ID, actorID, err)
+			continue
+		}
+		cp.byPurpose[m.Purpose] = append(cp.byPurpose[m.Purpose], models.PolicyAndID{
+			ID:      m.ID,
+			Policy:  m.Policy,
+			Monitor: m.Monitor,
+		})
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Do not cache policies loaded before a concurrent change.
+	if gen == p.gen {
+		if p.cache == nil {
+			p.cache = make(map[string]compiledPolicies)
+		}
+		p.cache[key] = cp
+	}
+	return cp.byPurpose[purpose], nil
+}
+
+// invalidate drops the actor's cached policies after they change.
+func (p *Policies) invalidate(actorID *url.URL) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.gen++
+	delete(p.cache, actorID.String())
+}
+
+// resolve applies the policy to the data. When the resolution would not be
+// recorded under the logging configuration, its log is not built, and record
+// is false.
+func (p *Policies) resolve(po models.PolicyAndID, scope models.Scope, jsonb []byte) (res models.Resolution, record bool, err error) {
+	record = p.Logging == LogAllResolutions || len(p.Logging) == 0
+	if !record && p.Logging == LogSampledResolutions {
+		p.mu.Lock()
+		record = p.Rand.Float64() < p.SampleRate
+		p.mu.Unlock()
+	}
+	res.Time = p.Clock.Now()
+	res.Scope = scope
+	res.DiscardLog = !record
+	if err = po.Policy.Resolve(jsonb, &res); err != nil || record || !res.Matched {
+		return
+	}
+	// Matches are always recorded, so resolve again to build the log.
+	res = models.Resolution{Time: res.Time, Scope: scope}
+	record = true
+	err = po.Policy.Resolve(jsonb, &res)
+	return
+}
+
+// record stores the resolutions in one transaction, returning their ids.
+func (p *Policies) record(c util.Context, rs []models.CreateResolution) (ids []string, err error) {
+	if len(rs) == 0 {
+		return
+	}
+	return ids, doInTx(c, p.DB, func(tx *sql.Tx) error {
+		for _, r := range rs {
+			id, err := p.Resolutions.Create(c, tx, r)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return nil
+	})
+}
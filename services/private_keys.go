@@ -36,10 +36,14 @@ import (
 
 const (
 	minKeySize = 1024
+	// minOpenIDKeySize is the smallest key allowed to sign OpenID Connect
+	// ID tokens with RS256.
+	minOpenIDKeySize = 2048
 )
 
 const (
 	pKeyHttpSigPurpose = "http-signature"
+	pKeyOpenIDPurpose  = "openid-signing"
 )
 
 type PrivateKeys struct {
@@ -47,12 +51,23 @@ type PrivateKeys struct {
 	Host        string
 	DB          *sql.DB
 	PrivateKeys *models.PrivateKeys
+	Users       *models.Users
 }
 
 func (p *PrivateKeys) GetUserHTTPSignatureKey(c util.Context, userID paths.UUID) (k *rsa.PrivateKey, iri *url.URL, err error) {
 	var kb []byte
+	var isInstanceActor bool
 	err = doInTx(c, p.DB, func(tx *sql.Tx) error {
 		kb, err = p.PrivateKeys.GetByUserID(c, tx, string(userID), pKeyHttpSigPurpose)
+		if err != nil {
+			return err
+		}
+		// Deliveries made by the instance actor, such as forwarded
+		// reports, are retried by its id, but its key is published
+		// at its actor path.
+		var ia *models.User
+		ia, err = p.Users.InstanceActorUser(c, tx)
+		isInstanceActor = ia != nil && ia.ID == string(userID)
 		return err
 	})
 	if err != nil {
@@ -66,10 +81,22 @@ func (p *PrivateKeys) GetUserHTTPSignatureKey(c util.Context, userID paths.UUID)
 		err = errors.New("private key is not of type *rsa.PrivateKey")
 		return
 	}
-	iri = paths.UUIDIRIFor(p.Scheme, p.Host, paths.HttpSigPubKeyKey, userID)
+	if isInstanceActor {
+		iri = paths.ActorIRIFor(p.Scheme, p.Host, paths.HttpSigPubKeyKey, paths.InstanceActor)
+	} else {
+		iri = paths.UUIDIRIFor(p.Scheme, p.Host, paths.HttpSigPubKeyKey, userID)
+	}
 	return
 }
 
+// PurgeDeletedUsers removes the private keys of deleted users once none of
+// their deliveries are in flight or awaiting a retry.
+func (p *PrivateKeys) PurgeDeletedUsers(c util.Context) error {
+	return doInTx(c, p.DB, func(tx *sql.Tx) error {
+		return p.PrivateKeys.DeleteForDeletedUsers(c, tx)
+	})
+}
+
 func (p *PrivateKeys) GetUserHTTPSignatureKeyForInstanceActor(c util.Context) (k *rsa.PrivateKey, iri *url.URL, err error) {
 	var kb []byte
 	err = doInTx(c, p.DB, func(tx *sql.Tx) error {
@@ -91,6 +118,68 @@ func (p *PrivateKeys) GetUserHTTPSignatureKeyForInstanceActor(c util.Context) (k
 	return
 }
 
+// GetOrCreateOpenIDSigningKey returns the key the instance uses to sign
+// OpenID Connect ID tokens. The key belongs to the instance actor and is
+// created the first time it is needed, with at least minOpenIDKeySize bits.
+// When several are created at once, the one stored first is returned by all.
+func (p *PrivateKeys) GetOrCreateOpenIDSigningKey(c util.Context, n int) (k *rsa.PrivateKey, err error) {
+	if n < minOpenIDKeySize {
+		n = minOpenIDKeySize
+	}
+	k, err = p.getOpenIDSigningKey(c)
+	if err != nil || k != nil {
+		return
+	}
+	// Generate the key outside of the transaction, as it is slow.
+	k, err = createRSAPrivateKey(n)
+	if err != nil {
+		return
+	}
+	kb, err := serializeRSAPrivateKey(k)
+	if err != nil {
+		return
+	}
+	err = doInTx(c, p.DB, func(tx *sql.Tx) error {
+		u, err := p.Users.InstanceActorUser(c, tx)
+		if err != nil {
+			return err
+		} else if u == nil {
+			return errors.New("no instance actor exists")
+		}
+		return p.PrivateKeys.CreateIfNotExists(c, tx, u.ID, pKeyOpenIDPurpose, kb)
+	})
+	if err != nil {
+		return
+	}
+	k, err = p.getOpenIDSigningKey(c)
+	if err == nil && k == nil {
+		err = errors.New("OpenID signing key was not stored")
+	}
+	return
+}
+
+// getOpenIDSigningKey returns the stored OpenID Connect signing key, or nil
+// if it does not exist yet.
+func (p *PrivateKeys) getOpenIDSigningKey(c util.Context) (k *rsa.PrivateKey, err error) {
+	err = doInTx(c, p.DB, func(tx *sql.Tx) error {
+		kb, err := p.PrivateKeys.GetInstanceActor(c, tx, pKeyOpenIDPurpose)
+		if err != nil || kb == nil {
+			return err
+		}
+		pk, err := deserializeRSAPrivateKey(kb)
+		if err != nil {
+			return err
+		}
+		var ok bool
+		k, ok = pk.(*rsa.PrivateKey)
+		if !ok {
+			return errors.New("private key is not of type *rsa.PrivateKey")
+		}
+		return nil
+	})
+	return
+}
+
 // CreateKeyFile writes a symmetric key of random bytes to a file.
 func CreateKeyFile(file string) (err error) {
 	c := 32
@@ -0,0 +1,160 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-fed/activity/pub"
+	"github.com/go-fed/activity/streams/vocab"
+	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/util"
+)
+
+// ErrQuarantined is returned when an incoming activity is held for moderation
+// instead of being delivered to the recipient's inbox.
+var ErrQuarantined = errors.New("activity quarantined for moderation")
+
+// Quarantined is an incoming activity held for moderators to approve or
+// reject.
+type Quarantined struct {
+	ID       string
+	Created  time.Time
+	Actor    *url.URL
+	Activity vocab.Type
+	Status   models.QuarantineStatus
+	// Reviewed is the zero time while the activity is pending.
+	Reviewed time.Time
+	// PolicyID and Resolution are those of the policy that quarantined
+	// the activity. They are empty and nil if the policy was deleted.
+	PolicyID   string
+	Resolution *Resolution
+}
+
+type Quarantine struct {
+	DB         *sql.DB
+	Quarantine *models.Quarantine
+}
+
+// Hold puts the activity addressed to the actor in the moderation queue,
+// linked to the Resolution that quarantined it.
+func (q *Quarantine) Hold(c util.Context, actor *url.URL, a pub.Activity, resolutionID string) (id string, err error) {
+	var aid *url.URL
+	if aid, err = pub.GetId(a); err != nil {
+		return
+	}
+	return id, doInTx(c, q.DB, func(tx *sql.Tx) error {
+		id, err = q.Quarantine.Create(c, tx, models.CreateQuarantined{
+			ActorID:      actor,
+			ActivityID:   aid,
+			Activity:     models.ActivityStreams{a},
+			ResolutionID: resolutionID,
+		})
+		return err
+	})
+}
+
+// Get fetches a held activity. It returns nil if there is no such activity.
+func (q *Quarantine) Get(c util.Context, id string) (qa *Quarantined, err error) {
+	return qa, doInTx(c, q.DB, func(tx *sql.Tx) error {
+		found, mq, err := q.Quarantine.Get(c, tx, id)
+		if err != nil || !found {
+			return err
+		}
+		qa, err = toQuarantined(mq)
+		return err
+	})
+}
+
+// List fetches a page of the held activities with the status, oldest first.
+func (q *Quarantine) List(c util.Context, status models.QuarantineStatus, offset, n int) (qs []*Quarantined, err error) {
+	return qs, doInTx(c, q.DB, func(tx *sql.Tx) error {
+		mqs, err := q.Quarantine.GetByStatus(c, tx, status, offset, n)
+		if err != nil {
+			return err
+		}
+		for _, mq := range mqs {
+			qa, err := toQuarantined(mq)
+			if err != nil {
+				return err
+			}
+			qs = append(qs, qa)
+		}
+		return nil
+	})
+}
+
+// Review approves or rejects a pending activity. It does not deliver an
+// approved activity.
+func (q *Quarantine) Review(c util.Context, id string, status models.QuarantineStatus) error {
+	return doInTx(c, q.DB, func(tx *sql.Tx) error {
+		reviewed, err := q.Quarantine.Review(c, tx, id, status)
+		if err != nil {
+			return err
+		} else if !reviewed {
+			return fmt.Errorf("quarantined activity %s does not exist or was already reviewed", id)
+		}
+		return nil
+	})
+}
+
+// Reopen returns an activity with the status to the pending queue, such as an
+// approved activity whose redelivery failed.
+func (q *Quarantine) Reopen(c util.Context, id string, status models.QuarantineStatus) error {
+	return doInTx(c, q.DB, func(tx *sql.Tx) error {
+		reopened, err := q.Quarantine.Reopen(c, tx, id, status)
+		if err != nil {
+			return err
+		} else if !reopened {
+			return fmt.Errorf("quarantined activity %s does not exist or is not %s", id, status)
+		}
+		return nil
+	})
+}
+
+func toQuarantined(mq models.Quarantined) (qa *Quarantined, err error) {
+	qa = &Quarantined{
+		ID:       mq.ID,
+		Created:  mq.Created,
+		Activity: mq.Activity.Type,
+		Status:   mq.Status,
+		PolicyID: mq.PolicyID.String,
+	}
+	if qa.Actor, err = url.Parse(mq.ActorID); err != nil {
+		return
+	}
+	if mq.Reviewed.Valid {
+		qa.Reviewed = mq.Reviewed.Time
+	}
+	if mq.Resolution != nil {
+		qa.Resolution = &Resolution{
+			ID:       mq.ResolutionID.String,
+			Time:     mq.Resolution.Time,
+			Matched:  mq.Resolution.Matched,
+			MatchLog: mq.Resolution.MatchLog,
+			Scope:    mq.Resolution.Scope,
+		}
+		if qa.Resolution.IRI, err = url.Parse(mq.ActivityID); err != nil {
+			return
+		}
+	}
+	return
+}
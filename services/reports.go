@@ -0,0 +1,227 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-fed/activity/pub"
+	"github.com/go-fed/activity/streams/vocab"
+	"github.com/go-fed/apcore/models"
+	"github.com/go-fed/apcore/paths"
+	"github.com/go-fed/apcore/util"
+)
+
+// Report is a report about an actor for moderators to review.
+type Report struct {
+	ID       string
+	Created  time.Time
+	Reporter *url.URL
+	Target   *url.URL
+	Objects  []*url.URL
+	Content  string
+	// Activity is the id of the Flag that was received, or that forwarded
+	// the report. It is nil if there is no such Flag.
+	Activity  *url.URL
+	Forwarded bool
+	// Assignee is nil if no moderator is handling the report.
+	Assignee *url.URL
+	// Resolved is the zero time if the report is still open.
+	Resolved   time.Time
+	Resolution string
+}
+
+type Reports struct {
+	DB      *sql.DB
+	Reports *models.Reports
+	Host    string
+}
+
+// Receive stores the report made by an incoming Flag activity. The reported
+// actor is the first flagged object that is a local user, or otherwise the
+// first flagged object; the other flagged objects are what the report is
+// about. The returned id is empty if the Flag was already received, such as
+// when it was delivered to several local inboxes.
+func (r *Reports) Receive(c util.Context, flag vocab.ActivityStreamsFlag) (id string, err error) {
+	cr := models.CreateReport{}
+	if cr.ActivityID, err = pub.GetId(flag); err != nil {
+		return
+	}
+	ap := flag.GetActivityStreamsActor()
+	if ap == nil || ap.Len() == 0 {
+		err = errors.New("flag has no actor")
+		return
+	}
+	if cr.ReporterID, err = pub.ToId(ap.At(0)); err != nil {
+		return
+	}
+	op := flag.GetActivityStreamsObject()
+	if op == nil || op.Len() == 0 {
+		err = errors.New("flag has no object")
+		return
+	}
+	var objs []*url.URL
+	for iter := op.Begin(); iter != op.End(); iter = iter.Next() {
+		var o *url.URL
+		if o, err = pub.ToId(iter); err != nil {
+			return
+		}
+		objs = append(objs, o)
+	}
+	target := 0
+	for i, o := range objs {
+		if o.Host == r.Host && paths.IsUserPath(o) {
+			target = i
+			break
+		}
+	}
+	cr.TargetID = objs[target]
+	cr.ObjectIDs = append(objs[:target:target], objs[target+1:]...)
+	if cp := flag.GetActivityStreamsContent(); cp != nil {
+		for iter := cp.Begin(); iter != cp.End(); iter = iter.Next() {
+			if iter.IsXMLSchemaString() {
+				cr.Content = iter.GetXMLSchemaString()
+				break
+			} else if iter.IsRDFLangString() {
+				for _, s := range iter.GetRDFLangString() {
+					cr.Content = s
+					break
+				}
+				break
+			}
+		}
+	}
+	return r.create(c, cr)
+}
+
+// File stores a report made by a local user.
+func (r *Reports) File(c util.Context, reporter, target *url.URL, objects []*url.URL, content string) (id string, err error) {
+	return r.create(c, models.CreateReport{
+		ReporterID: reporter,
+		TargetID:   target,
+		ObjectIDs:  objects,
+		Content:    content,
+	})
+}
+
+func (r *Reports) create(c util.Context, cr models.CreateReport) (id string, err error) {
+	return id, doInTx(c, r.DB, func(tx *sql.Tx) error {
+		id, err = r.Reports.Create(c, tx, cr)
+		return err
+	})
+}
+
+// MarkForwarded records the Flag that forwarded the report to the server of
+// the reported actor.
+func (r *Reports) MarkForwarded(c util.Context, id string, activity *url.URL) error {
+	return doInTx(c, r.DB, func(tx *sql.Tx) error {
+		return r.Reports.MarkForwarded(c, tx, id, activity)
+	})
+}
+
+// Get fetches a report. It returns nil if there is no such report.
+func (r *Reports) Get(c util.Context, id string) (rep *Report, err error) {
+	return rep, doInTx(c, r.DB, func(tx *sql.Tx) error {
+		found, mr, err := r.Reports.Get(c, tx, id)
+		if err != nil || !found {
+			return err
+		}
+		rep, err = toReport(mr)
+		return err
+	})
+}
+
+// List fetches a page of either the open or the resolved reports, oldest
+// first.
+func (r *Reports) List(c util.Context, resolved bool, offset, n int) (rs []*Report, err error) {
+	return rs, doInTx(c, r.DB, func(tx *sql.Tx) error {
+		mrs, err := r.Reports.List(c, tx, resolved, offset, n)
+		if err != nil {
+			return err
+		}
+		for _, mr := range mrs {
+			rep, err := toReport(mr)
+			if err != nil {
+				return err
+			}
+			rs = append(rs, rep)
+		}
+		return nil
+	})
+}
+
+// Assign sets the moderator handling the report. A nil assignee unassigns
+// it.
+func (r *Reports) Assign(c util.Context, id string, assignee *url.URL) error {
+	return doInTx(c, r.DB, func(tx *sql.Tx) error {
+		return r.Reports.Assign(c, tx, id, assignee)
+	})
+}
+
+// Resolve closes an open report with the moderator's resolution.
+func (r *Reports) Resolve(c util.Context, id, resolution string) error {
+	return doInTx(c, r.DB, func(tx *sql.Tx) error {
+		resolved, err := r.Reports.Resolve(c, tx, id, resolution)
+		if err != nil {
+			return err
+		} else if !resolved {
+			return fmt.Errorf("report %s does not exist or is already resolved", id)
+		}
+		return nil
+	})
+}
+
+func toReport(mr models.Report) (rep *Report, err error) {
+	rep = &Report{
+		ID:         mr.ID,
+		Created:    mr.Created,
+		Content:    mr.Content,
+		Forwarded:  mr.Forwarded,
+		Resolution: mr.Resolution,
+	}
+	if rep.Reporter, err = url.Parse(mr.ReporterID); err != nil {
+		return
+	}
+	if rep.Target, err = url.Parse(mr.TargetID); err != nil {
+		return
+	}
+	for _, o := range mr.ObjectIDs {
+		var u *url.URL
+		if u, err = url.Parse(o); err != nil {
+			return
+		}
+		rep.Objects = append(rep.Objects, u)
+	}
+	if mr.ActivityID.Valid {
+		if rep.Activity, err = url.Parse(mr.ActivityID.String); err != nil {
+			return
+		}
+	}
+	if mr.AssigneeID.Valid {
+		if rep.Assignee, err = url.Parse(mr.AssigneeID.String); err != nil {
+			return
+		}
+	}
+	if mr.Resolved.Valid {
+		rep.Resolved = mr.Resolved.Time
+	}
+	return
+}
@@ -0,0 +1,228 @@
+// apcore is a server framework for implementing an ActivityPub application.
+// Copyright (C) 2020 Cory Slep
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package services
+
+import (
+	"net/url"
+
+	"github.com/go-fed/activity/pub"
+	"github.com/go-fed/activity/streams"
+	"github.com/go-fed/activity/streams/vocab"
+	"github.com/go-fed/apcore/models"
+)
+
+// sensitiveProperty is the "as:sensitive" extension used to hide media behind
+// a content warning. It is not part of the generated vocabulary, so it is kept
+// among a type's unknown properties.
+const sensitiveProperty = "sensitive"
+
+// rewriteFn rewrites incoming data in place for a matching policy, logging each
+// change to the Resolution.
+type rewriteFn func(t vocab.Type, p models.Policy, r *models.Resolution)
+
+var rewrites = map[models.Purpose]rewriteFn{
+	models.ForceSensitivePurpose: forceSensitive,
+	models.StripMediaPurpose:     stripMedia,
+	models.DropPublicPurpose:     dropPublic,
+	models.DelistPurpose:         delist,
+}
+
+// forceSensitive marks the objects as sensitive. Objects without a
+// content warning are given the policy's name as one.
+func forceSensitive(d vocab.Type, p models.Policy, r *models.Resolution) {
+	for _, t := range objectsOf(d) {
+		u, ok := t.(interface {
+			GetUnknownProperties() map[string]interface{}
+		})
+		if !ok || u.GetUnknownProperties() == nil {
+			r.Logf("rewrite: cannot mark %s as sensitive", describe(t))
+			continue
+		}
+		u.GetUnknownProperties()[sensitiveProperty] = true
+		r.Logf("rewrite: marked %s as sensitive", describe(t))
+		s, ok := t.(interface {
+			GetActivityStreamsSummary() vocab.ActivityStreamsSummaryProperty
+			SetActivityStreamsSummary(vocab.ActivityStreamsSummaryProperty)
+		})
+		if ok && (s.GetActivityStreamsSummary() == nil || s.GetActivityStreamsSummary().Len() == 0) {
+			summary := streams.NewActivityStreamsSummaryProperty()
+			summary.AppendXMLSchemaString(p.Name)
+			s.SetActivityStreamsSummary(summary)
+			r.Logf("rewrite: set content warning of %s to %q", describe(t), p.Name)
+		}
+	}
+}
+
+// stripMedia removes the attachments of the objects.
+func stripMedia(d vocab.Type, p models.Policy, r *models.Resolution) {
+	for _, t := range objectsOf(d) {
+		at, ok := t.(interface {
+			GetActivityStreamsAttachment() vocab.ActivityStreamsAttachmentProperty
+			SetActivityStreamsAttachment(vocab.ActivityStreamsAttachmentProperty)
+		})
+		if !ok || at.GetActivityStreamsAttachment() == nil {
+			continue
+		}
+		n := at.GetActivityStreamsAttachment().Len()
+		at.SetActivityStreamsAttachment(nil)
+		r.Logf("rewrite: removed %d attachments from %s", n, describe(t))
+	}
+}
+
+// dropPublic removes the Public collection from the to and cc of the activity
+// and its embedded objects, or of the object.
+func dropPublic(d vocab.Type, p models.Policy, r *models.Resolution) {
+	for _, t := range addressedBy(d) {
+		if to, ok := t.(interface {
+			GetActivityStreamsTo() vocab.ActivityStreamsToProperty
+		}); ok && removePublic(to.GetActivityStreamsTo()) {
+			r.Logf("rewrite: removed Public from to of %s", describe(t))
+		}
+		if cc, ok := t.(interface {
+			GetActivityStreamsCc() vocab.ActivityStreamsCcProperty
+		}); ok && removePublic(cc.GetActivityStreamsCc()) {
+			r.Logf("rewrite: removed Public from cc of %s", describe(t))
+		}
+	}
+}
+
+// delist moves the Public collection from the to of the activity and its
+// embedded objects, or of the object, to their cc, so they are no longer listed
+// publicly.
+func delist(d vocab.Type, p models.Policy, r *models.Resolution) {
+	for _, t := range addressedBy(d) {
+		to, ok := t.(interface {
+			GetActivityStreamsTo() vocab.ActivityStreamsToProperty
+			GetActivityStreamsCc() vocab.ActivityStreamsCcProperty
+			SetActivityStreamsCc(vocab.ActivityStreamsCcProperty)
+		})
+		if !ok || !removePublic(to.GetActivityStreamsTo()) {
+			continue
+		}
+		cc := to.GetActivityStreamsCc()
+		if cc == nil {
+			cc = streams.NewActivityStreamsCcProperty()
+			to.SetActivityStreamsCc(cc)
+		}
+		if !hasPublic(cc) {
+			if public, err := url.Parse(pub.PublicActivityPubIRI); err == nil {
+				cc.AppendIRI(public)
+			}
+		}
+		r.Logf("rewrite: moved Public from to to cc of %s", describe(t))
+	}
+}
+
+// removePublic removes the Public collection from the to or cc property,
+// returning whether it was present.
+func removePublic(prop interface{}) (removed bool) {
+	it, ok := addressingIters(prop)
+	if !ok {
+		return
+	}
+	for i := it.n - 1; i >= 0; i-- {
+		if id, err := pub.ToId(it.at(i)); err == nil && isPublic(id.String()) {
+			it.remove(i)
+			removed = true
+		}
+	}
+	return
+}
+
+func hasPublic(prop interface{}) bool {
+	it, ok := addressingIters(prop)
+	if !ok {
+		return false
+	}
+	for i := 0; i < it.n; i++ {
+		if id, err := pub.ToId(it.at(i)); err == nil && isPublic(id.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// addressingIter abstracts over the to and cc properties, whose iterators are
+// distinct types.
+type addressingIter struct {
+	n      int
+	at     func(int) pub.IdProperty
+	remove func(int)
+}
+
+func addressingIters(prop interface{}) (it addressingIter, ok bool) {
+	switch p := prop.(type) {
+	case vocab.ActivityStreamsToProperty:
+		return addressingIter{
+			n:      p.Len(),
+			at:     func(i int) pub.IdProperty { return p.At(i) },
+			remove: p.Remove,
+		}, true
+	case vocab.ActivityStreamsCcProperty:
+		return addressingIter{
+			n:      p.Len(),
+			at:     func(i int) pub.IdProperty { return p.At(i) },
+			remove: p.Remove,
+		}, true
+	}
+	return
+}
+
+func isPublic(iri string) bool {
+	return iri == pub.PublicActivityPubIRI || iri == "as:Public" || iri == "Public"
+}
+
+// objectsOf are the objects a rewrite applies to: the embedded objects of an
+// activity, or the object itself.
+func objectsOf(t vocab.Type) []vocab.Type {
+	if a, ok := t.(pub.Activity); ok {
+		return embeddedObjects(a)
+	}
+	return []vocab.Type{t}
+}
+
+// addressedBy are the types whose addressing a rewrite applies to: an activity
+// and its embedded objects, or the object itself.
+func addressedBy(t vocab.Type) []vocab.Type {
+	if a, ok := t.(pub.Activity); ok {
+		return append([]vocab.Type{a}, embeddedObjects(a)...)
+	}
+	return []vocab.Type{t}
+}
+
+// embeddedObjects are the objects of the activity that are embedded rather
+// than referred to by IRI.
+func embeddedObjects(a pub.Activity) (ts []vocab.Type) {
+	op := a.GetActivityStreamsObject()
+	if op == nil {
+		return
+	}
+	for iter := op.Begin(); iter != op.End(); iter = iter.Next() {
+		if t := iter.GetType(); t != nil {
+			ts = append(ts, t)
+		}
+	}
+	return
+}
+
+// describe identifies the type in the Resolution log.
+func describe(t vocab.Type) string {
+	if id, err := pub.GetId(t); err == nil {
+		return id.String()
+	}
+	return "embedded " + t.GetTypeName()
+}
@@ -20,8 +20,10 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/url"
 	"sync"
+	"time"
 
 	"github.com/go-fed/activity/pub"
 	"github.com/go-fed/activity/streams"
@@ -56,6 +58,10 @@ type User struct {
 	ID    string
 	Email string
 	Actor vocab.Type
+	// Suspended is the zero time if the user is not suspended.
+	Suspended time.Time
+	// Deleted is the zero time if the user is not deleted.
+	Deleted time.Time
 }
 
 type Users struct {
@@ -68,6 +74,10 @@ type Users struct {
 	Followers   *models.Followers
 	Following   *models.Following
 	Liked       *models.Liked
+	Featured    *models.Featured
+	// LocalData and TokenInfos are used to purge a deleted user.
+	LocalData  *models.LocalData
+	TokenInfos *models.TokenInfos
 	// muCheck is required to ensure certain database constraints are
 	// enforced and then maintained between different transactions, since
 	// databases are not guaranteed to be able to enforce unique constraints
@@ -207,7 +217,15 @@ func (u *Users) createUser(c util.Context,
 		}
 		// Create the ActivityStreams collections based on the userID.
 		actor, actorID := actor(userID, pubKey)
-		var inbox, outbox vocab.ActivityStreamsOrderedCollection
+		actor.Type, err = withManuallyApprovesFollowers(c, actor.Type, pub.OnFollowBehavior(prefs.OnFollow))
+		if err != nil {
+			return err
+		}
+		actor.Type, err = withEndpoints(c, actor.Type, u.App)
+		if err != nil {
+			return err
+		}
+		var inbox, outbox, featured vocab.ActivityStreamsOrderedCollection
 		inbox, err = emptyInbox(actorID)
 		if err != nil {
 			return err
@@ -216,6 +234,10 @@ func (u *Users) createUser(c util.Context,
 		if err != nil {
 			return err
 		}
+		featured, err = emptyFeatured(actorID)
+		if err != nil {
+			return err
+		}
 		var followers, following, liked vocab.ActivityStreamsCollection
 		followers, err = emptyFollowers(actorID)
 		if err != nil {
@@ -239,7 +261,7 @@ func (u *Users) createUser(c util.Context,
 		if err != nil {
 			return err
 		}
-		// Insert empty inbox, outbox, followers, following, liked
+		// Insert empty inbox, outbox, followers, following, liked, featured
 		err = u.Inboxes.Create(c, tx, actorID, models.ActivityStreamsOrderedCollection{inbox})
 		if err != nil {
 			return err
@@ -256,7 +278,11 @@ func (u *Users) createUser(c util.Context,
 		if err != nil {
 			return err
 		}
-		return u.Liked.Create(c, tx, actorID, models.ActivityStreamsCollection{liked})
+		err = u.Liked.Create(c, tx, actorID, models.ActivityStreamsCollection{liked})
+		if err != nil {
+			return err
+		}
+		return u.Featured.Create(c, tx, actorID, models.ActivityStreamsOrderedCollection{featured})
 	})
 }
 
@@ -341,9 +367,11 @@ func (u *Users) UserByUsername(c util.Context, name string) (s *User, err error)
 			return err
 		}
 		s = &User{
-			ID:    a.ID,
-			Email: a.Email,
-			Actor: vocab.Type(a.Actor),
+			ID:        a.ID,
+			Email:     a.Email,
+			Actor:     vocab.Type(a.Actor),
+			Suspended: a.Suspended.Time,
+			Deleted:   a.Deleted.Time,
 		}
 		return nil
 	})
@@ -357,14 +385,92 @@ func (u *Users) UserByID(c util.Context, id paths.UUID) (s *User, err error) {
 			return err
 		}
 		s = &User{
-			ID:    a.ID,
-			Email: a.Email,
-			Actor: a.Actor.Type,
+			ID:        a.ID,
+			Email:     a.Email,
+			Actor:     a.Actor.Type,
+			Suspended: a.Suspended.Time,
+			Deleted:   a.Deleted.Time,
+		}
+		return nil
+	})
+}
+
+// SuspendUser suspends the user and revokes its OAuth2 tokens. A suspended
+// user cannot log in nor federate, and its actor is served as a Tombstone.
+func (u *Users) SuspendUser(c util.Context, uuid paths.UUID) error {
+	return doInTx(c, u.DB, func(tx *sql.Tx) error {
+		if err := u.checkNotInstanceActor(c, tx, uuid); err != nil {
+			return err
+		}
+		if err := u.Users.Suspend(c, tx, string(uuid)); err != nil {
+			return err
+		}
+		return u.TokenInfos.RemoveForUser(c, tx, string(uuid))
+	})
+}
+
+// UnsuspendUser lifts a suspension of the user.
+func (u *Users) UnsuspendUser(c util.Context, uuid paths.UUID) error {
+	return doInTx(c, u.DB, func(tx *sql.Tx) error {
+		return u.Users.Unsuspend(c, tx, string(uuid))
+	})
+}
+
+// DeleteUser purges the user's local data and OAuth2 tokens.
+//
+// The user itself is kept as a tombstone so that its username cannot be
+// reused. Its private keys are kept so that pending deliveries, such as its
+// Delete, can still be signed when retried; PrivateKeys.PurgeDeletedUsers
+// removes them once those deliveries are done.
+func (u *Users) DeleteUser(c util.Context, uuid paths.UUID) error {
+	return doInTx(c, u.DB, func(tx *sql.Tx) error {
+		if err := u.checkNotInstanceActor(c, tx, uuid); err != nil {
+			return err
+		}
+		a, err := u.Users.UserByID(c, tx, string(uuid))
+		if err != nil {
+			return err
+		}
+		actorID, err := pub.GetId(a.Actor.Type)
+		if err != nil {
+			return err
+		}
+		if err := u.LocalData.DeleteForActor(c, tx, actorID); err != nil {
+			return err
+		}
+		if err := u.TokenInfos.RemoveForUser(c, tx, string(uuid)); err != nil {
+			return err
 		}
+		return u.Users.MarkDeleted(c, tx, string(uuid))
+	})
+}
+
+// InstanceActorID returns the id of the user representing the instance.
+func (u *Users) InstanceActorID(c util.Context) (id paths.UUID, err error) {
+	return id, doInTx(c, u.DB, func(tx *sql.Tx) error {
+		a, err := u.Users.InstanceActorUser(c, tx)
+		if err != nil {
+			return err
+		} else if a == nil {
+			return errors.New("no instance actor exists")
+		}
+		id = paths.UUID(a.ID)
 		return nil
 	})
 }
 
+func (u *Users) checkNotInstanceActor(c util.Context, tx *sql.Tx, uuid paths.UUID) error {
+	a, err := u.Users.UserByID(c, tx, string(uuid))
+	if err != nil {
+		return err
+	} else if a == nil {
+		return fmt.Errorf("no user with id %q", uuid)
+	} else if a.Privileges.InstanceActor {
+		return errors.New("cannot suspend nor delete the instance actor")
+	}
+	return nil
+}
+
 type Preferences struct {
 	OnFollow       pub.OnFollowBehavior
 	AppPreferences interface{}
@@ -456,15 +562,83 @@ func (u *Users) Privileges(c util.Context, uuid string, appPriv interface{}) (p
 	return
 }
 
+// UpdatePreferences also updates the manuallyApprovesFollowers property of
+// the user's actor to reflect the OnFollow preference.
 func (u *Users) UpdatePreferences(c util.Context, uuid string, p *Preferences) (err error) {
 	var pref models.Preferences
 	pref, err = p.toModel()
+	if err != nil {
+		return
+	}
 	err = doInTx(c, u.DB, func(tx *sql.Tx) error {
-		return u.Users.UpdatePreferences(c, tx, uuid, pref)
+		if err := u.Users.UpdatePreferences(c, tx, uuid, pref); err != nil {
+			return err
+		}
+		a, err := u.Users.UserByID(c, tx, uuid)
+		if err != nil {
+			return err
+		}
+		actor, err := withManuallyApprovesFollowers(c, a.Actor.Type, p.OnFollow)
+		if err != nil {
+			return err
+		}
+		actor, err = withEndpoints(c, actor, u.App)
+		if err != nil {
+			return err
+		}
+		return u.Users.UpdateActor(c, tx, uuid, models.ActivityStreams{actor})
 	})
 	return
 }
 
+// BackfillFeatured gives the users created before featured collections were
+// supported an empty featured collection, and sets the featured property of
+// their actors so that remote servers can discover it.
+func (u *Users) BackfillFeatured(c util.Context) error {
+	var ids []string
+	err := doInTx(c, u.DB, func(tx *sql.Tx) (err error) {
+		ids, err = u.Users.UserIDsWithoutFeatured(c, tx)
+		return
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		err = doInTx(c, u.DB, func(tx *sql.Tx) error {
+			a, err := u.Users.UserByID(c, tx, id)
+			if err != nil {
+				return err
+			}
+			actorID, err := pub.GetId(a.Actor.Type)
+			if err != nil {
+				return err
+			}
+			if err := ensureLocalFeatured(c, tx, u.Featured, actorID); err != nil {
+				return err
+			}
+			f, ok := a.Actor.Type.(interface {
+				GetTootFeatured() vocab.TootFeaturedProperty
+				SetTootFeatured(vocab.TootFeaturedProperty)
+			})
+			if !ok || f.GetTootFeatured() != nil {
+				return nil
+			}
+			featured, err := paths.IRIForActorID(paths.FeaturedPathKey, actorID)
+			if err != nil {
+				return err
+			}
+			fp := streams.NewTootFeaturedProperty()
+			fp.SetIRI(featured)
+			f.SetTootFeatured(fp)
+			return u.Users.UpdateActor(c, tx, id, a.Actor)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (u *Users) UpdatePrivileges(c util.Context, uuid string, p *Privileges) (err error) {
 	var priv models.Privileges
 	priv, err = p.toModel()
@@ -496,6 +670,7 @@ func (u *Users) GetServerPreferences(c util.Context) (p ServerPreferences, err e
 		OpenRegistrations: iap.OpenRegistrations,
 		ServerBaseURL:     iap.ServerBaseURL,
 		ServerName:        iap.ServerName,
+		ServerDescription: iap.ServerDescription,
 		OrgName:           iap.OrgName,
 		OrgContact:        iap.OrgContact,
 		OrgAccount:        iap.OrgAccount,
@@ -510,6 +685,7 @@ func (u *Users) SetServerPreferences(c util.Context, p ServerPreferences) (err e
 		OpenRegistrations: p.OpenRegistrations,
 		ServerBaseURL:     p.ServerBaseURL,
 		ServerName:        p.ServerName,
+		ServerDescription: p.ServerDescription,
 		OrgName:           p.OrgName,
 		OrgContact:        p.OrgContact,
 		OrgAccount:        p.OrgAccount,
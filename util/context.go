@@ -35,6 +35,8 @@ const (
 	actorIRIContextKey           = "actorIRI"
 	completeRequestURLContextKey = "completeRequestURL"
 	privateScopeContextKey       = "privateScope"
+	quarantineApprovedContextKey = "quarantineApproved"
+	openIDNonceContextKey        = "openIDNonce"
 )
 
 type Context struct {
@@ -92,6 +94,19 @@ func (c *Context) WithPrivateScope(b bool) {
 	c.Context = context.WithValue(c.Context, privateScopeContextKey, b)
 }
 
+// WithQuarantineApproved is set when an approved quarantined activity is
+// redelivered to its recipient's inbox.
+func (c *Context) WithQuarantineApproved() {
+	c.Context = context.WithValue(c.Context, quarantineApprovedContextKey, true)
+}
+
+// WithOpenIDNonce is set in OAuth2 authorization and token requests. It holds
+// the OpenID Connect nonce stored with a new authorization code, and receives
+// the nonce of a code being exchanged so it is stored with the new tokens.
+func (c *Context) WithOpenIDNonce(n *string) {
+	c.Context = context.WithValue(c.Context, openIDNonceContextKey, n)
+}
+
 // Activity is available in federating contexts.
 func (c Context) Activity() (t pub.Activity, err error) {
 	v := c.Value(activityContextKey)
@@ -131,6 +146,19 @@ func (c Context) CompleteRequestURL() (u *url.URL, err error) {
 	return c.toURLValue("complete Request URL", completeRequestURLContextKey)
 }
 
+// IsQuarantineApproved is true when redelivering an approved quarantined
+// activity.
+func (c Context) IsQuarantineApproved() bool {
+	b, _ := c.Value(quarantineApprovedContextKey).(bool)
+	return b
+}
+
+// OpenIDNonce is available in OAuth2 authorization and token requests.
+func (c Context) OpenIDNonce() (n *string, ok bool) {
+	n, ok = c.Value(openIDNonceContextKey).(*string)
+	return
+}
+
 // HasPrivateScope is available in all GET http requests.
 func (c *Context) HasPrivateScope() bool {
 	v := c.Value(privateScopeContextKey)